@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+/* The compressing framer.
+
+This is a framer for use in MessageConnections, which sits on top of another framer. It implements the Framer
+interface.
+
+Each message is prefixed with a single byte saying how the rest of it is encoded: either as is, or compressed with
+gzip. The whole thing is then sent by the underlying framer, so for the pre length framer the length field gives the
+length of the compressed bytes.
+
+The receiver handles either encoding for every message, so only the sender has to choose whether to compress. By
+default we don't, and once compression is enabled we only compress messages large enough to benefit, so that small
+control messages are not slowed down.
+
+*/
+
+package comms
+
+import "bytes"
+import "compress/gzip"
+import "fmt"
+import "io"
+
+
+// The compression types that may be passed to SetCompression.
+const (
+    CompressionNone = "none"
+    CompressionGzip = "gzip"
+)
+
+
+// compressionThreshold - Messages shorter than this are never compressed.
+const compressionThreshold = 4096
+
+
+// The values of the byte at the start of each message.
+const (
+    frameRaw = 0
+    frameGzip = 1
+)
+
+
+// External API.
+
+// makeCompressingFramer - Make a compressing framer that sits on top of the given framer.
+func makeCompressingFramer(framer Framer) *compressingFramer {
+    var cf compressingFramer
+    cf.framer = framer
+    cf.compression = CompressionNone
+    return &cf
+}
+
+
+// SetCompression - Set how we compress the large messages that we send.
+func (me *compressingFramer) SetCompression(compression string) error {
+    if (compression != CompressionNone) && (compression != CompressionGzip) {
+        return fmt.Errorf("Bad compression: %v.  Should be %v or %v", compression, CompressionNone, CompressionGzip)
+    }
+
+    me.compression = compression
+    return nil
+}
+
+
+// Send - Send the given message.
+func (me *compressingFramer) Send(message []byte) error {
+    var buf bytes.Buffer
+
+    if (me.compression == CompressionGzip) && (len(message) >= compressionThreshold) {
+        buf.WriteByte(frameGzip)
+
+        // BestSpeed gets most of the benefit for our stats, which compress well, for a fraction of the CPU.
+        zw, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+        if err != nil { return err }  // Propogate error.
+
+        _, err = zw.Write(message)
+        if err != nil { return err }  // Propogate error.
+
+        err = zw.Close()
+        if err != nil { return err }  // Propogate error.
+    } else {
+        buf.WriteByte(frameRaw)
+        buf.Write(message)
+    }
+
+    return me.framer.Send(buf.Bytes())
+}
+
+
+// Receive - Blocking call to receive the next message.
+func (me *compressingFramer) Receive() (message []byte, err error) {
+    frame, err := me.framer.Receive()
+    if err != nil { return nil, err }  // Propogate error.
+
+    if len(frame) == 0 {
+        return nil, fmt.Errorf("Received empty frame")
+    }
+
+    switch frame[0] {
+        case frameRaw:
+            return frame[1:], nil
+
+        case frameGzip:
+            zr, err := gzip.NewReader(bytes.NewReader(frame[1:]))
+            if err != nil {
+                return nil, fmt.Errorf("Error decompressing received message, %v", err)
+            }
+
+            // The maximum frame size applies to what we decompress too, so that a small frame can't make us
+            // allocate more than a large one could.
+            message, err = io.ReadAll(io.LimitReader(zr, int64(maxFrameSize) + 1))
+            if err != nil {
+                return nil, fmt.Errorf("Error decompressing received message, %v", err)
+            }
+
+            if uint(len(message)) > maxFrameSize {
+                return nil, fmt.Errorf("Received compressed frame larger than the maximum of %d bytes", maxFrameSize)
+            }
+
+            return message, nil
+    }
+
+    return nil, fmt.Errorf("Received frame with unknown encoding %v", frame[0])
+}
+
+
+// Internals.
+
+// compressingFramer - A framer that may compress each message before passing it to another framer.
+type compressingFramer struct {
+    framer Framer
+    compression string
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the compressing framer.
+
+package comms
+
+import "bytes"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+// makeCompressibleMessage - Make a message which compresses well, as our stats do.
+func makeCompressibleMessage(length int) []byte {
+    return bytes.Repeat([]byte{1, 2, 3, 4, 0, 0, 0, 0}, length / 8)
+}
+
+
+// sendAndReceive - Send a message with the given compression, and return both what went over the wire and what the
+// receiver made of it.
+func sendAndReceive(t *testing.T, compression string, message []byte) (wire []byte, received []byte) {
+    sendConn := makeTestByteConn(nil)
+    sender := makeCompressingFramer(makePreLengthFramer(sendConn))
+
+    err := sender.SetCompression(compression)
+    testutil.CheckNoError(t, err)
+
+    err = sender.Send(message)
+    testutil.CheckNoError(t, err)
+
+    wire = sendConn.WriteBytes()
+
+    receiveConn := makeTestByteConn(wire)
+    receiver := makeCompressingFramer(makePreLengthFramer(receiveConn))
+
+    received, err = receiver.Receive()
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 0, receiveConn.UnreadByteCount())
+
+    return wire, received
+}
+
+
+// Test functions.
+
+// Without compression, a message just gains its encoding byte.
+func TestCompressingFramerNone(t *testing.T) {
+    wire, received := sendAndReceive(t, CompressionNone, []byte{4, 5})
+
+    testutil.CheckBytes(t, []byte{3, 0, 0, 0, frameRaw, 4, 5}, wire)
+    testutil.CheckBytes(t, []byte{4, 5}, received)
+}
+
+
+// A large message is compressed, and the length field frames the compressed bytes.
+func TestCompressingFramerGzipLarge(t *testing.T) {
+    message := makeCompressibleMessage(64 * 1024)
+    wire, received := sendAndReceive(t, CompressionGzip, message)
+
+    testutil.CheckBool(t, true, len(wire) < len(message) / 10)
+    testutil.CheckInt(t, frameGzip, int(wire[4]))
+
+    frameLen := int(wire[0]) | (int(wire[1]) << 8) | (int(wire[2]) << 16) | (int(wire[3]) << 24)
+    testutil.CheckInt(t, len(wire) - 4, frameLen)
+
+    testutil.CheckBytes(t, message, received)
+}
+
+
+// A small message is sent as is, even with compression enabled.
+func TestCompressingFramerGzipSmall(t *testing.T) {
+    message := makeCompressibleMessage(compressionThreshold - 8)
+    wire, received := sendAndReceive(t, CompressionGzip, message)
+
+    testutil.CheckInt(t, frameRaw, int(wire[4]))
+    testutil.CheckBytes(t, message, received)
+}
+
+
+// A compressed message which is too large once decompressed is rejected.
+func TestCompressingFramerGzipOversized(t *testing.T) {
+    sendConn := makeTestByteConn(nil)
+    sender := makeCompressingFramer(makePreLengthFramer(sendConn))
+    sender.SetCompression(CompressionGzip)
+
+    err := sender.Send(makeCompressibleMessage(64 * 1024))
+    testutil.CheckNoError(t, err)
+
+    SetMaxFrameSize(32 * 1024)
+    defer SetMaxFrameSize(DefaultMaxFrameSize)
+
+    receiver := makeCompressingFramer(makePreLengthFramer(makeTestByteConn(sendConn.WriteBytes())))
+    _, err = receiver.Receive()
+    testutil.CheckError(t, err)
+}
+
+
+// Unknown compression types are rejected.
+func TestCompressingFramerBadCompression(t *testing.T) {
+    framer := makeCompressingFramer(makePreLengthFramer(makeTestByteConn(nil)))
+
+    err := framer.SetCompression("zip")
+    testutil.CheckError(t, err)
+}
+
+
+// Frames with an unknown encoding byte are rejected.
+func TestCompressingFramerBadEncoding(t *testing.T) {
+    conn := makeTestByteConn([]byte{2, 0, 0, 0, 9, 4})
+    framer := makeCompressingFramer(makePreLengthFramer(conn))
+
+    _, err := framer.Receive()
+    testutil.CheckError(t, err)
+}
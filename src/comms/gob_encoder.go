@@ -26,7 +26,7 @@ func MakeGobEncoderFactory() EncoderFactory {
 
 // Make - Make a new Gob encoder that sits on top of the given byte connection.
 func (me *gobEncoderFactory) Make(connection ByteConnection) Encoder {
-    framer := makePreLengthFramer(connection)
+    framer := makeCompressingFramer(makePreLengthFramer(connection))
     encoder := makeGobEncoder(framer)
     return encoder
 }
@@ -65,6 +65,12 @@ func (me *gobEncoder) Receive() (ReceivedMessage, error) {
 }
 
 
+// SetCompression - Set how we compress the large messages that we send.
+func (me *gobEncoder) SetCompression(compression string) error {
+    return me.framer.SetCompression(compression)
+}
+
+
 // Received message external API.
 
 // ID - Report our message ID.
@@ -89,7 +95,7 @@ type gobEncoderFactory struct {
 
 // gobEncoder - An encoder that packs everything in Gob.
 type gobEncoder struct {
-    framer Framer
+    framer *compressingFramer
 
 }
 
@@ -102,7 +108,7 @@ type gobReceivedMessage struct {
 
 
 // makeGobEncoder - Make a Gob encoder that sits on top of the given framer.
-func makeGobEncoder(framer Framer) *gobEncoder {
+func makeGobEncoder(framer *compressingFramer) *gobEncoder {
     var encoder gobEncoder
     encoder.framer = framer
     return &encoder
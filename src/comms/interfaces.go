@@ -34,6 +34,9 @@ type Encoder interface {
 
     // Receive - Blocking call to receive, and decode, the next message.
     Receive() (ReceivedMessage, error)
+
+    // SetCompression - Set how we compress the large messages that we send: CompressionNone or CompressionGzip.
+    SetCompression(compression string) error
 }
 
 
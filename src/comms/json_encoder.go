@@ -39,7 +39,7 @@ func MakeJSONEncoderFactory() EncoderFactory {
 
 // Make - Make a new JSON encoder that sits on top of the given byte connection.
 func (me *jsonEncoderFactory) Make(connection ByteConnection) Encoder {
-    framer := makePreLengthFramer(connection)
+    framer := makeCompressingFramer(makePreLengthFramer(connection))
     encoder := makeJSONEncoder(framer)
     return encoder
 }
@@ -82,6 +82,12 @@ func (me *jsonEncoder) Receive() (ReceivedMessage, error) {
 }
 
 
+// SetCompression - Set how we compress the large messages that we send.
+func (me *jsonEncoder) SetCompression(compression string) error {
+    return me.framer.SetCompression(compression)
+}
+
+
 // Received message external API.
 
 // ID - Report our message ID.
@@ -109,7 +115,7 @@ type jsonEncoderFactory struct {
 
 // jsonEncoder - An encoder that packs everything in JSON.
 type jsonEncoder struct {
-    framer Framer
+    framer *compressingFramer
 }
 
 // jsonReceivedMessage - A message received by a JSON encoder.
@@ -120,7 +126,7 @@ type jsonReceivedMessage struct {
 
 
 // makeJSONEncoder - Make a JSON encoder that sits on top of the given framer.
-func makeJSONEncoder(framer Framer) *jsonEncoder {
+func makeJSONEncoder(framer *compressingFramer) *jsonEncoder {
     var encoder jsonEncoder
     encoder.framer = framer
     return &encoder
@@ -7,6 +7,9 @@ This is a simple framer for use in MessageConnections. It implements the Framer
 
 The framer prepends a length field onto messages. The length field is always 4 bytes and little endian.
 
+The receiver refuses any frame longer than the maximum frame size, rather than trusting a corrupt or hostile length
+field and trying to allocate however much memory it asks for.
+
 */
 
 package comms
@@ -14,8 +17,24 @@ package comms
 import "fmt"
 
 
+// DefaultMaxFrameSize - The default for the largest frame we will receive. Our largest messages are the chunks of
+// stats that servers upload, which come to less than 10MB even in JSON, so this leaves plenty of headroom.
+const DefaultMaxFrameSize = 256 * 1024 * 1024
+
+
+// maxFrameSize - The largest frame we will receive.
+var maxFrameSize uint = DefaultMaxFrameSize
+
+
 // External API.
 
+// SetMaxFrameSize - Set the largest frame, in bytes, that pre length framers will receive. Larger frames give an error
+// on the receiving end. Must be called before any connections are made.
+func SetMaxFrameSize(size uint) {
+    maxFrameSize = size
+}
+
+
 // makePreLengthFramer - Make a pre length framer that sits on top of the given byte connection.
 func makePreLengthFramer(conn ByteConnection) Framer {
     var framer preLengthFramer
@@ -53,7 +72,11 @@ func (me *preLengthFramer) Receive() (message []byte, err error) {
     if err != nil { return nil, err }  // Propogate error.
 
     messageLen := uint(header[0]) | (uint(header[1]) << 8) | (uint(header[2]) << 16) | (uint(header[3]) << 24)
-    // TODO: Do we want to impose any limits on this length as a sanity check?
+
+    // Check the length before we allocate anything for it.
+    if messageLen > maxFrameSize {
+        return nil, fmt.Errorf("Received frame of %d bytes, larger than the maximum of %d", messageLen, maxFrameSize)
+    }
 
     // Now we can get the message body.
     message, err = me.receiveBytes(messageLen)
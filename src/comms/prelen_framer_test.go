@@ -103,6 +103,38 @@ func TestPrelenFramerDecodeExcessData(t *testing.T) {
 }
 
 
+// Reject a frame claiming to be larger than the maximum, without trying to read it.
+func TestPrelenFramerDecodeOversized(t *testing.T) {
+    readBytes := []byte{0xFF, 0xFF, 0xFF, 0xFF, 4, 5, 6}
+
+    conn := makeTestByteConn(readBytes)
+    framer := makePreLengthFramer(conn)
+
+    message, err := framer.Receive()
+
+    testutil.CheckError(t, err)
+    testutil.CheckBool(t, true, message == nil)
+    testutil.CheckInt(t, 3, conn.UnreadByteCount())
+}
+
+
+// A lower maximum frame size applies to small frames too.
+func TestPrelenFramerDecodeMaxFrameSize(t *testing.T) {
+    SetMaxFrameSize(3)
+    defer SetMaxFrameSize(DefaultMaxFrameSize)
+
+    conn := makeTestByteConn([]byte{3, 0, 0, 0, 4, 5, 6, 4, 0, 0, 0, 7, 8, 9, 10})
+    framer := makePreLengthFramer(conn)
+
+    message, err := framer.Receive()
+    testutil.CheckNoError(t, err)
+    testutil.CheckBytes(t, []byte{4, 5, 6}, message)
+
+    _, err = framer.Receive()
+    testutil.CheckError(t, err)
+}
+
+
 // Decode 2 message from a single stream.
 func TestPrelenFramerDecode2(t *testing.T) {
     readBytes := []byte{3, 0, 0, 0, 4, 5, 6, 2, 0, 0, 0, 7, 8}
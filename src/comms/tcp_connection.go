@@ -32,6 +32,11 @@ Once a message connection is established, messages can be received in either of
 It is not possible to use both of these on a single connection. Once a receive channel has been providing, Receive()
 must not be called.
 
+When receiving to a channel, a failure of the connection (including the other end closing it) is sent to the channel
+as an error, after which nothing more is sent. Nothing is sent when we close the connection ourselves: Close() waits
+for the receiving Goroutine to exit, so the channel is never written to once it returns. Closed() provides a channel
+for anything that needs to know when that happens.
+
 Whichever method is used for receiving, messages are sent with the Send() method.
 
 */
@@ -40,7 +45,9 @@ package comms
 
 import "fmt"
 import "io"
+import "logger"
 import "net"
+import "sync"
 import "time"
 
 // TCPMessageFmt - Format of TCP messages.
@@ -51,11 +58,63 @@ type TCPMessageFmt struct {
 }
 
 
+// noDelay - Whether we disable Nagle's algorithm (set TCP_NODELAY) on new connections.
+// Our messages are small and latency-sensitive, so this is on by default.
+var noDelay = true
+
+
+// keepAlive - The interval between TCP keepalive probes on new connections, or 0 for no keepalives.
+// A run can sit quietly for a long time between phases, so this lets us notice a peer that has vanished, and stops
+// firewalls and NAT from dropping an idle connection.  This is the same interval that Go would use by default.
+var keepAlive = 15 * time.Second
+
+
+// The wire formats that MakeEncoderFactory can use.
+const (
+    WireFormatGob = "gob"
+    WireFormatJSON = "json"
+)
+
+
+// wireFormat - The encoding that MakeEncoderFactory uses.  Gob is far cheaper than JSON for the large
+// batches of stats that we upload at the end of each phase, so it is our default.
+var wireFormat = WireFormatGob
+
+
 // External API.
 
-// MakeEncoderFactory - Make a factory for our default encoder.
+// SetNoDelay - Set whether new connections (both dialled and accepted) disable Nagle's algorithm.
+// Must be called before any connections are made.
+func SetNoDelay(enabled bool) {
+    noDelay = enabled
+}
+
+
+// SetKeepAlive - Set the interval between TCP keepalive probes on new connections (both dialled and accepted).
+// An interval of 0 turns keepalives off.  Must be called before any connections are made.
+func SetKeepAlive(interval time.Duration) {
+    keepAlive = interval
+}
+
+
+// SetWireFormat - Set the encoding that MakeEncoderFactory uses: WireFormatGob or WireFormatJSON.
+// Both ends of a connection must use the same format.  Must be called before any connections are made.
+func SetWireFormat(format string) error {
+    if (format != WireFormatGob) && (format != WireFormatJSON) {
+        return fmt.Errorf("Bad wire format: %v.  Should be %v or %v", format, WireFormatGob, WireFormatJSON)
+    }
+
+    wireFormat = format
+    return nil
+}
+
+
+// MakeEncoderFactory - Make a factory for the encoder selected with SetWireFormat.
 func MakeEncoderFactory() EncoderFactory {
-    // return MakeJSONEncoderFactory()
+    if wireFormat == WireFormatJSON {
+        return MakeJSONEncoderFactory()
+    }
+
     return MakeGobEncoderFactory()
 }
 
@@ -116,18 +175,29 @@ func ConnectTCP(address string, encoder EncoderFactory, timeout time.Duration) (
 }
 
 
-// Close - Close this connection.
+// Close - Close this connection. Safe to call more than once.
+// If we have a receive channel, this waits for our receiving Goroutine to exit, so nothing more will be sent to the
+// channel once it returns.
 func (me *MessageConnection) Close() {
-    // Tell our underlying connection to close.
-    me.conn.Close()
+    me.closeOnce.Do(func() {
+        close(me.closed)
 
-    // If we have a receive channel, send nil to it.
-    if me.rxChannel != nil {
-        me.rxChannel<- nil
+        // Tell our underlying connection to close, which wakes up anything blocked receiving on it.
+        me.conn.Close()
+    })
+
+    if me.receiverDone != nil {
+        <-me.receiverDone
     }
 }
 
 
+// Closed - Return a channel which is closed once Close() has been called.
+func (me *MessageConnection) Closed() <-chan struct{} {
+    return me.closed
+}
+
+
 // RemoteIP - Report the address of the machine at the other end of this connection, in IP:port form.
 func (me *MessageConnection) RemoteIP() string {
     return me.conn.RemoteAddr().String()
@@ -140,19 +210,49 @@ func (me* MessageConnection) Send(MessageID uint8, data interface{}) error {
 }
 
 
-// Receive - Receive a single message, blocking until one is available.
+// SetCompression - Set how we compress the large messages that we send: CompressionNone or CompressionGzip.
+// The other end handles either, so it doesn't need to be told.  Like Send, must not be called at the same time as
+// any other send on this connection.
+func (me *MessageConnection) SetCompression(compression string) error {
+    return me.encoder.SetCompression(compression)
+}
+
+
+// Receive - Receive a single message, blocking until one is available or the timeout expires.
+// The timeout is optional, pass 0 for no timeout. If it expires part way through a message, then the rest of that
+// message is still waiting to be read, so the connection should be closed rather than used again.
 // May not be called after a receive channel has been provided.
 func (me *MessageConnection) Receive(timeout time.Duration) (ReceivedMessage, error) {
     if me.rxChannel != nil {
         return nil, fmt.Errorf("Cannot call Receive() on a MessageConnection that has a receive channel")
     }
 
-    // TODO: Handle timeout.
+    if timeout != 0 {
+        err := me.conn.SetReadDeadline(time.Now().Add(timeout))
+        if err != nil { return nil, err }  // Propogate error.
+
+        // Clear the deadline again, so that it doesn't apply to whatever is received next.
+        defer me.conn.SetReadDeadline(time.Time{})
+    }
 
     return me.encoder.Receive()
 }
 
 
+// SendReceive - Send the given message, and then receive a single message in response, as for Receive.
+// May not be called after a receive channel has been provided.
+func (me *MessageConnection) SendReceive(MessageID uint8, data interface{}, timeout time.Duration) (ReceivedMessage, error) {
+    if me.rxChannel != nil {
+        return nil, fmt.Errorf("Cannot call SendReceive() on a MessageConnection that has a receive channel")
+    }
+
+    err := me.Send(MessageID, data)
+    if err != nil { return nil, err }  // Propogate error.
+
+    return me.Receive(timeout)
+}
+
+
 // ReceiveToChannel - Start receiving messages in the background and send them to the given channel.
 // Kicks off a Goroutine to handle receiving.
 // Once this has been called, messages may not be received by calling Receive() or SendReceive().
@@ -163,6 +263,7 @@ func (me *MessageConnection) ReceiveToChannel(notify chan<- *ReceivedMessageInfo
     }
 
     me.rxChannel = notify
+    me.receiverDone = make(chan struct{})
 
     // Kick off a Goroutine to receive messages.
     go me.processReceives()
@@ -181,6 +282,9 @@ type MessageConnection struct {
     conn net.Conn  // Underlying TCP connection.
     rxChannel chan<- *ReceivedMessageInfo
     encoder Encoder
+    closed chan struct{}  // Closed by Close().
+    closeOnce sync.Once
+    receiverDone chan struct{}  // Closed when our receiving Goroutine exits, if we have one.
 }
 
 
@@ -188,9 +292,27 @@ type MessageConnection struct {
 
 // makeMessageConn - Make a message connection based on the given TCP connection.
 func makeMessageConn(conn net.Conn, encoderFactory EncoderFactory) *MessageConnection {
+    if tcpConn, ok := conn.(*net.TCPConn); ok {
+        err := tcpConn.SetNoDelay(noDelay)
+        if err != nil {
+            // Not fatal: the connection still works, just with different latency.
+            logger.Warnf("Failure setting TCP_NODELAY on connection to %v: %v\n", conn.RemoteAddr(), err)
+        }
+
+        err = tcpConn.SetKeepAlive(keepAlive > 0)
+        if (err == nil) && (keepAlive > 0) {
+            err = tcpConn.SetKeepAlivePeriod(keepAlive)
+        }
+
+        if err != nil {
+            logger.Warnf("Failure setting TCP keepalive on connection to %v: %v\n", conn.RemoteAddr(), err)
+        }
+    }
+
     var mc MessageConnection
     mc.conn = conn
     mc.encoder = encoderFactory.Make(conn)
+    mc.closed = make(chan struct{})
     return &mc
 }
 
@@ -216,15 +338,22 @@ func acceptTCP(listener net.Listener, encoders EncoderFactory, notify chan<- *Me
 
 
 // processReceives - Process messages received on the given connection and send them via the given channel.
-// Only returns on connection failure.
+// Only returns on connection failure, or when we are closed.
 // Should be called as a Goroutine.
 func (me *MessageConnection) processReceives() {
+    defer close(me.receiverDone)
+
     for {
         // Try to get a packet.
         message, err := me.encoder.Receive()
 
-        // TODO: Handle connection closing.
-        // TODO: Should we exit on error?
+        // If we've been closed, then any error is just the result of that, and nobody is waiting for it.
+        select {
+            case <-me.closed:
+                return
+
+            default:
+        }
 
         // Wrap up the message so we can put in on the channel.
         var info ReceivedMessageInfo
@@ -232,7 +361,13 @@ func (me *MessageConnection) processReceives() {
         info.Connection = me
         info.Error = err
 
-        me.rxChannel<- &info
+        // Whoever reads the channel may stop doing so once they've closed us, so we mustn't block forever.
+        select {
+            case me.rxChannel<- &info:
+
+            case <-me.closed:
+                return
+        }
 
         if err != nil {
             // Something's gone wrong with the connection, give up and close it.
@@ -244,4 +379,3 @@ func (me *MessageConnection) processReceives() {
         }
     }
 }
-
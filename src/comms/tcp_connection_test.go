@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for message connections, using a paired in-memory connection in place of TCP.
+
+package comms
+
+import "errors"
+import "net"
+import "os"
+import "runtime"
+import "testing"
+import "time"
+import "silib/testutil"
+
+
+// Helper functions.
+
+// makeTestMessageConnPair - Make a pair of message connections, each of which talks to the other.
+func makeTestMessageConnPair() (*MessageConnection, *MessageConnection) {
+    a, b := net.Pipe()
+    return makeMessageConn(a, MakeGobEncoderFactory()), makeMessageConn(b, MakeGobEncoderFactory())
+}
+
+
+// testPayload - Message data for our tests.
+type testPayload struct {
+    Name string
+    Value int
+}
+
+
+// echoOnce - Receive a single message and send it back with its value doubled.
+// Should be called as a Goroutine.
+func echoOnce(conn *MessageConnection) {
+    msg, err := conn.Receive(0)
+    if err != nil {
+        return
+    }
+
+    var p testPayload
+    msg.Data(&p)
+    p.Value *= 2
+    conn.Send(msg.ID() + 1, &p)
+}
+
+
+// Test functions.
+
+// A request gets its response.
+func TestMessageConnSendReceive(t *testing.T) {
+    client, server := makeTestMessageConnPair()
+    defer client.Close()
+    defer server.Close()
+
+    go echoOnce(server)
+
+    msg, err := client.SendReceive(7, &testPayload{ "request", 21 }, time.Second)
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 8, int(msg.ID()))
+
+    var p testPayload
+    msg.Data(&p)
+    testutil.CheckString(t, "request", p.Name)
+    testutil.CheckInt(t, 42, p.Value)
+}
+
+
+// Receiving gives up once the timeout expires, and the deadline doesn't outlive the call.
+func TestMessageConnReceiveTimeout(t *testing.T) {
+    client, server := makeTestMessageConnPair()
+    defer client.Close()
+    defer server.Close()
+
+    start := time.Now()
+    _, err := client.Receive(50 * time.Millisecond)
+    testutil.CheckBool(t, true, errors.Is(err, os.ErrDeadlineExceeded))
+    testutil.CheckBool(t, true, time.Since(start) < time.Second)
+
+    // A message that arrives well after the first timeout must still be received.
+    go func() {
+        time.Sleep(100 * time.Millisecond)
+        server.Send(3, &testPayload{ "late", 1 })
+    }()
+
+    msg, err := client.Receive(0)
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 3, int(msg.ID()))
+}
+
+
+// A response that doesn't arrive in time gives a timeout error.
+func TestMessageConnSendReceiveTimeout(t *testing.T) {
+    client, server := makeTestMessageConnPair()
+    defer client.Close()
+    defer server.Close()
+
+    // Read the request, but never respond.
+    go server.Receive(0)
+
+    _, err := client.SendReceive(7, &testPayload{ "request", 21 }, 50 * time.Millisecond)
+    testutil.CheckBool(t, true, errors.Is(err, os.ErrDeadlineExceeded))
+}
+
+
+// Neither Receive nor SendReceive may be used once we have a receive channel.
+func TestMessageConnReceiveWithChannel(t *testing.T) {
+    client, server := makeTestMessageConnPair()
+    defer server.Close()
+
+    notify := make(chan *ReceivedMessageInfo, 2)
+    client.ReceiveToChannel(notify)
+
+    _, err := client.Receive(0)
+    testutil.CheckError(t, err)
+
+    _, err = client.SendReceive(7, nil, 0)
+    testutil.CheckError(t, err)
+
+    client.Close()
+}
+
+
+// Closing a connection whose receive channel nobody is reading stops the receiving Goroutine, without it ever writing
+// to the channel.
+func TestMessageConnCloseMidReceive(t *testing.T) {
+    baseline := runtime.NumGoroutine()
+
+    client, server := makeTestMessageConnPair()
+    defer server.Close()
+
+    notify := make(chan *ReceivedMessageInfo)
+    client.ReceiveToChannel(notify)
+
+    // Get the receiving Goroutine stuck trying to deliver a message that nobody reads.
+    go server.Send(3, &testPayload{ "unread", 1 })
+    time.Sleep(50 * time.Millisecond)
+
+    closed := make(chan struct{})
+    go func() {
+        client.Close()
+        close(closed)
+    }()
+
+    select {
+        case <-closed:
+        case <-time.After(time.Second):
+            t.Fatalf("Close did not return")
+    }
+
+    select {
+        case <-client.receiverDone:
+        default:
+            t.Fatalf("Receiving Goroutine still running after Close")
+    }
+
+    select {
+        case <-client.Closed():
+        default:
+            t.Fatalf("Closed channel not closed")
+    }
+
+    select {
+        case info := <-notify:
+            t.Fatalf("Receive channel written to after Close: %v", info)
+        case <-time.After(50 * time.Millisecond):
+    }
+
+    // Closing again is harmless.
+    client.Close()
+    server.Close()
+
+    // Give the Goroutines we started above a moment to finish, then check nothing has leaked.
+    deadline := time.Now().Add(time.Second)
+    for (runtime.NumGoroutine() > baseline) && time.Now().Before(deadline) {
+        time.Sleep(10 * time.Millisecond)
+    }
+
+    testutil.CheckInt(t, baseline, runtime.NumGoroutine())
+}
+
+
+// When the other end closes, we get a single error on the receive channel, and then nothing more.
+func TestMessageConnRemoteClose(t *testing.T) {
+    client, server := makeTestMessageConnPair()
+    defer client.Close()
+
+    notify := make(chan *ReceivedMessageInfo, 2)
+    client.ReceiveToChannel(notify)
+
+    server.Close()
+
+    select {
+        case info := <-notify:
+            testutil.CheckError(t, info.Error)
+        case <-time.After(time.Second):
+            t.Fatalf("No error received after remote close")
+    }
+
+    select {
+        case <-client.receiverDone:
+        case <-time.After(time.Second):
+            t.Fatalf("Receiving Goroutine still running after remote close")
+    }
+
+    testutil.CheckInt(t, 0, len(notify))
+}
+
+
+// Real TCP connections work both with keepalives and without them.
+func TestConnectTCPKeepAlive(t *testing.T) {
+    defer SetKeepAlive(keepAlive)
+
+    for _, interval := range []time.Duration{ 0, time.Second } {
+        SetKeepAlive(interval)
+
+        accepted := make(chan *MessageConnection, 1)
+        listener, err := ListenTCP("127.0.0.1:0", MakeGobEncoderFactory(), accepted)
+        testutil.CheckNoError(t, err)
+
+        client, err := ConnectTCP(listener.listener.Addr().String(), MakeGobEncoderFactory(), time.Second)
+        testutil.CheckNoError(t, err)
+
+        server := <-accepted
+        go echoOnce(server)
+
+        msg, err := client.SendReceive(1, &testPayload{ "tcp", 21 }, time.Second)
+        testutil.CheckNoError(t, err)
+
+        var p testPayload
+        msg.Data(&p)
+        testutil.CheckInt(t, 42, p.Value)
+
+        client.Close()
+        server.Close()
+        listener.StopListening()
+    }
+}
@@ -4,7 +4,12 @@
 
 package logger
 
+import "encoding/json"
 import "fmt"
+import "io"
+import "os"
+import "strings"
+import "time"
 
 /* Logging levels. */
 type LogLevel int
@@ -17,7 +22,25 @@ const (
 )
 
 
+/* The names of our levels, as used in JSON output. */
+var levelNames = [...]string{ "error", "warn", "info", "debug", "trace" }
+
+
+/* Logging formats. */
+type LogFormat int
+const (
+    // Just the message, with a prefix for errors and warnings.  This is what a person at a terminal wants.
+    Plain LogFormat = iota
+
+    // One JSON object per line, with a timestamp, level and host, for aggregating logs from many nodes.
+    JSON
+)
+
+
 var level LogLevel = Info
+var logFormat LogFormat = Plain
+var out io.Writer = os.Stderr
+var host string
 
 
 func SetLevel(l LogLevel) {
@@ -25,6 +48,21 @@ func SetLevel(l LogLevel) {
 }
 
 
+/* Send log messages somewhere other than stderr, such as a file. */
+func SetOutput(w io.Writer) {
+    out = w
+}
+
+
+func SetFormat(f LogFormat) {
+    logFormat = f
+
+    if f == JSON {
+        host, _ = os.Hostname()
+    }
+}
+
+
 func IsError() bool {
     // Error logging is always enabled.
     return true
@@ -53,36 +91,72 @@ func IsTrace() bool {
 
 func Errorf(format string, args ...interface{}) {
     if IsError() {
-        fmt.Printf("ERROR: " + format, args...)
+        output(Error, "ERROR: ", format, args...)
     }
 }
 
 
 func Warnf(format string, args ...interface{}) {
     if IsWarn() {
-        fmt.Printf("Warning: " + format, args...)
+        output(Warn, "Warning: ", format, args...)
     }
 }
 
 
 func Infof(format string, args ...interface{}) {
     if IsInfo() {
-        fmt.Printf(format, args...)
+        output(Info, "", format, args...)
     }
 }
 
 
 func Debugf(format string, args ...interface{}) {
     if IsDebug() {
-        fmt.Printf(format, args...)
+        output(Debug, "", format, args...)
     }
 }
 
 
 func Tracef(format string, args ...interface{}) {
     if IsTrace() {
-        fmt.Printf(format, args...)
+        output(Trace, "", format, args...)
     }
 }
 
 
+/* A single log line in JSON format. */
+type jsonLine struct {
+    Time string      `json:"time"`
+    Level string     `json:"level"`
+    Host string      `json:"host"`
+    Message string   `json:"msg"`
+}
+
+
+/*
+ * Write a message in our current format.
+ *
+ * Our callers put their own newlines around their messages, as they would with Printf.  In JSON format
+ * we strip those off, since each message gets a line of its own anyway, and drop messages which are nothing
+ * but whitespace, since they are only there to space out the plain output.
+ */
+func output(l LogLevel, prefix string, format string, args ...interface{}) {
+    if logFormat == Plain {
+        fmt.Fprintf(out, prefix + format, args...)
+        return
+    }
+
+    msg := strings.Trim(fmt.Sprintf(format, args...), "\n")
+    if strings.TrimSpace(msg) == "" {
+        return
+    }
+
+    line, err := json.Marshal(jsonLine{ time.Now().UTC().Format(time.RFC3339Nano), levelNames[l], host, msg })
+    if err != nil {
+        fmt.Fprintf(out, prefix + format, args...)
+        return
+    }
+
+    // A single write, so that lines from different Goroutines don't get mixed up.
+    out.Write(append(line, '\n'))
+}
\ No newline at end of file
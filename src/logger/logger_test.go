@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for plain and JSON log output.
+
+package logger
+
+import "bytes"
+import "encoding/json"
+import "os"
+import "strings"
+import "testing"
+import "time"
+import "silib/testutil"
+
+
+// Helper functions.
+
+/* Capture everything logged by f, in the given format. */
+func captureLog(f LogFormat, log func()) string {
+    var buf bytes.Buffer
+    SetOutput(&buf)
+    SetFormat(f)
+
+    defer func() {
+        SetOutput(os.Stderr)
+        SetFormat(Plain)
+    }()
+
+    log()
+    return buf.String()
+}
+
+
+// Test functions.
+
+// Plain output is just the message, with a prefix for errors and warnings.
+func TestPlainFormat(t *testing.T) {
+    log := captureLog(Plain, func() {
+        Errorf("bad %v\n", 1)
+        Warnf("odd\n")
+        Infof("fine")
+        Debugf("hidden\n")
+    })
+
+    testutil.CheckString(t, "ERROR: bad 1\nWarning: odd\nfine", log)
+}
+
+
+// JSON output is a line per message, without the newlines, and without any blank lines.
+func TestJSONFormat(t *testing.T) {
+    log := captureLog(JSON, func() {
+        Errorf("bad %v\n", 1)
+        Infof("\n")
+        Warnf("\nodd")
+    })
+
+    lines := strings.Split(strings.TrimSuffix(log, "\n"), "\n")
+    testutil.CheckInt(t, 2, len(lines))
+
+    var l jsonLine
+    testutil.CheckNoError(t, json.Unmarshal([]byte(lines[0]), &l))
+    testutil.CheckString(t, "error", l.Level)
+    testutil.CheckString(t, "bad 1", l.Message)
+
+    hostname, _ := os.Hostname()
+    testutil.CheckString(t, hostname, l.Host)
+
+    _, err := time.Parse(time.RFC3339Nano, l.Time)
+    testutil.CheckNoError(t, err)
+
+    testutil.CheckNoError(t, json.Unmarshal([]byte(lines[1]), &l))
+    testutil.CheckString(t, "warn", l.Level)
+    testutil.CheckString(t, "odd", l.Message)
+}
@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "encoding/json"
+import "fmt"
+import "os"
+
+
+/*
+ * A Baseline is the report of an earlier run, which we compare our own results against.
+ *
+ * We only load the parts of the report that we need.  A report written with --individual-stats
+ * can be very large, but we still have to read all of it, since encoding/json has no way to skip
+ * a section without parsing it.
+ */
+type Baseline struct {
+    File string
+    Arguments *baselineArguments
+    Analyses []*Analysis
+}
+
+
+/* The few arguments of the baseline's run that we check against our own. */
+type baselineArguments struct {
+    ObjectSize string
+    ReadWriteMix int
+    Targets []string
+}
+
+
+/*
+ * How one of our analyses compares with the analysis of the same name in the baseline.  Each
+ * delta is the percentage change from the baseline's value to ours.
+ *
+ * A delta is left out if the baseline's value was zero, since there is no percentage change from
+ * nothing.  If we couldn't compare with the baseline at all, then Note says why.
+ */
+type BaselineComparison struct {
+    Note string                             `json:",omitempty"`
+    BandwidthPercent *float64               `json:",omitempty"`
+    ResTimeAvgPercent *float64              `json:",omitempty"`
+    ResTime95Percent *float64               `json:",omitempty"`
+    ResTimePercentiles []PercentileDelta    `json:",omitempty"`
+}
+
+
+/* The change in the response time at one percentile. */
+type PercentileDelta struct {
+    Percentile float64
+    DeltaPercent float64
+}
+
+
+/* What we write into a report about the baseline it was compared with. */
+type BaselineSummary struct {
+    File string
+    Notes []string
+}
+
+
+/* Load the report of an earlier run, to use as a baseline. */
+func LoadBaseline(filename string) (*Baseline, error) {
+    data, err := os.ReadFile(filename)
+    if err != nil {
+        return nil, err
+    }
+
+    var b Baseline
+    err = json.Unmarshal(data, &b)
+    if err != nil {
+        return nil, fmt.Errorf("Unable to parse %v as a sibench report: %v", filename, err)
+    }
+
+    b.File = filename
+    return &b, nil
+}
+
+
+/*
+ * Return notes on any differences between the baseline's run and ours which make comparing them
+ * less meaningful.  These are only warnings: it is up to the user to decide what to make of them.
+ */
+func (b *Baseline) Notes(args *Arguments) []string {
+    notes := make([]string, 0)
+
+    if b.Arguments == nil {
+        return append(notes, "The baseline has no arguments, so it may not be a sibench report")
+    }
+
+    if len(b.Analyses) == 0 {
+        notes = append(notes, "The baseline has no analyses to compare with")
+    }
+
+    if b.Arguments.ObjectSize != args.ObjectSize {
+        notes = append(notes, fmt.Sprintf("The baseline used an object size of %v, but this run uses %v", b.Arguments.ObjectSize, args.ObjectSize))
+    }
+
+    if b.Arguments.ReadWriteMix != args.ReadWriteMix {
+        notes = append(notes, fmt.Sprintf("The baseline used a read/write mix of %v, but this run uses %v", b.Arguments.ReadWriteMix, args.ReadWriteMix))
+    }
+
+    if len(b.Arguments.Targets) != len(args.Targets) {
+        notes = append(notes, fmt.Sprintf("The baseline used %v targets, but this run uses %v", len(b.Arguments.Targets), len(args.Targets)))
+    }
+
+    return notes
+}
+
+
+/* Compare one of our analyses with the analysis of the same name in the baseline. */
+func (b *Baseline) Compare(a *Analysis) *BaselineComparison {
+    var base *Analysis
+    for _, ba := range b.Analyses {
+        if ba.Name == a.Name {
+            base = ba
+            break
+        }
+    }
+
+    switch {
+        case base == nil:
+            return &BaselineComparison{ Note: "The baseline has no analysis called " + a.Name }
+
+        case base.Phase != a.Phase:
+            return &BaselineComparison{ Note: fmt.Sprintf("The baseline's analysis is of a %v phase, not %v", base.Phase, a.Phase) }
+
+        case base.Successes == 0:
+            return &BaselineComparison{ Note: "The baseline's analysis has no successful operations" }
+    }
+
+    var c BaselineComparison
+    c.BandwidthPercent = percentChange(base.Bandwidth, a.Bandwidth)
+    c.ResTimeAvgPercent = percentChange(base.ResTimeAvg, a.ResTimeAvg)
+    c.ResTime95Percent = percentChange(base.ResTime95, a.ResTime95)
+
+    for _, p := range a.ResTimePercentiles {
+        for _, bp := range base.ResTimePercentiles {
+            if bp.Percentile != p.Percentile {
+                continue
+            }
+
+            if delta := percentChange(bp.ResTime, p.ResTime); delta != nil {
+                c.ResTimePercentiles = append(c.ResTimePercentiles, PercentileDelta{ p.Percentile, *delta })
+            }
+        }
+    }
+
+    return &c
+}
+
+
+/* A short summary of a comparison, for printing alongside an analysis. */
+func (c *BaselineComparison) String() string {
+    if c.Note != "" {
+        return c.Note
+    }
+
+    return fmt.Sprintf("bandwidth: %v,  res-95: %v,  res-avg: %v",
+        formatDelta(c.BandwidthPercent),
+        formatDelta(c.ResTime95Percent),
+        formatDelta(c.ResTimeAvgPercent))
+}
+
+
+/* Return the percentage change from one value to another, or nil if the first is zero. */
+func percentChange(from uint64, to uint64) *float64 {
+    if from == 0 {
+        return nil
+    }
+
+    delta := 100 * (float64(to) - float64(from)) / float64(from)
+    return &delta
+}
+
+
+func formatDelta(delta *float64) string {
+    if delta == nil {
+        return "n/a"
+    }
+
+    return fmt.Sprintf("%+.1f%%", *delta)
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for comparing a run's analyses with those of a baseline report.
+
+package main
+
+import "os"
+import "path/filepath"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+func makeTestAnalysis(name string, phase string, bandwidth uint64, res95 uint64) *Analysis {
+    return &Analysis{
+        Name: name,
+        Phase: phase,
+        IsTotal: true,
+        ResTime95: res95,
+        ResTimeAvg: res95 / 2,
+        ResTimePercentiles: []PercentileResTime{ { 50, res95 / 2 }, { 95, res95 } },
+        Bandwidth: bandwidth,
+        Successes: 100 }
+}
+
+
+// Test functions.
+
+func TestBaselineCompare(t *testing.T) {
+    b := Baseline{ Analyses: []*Analysis{ makeTestAnalysis("Total Read", "Read", 1000, 200) } }
+
+    c := b.Compare(makeTestAnalysis("Total Read", "Read", 1100, 150))
+
+    testutil.CheckString(t, "", c.Note)
+    testutil.CheckBool(t, true, *c.BandwidthPercent == 10)
+    testutil.CheckBool(t, true, *c.ResTime95Percent == -25)
+    testutil.CheckBool(t, true, *c.ResTimeAvgPercent == -25)
+    testutil.CheckInt(t, 2, len(c.ResTimePercentiles))
+    testutil.CheckBool(t, true, c.ResTimePercentiles[1].Percentile == 95)
+    testutil.CheckBool(t, true, c.ResTimePercentiles[1].DeltaPercent == -25)
+    testutil.CheckString(t, "bandwidth: +10.0%,  res-95: -25.0%,  res-avg: -25.0%", c.String())
+}
+
+
+// Analyses which we can't compare get a note, and values with nothing to compare against are left out.
+func TestBaselineCompareMismatches(t *testing.T) {
+    b := Baseline{ Analyses: []*Analysis{ makeTestAnalysis("Total Read", "Write", 1000, 200), makeTestAnalysis("Total Stat", "Stat", 0, 10) } }
+
+    c := b.Compare(makeTestAnalysis("Total Write", "Write", 1000, 200))
+    testutil.CheckString(t, "The baseline has no analysis called Total Write", c.Note)
+
+    c = b.Compare(makeTestAnalysis("Total Read", "Read", 1000, 200))
+    testutil.CheckBool(t, true, c.Note != "")
+    testutil.CheckBool(t, true, c.BandwidthPercent == nil)
+
+    c = b.Compare(makeTestAnalysis("Total Stat", "Stat", 0, 20))
+    testutil.CheckString(t, "", c.Note)
+    testutil.CheckBool(t, true, c.BandwidthPercent == nil)
+    testutil.CheckBool(t, true, *c.ResTime95Percent == 100)
+    testutil.CheckString(t, "bandwidth: n/a,  res-95: +100.0%,  res-avg: +100.0%", c.String())
+}
+
+
+// We must be able to load a report, ignoring the sections we don't need.
+func TestBaselineLoad(t *testing.T) {
+    filename := filepath.Join(t.TempDir(), "baseline.json")
+    report := `{
+  "Arguments": { "ObjectSize": "64K", "ReadWriteMix": 0, "Targets": [ "a", "b" ], "Verbosity": "off" },
+  "Stats": [ { "Phase": 2 } ],
+  "Analyses": [ { "Name": "Total Read", "Phase": "Read", "IsTotal": true, "Bandwidth": 1000, "Successes": 10 } ]
+}`
+
+    err := os.WriteFile(filename, []byte(report), 0644)
+    testutil.CheckNoError(t, err)
+
+    b, err := LoadBaseline(filename)
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 1, len(b.Analyses))
+    testutil.CheckString(t, "Total Read", b.Analyses[0].Name)
+
+    args := Arguments{ ObjectSize: "64K", Targets: []string{ "a", "b" } }
+    testutil.CheckInt(t, 0, len(b.Notes(&args)))
+
+    args = Arguments{ ObjectSize: "1M", Targets: []string{ "a" } }
+    testutil.CheckInt(t, 2, len(b.Notes(&args)))
+
+    _, err = LoadBaseline(filepath.Join(t.TempDir(), "missing.json"))
+    testutil.CheckError(t, err)
+
+    err = os.WriteFile(filename, []byte("not json"), 0644)
+    testutil.CheckNoError(t, err)
+    _, err = LoadBaseline(filename)
+    testutil.CheckError(t, err)
+}
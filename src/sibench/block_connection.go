@@ -3,6 +3,7 @@
 
 package main
 
+import "context"
 import "fmt"
 import "io"
 import "logger"
@@ -22,6 +23,7 @@ type BlockConnection struct {
     device string
     protocol ProtocolConfig
     worker WorkerConnectionConfig
+    layout blockLayout
 
     /* either a unix file descriptor int or a windows Handle. */
     fd FileDescriptor
@@ -33,6 +35,9 @@ func NewBlockConnection(target string, protocol ProtocolConfig, worker WorkerCon
     conn.device = target
     conn.protocol = protocol
     conn.worker = worker
+
+    // The device is shared by all the workers on this foreman, so we lay it out over the foreman's range.
+    conn.layout = worker.foremanBlockLayout()
     return &conn, nil
 }
 
@@ -55,7 +60,12 @@ func (conn *BlockConnection) ManagerClose(cleanup bool) error {
 func (conn *BlockConnection) WorkerConnect() error {
     var err error
 
-    conn.fd, err = Open(conn.device, syscall.O_RDWR, 0644)
+    if conn.worker.ExplicitFlush {
+        conn.fd, err = OpenUnsynced(conn.device, syscall.O_RDWR, 0644)
+    } else {
+        conn.fd, err = Open(conn.device, syscall.O_RDWR, 0644)
+    }
+
     if err != nil {
         conn.fd = 0
         return err
@@ -66,7 +76,7 @@ func (conn *BlockConnection) WorkerConnect() error {
         return err
     }
 
-    minSize := (conn.worker.ForemanRangeEnd - conn.worker.ForemanRangeStart) * conn.worker.ObjectSize
+    minSize := conn.layout.Size()
     if offset < int64(minSize) {
         return fmt.Errorf("Block device %v too small: only %v bytes when we need %v", conn.device, offset, minSize)
     }
@@ -89,21 +99,17 @@ func (conn *BlockConnection) CanDelete() bool {
     return false
 }
 
-/* 
- * Helper function to determine an object's offset into the image from an object id
- */
-func (conn *BlockConnection) objectOffset(id uint64) int64 {
-    return int64((id - conn.worker.ForemanRangeStart) * conn.worker.ObjectSize)
-}
-
+func (conn *BlockConnection) PutObject(ctx context.Context, key string, id uint64, buffer []byte) error {
+    offset, err := conn.layout.Offset(id, 0, uint64(len(buffer)))
+    if err != nil {
+        return err
+    }
 
-func (conn *BlockConnection) PutObject(key string, id uint64, buffer []byte) error {
-    offset := conn.objectOffset(id)
     logger.Tracef("Put block object %v on %v with size %v and offset %v\n", id, conn.device, len(buffer), offset)
 
     for len(buffer) > 0 {
         n, err := conn.fd.Pwrite(buffer, offset)
-        if err == nil {
+        if err != nil {
             return err
         }
 
@@ -115,23 +121,34 @@ func (conn *BlockConnection) PutObject(key string, id uint64, buffer []byte) err
 }
 
 
-func (conn *BlockConnection) GetObject(key string, id uint64, buffer []byte) error {
-    offset := conn.objectOffset(id)
-    logger.Tracef("Get block object %v on %v with size %v and offset %v\n", key, conn.device, conn.worker.ObjectSize, offset)
+/* Syncs the whole device, since a sync can't be limited to the blocks of one object. */
+func (conn *BlockConnection) FlushObject(key string, id uint64) error {
+    return conn.fd.Sync()
+}
 
-    remaining := conn.worker.ObjectSize
-    start := 0
 
-    if remaining != uint64(cap(buffer)) {
-        return fmt.Errorf("Object has wrong size: expected %v, but got %v", cap(buffer), remaining)
+/* With target sizes or a size mix, the object may be smaller than its slot, so we read just len(buffer) bytes. */
+func (conn *BlockConnection) GetObject(ctx context.Context, key string, id uint64, buffer []byte) error {
+    offset, err := conn.layout.Offset(id, 0, uint64(len(buffer)))
+    if err != nil {
+        return err
     }
 
+    logger.Tracef("Get block object %v on %v with size %v and offset %v\n", key, conn.device, len(buffer), offset)
+
+    remaining := uint64(len(buffer))
+    start := 0
+
     for remaining > 0 {
         n, err := conn.fd.Pread(buffer[start:], offset)
         if err != nil {
             return err
         }
 
+        if n == 0 {
+            return fmt.Errorf("Short read: hit the end of %v at offset %v", conn.device, offset)
+        }
+
         start += n
         offset += int64(n)
         remaining -= uint64(n)
@@ -141,7 +158,40 @@ func (conn *BlockConnection) GetObject(key string, id uint64, buffer []byte) err
 }
 
 
-func (conn *BlockConnection) DeleteObject(key string, id uint64) error {
+func (conn *BlockConnection) GetObjectRange(ctx context.Context, key string, id uint64, offset uint64, buffer []byte) error {
+    start, err := conn.layout.Offset(id, offset, uint64(len(buffer)))
+    if err != nil {
+        return err
+    }
+
+    logger.Tracef("Get block object range %v on %v with offset %v and length %v\n", key, conn.device, start, len(buffer))
+
+    for len(buffer) > 0 {
+        n, err := conn.fd.Pread(buffer, start)
+        if err != nil {
+            return err
+        }
+
+        if n == 0 {
+            return fmt.Errorf("Short read: hit the end of %v at offset %v", conn.device, start)
+        }
+
+        buffer = buffer[n:]
+        start += int64(n)
+    }
+
+    return nil
+}
+
+
+/* Our objects are just regions of the device, so all we can check is that the object fits on it. */
+func (conn *BlockConnection) StatObject(key string, id uint64) error {
+    _, err := conn.layout.Offset(id, 0, conn.worker.ObjectSize)
+    return err
+}
+
+
+func (conn *BlockConnection) DeleteObject(ctx context.Context, key string, id uint64) error {
     return nil
 }
 
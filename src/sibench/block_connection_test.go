@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// +build darwin linux
+
+// Tests for BlockConnection, using a plain file as the device.
+
+package main
+
+import "context"
+import "os"
+import "path/filepath"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+// Create a device file of the given size, and connect to it as a worker.
+func connectTestBlockDevice(t *testing.T, size int64) (*BlockConnection, error) {
+    t.Helper()
+
+    device := filepath.Join(t.TempDir(), "device")
+    err := os.WriteFile(device, nil, 0644)
+    testutil.CheckNoError(t, err)
+    err = os.Truncate(device, size)
+    testutil.CheckNoError(t, err)
+
+    conn, err := NewBlockConnection(device, ProtocolConfig{}, testBlockConfig())
+    testutil.CheckNoError(t, err)
+
+    err = conn.WorkerConnect()
+    if err != nil {
+        conn.WorkerClose(false)
+        return nil, err
+    }
+
+    t.Cleanup(func() { conn.WorkerClose(false) })
+    return conn, nil
+}
+
+
+// Test functions.
+
+// A device which is exactly big enough must hold the object at the top of the foreman's range.
+func TestBlockConnectionTopOfRange(t *testing.T) {
+    config := testBlockConfig()
+    conn, err := connectTestBlockDevice(t, int64(config.foremanBlockLayout().Size()))
+    testutil.CheckNoError(t, err)
+
+    data := make([]byte, config.ObjectSize)
+    for i := range data {
+        data[i] = byte(i)
+    }
+
+    err = conn.PutObject(context.Background(), "", config.ForemanRangeEnd - 1, data)
+    testutil.CheckNoError(t, err)
+
+    buffer := make([]byte, config.ObjectSize)
+    err = conn.GetObject(context.Background(), "", config.ForemanRangeEnd - 1, buffer)
+    testutil.CheckNoError(t, err)
+    testutil.CheckBytes(t, data, buffer)
+
+    // Ranged reads are aligned for O_DIRECT, so read the last aligned block of the object.
+    tail := make([]byte, readRangeAlignment)
+    err = conn.GetObjectRange(context.Background(), "", config.ForemanRangeEnd - 1, config.ObjectSize - readRangeAlignment, tail)
+    testutil.CheckNoError(t, err)
+    testutil.CheckBytes(t, data[config.ObjectSize - readRangeAlignment:], tail)
+
+    // Nothing may be written beyond the end of the range.
+    err = conn.PutObject(context.Background(), "", config.ForemanRangeEnd, data)
+    testutil.CheckError(t, err)
+
+    info, err := os.Stat(conn.device)
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, int(config.foremanBlockLayout().Size()), int(info.Size()))
+}
+
+
+// A device which is one byte too small for the foreman's range must be rejected.
+func TestBlockConnectionDeviceTooSmall(t *testing.T) {
+    config := testBlockConfig()
+    _, err := connectTestBlockDevice(t, int64(config.foremanBlockLayout().Size()) - 1)
+    testutil.CheckError(t, err)
+}
+
+
+// An object that is overwritten in place must verify from the cycle in its header, without us
+// having to track it, and must fail if we expect the cycle that it was overwritten from.
+func TestBlockConnectionOverwriteVerifies(t *testing.T) {
+    config := testBlockConfig()
+    conn, err := connectTestBlockDevice(t, int64(config.foremanBlockLayout().Size()))
+    testutil.CheckNoError(t, err)
+
+    // The prng generator keeps the cycle itself in its header, and the others a seed made from it.
+    configs := map[string]GeneratorConfig{ "prng": {}, "entropy": { "entropy": "4" } }
+
+    for genType, genConfig := range configs {
+        g, err := CreateGenerator(genType, 1, genConfig)
+        testutil.CheckNoError(t, err)
+
+        id := config.ForemanRangeStart
+        data := make([]byte, config.ObjectSize)
+        scratch := make([]byte, config.ObjectSize)
+
+        for cycle := uint64(0); cycle < 3; cycle++ {
+            g.Generate(config.ObjectSize, id, cycle, &data)
+            testutil.CheckNoError(t, conn.PutObject(context.Background(), "", id, data))
+        }
+
+        buffer := make([]byte, config.ObjectSize)
+        testutil.CheckNoError(t, conn.GetObject(context.Background(), "", id, buffer))
+        testutil.CheckNoError(t, g.Verify(config.ObjectSize, id, AnyCycle, &buffer, &scratch))
+        testutil.CheckNoError(t, g.Verify(config.ObjectSize, id, 2, &buffer, &scratch))
+        testutil.CheckError(t, g.Verify(config.ObjectSize, id, 1, &buffer, &scratch))
+    }
+}
+
+
+// An object smaller than its slot, as with target sizes or a size mix, must round trip whole.
+func TestBlockConnectionSmallerObject(t *testing.T) {
+    config := testBlockConfig()
+    conn, err := connectTestBlockDevice(t, int64(config.foremanBlockLayout().Size()))
+    testutil.CheckNoError(t, err)
+
+    data := make([]byte, config.ObjectSize / 2)
+    for i := range data {
+        data[i] = byte(i + 1)
+    }
+
+    testutil.CheckNoError(t, conn.PutObject(context.Background(), "", config.ForemanRangeStart, data))
+
+    buffer := make([]byte, len(data))
+    testutil.CheckNoError(t, conn.GetObject(context.Background(), "", config.ForemanRangeStart, buffer))
+    testutil.CheckBytes(t, data, buffer)
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+
+
+/*
+ * A blockLayout places objects end to end on a block device or RBD image.  Each object gets a slot
+ * of ObjectSize bytes (the largest size, if we have a size mix), with the first id in the range
+ * at offset zero.
+ *
+ * Which range we use depends on who shares the device: a block device is shared by all of a
+ * foreman's workers, and so is laid out over the foreman's range, whilst each RBD worker creates
+ * its own image, which is laid out over just that worker's range.  Either way, the same layout
+ * must be used both to size (or check) the device and to find each object on it, or we could
+ * read or write beyond its end.
+ */
+type blockLayout struct {
+    rangeStart uint64
+    rangeEnd uint64
+    objectSize uint64
+}
+
+
+/* The layout for a device shared by all the workers on a foreman. */
+func (w *WorkerConnectionConfig) foremanBlockLayout() blockLayout {
+    return blockLayout{ w.ForemanRangeStart, w.ForemanRangeEnd, w.ObjectSize }
+}
+
+
+/* The layout for a device used by only this worker. */
+func (w *WorkerConnectionConfig) workerBlockLayout() blockLayout {
+    return blockLayout{ w.WorkerRangeStart, w.WorkerRangeEnd, w.ObjectSize }
+}
+
+
+/* The number of bytes needed to hold every object in the range. */
+func (l blockLayout) Size() uint64 {
+    return (l.rangeEnd - l.rangeStart) * l.objectSize
+}
+
+
+/*
+ * Return the device offset of length bytes, starting offset bytes into an object.  It is an
+ * error for the object to be outside our range, or for the bytes to spill into the next slot.
+ */
+func (l blockLayout) Offset(id uint64, offset uint64, length uint64) (int64, error) {
+    if (id < l.rangeStart) || (id >= l.rangeEnd) {
+        return 0, fmt.Errorf("Object %v is outside the range %v to %v", id, l.rangeStart, l.rangeEnd)
+    }
+
+    if offset + length > l.objectSize {
+        return 0, fmt.Errorf("%v bytes at offset %v overflow object %v, which has %v bytes", length, offset, id, l.objectSize)
+    }
+
+    return int64((id - l.rangeStart) * l.objectSize + offset), nil
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for laying out objects on block devices and RBD images.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+// A foreman with objects 100 to 200, of which this worker has 150 to 175.
+func testBlockConfig() WorkerConnectionConfig {
+    return WorkerConnectionConfig{
+        ObjectSize: 8192,
+        ForemanRangeStart: 100,
+        ForemanRangeEnd: 200,
+        WorkerRangeStart: 150,
+        WorkerRangeEnd: 175 }
+}
+
+
+// Test functions.
+
+// The object at the top of the range must end exactly at the end of the device.
+func TestBlockLayoutTopOfRange(t *testing.T) {
+    config := testBlockConfig()
+
+    for _, l := range []blockLayout{ config.foremanBlockLayout(), config.workerBlockLayout() } {
+        offset, err := l.Offset(l.rangeEnd - 1, 0, l.objectSize)
+        testutil.CheckNoError(t, err)
+        testutil.CheckBool(t, true, uint64(offset) + l.objectSize == l.Size())
+
+        // A range read of the last byte must also fit.
+        offset, err = l.Offset(l.rangeEnd - 1, l.objectSize - 1, 1)
+        testutil.CheckNoError(t, err)
+        testutil.CheckBool(t, true, uint64(offset) + 1 == l.Size())
+    }
+}
+
+
+// The first object in each layout's range is at the start of its device.
+func TestBlockLayoutBottomOfRange(t *testing.T) {
+    config := testBlockConfig()
+
+    offset, err := config.foremanBlockLayout().Offset(100, 0, 8192)
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 0, int(offset))
+
+    offset, err = config.workerBlockLayout().Offset(150, 0, 8192)
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 0, int(offset))
+
+    // On the shared device, the worker's first object comes after those of the workers before it.
+    offset, err = config.foremanBlockLayout().Offset(150, 0, 8192)
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 50 * 8192, int(offset))
+}
+
+
+// Objects outside the range, and bytes which spill out of an object's slot, must be rejected.
+func TestBlockLayoutOutOfRange(t *testing.T) {
+    config := testBlockConfig()
+
+    worker := config.workerBlockLayout()
+    _, err := worker.Offset(175, 0, 8192)
+    testutil.CheckError(t, err)
+    _, err = worker.Offset(149, 0, 8192)
+    testutil.CheckError(t, err)
+    _, err = worker.Offset(174, 1, 8192)
+    testutil.CheckError(t, err)
+
+    foreman := config.foremanBlockLayout()
+    _, err = foreman.Offset(200, 0, 8192)
+    testutil.CheckError(t, err)
+    _, err = foreman.Offset(99, 0, 8192)
+    testutil.CheckError(t, err)
+}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "encoding/json"
+import "fmt"
+import "logger"
+import "os"
+
+
+/*
+ * Calibration is a heuristic to help pick a value for --workers.
+ *
+ * We do a series of short probe benchmarks with an increasing worker factor, and look at the total
+ * bandwidth (reads plus writes) that each one achieves.  Once doubling the number of workers stops
+ * improving the bandwidth by a worthwhile amount, we assume that we have hit the point of
+ * diminishing returns, and recommend the best factor we have seen.
+ *
+ * The probes are short, so the results are noisy, and the best factor for a long run against a
+ * busy backend may well be different.  It's a starting point, not a measurement.
+ */
+
+/* The worker factors we probe, in order. */
+var calibrationFactors = []float64{ 1, 2, 4, 8, 16 }
+
+/* The durations (in seconds) of each phase of a probe. */
+const calibrationRampUp = 2
+const calibrationRunTime = 5
+const calibrationRampDown = 1
+
+/* The fractional improvement in bandwidth that we need to see to make more workers worthwhile. */
+const calibrationMinGain = 0.1
+
+
+/* The results of a single calibration probe. */
+type CalibrationProbe struct {
+    WorkerFactor float64
+    Bandwidth uint64        // The total bandwidth of all phases, in bits per second.
+    BandwidthBytes uint64
+    Failures uint64
+}
+
+
+/* The report we write when calibrating, in place of the usual benchmark report. */
+type CalibrationReport struct {
+    Arguments *Arguments
+    Lineage Lineage
+    Probes []*CalibrationProbe
+    RecommendedWorkerFactor float64
+}
+
+
+/*
+ * Run the calibration probes for a job, and report which worker factor we recommend.
+ * The job's own worker factor and durations are ignored.
+ */
+func RunCalibration(j *Job) error {
+    var report CalibrationReport
+    report.Arguments = j.arguments
+    report.Lineage = j.lineage
+
+    var best *CalibrationProbe
+
+    for _, factor := range calibrationFactors {
+        logger.Infof("%v", banner(fmt.Sprintf("CALIBRATION PROBE: worker factor %v", factor), '='))
+
+        probe, err := runCalibrationProbe(j, factor)
+        if err != nil {
+            return err
+        }
+
+        report.Probes = append(report.Probes, probe)
+
+        if best != nil {
+            gain := (float64(probe.Bandwidth) - float64(best.Bandwidth)) / float64(best.Bandwidth)
+            if gain < calibrationMinGain {
+                break
+            }
+        }
+
+        best = probe
+    }
+
+    report.RecommendedWorkerFactor = best.WorkerFactor
+
+    logger.Infof("%v", banner("CALIBRATION RESULTS", '='))
+    for _, p := range report.Probes {
+        bwstr := fmt.Sprintf("%vb/s", ToUnits(p.Bandwidth))
+        if j.useBytes {
+            bwstr = fmt.Sprintf("%vB/s", ToUnits(p.BandwidthBytes))
+        }
+
+        logger.Infof("Worker factor: %5v   bandwidth: %7v,  fail: %6v\n", p.WorkerFactor, bwstr, p.Failures)
+    }
+
+    logger.Infof("\nRecommended worker factor: %v\n", report.RecommendedWorkerFactor)
+    logger.Infof("This is a heuristic from short probe runs: treat it as a starting point.\n")
+
+    data, err := json.MarshalIndent(report, "", "  ")
+    if err == nil {
+        err = writeOutput(j.arguments.Output, data)
+    }
+
+    if err != nil {
+        return fmt.Errorf("Failure writing calibration report %v: %v", j.arguments.Output, err)
+    }
+
+    return nil
+}
+
+
+/* Run a single short benchmark with a given worker factor, and summarise its results. */
+func runCalibrationProbe(j *Job, factor float64) (*CalibrationProbe, error) {
+    // Our probes don't write the usual report, so give them their own arguments.
+    args := *j.arguments
+    args.Output = os.DevNull
+    args.AtomicReport = false
+
+    probe := *j
+    probe.arguments = &args
+    probe.order.WorkerFactor = factor
+    probe.rampUp = calibrationRampUp
+    probe.runTime = calibrationRunTime
+    probe.rampDown = calibrationRampDown
+    probe.phaseRampUp = probe.rampUp
+
+    analyses, err := RunBenchmark(&probe)
+    if err != nil {
+        return nil, err
+    }
+
+    result := &CalibrationProbe{ WorkerFactor: factor }
+    found := false
+
+    for _, a := range analyses {
+        if a.IsTotal {
+            result.Bandwidth += a.Bandwidth
+            result.BandwidthBytes += a.BandwidthBytes
+            result.Failures += a.Failures()
+            found = true
+        }
+    }
+
+    // We can end up with no results if we were interrupted.
+    if !found {
+        return nil, fmt.Errorf("No results from calibration probe with worker factor %v", factor)
+    }
+
+    return result, nil
+}
@@ -8,6 +8,7 @@ package main
 import "fmt"
 import "logger"
 import "github.com/ceph/go-ceph/rados"
+import "github.com/ceph/go-ceph/rbd"
 
 
 
@@ -63,19 +64,45 @@ func NewCephClient(monitor string, config ProtocolConfig) (*rados.Conn, error) {
     pool := config["pool"]
 
     // Check the pool we want exists so we can give a decent error message. 
+    err = retryProvisioning(config, "checking for Ceph pool " + pool, func() error {
+        return checkPoolExists(client, pool)
+    })
+
+    if err != nil {
+        client.Shutdown()
+        return nil, err
+    }
+
+    return client, nil
+}
+
+
+func checkPoolExists(client *rados.Conn, pool string) error {
     pools, err := client.ListPools()
-    found := false
+    if err != nil {
+        return fmt.Errorf("Failure listing Ceph pools: %w", err)
+    }
+
     for _, p := range pools {
         if p == pool {
-            found = true
+            return nil
         }
     }
 
-    if !found {
-        client.Shutdown()
-        return nil, fmt.Errorf("No such Ceph pool: %v\n", pool)
-    }
-
-    return client, nil
+    return fmt.Errorf("No such Ceph pool: %v", pool)
 }
 
+
+/*
+ * Returns the versions of the librados and librbd libraries that we are linked against, so that
+ * drivers with mismatched Ceph clients can be spotted.
+ */
+func CephLibraryVersions() (string, string) {
+    major, minor, extra := rados.Version()
+    radosVersion := fmt.Sprintf("%v.%v.%v", major, minor, extra)
+
+    major, minor, extra = rbd.Version()
+    rbdVersion := fmt.Sprintf("%v.%v.%v", major, minor, extra)
+
+    return radosVersion, rbdVersion
+}
@@ -79,7 +79,7 @@ func (conn *CephFSConnection) ManagerClose(cleanup bool) error {
 
 
 func (conn *CephFSConnection) WorkerConnect() error {
-    logger.Infof("Creating cephfs connection to %v in %v as %v\n", conn.monitor, conn.mountPoint, conn.protocol["username"])
+    logger.Debugf("Creating cephfs connection to %v in %v as %v\n", conn.monitor, conn.mountPoint, conn.protocol["username"])
 
     if mountManager.Acquire(conn.mountPoint) {
         // The mount doesn't exist yet, and we've been told to create it.
@@ -107,6 +107,10 @@ func (conn *CephFSConnection) WorkerConnect() error {
         // Now do the actual mount
 
         options := fmt.Sprintf("name=%v,secret=%v", conn.protocol["username"], conn.protocol["key"])
+        if conn.protocol["options"] != "" {
+            options += "," + conn.protocol["options"]
+        }
+
         logger.Debugf("CephFSConnection mounting with monitor: %v, mountpoint: %v, options: %v\n", monitor_ips[0], conn.mountPoint, options)
 
         err = Mount(monitor_ips[0] + ":/", conn.mountPoint, "ceph", 0, options)
@@ -120,13 +124,13 @@ func (conn *CephFSConnection) WorkerConnect() error {
     }
 
     // Tell our FileConnection delegate which directories to use for its root and its dir within that root.
-    conn.InitFileConnectionBase(conn.mountPoint, conn.protocol["dir"])
+    conn.InitFileConnectionBase(conn.mountPoint, conn.protocol["dir"], conn.worker.ExplicitFlush)
     return nil
 }
 
 
 func (conn *CephFSConnection) WorkerClose(cleanup bool) error {
-    logger.Infof("Closing cephfs connection to %v\n", conn.monitor)
+    logger.Debugf("Closing cephfs connection to %v\n", conn.monitor)
 
     if mountManager.Release(conn.mountPoint) {
         logger.Debugf("Unmounting %v\n", conn.mountPoint)
@@ -16,5 +16,7 @@ var globalConfig Config
  */
 type Config struct {
     ListenPort uint16
+    StatusPort uint16   // HTTP port for the Foreman's status, or 0 if disabled.
+    GrpcPort uint16     // Port for the Foreman's gRPC control plane, or 0 if disabled.
     MountsDir string
 }
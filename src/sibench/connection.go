@@ -3,8 +3,13 @@
 
 package main
 
+import "context"
+import "errors"
 import "fmt"
+import "logger"
 import "runtime"
+import "strconv"
+import "time"
 
 
 /* 
@@ -19,6 +24,8 @@ type Connection interface {
     ManagerConnect() error
     ManagerClose(cleanup bool) error
 
+    /* If connection reuse is disabled, then workers will also close and re-open their connections
+     * around every operation, so these need to be safe to call repeatedly. */
     WorkerConnect() error
     WorkerClose(cleanup bool) error
 
@@ -49,11 +56,28 @@ type Connection interface {
      * operations inside Put or Get (ie, not while we're inside the timed section of the code).
      *
      * Both methods take a slice with exactly the capacity we expect to read or write.
+     *
+     * The context is the worker's, and is cancelled when the worker terminates, so that any op
+     * still in flight can be abandoned.  Backends which can't cancel an op may ignore it.
+     */
+
+    PutObject(ctx context.Context, key string, id uint64, buffer []byte) error
+    GetObject(ctx context.Context, key string, id uint64, buffer []byte) error
+
+    /*
+     * Reads len(buffer) bytes of an object, starting at offset, without fetching the rest of it.
+     * The range will always lie within the object.
+     */
+    GetObjectRange(ctx context.Context, key string, id uint64, offset uint64, buffer []byte) error
+
+    /*
+     * Checks that an object exists, without reading any of its data, so that we can benchmark
+     * metadata ops (such as S3's HeadObject) on their own.  This should be as cheap as the backend
+     * allows: backends without any per-object metadata need only check that the object could exist.
      */
+    StatObject(key string, id uint64) error
 
-    PutObject(key string, id uint64, buffer []byte) error
-    GetObject(key string, id uint64, buffer []byte) error
-    DeleteObject(key string, id uint64) error
+    DeleteObject(ctx context.Context, key string, id uint64) error
 
     /*
      * Some connection types can have their cache explitly cleared (which we do between the
@@ -63,6 +87,95 @@ type Connection interface {
 }
 
 
+/*
+ * Connections to object stores with buckets implement this as well, so that we can benchmark
+ * creating and deleting buckets.  These are timed operations, so they should do nothing but the
+ * create or delete itself.
+ */
+type BucketConnection interface {
+    CreateBucket(bucket string) error
+    DeleteBucket(bucket string) error
+}
+
+
+/*
+ * Connections to backends which can append to an object implement this as well, so that we can
+ * benchmark many clients appending to a few shared objects, as they would to a log.  Workers on
+ * every server may append to the same object at once, so each append must land whole at the end
+ * of the object, without overwriting or interleaving with any other.  Like PutObject, this is
+ * timed, and the object is created if it doesn't yet exist.
+ */
+type AppendConnection interface {
+    AppendObject(key string, id uint64, buffer []byte) error
+}
+
+
+/*
+ * Connections to backends whose writes we can make durable separately implement this as well, so
+ * that we can time a write apart from the cost of flushing it.  When the worker config asks for
+ * explicit flushes, PutObject should not sync, and the write is only durable once FlushObject has
+ * returned.  Like PutObject, this is timed.
+ */
+type FlushConnection interface {
+    FlushObject(key string, id uint64) error
+}
+
+
+/*
+ * Connections which can be held open and idle, to find out how many connections a gateway can
+ * sustain, implement this as well.  Keepalive should make the cheapest request it can, which
+ * mustn't depend on any of our objects existing.  If the request needed a new network connection
+ * because the backend had closed the one we were holding, it should return ErrConnectionDropped
+ * (even if the request itself succeeded).
+ */
+type KeepaliveConnection interface {
+    Keepalive() error
+}
+
+
+var ErrConnectionDropped = errors.New("Held connection was dropped by the backend")
+
+
+/*
+ * Errors from HTTP-based connections (such as S3) should implement this interface - either
+ * directly or by wrapping an error that does - so that failures can be broken down by HTTP status
+ * in our stats.  The AWS SDK's RequestFailure errors already implement it.
+ */
+type HttpStatusError interface {
+    error
+    StatusCode() int
+}
+
+
+/* How long we wait before retrying a failed provisioning step.  Each retry waits twice as long as the last. */
+const provisionRetryBackoff = time.Second
+
+
+/*
+ * Runs a step which provisions something for a connection, such as creating a bucket or checking
+ * that a pool exists, retrying it with backoff if it fails transiently.  A busy gateway or cluster
+ * can time out or refuse us for a moment, and that shouldn't abort a whole run.  We classify
+ * failures the same way as for ops (see isTransient), so that a missing pool or bad credentials
+ * fail straight away.
+ *
+ * The number of retries is the protocol config's "provision_retries".  Without one, we don't retry.
+ */
+func retryProvisioning(protocol ProtocolConfig, what string, step func() error) error {
+    retries, _ := strconv.Atoi(protocol["provision_retries"])
+    backoff := provisionRetryBackoff
+
+    err := step()
+    for i := 0; (err != nil) && (i < retries) && isTransient(context.Background(), err); i++ {
+        logger.Warnf("Failure %v: %v.  Retrying in %v\n", what, err, backoff)
+        time.Sleep(backoff)
+        backoff *= 2
+        err = step()
+    }
+
+    return err
+}
+
+
 /* 
  * WorkerConnectionConfig is all the non-protocol specific information that a particular worker
  * knows that might be useful when constructing a new connection.
@@ -75,6 +188,10 @@ type WorkerConnectionConfig struct {
     ForemanRangeEnd uint64
     WorkerRangeStart uint64
     WorkerRangeEnd uint64
+    ConnectionReuse bool
+    HoldConnection bool     // Whether the connection will be held idle, and so needs a network connection of its own.
+    OpTimeout time.Duration // If non-zero, connections which can cancel an op should do so after this long.
+    ExplicitFlush bool      // Whether writes should skip syncing, since we will call FlushObject ourselves.
 }
 
 
@@ -93,6 +210,8 @@ func NewConnection(connectionType string, target string, protocolConfig Protocol
             case "rados":   return NewRadosConnection(target, protocolConfig, workerConfig)
             case "cephfs":  return NewCephFSConnection(target, protocolConfig, workerConfig)
             case "rbd":     return NewRbdConnection(target, protocolConfig, workerConfig)
+            case "nfs":     return NewNFSConnection(target, protocolConfig, workerConfig)
+            case "iscsi":   return NewIscsiConnection(target, protocolConfig, workerConfig)
         }
     }
 
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the helpers shared by our connections.
+
+package main
+
+import "fmt"
+import "syscall"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+/* Make a provisioning step which fails with each of the given errors in turn, and then succeeds. */
+func failingStep(attempts *int, errs ...error) func() error {
+    return func() error {
+        var err error
+        if *attempts < len(errs) {
+            err = errs[*attempts]
+        }
+
+        *attempts++
+        return err
+    }
+}
+
+
+// Test functions.
+
+// Transient failures are retried, but anything else, such as a missing pool, fails straight away.
+func TestRetryProvisioning(t *testing.T) {
+    protocol := ProtocolConfig{ "provision_retries": "3" }
+
+    attempts := 0
+    err := retryProvisioning(protocol, "checking for Ceph pool", failingStep(&attempts, fmt.Errorf("No such Ceph pool: sibench")))
+    testutil.CheckError(t, err)
+    testutil.CheckInt(t, 1, attempts)
+
+    attempts = 0
+    err = retryProvisioning(protocol, "creating bucket", failingStep(&attempts, statusError(403)))
+    testutil.CheckError(t, err)
+    testutil.CheckInt(t, 1, attempts)
+
+    attempts = 0
+    err = retryProvisioning(protocol, "creating bucket", failingStep(&attempts, fmt.Errorf("Create failed: %w", syscall.ECONNREFUSED)))
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 2, attempts)
+
+    // Without any retries, even a transient failure is final.
+    attempts = 0
+    err = retryProvisioning(ProtocolConfig{}, "creating bucket", failingStep(&attempts, statusError(503)))
+    testutil.CheckError(t, err)
+    testutil.CheckInt(t, 1, attempts)
+}
@@ -0,0 +1,1560 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// The gRPC control plane for a sibench server (a foreman).
+//
+// This lets other tooling drive a foreman without speaking sibench's own TCP protocol.  The
+// messages mirror the Go types that the foreman uses (WorkOrder, Discovery and StatSummary), and
+// the foreman converts between the two, so see messages.go for what each field means.
+//
+// After changing this file, regenerate the Go code with "make proto".
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.30.0
+// 	protoc        (unknown)
+// source: control.proto
+
+package controlpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Phase int32
+
+const (
+	Phase_PHASE_UNSPECIFIED      Phase = 0
+	Phase_PHASE_WRITE            Phase = 1
+	Phase_PHASE_PREPARE          Phase = 2
+	Phase_PHASE_STAT             Phase = 3
+	Phase_PHASE_READ             Phase = 4
+	Phase_PHASE_READ_WRITE       Phase = 5
+	Phase_PHASE_DELETE           Phase = 6
+	Phase_PHASE_BUCKET_OPS       Phase = 7
+	Phase_PHASE_HOLD_CONNECTIONS Phase = 8
+	Phase_PHASE_VERIFY           Phase = 9
+)
+
+// Enum value maps for Phase.
+var (
+	Phase_name = map[int32]string{
+		0: "PHASE_UNSPECIFIED",
+		1: "PHASE_WRITE",
+		2: "PHASE_PREPARE",
+		3: "PHASE_STAT",
+		4: "PHASE_READ",
+		5: "PHASE_READ_WRITE",
+		6: "PHASE_DELETE",
+		7: "PHASE_BUCKET_OPS",
+		8: "PHASE_HOLD_CONNECTIONS",
+		9: "PHASE_VERIFY",
+	}
+	Phase_value = map[string]int32{
+		"PHASE_UNSPECIFIED":      0,
+		"PHASE_WRITE":            1,
+		"PHASE_PREPARE":          2,
+		"PHASE_STAT":             3,
+		"PHASE_READ":             4,
+		"PHASE_READ_WRITE":       5,
+		"PHASE_DELETE":           6,
+		"PHASE_BUCKET_OPS":       7,
+		"PHASE_HOLD_CONNECTIONS": 8,
+		"PHASE_VERIFY":           9,
+	}
+)
+
+func (x Phase) Enum() *Phase {
+	p := new(Phase)
+	*p = x
+	return p
+}
+
+func (x Phase) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Phase) Descriptor() protoreflect.EnumDescriptor {
+	return file_control_proto_enumTypes[0].Descriptor()
+}
+
+func (Phase) Type() protoreflect.EnumType {
+	return &file_control_proto_enumTypes[0]
+}
+
+func (x Phase) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Phase.Descriptor instead.
+func (Phase) EnumDescriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{0}
+}
+
+// Used for RPCs which have nothing to send or return.
+type Empty struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{0}
+}
+
+type PhaseRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Phase Phase `protobuf:"varint,1,opt,name=phase,proto3,enum=sibench.Phase" json:"phase,omitempty"`
+}
+
+func (x *PhaseRequest) Reset() {
+	*x = PhaseRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PhaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PhaseRequest) ProtoMessage() {}
+
+func (x *PhaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PhaseRequest.ProtoReflect.Descriptor instead.
+func (*PhaseRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PhaseRequest) GetPhase() Phase {
+	if x != nil {
+		return x.Phase
+	}
+	return Phase_PHASE_UNSPECIFIED
+}
+
+type Discovery struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cores           uint64 `protobuf:"varint,1,opt,name=cores,proto3" json:"cores,omitempty"`
+	Ram             uint64 `protobuf:"varint,2,opt,name=ram,proto3" json:"ram,omitempty"`
+	Version         string `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	GoVersion       string `protobuf:"bytes,4,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`
+	LibradosVersion string `protobuf:"bytes,5,opt,name=librados_version,json=libradosVersion,proto3" json:"librados_version,omitempty"`
+	LibrbdVersion   string `protobuf:"bytes,6,opt,name=librbd_version,json=librbdVersion,proto3" json:"librbd_version,omitempty"`
+}
+
+func (x *Discovery) Reset() {
+	*x = Discovery{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Discovery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Discovery) ProtoMessage() {}
+
+func (x *Discovery) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Discovery.ProtoReflect.Descriptor instead.
+func (*Discovery) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Discovery) GetCores() uint64 {
+	if x != nil {
+		return x.Cores
+	}
+	return 0
+}
+
+func (x *Discovery) GetRam() uint64 {
+	if x != nil {
+		return x.Ram
+	}
+	return 0
+}
+
+func (x *Discovery) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Discovery) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+func (x *Discovery) GetLibradosVersion() string {
+	if x != nil {
+		return x.LibradosVersion
+	}
+	return ""
+}
+
+func (x *Discovery) GetLibrbdVersion() string {
+	if x != nil {
+		return x.LibrbdVersion
+	}
+	return ""
+}
+
+// The op counts for one phase, keyed by error type ("None" for successful ops).
+type PhaseSummary struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Counts map[string]uint64 `protobuf:"bytes,1,rep,name=counts,proto3" json:"counts,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (x *PhaseSummary) Reset() {
+	*x = PhaseSummary{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PhaseSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PhaseSummary) ProtoMessage() {}
+
+func (x *PhaseSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PhaseSummary.ProtoReflect.Descriptor instead.
+func (*PhaseSummary) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PhaseSummary) GetCounts() map[string]uint64 {
+	if x != nil {
+		return x.Counts
+	}
+	return nil
+}
+
+// A foreman's op counts for the last second, keyed by phase.
+type StatSummary struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Phases map[string]*PhaseSummary `protobuf:"bytes,1,rep,name=phases,proto3" json:"phases,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *StatSummary) Reset() {
+	*x = StatSummary{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatSummary) ProtoMessage() {}
+
+func (x *StatSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatSummary.ProtoReflect.Descriptor instead.
+func (*StatSummary) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StatSummary) GetPhases() map[string]*PhaseSummary {
+	if x != nil {
+		return x.Phases
+	}
+	return nil
+}
+
+// Durations are in nanoseconds.
+type ThinkTime struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Min  int64 `protobuf:"varint,1,opt,name=min,proto3" json:"min,omitempty"`
+	Max  int64 `protobuf:"varint,2,opt,name=max,proto3" json:"max,omitempty"`
+	Mean int64 `protobuf:"varint,3,opt,name=mean,proto3" json:"mean,omitempty"`
+}
+
+func (x *ThinkTime) Reset() {
+	*x = ThinkTime{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ThinkTime) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ThinkTime) ProtoMessage() {}
+
+func (x *ThinkTime) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ThinkTime.ProtoReflect.Descriptor instead.
+func (*ThinkTime) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ThinkTime) GetMin() int64 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *ThinkTime) GetMax() int64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *ThinkTime) GetMean() int64 {
+	if x != nil {
+		return x.Mean
+	}
+	return 0
+}
+
+type PhaseCaps struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Write     uint64 `protobuf:"varint,1,opt,name=write,proto3" json:"write,omitempty"`
+	Read      uint64 `protobuf:"varint,2,opt,name=read,proto3" json:"read,omitempty"`
+	ReadWrite uint64 `protobuf:"varint,3,opt,name=read_write,json=readWrite,proto3" json:"read_write,omitempty"`
+	Stat      uint64 `protobuf:"varint,4,opt,name=stat,proto3" json:"stat,omitempty"`
+}
+
+func (x *PhaseCaps) Reset() {
+	*x = PhaseCaps{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PhaseCaps) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PhaseCaps) ProtoMessage() {}
+
+func (x *PhaseCaps) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PhaseCaps.ProtoReflect.Descriptor instead.
+func (*PhaseCaps) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PhaseCaps) GetWrite() uint64 {
+	if x != nil {
+		return x.Write
+	}
+	return 0
+}
+
+func (x *PhaseCaps) GetRead() uint64 {
+	if x != nil {
+		return x.Read
+	}
+	return 0
+}
+
+func (x *PhaseCaps) GetReadWrite() uint64 {
+	if x != nil {
+		return x.ReadWrite
+	}
+	return 0
+}
+
+func (x *PhaseCaps) GetStat() uint64 {
+	if x != nil {
+		return x.Stat
+	}
+	return 0
+}
+
+type SizeMix struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sizes      []uint64  `protobuf:"varint,1,rep,packed,name=sizes,proto3" json:"sizes,omitempty"`
+	Cumulative []float64 `protobuf:"fixed64,2,rep,packed,name=cumulative,proto3" json:"cumulative,omitempty"`
+}
+
+func (x *SizeMix) Reset() {
+	*x = SizeMix{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SizeMix) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SizeMix) ProtoMessage() {}
+
+func (x *SizeMix) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SizeMix.ProtoReflect.Descriptor instead.
+func (*SizeMix) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SizeMix) GetSizes() []uint64 {
+	if x != nil {
+		return x.Sizes
+	}
+	return nil
+}
+
+func (x *SizeMix) GetCumulative() []float64 {
+	if x != nil {
+		return x.Cumulative
+	}
+	return nil
+}
+
+type ReadRange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Offset   uint64  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length   uint64  `protobuf:"varint,2,opt,name=length,proto3" json:"length,omitempty"`
+	Fraction float64 `protobuf:"fixed64,3,opt,name=fraction,proto3" json:"fraction,omitempty"`
+}
+
+func (x *ReadRange) Reset() {
+	*x = ReadRange{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReadRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadRange) ProtoMessage() {}
+
+func (x *ReadRange) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadRange.ProtoReflect.Descriptor instead.
+func (*ReadRange) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReadRange) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ReadRange) GetLength() uint64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *ReadRange) GetFraction() float64 {
+	if x != nil {
+		return x.Fraction
+	}
+	return 0
+}
+
+type WorkOrder struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	JobId                   uint64     `protobuf:"varint,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Bandwidth               uint64     `protobuf:"varint,2,opt,name=bandwidth,proto3" json:"bandwidth,omitempty"`
+	WorkerFactor            float64    `protobuf:"fixed64,3,opt,name=worker_factor,json=workerFactor,proto3" json:"worker_factor,omitempty"`
+	SkipReadValidation      bool       `protobuf:"varint,4,opt,name=skip_read_validation,json=skipReadValidation,proto3" json:"skip_read_validation,omitempty"`
+	ReadWriteMix            uint64     `protobuf:"varint,5,opt,name=read_write_mix,json=readWriteMix,proto3" json:"read_write_mix,omitempty"`
+	ConnectionReuse         bool       `protobuf:"varint,6,opt,name=connection_reuse,json=connectionReuse,proto3" json:"connection_reuse,omitempty"`
+	HttpErrorStats          bool       `protobuf:"varint,7,opt,name=http_error_stats,json=httpErrorStats,proto3" json:"http_error_stats,omitempty"`
+	VerifyOverwrites        bool       `protobuf:"varint,8,opt,name=verify_overwrites,json=verifyOverwrites,proto3" json:"verify_overwrites,omitempty"`
+	HotSetSize              uint64     `protobuf:"varint,9,opt,name=hot_set_size,json=hotSetSize,proto3" json:"hot_set_size,omitempty"`
+	HotSetRandom            bool       `protobuf:"varint,10,opt,name=hot_set_random,json=hotSetRandom,proto3" json:"hot_set_random,omitempty"`
+	ReadRandom              bool       `protobuf:"varint,11,opt,name=read_random,json=readRandom,proto3" json:"read_random,omitempty"`
+	NoWrite                 bool       `protobuf:"varint,12,opt,name=no_write,json=noWrite,proto3" json:"no_write,omitempty"`
+	StatUploadWindow        uint64     `protobuf:"varint,13,opt,name=stat_upload_window,json=statUploadWindow,proto3" json:"stat_upload_window,omitempty"`
+	ThinkTime               *ThinkTime `protobuf:"bytes,14,opt,name=think_time,json=thinkTime,proto3" json:"think_time,omitempty"`
+	BurstOps                uint64     `protobuf:"varint,15,opt,name=burst_ops,json=burstOps,proto3" json:"burst_ops,omitempty"`
+	BurstIdleMillis         uint64     `protobuf:"varint,16,opt,name=burst_idle_millis,json=burstIdleMillis,proto3" json:"burst_idle_millis,omitempty"`
+	QueueDepth              uint64     `protobuf:"varint,17,opt,name=queue_depth,json=queueDepth,proto3" json:"queue_depth,omitempty"`
+	ReadAhead               uint64     `protobuf:"varint,18,opt,name=read_ahead,json=readAhead,proto3" json:"read_ahead,omitempty"`
+	PinWorkers              bool       `protobuf:"varint,19,opt,name=pin_workers,json=pinWorkers,proto3" json:"pin_workers,omitempty"`
+	OpTimeoutMillis         uint64     `protobuf:"varint,20,opt,name=op_timeout_millis,json=opTimeoutMillis,proto3" json:"op_timeout_millis,omitempty"`
+	MaxRetries              uint64     `protobuf:"varint,21,opt,name=max_retries,json=maxRetries,proto3" json:"max_retries,omitempty"`
+	BucketOps               uint64     `protobuf:"varint,22,opt,name=bucket_ops,json=bucketOps,proto3" json:"bucket_ops,omitempty"`
+	Churn                   float64    `protobuf:"fixed64,23,opt,name=churn,proto3" json:"churn,omitempty"`
+	RampShape               string     `protobuf:"bytes,24,opt,name=ramp_shape,json=rampShape,proto3" json:"ramp_shape,omitempty"`
+	RampUp                  uint64     `protobuf:"varint,25,opt,name=ramp_up,json=rampUp,proto3" json:"ramp_up,omitempty"`
+	StatCompression         string     `protobuf:"bytes,26,opt,name=stat_compression,json=statCompression,proto3" json:"stat_compression,omitempty"`
+	MaxConcurrencyPerTarget uint64     `protobuf:"varint,27,opt,name=max_concurrency_per_target,json=maxConcurrencyPerTarget,proto3" json:"max_concurrency_per_target,omitempty"`
+	AppendObjects           uint64     `protobuf:"varint,28,opt,name=append_objects,json=appendObjects,proto3" json:"append_objects,omitempty"`
+	ExplicitFlush           bool       `protobuf:"varint,29,opt,name=explicit_flush,json=explicitFlush,proto3" json:"explicit_flush,omitempty"`
+	VerifyOnly              bool       `protobuf:"varint,30,opt,name=verify_only,json=verifyOnly,proto3" json:"verify_only,omitempty"`
+	PrewarmReads            bool       `protobuf:"varint,31,opt,name=prewarm_reads,json=prewarmReads,proto3" json:"prewarm_reads,omitempty"`
+	StatObjects             bool       `protobuf:"varint,32,opt,name=stat_objects,json=statObjects,proto3" json:"stat_objects,omitempty"`
+	PhaseCaps               *PhaseCaps `protobuf:"bytes,33,opt,name=phase_caps,json=phaseCaps,proto3" json:"phase_caps,omitempty"`
+	HoldConnections         uint64     `protobuf:"varint,34,opt,name=hold_connections,json=holdConnections,proto3" json:"hold_connections,omitempty"`
+	KeepaliveInterval       uint64     `protobuf:"varint,35,opt,name=keepalive_interval,json=keepaliveInterval,proto3" json:"keepalive_interval,omitempty"`
+	// Object parameters
+	ObjectKeyPrefix string     `protobuf:"bytes,36,opt,name=object_key_prefix,json=objectKeyPrefix,proto3" json:"object_key_prefix,omitempty"`
+	ObjectKeyScheme string     `protobuf:"bytes,37,opt,name=object_key_scheme,json=objectKeyScheme,proto3" json:"object_key_scheme,omitempty"`
+	ObjectSize      uint64     `protobuf:"varint,38,opt,name=object_size,json=objectSize,proto3" json:"object_size,omitempty"`
+	SizeMix         *SizeMix   `protobuf:"bytes,39,opt,name=size_mix,json=sizeMix,proto3" json:"size_mix,omitempty"`
+	PadSize         uint64     `protobuf:"varint,40,opt,name=pad_size,json=padSize,proto3" json:"pad_size,omitempty"`
+	ReadRange       *ReadRange `protobuf:"bytes,41,opt,name=read_range,json=readRange,proto3" json:"read_range,omitempty"`
+	Seed            uint64     `protobuf:"varint,42,opt,name=seed,proto3" json:"seed,omitempty"`
+	GeneratorType   string     `protobuf:"bytes,43,opt,name=generator_type,json=generatorType,proto3" json:"generator_type,omitempty"`
+	RangeStart      uint64     `protobuf:"varint,44,opt,name=range_start,json=rangeStart,proto3" json:"range_start,omitempty"`
+	RangeEnd        uint64     `protobuf:"varint,45,opt,name=range_end,json=rangeEnd,proto3" json:"range_end,omitempty"`
+	// Connection parameters
+	ConnectionType          string            `protobuf:"bytes,46,opt,name=connection_type,json=connectionType,proto3" json:"connection_type,omitempty"`
+	Targets                 []string          `protobuf:"bytes,47,rep,name=targets,proto3" json:"targets,omitempty"`
+	TargetWeights           []uint64          `protobuf:"varint,48,rep,packed,name=target_weights,json=targetWeights,proto3" json:"target_weights,omitempty"`
+	TargetSizes             []uint64          `protobuf:"varint,49,rep,packed,name=target_sizes,json=targetSizes,proto3" json:"target_sizes,omitempty"`
+	ReferenceTarget         string            `protobuf:"bytes,50,opt,name=reference_target,json=referenceTarget,proto3" json:"reference_target,omitempty"`
+	ConsistencyStride       uint64            `protobuf:"varint,51,opt,name=consistency_stride,json=consistencyStride,proto3" json:"consistency_stride,omitempty"`
+	ProtocolConfig          map[string]string `protobuf:"bytes,52,rep,name=protocol_config,json=protocolConfig,proto3" json:"protocol_config,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	GeneratorConfig         map[string]string `protobuf:"bytes,53,rep,name=generator_config,json=generatorConfig,proto3" json:"generator_config,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	CleanUpOnClose          bool              `protobuf:"varint,54,opt,name=clean_up_on_close,json=cleanUpOnClose,proto3" json:"clean_up_on_close,omitempty"`
+	ReferenceProtocolConfig map[string]string `protobuf:"bytes,55,rep,name=reference_protocol_config,json=referenceProtocolConfig,proto3" json:"reference_protocol_config,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *WorkOrder) Reset() {
+	*x = WorkOrder{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_control_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WorkOrder) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WorkOrder) ProtoMessage() {}
+
+func (x *WorkOrder) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WorkOrder.ProtoReflect.Descriptor instead.
+func (*WorkOrder) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WorkOrder) GetJobId() uint64 {
+	if x != nil {
+		return x.JobId
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetBandwidth() uint64 {
+	if x != nil {
+		return x.Bandwidth
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetWorkerFactor() float64 {
+	if x != nil {
+		return x.WorkerFactor
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetSkipReadValidation() bool {
+	if x != nil {
+		return x.SkipReadValidation
+	}
+	return false
+}
+
+func (x *WorkOrder) GetReadWriteMix() uint64 {
+	if x != nil {
+		return x.ReadWriteMix
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetConnectionReuse() bool {
+	if x != nil {
+		return x.ConnectionReuse
+	}
+	return false
+}
+
+func (x *WorkOrder) GetHttpErrorStats() bool {
+	if x != nil {
+		return x.HttpErrorStats
+	}
+	return false
+}
+
+func (x *WorkOrder) GetVerifyOverwrites() bool {
+	if x != nil {
+		return x.VerifyOverwrites
+	}
+	return false
+}
+
+func (x *WorkOrder) GetHotSetSize() uint64 {
+	if x != nil {
+		return x.HotSetSize
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetHotSetRandom() bool {
+	if x != nil {
+		return x.HotSetRandom
+	}
+	return false
+}
+
+func (x *WorkOrder) GetReadRandom() bool {
+	if x != nil {
+		return x.ReadRandom
+	}
+	return false
+}
+
+func (x *WorkOrder) GetNoWrite() bool {
+	if x != nil {
+		return x.NoWrite
+	}
+	return false
+}
+
+func (x *WorkOrder) GetStatUploadWindow() uint64 {
+	if x != nil {
+		return x.StatUploadWindow
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetThinkTime() *ThinkTime {
+	if x != nil {
+		return x.ThinkTime
+	}
+	return nil
+}
+
+func (x *WorkOrder) GetBurstOps() uint64 {
+	if x != nil {
+		return x.BurstOps
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetBurstIdleMillis() uint64 {
+	if x != nil {
+		return x.BurstIdleMillis
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetQueueDepth() uint64 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetReadAhead() uint64 {
+	if x != nil {
+		return x.ReadAhead
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetPinWorkers() bool {
+	if x != nil {
+		return x.PinWorkers
+	}
+	return false
+}
+
+func (x *WorkOrder) GetOpTimeoutMillis() uint64 {
+	if x != nil {
+		return x.OpTimeoutMillis
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetMaxRetries() uint64 {
+	if x != nil {
+		return x.MaxRetries
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetBucketOps() uint64 {
+	if x != nil {
+		return x.BucketOps
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetChurn() float64 {
+	if x != nil {
+		return x.Churn
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetRampShape() string {
+	if x != nil {
+		return x.RampShape
+	}
+	return ""
+}
+
+func (x *WorkOrder) GetRampUp() uint64 {
+	if x != nil {
+		return x.RampUp
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetStatCompression() string {
+	if x != nil {
+		return x.StatCompression
+	}
+	return ""
+}
+
+func (x *WorkOrder) GetMaxConcurrencyPerTarget() uint64 {
+	if x != nil {
+		return x.MaxConcurrencyPerTarget
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetAppendObjects() uint64 {
+	if x != nil {
+		return x.AppendObjects
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetExplicitFlush() bool {
+	if x != nil {
+		return x.ExplicitFlush
+	}
+	return false
+}
+
+func (x *WorkOrder) GetVerifyOnly() bool {
+	if x != nil {
+		return x.VerifyOnly
+	}
+	return false
+}
+
+func (x *WorkOrder) GetPrewarmReads() bool {
+	if x != nil {
+		return x.PrewarmReads
+	}
+	return false
+}
+
+func (x *WorkOrder) GetStatObjects() bool {
+	if x != nil {
+		return x.StatObjects
+	}
+	return false
+}
+
+func (x *WorkOrder) GetPhaseCaps() *PhaseCaps {
+	if x != nil {
+		return x.PhaseCaps
+	}
+	return nil
+}
+
+func (x *WorkOrder) GetHoldConnections() uint64 {
+	if x != nil {
+		return x.HoldConnections
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetKeepaliveInterval() uint64 {
+	if x != nil {
+		return x.KeepaliveInterval
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetObjectKeyPrefix() string {
+	if x != nil {
+		return x.ObjectKeyPrefix
+	}
+	return ""
+}
+
+func (x *WorkOrder) GetObjectKeyScheme() string {
+	if x != nil {
+		return x.ObjectKeyScheme
+	}
+	return ""
+}
+
+func (x *WorkOrder) GetObjectSize() uint64 {
+	if x != nil {
+		return x.ObjectSize
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetSizeMix() *SizeMix {
+	if x != nil {
+		return x.SizeMix
+	}
+	return nil
+}
+
+func (x *WorkOrder) GetPadSize() uint64 {
+	if x != nil {
+		return x.PadSize
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetReadRange() *ReadRange {
+	if x != nil {
+		return x.ReadRange
+	}
+	return nil
+}
+
+func (x *WorkOrder) GetSeed() uint64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetGeneratorType() string {
+	if x != nil {
+		return x.GeneratorType
+	}
+	return ""
+}
+
+func (x *WorkOrder) GetRangeStart() uint64 {
+	if x != nil {
+		return x.RangeStart
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetRangeEnd() uint64 {
+	if x != nil {
+		return x.RangeEnd
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetConnectionType() string {
+	if x != nil {
+		return x.ConnectionType
+	}
+	return ""
+}
+
+func (x *WorkOrder) GetTargets() []string {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+func (x *WorkOrder) GetTargetWeights() []uint64 {
+	if x != nil {
+		return x.TargetWeights
+	}
+	return nil
+}
+
+func (x *WorkOrder) GetTargetSizes() []uint64 {
+	if x != nil {
+		return x.TargetSizes
+	}
+	return nil
+}
+
+func (x *WorkOrder) GetReferenceTarget() string {
+	if x != nil {
+		return x.ReferenceTarget
+	}
+	return ""
+}
+
+func (x *WorkOrder) GetConsistencyStride() uint64 {
+	if x != nil {
+		return x.ConsistencyStride
+	}
+	return 0
+}
+
+func (x *WorkOrder) GetProtocolConfig() map[string]string {
+	if x != nil {
+		return x.ProtocolConfig
+	}
+	return nil
+}
+
+func (x *WorkOrder) GetGeneratorConfig() map[string]string {
+	if x != nil {
+		return x.GeneratorConfig
+	}
+	return nil
+}
+
+func (x *WorkOrder) GetCleanUpOnClose() bool {
+	if x != nil {
+		return x.CleanUpOnClose
+	}
+	return false
+}
+
+func (x *WorkOrder) GetReferenceProtocolConfig() map[string]string {
+	if x != nil {
+		return x.ReferenceProtocolConfig
+	}
+	return nil
+}
+
+var File_control_proto protoreflect.FileDescriptor
+
+var file_control_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x07, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x22, 0x34, 0x0a, 0x0c, 0x50, 0x68, 0x61, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x24, 0x0a, 0x05, 0x70, 0x68, 0x61, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x0e, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x50, 0x68, 0x61, 0x73, 0x65,
+	0x52, 0x05, 0x70, 0x68, 0x61, 0x73, 0x65, 0x22, 0xbe, 0x01, 0x0a, 0x09, 0x44, 0x69, 0x73, 0x63,
+	0x6f, 0x76, 0x65, 0x72, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x72,
+	0x61, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x72, 0x61, 0x6d, 0x12, 0x18, 0x0a,
+	0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x67, 0x6f, 0x5f, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x67, 0x6f, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x29, 0x0a, 0x10, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x64,
+	0x6f, 0x73, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0f, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x64, 0x6f, 0x73, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x25, 0x0a, 0x0e, 0x6c, 0x69, 0x62, 0x72, 0x62, 0x64, 0x5f, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6c, 0x69, 0x62, 0x72, 0x62,
+	0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x84, 0x01, 0x0a, 0x0c, 0x50, 0x68, 0x61,
+	0x73, 0x65, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12, 0x39, 0x0a, 0x06, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x73, 0x69, 0x62, 0x65,
+	0x6e, 0x63, 0x68, 0x2e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79,
+	0x2e, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x63, 0x6f,
+	0x75, 0x6e, 0x74, 0x73, 0x1a, 0x39, 0x0a, 0x0b, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22,
+	0x99, 0x01, 0x0a, 0x0b, 0x53, 0x74, 0x61, 0x74, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12,
+	0x38, 0x0a, 0x06, 0x70, 0x68, 0x61, 0x73, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x20, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x53, 0x75,
+	0x6d, 0x6d, 0x61, 0x72, 0x79, 0x2e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x06, 0x70, 0x68, 0x61, 0x73, 0x65, 0x73, 0x1a, 0x50, 0x0a, 0x0b, 0x50, 0x68, 0x61,
+	0x73, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x2b, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x73, 0x69, 0x62, 0x65,
+	0x6e, 0x63, 0x68, 0x2e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x43, 0x0a, 0x09, 0x54,
+	0x68, 0x69, 0x6e, 0x6b, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x69, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x6d, 0x69, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x61,
+	0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x6d, 0x61, 0x78, 0x12, 0x12, 0x0a, 0x04,
+	0x6d, 0x65, 0x61, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x6d, 0x65, 0x61, 0x6e,
+	0x22, 0x68, 0x0a, 0x09, 0x50, 0x68, 0x61, 0x73, 0x65, 0x43, 0x61, 0x70, 0x73, 0x12, 0x14, 0x0a,
+	0x05, 0x77, 0x72, 0x69, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x77, 0x72,
+	0x69, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x65, 0x61, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x04, 0x72, 0x65, 0x61, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x61, 0x64, 0x5f,
+	0x77, 0x72, 0x69, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x72, 0x65, 0x61,
+	0x64, 0x57, 0x72, 0x69, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x74, 0x61, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x73, 0x74, 0x61, 0x74, 0x22, 0x3f, 0x0a, 0x07, 0x53, 0x69,
+	0x7a, 0x65, 0x4d, 0x69, 0x78, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x69, 0x7a, 0x65, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x04, 0x52, 0x05, 0x73, 0x69, 0x7a, 0x65, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x63,
+	0x75, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x69, 0x76, 0x65, 0x18, 0x02, 0x20, 0x03, 0x28, 0x01, 0x52,
+	0x0a, 0x63, 0x75, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x69, 0x76, 0x65, 0x22, 0x57, 0x0a, 0x09, 0x52,
+	0x65, 0x61, 0x64, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73,
+	0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74,
+	0x12, 0x16, 0x0a, 0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x06, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x72, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x66, 0x72, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x22, 0x98, 0x13, 0x0a, 0x09, 0x57, 0x6f, 0x72, 0x6b, 0x4f, 0x72, 0x64,
+	0x65, 0x72, 0x12, 0x15, 0x0a, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x05, 0x6a, 0x6f, 0x62, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x61, 0x6e,
+	0x64, 0x77, 0x69, 0x64, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x62, 0x61,
+	0x6e, 0x64, 0x77, 0x69, 0x64, 0x74, 0x68, 0x12, 0x23, 0x0a, 0x0d, 0x77, 0x6f, 0x72, 0x6b, 0x65,
+	0x72, 0x5f, 0x66, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0c,
+	0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x30, 0x0a, 0x14,
+	0x73, 0x6b, 0x69, 0x70, 0x5f, 0x72, 0x65, 0x61, 0x64, 0x5f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x12, 0x73, 0x6b, 0x69, 0x70,
+	0x52, 0x65, 0x61, 0x64, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x24,
+	0x0a, 0x0e, 0x72, 0x65, 0x61, 0x64, 0x5f, 0x77, 0x72, 0x69, 0x74, 0x65, 0x5f, 0x6d, 0x69, 0x78,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x72, 0x65, 0x61, 0x64, 0x57, 0x72, 0x69, 0x74,
+	0x65, 0x4d, 0x69, 0x78, 0x12, 0x29, 0x0a, 0x10, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x5f, 0x72, 0x65, 0x75, 0x73, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0f,
+	0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x75, 0x73, 0x65, 0x12,
+	0x28, 0x0a, 0x10, 0x68, 0x74, 0x74, 0x70, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x73, 0x74,
+	0x61, 0x74, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x68, 0x74, 0x74, 0x70, 0x45,
+	0x72, 0x72, 0x6f, 0x72, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x2b, 0x0a, 0x11, 0x76, 0x65, 0x72,
+	0x69, 0x66, 0x79, 0x5f, 0x6f, 0x76, 0x65, 0x72, 0x77, 0x72, 0x69, 0x74, 0x65, 0x73, 0x18, 0x08,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x76, 0x65, 0x72, 0x69, 0x66, 0x79, 0x4f, 0x76, 0x65, 0x72,
+	0x77, 0x72, 0x69, 0x74, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x0c, 0x68, 0x6f, 0x74, 0x5f, 0x73, 0x65,
+	0x74, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x68, 0x6f,
+	0x74, 0x53, 0x65, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x24, 0x0a, 0x0e, 0x68, 0x6f, 0x74, 0x5f,
+	0x73, 0x65, 0x74, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0c, 0x68, 0x6f, 0x74, 0x53, 0x65, 0x74, 0x52, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x12, 0x1f,
+	0x0a, 0x0b, 0x72, 0x65, 0x61, 0x64, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x18, 0x0b, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0a, 0x72, 0x65, 0x61, 0x64, 0x52, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x12,
+	0x19, 0x0a, 0x08, 0x6e, 0x6f, 0x5f, 0x77, 0x72, 0x69, 0x74, 0x65, 0x18, 0x0c, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x6e, 0x6f, 0x57, 0x72, 0x69, 0x74, 0x65, 0x12, 0x2c, 0x0a, 0x12, 0x73, 0x74,
+	0x61, 0x74, 0x5f, 0x75, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x5f, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77,
+	0x18, 0x0d, 0x20, 0x01, 0x28, 0x04, 0x52, 0x10, 0x73, 0x74, 0x61, 0x74, 0x55, 0x70, 0x6c, 0x6f,
+	0x61, 0x64, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x12, 0x31, 0x0a, 0x0a, 0x74, 0x68, 0x69, 0x6e,
+	0x6b, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73,
+	0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x54, 0x68, 0x69, 0x6e, 0x6b, 0x54, 0x69, 0x6d, 0x65,
+	0x52, 0x09, 0x74, 0x68, 0x69, 0x6e, 0x6b, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x62,
+	0x75, 0x72, 0x73, 0x74, 0x5f, 0x6f, 0x70, 0x73, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08,
+	0x62, 0x75, 0x72, 0x73, 0x74, 0x4f, 0x70, 0x73, 0x12, 0x2a, 0x0a, 0x11, 0x62, 0x75, 0x72, 0x73,
+	0x74, 0x5f, 0x69, 0x64, 0x6c, 0x65, 0x5f, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x73, 0x18, 0x10, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x0f, 0x62, 0x75, 0x72, 0x73, 0x74, 0x49, 0x64, 0x6c, 0x65, 0x4d, 0x69,
+	0x6c, 0x6c, 0x69, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x71, 0x75, 0x65, 0x75, 0x65, 0x5f, 0x64, 0x65,
+	0x70, 0x74, 0x68, 0x18, 0x11, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x71, 0x75, 0x65, 0x75, 0x65,
+	0x44, 0x65, 0x70, 0x74, 0x68, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x61, 0x64, 0x5f, 0x61, 0x68,
+	0x65, 0x61, 0x64, 0x18, 0x12, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x72, 0x65, 0x61, 0x64, 0x41,
+	0x68, 0x65, 0x61, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x69, 0x6e, 0x5f, 0x77, 0x6f, 0x72, 0x6b,
+	0x65, 0x72, 0x73, 0x18, 0x13, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x70, 0x69, 0x6e, 0x57, 0x6f,
+	0x72, 0x6b, 0x65, 0x72, 0x73, 0x12, 0x2a, 0x0a, 0x11, 0x6f, 0x70, 0x5f, 0x74, 0x69, 0x6d, 0x65,
+	0x6f, 0x75, 0x74, 0x5f, 0x6d, 0x69, 0x6c, 0x6c, 0x69, 0x73, 0x18, 0x14, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x0f, 0x6f, 0x70, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x4d, 0x69, 0x6c, 0x6c, 0x69,
+	0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x5f, 0x72, 0x65, 0x74, 0x72, 0x69, 0x65, 0x73,
+	0x18, 0x15, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x6d, 0x61, 0x78, 0x52, 0x65, 0x74, 0x72, 0x69,
+	0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x5f, 0x6f, 0x70, 0x73,
+	0x18, 0x16, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x4f, 0x70,
+	0x73, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x68, 0x75, 0x72, 0x6e, 0x18, 0x17, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x05, 0x63, 0x68, 0x75, 0x72, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x61, 0x6d, 0x70, 0x5f,
+	0x73, 0x68, 0x61, 0x70, 0x65, 0x18, 0x18, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x61, 0x6d,
+	0x70, 0x53, 0x68, 0x61, 0x70, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x61, 0x6d, 0x70, 0x5f, 0x75,
+	0x70, 0x18, 0x19, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x72, 0x61, 0x6d, 0x70, 0x55, 0x70, 0x12,
+	0x29, 0x0a, 0x10, 0x73, 0x74, 0x61, 0x74, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x1a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x73, 0x74, 0x61, 0x74, 0x43,
+	0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x3b, 0x0a, 0x1a, 0x6d, 0x61,
+	0x78, 0x5f, 0x63, 0x6f, 0x6e, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x5f, 0x70, 0x65,
+	0x72, 0x5f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x1b, 0x20, 0x01, 0x28, 0x04, 0x52, 0x17,
+	0x6d, 0x61, 0x78, 0x43, 0x6f, 0x6e, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x50, 0x65,
+	0x72, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x61, 0x70, 0x70, 0x65, 0x6e,
+	0x64, 0x5f, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x73, 0x18, 0x1c, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x0d, 0x61, 0x70, 0x70, 0x65, 0x6e, 0x64, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x73, 0x12, 0x25,
+	0x0a, 0x0e, 0x65, 0x78, 0x70, 0x6c, 0x69, 0x63, 0x69, 0x74, 0x5f, 0x66, 0x6c, 0x75, 0x73, 0x68,
+	0x18, 0x1d, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x65, 0x78, 0x70, 0x6c, 0x69, 0x63, 0x69, 0x74,
+	0x46, 0x6c, 0x75, 0x73, 0x68, 0x12, 0x1f, 0x0a, 0x0b, 0x76, 0x65, 0x72, 0x69, 0x66, 0x79, 0x5f,
+	0x6f, 0x6e, 0x6c, 0x79, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x76, 0x65, 0x72, 0x69,
+	0x66, 0x79, 0x4f, 0x6e, 0x6c, 0x79, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x72, 0x65, 0x77, 0x61, 0x72,
+	0x6d, 0x5f, 0x72, 0x65, 0x61, 0x64, 0x73, 0x18, 0x1f, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x70,
+	0x72, 0x65, 0x77, 0x61, 0x72, 0x6d, 0x52, 0x65, 0x61, 0x64, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x73,
+	0x74, 0x61, 0x74, 0x5f, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x73, 0x18, 0x20, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x0b, 0x73, 0x74, 0x61, 0x74, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x73, 0x12, 0x31,
+	0x0a, 0x0a, 0x70, 0x68, 0x61, 0x73, 0x65, 0x5f, 0x63, 0x61, 0x70, 0x73, 0x18, 0x21, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x50, 0x68, 0x61,
+	0x73, 0x65, 0x43, 0x61, 0x70, 0x73, 0x52, 0x09, 0x70, 0x68, 0x61, 0x73, 0x65, 0x43, 0x61, 0x70,
+	0x73, 0x12, 0x29, 0x0a, 0x10, 0x68, 0x6f, 0x6c, 0x64, 0x5f, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x22, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x68, 0x6f, 0x6c,
+	0x64, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x2d, 0x0a, 0x12,
+	0x6b, 0x65, 0x65, 0x70, 0x61, 0x6c, 0x69, 0x76, 0x65, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76,
+	0x61, 0x6c, 0x18, 0x23, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11, 0x6b, 0x65, 0x65, 0x70, 0x61, 0x6c,
+	0x69, 0x76, 0x65, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x2a, 0x0a, 0x11, 0x6f,
+	0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x6b, 0x65, 0x79, 0x5f, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78,
+	0x18, 0x24, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x4b, 0x65,
+	0x79, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x2a, 0x0a, 0x11, 0x6f, 0x62, 0x6a, 0x65, 0x63,
+	0x74, 0x5f, 0x6b, 0x65, 0x79, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x65, 0x18, 0x25, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0f, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x4b, 0x65, 0x79, 0x53, 0x63, 0x68,
+	0x65, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x73, 0x69,
+	0x7a, 0x65, 0x18, 0x26, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74,
+	0x53, 0x69, 0x7a, 0x65, 0x12, 0x2b, 0x0a, 0x08, 0x73, 0x69, 0x7a, 0x65, 0x5f, 0x6d, 0x69, 0x78,
+	0x18, 0x27, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68,
+	0x2e, 0x53, 0x69, 0x7a, 0x65, 0x4d, 0x69, 0x78, 0x52, 0x07, 0x73, 0x69, 0x7a, 0x65, 0x4d, 0x69,
+	0x78, 0x12, 0x19, 0x0a, 0x08, 0x70, 0x61, 0x64, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x28, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x07, 0x70, 0x61, 0x64, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x31, 0x0a, 0x0a,
+	0x72, 0x65, 0x61, 0x64, 0x5f, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x18, 0x29, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x12, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x52, 0x65, 0x61, 0x64, 0x52,
+	0x61, 0x6e, 0x67, 0x65, 0x52, 0x09, 0x72, 0x65, 0x61, 0x64, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x73, 0x65, 0x65, 0x64, 0x18, 0x2a, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x73,
+	0x65, 0x65, 0x64, 0x12, 0x25, 0x0a, 0x0e, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72,
+	0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x2b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x67, 0x65, 0x6e,
+	0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x61,
+	0x6e, 0x67, 0x65, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x2c, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x0a, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x53, 0x74, 0x61, 0x72, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x72,
+	0x61, 0x6e, 0x67, 0x65, 0x5f, 0x65, 0x6e, 0x64, 0x18, 0x2d, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08,
+	0x72, 0x61, 0x6e, 0x67, 0x65, 0x45, 0x6e, 0x64, 0x12, 0x27, 0x0a, 0x0f, 0x63, 0x6f, 0x6e, 0x6e,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x2e, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0e, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x79, 0x70,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x18, 0x2f, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x74,
+	0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x73, 0x18, 0x30, 0x20,
+	0x03, 0x28, 0x04, 0x52, 0x0d, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x57, 0x65, 0x69, 0x67, 0x68,
+	0x74, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x73, 0x69, 0x7a,
+	0x65, 0x73, 0x18, 0x31, 0x20, 0x03, 0x28, 0x04, 0x52, 0x0b, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x53, 0x69, 0x7a, 0x65, 0x73, 0x12, 0x29, 0x0a, 0x10, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e,
+	0x63, 0x65, 0x5f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x32, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0f, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x12, 0x2d, 0x0a, 0x12, 0x63, 0x6f, 0x6e, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x5f,
+	0x73, 0x74, 0x72, 0x69, 0x64, 0x65, 0x18, 0x33, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11, 0x63, 0x6f,
+	0x6e, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x53, 0x74, 0x72, 0x69, 0x64, 0x65, 0x12,
+	0x4f, 0x0a, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x18, 0x34, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e,
+	0x63, 0x68, 0x2e, 0x57, 0x6f, 0x72, 0x6b, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x2e, 0x50, 0x72, 0x6f,
+	0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x0e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x12, 0x52, 0x0a, 0x10, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x5f, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x18, 0x35, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x73, 0x69, 0x62,
+	0x65, 0x6e, 0x63, 0x68, 0x2e, 0x57, 0x6f, 0x72, 0x6b, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x2e, 0x47,
+	0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x52, 0x0f, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x12, 0x29, 0x0a, 0x11, 0x63, 0x6c, 0x65, 0x61, 0x6e, 0x5f, 0x75, 0x70,
+	0x5f, 0x6f, 0x6e, 0x5f, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x18, 0x36, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0e, 0x63, 0x6c, 0x65, 0x61, 0x6e, 0x55, 0x70, 0x4f, 0x6e, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x12,
+	0x6b, 0x0a, 0x19, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x5f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x37, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x2f, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x57, 0x6f, 0x72,
+	0x6b, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x2e, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65,
+	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x52, 0x17, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x50, 0x72,
+	0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x1a, 0x41, 0x0a, 0x13,
+	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a,
+	0x42, 0x0a, 0x14, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x1a, 0x4a, 0x0a, 0x1c, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65,
+	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x2a,
+	0xce, 0x01, 0x0a, 0x05, 0x50, 0x68, 0x61, 0x73, 0x65, 0x12, 0x15, 0x0a, 0x11, 0x50, 0x48, 0x41,
+	0x53, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00,
+	0x12, 0x0f, 0x0a, 0x0b, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x57, 0x52, 0x49, 0x54, 0x45, 0x10,
+	0x01, 0x12, 0x11, 0x0a, 0x0d, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x50, 0x52, 0x45, 0x50, 0x41,
+	0x52, 0x45, 0x10, 0x02, 0x12, 0x0e, 0x0a, 0x0a, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x53, 0x54,
+	0x41, 0x54, 0x10, 0x03, 0x12, 0x0e, 0x0a, 0x0a, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x52, 0x45,
+	0x41, 0x44, 0x10, 0x04, 0x12, 0x14, 0x0a, 0x10, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x52, 0x45,
+	0x41, 0x44, 0x5f, 0x57, 0x52, 0x49, 0x54, 0x45, 0x10, 0x05, 0x12, 0x10, 0x0a, 0x0c, 0x50, 0x48,
+	0x41, 0x53, 0x45, 0x5f, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x10, 0x06, 0x12, 0x14, 0x0a, 0x10,
+	0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x42, 0x55, 0x43, 0x4b, 0x45, 0x54, 0x5f, 0x4f, 0x50, 0x53,
+	0x10, 0x07, 0x12, 0x1a, 0x0a, 0x16, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x48, 0x4f, 0x4c, 0x44,
+	0x5f, 0x43, 0x4f, 0x4e, 0x4e, 0x45, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x53, 0x10, 0x08, 0x12, 0x10,
+	0x0a, 0x0c, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x56, 0x45, 0x52, 0x49, 0x46, 0x59, 0x10, 0x09,
+	0x32, 0xfa, 0x01, 0x0a, 0x07, 0x46, 0x6f, 0x72, 0x65, 0x6d, 0x61, 0x6e, 0x12, 0x31, 0x0a, 0x07,
+	0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x12, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63,
+	0x68, 0x2e, 0x57, 0x6f, 0x72, 0x6b, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x1a, 0x12, 0x2e, 0x73, 0x69,
+	0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x79, 0x12,
+	0x2e, 0x0a, 0x05, 0x53, 0x74, 0x61, 0x72, 0x74, 0x12, 0x15, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e,
+	0x63, 0x68, 0x2e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x0e, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12,
+	0x2d, 0x0a, 0x04, 0x53, 0x74, 0x6f, 0x70, 0x12, 0x15, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63,
+	0x68, 0x2e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e,
+	0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x2f,
+	0x0a, 0x05, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x0e, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63,
+	0x68, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x14, 0x2e, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63,
+	0x68, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x30, 0x01, 0x12,
+	0x2c, 0x0a, 0x0a, 0x44, 0x69, 0x73, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x12, 0x0e, 0x2e,
+	0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0e, 0x2e,
+	0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x13, 0x5a,
+	0x11, 0x73, 0x69, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x2f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c,
+	0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_control_proto_rawDescOnce sync.Once
+	file_control_proto_rawDescData = file_control_proto_rawDesc
+)
+
+func file_control_proto_rawDescGZIP() []byte {
+	file_control_proto_rawDescOnce.Do(func() {
+		file_control_proto_rawDescData = protoimpl.X.CompressGZIP(file_control_proto_rawDescData)
+	})
+	return file_control_proto_rawDescData
+}
+
+var file_control_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_control_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_control_proto_goTypes = []interface{}{
+	(Phase)(0),           // 0: sibench.Phase
+	(*Empty)(nil),        // 1: sibench.Empty
+	(*PhaseRequest)(nil), // 2: sibench.PhaseRequest
+	(*Discovery)(nil),    // 3: sibench.Discovery
+	(*PhaseSummary)(nil), // 4: sibench.PhaseSummary
+	(*StatSummary)(nil),  // 5: sibench.StatSummary
+	(*ThinkTime)(nil),    // 6: sibench.ThinkTime
+	(*PhaseCaps)(nil),    // 7: sibench.PhaseCaps
+	(*SizeMix)(nil),      // 8: sibench.SizeMix
+	(*ReadRange)(nil),    // 9: sibench.ReadRange
+	(*WorkOrder)(nil),    // 10: sibench.WorkOrder
+	nil,                  // 11: sibench.PhaseSummary.CountsEntry
+	nil,                  // 12: sibench.StatSummary.PhasesEntry
+	nil,                  // 13: sibench.WorkOrder.ProtocolConfigEntry
+	nil,                  // 14: sibench.WorkOrder.GeneratorConfigEntry
+	nil,                  // 15: sibench.WorkOrder.ReferenceProtocolConfigEntry
+}
+var file_control_proto_depIdxs = []int32{
+	0,  // 0: sibench.PhaseRequest.phase:type_name -> sibench.Phase
+	11, // 1: sibench.PhaseSummary.counts:type_name -> sibench.PhaseSummary.CountsEntry
+	12, // 2: sibench.StatSummary.phases:type_name -> sibench.StatSummary.PhasesEntry
+	6,  // 3: sibench.WorkOrder.think_time:type_name -> sibench.ThinkTime
+	7,  // 4: sibench.WorkOrder.phase_caps:type_name -> sibench.PhaseCaps
+	8,  // 5: sibench.WorkOrder.size_mix:type_name -> sibench.SizeMix
+	9,  // 6: sibench.WorkOrder.read_range:type_name -> sibench.ReadRange
+	13, // 7: sibench.WorkOrder.protocol_config:type_name -> sibench.WorkOrder.ProtocolConfigEntry
+	14, // 8: sibench.WorkOrder.generator_config:type_name -> sibench.WorkOrder.GeneratorConfigEntry
+	15, // 9: sibench.WorkOrder.reference_protocol_config:type_name -> sibench.WorkOrder.ReferenceProtocolConfigEntry
+	4,  // 10: sibench.StatSummary.PhasesEntry.value:type_name -> sibench.PhaseSummary
+	10, // 11: sibench.Foreman.Connect:input_type -> sibench.WorkOrder
+	2,  // 12: sibench.Foreman.Start:input_type -> sibench.PhaseRequest
+	2,  // 13: sibench.Foreman.Stop:input_type -> sibench.PhaseRequest
+	1,  // 14: sibench.Foreman.Stats:input_type -> sibench.Empty
+	1,  // 15: sibench.Foreman.Disconnect:input_type -> sibench.Empty
+	3,  // 16: sibench.Foreman.Connect:output_type -> sibench.Discovery
+	1,  // 17: sibench.Foreman.Start:output_type -> sibench.Empty
+	1,  // 18: sibench.Foreman.Stop:output_type -> sibench.Empty
+	5,  // 19: sibench.Foreman.Stats:output_type -> sibench.StatSummary
+	1,  // 20: sibench.Foreman.Disconnect:output_type -> sibench.Empty
+	16, // [16:21] is the sub-list for method output_type
+	11, // [11:16] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_control_proto_init() }
+func file_control_proto_init() {
+	if File_control_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_control_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Empty); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_control_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PhaseRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_control_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Discovery); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_control_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PhaseSummary); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_control_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatSummary); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_control_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ThinkTime); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_control_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PhaseCaps); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_control_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SizeMix); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_control_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReadRange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_control_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WorkOrder); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_control_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_control_proto_goTypes,
+		DependencyIndexes: file_control_proto_depIdxs,
+		EnumInfos:         file_control_proto_enumTypes,
+		MessageInfos:      file_control_proto_msgTypes,
+	}.Build()
+	File_control_proto = out.File
+	file_control_proto_rawDesc = nil
+	file_control_proto_goTypes = nil
+	file_control_proto_depIdxs = nil
+}
@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "bytes"
+import "encoding/binary"
+import "fmt"
+import "strconv"
+
+
+/* The size of the blocks from which we build objects.  Dedupe engines typically work on 4K chunks. */
+const dedupeBlockSize = 4096
+
+/* The number of blocks in our pool of duplicated content. */
+const dedupePoolSize = 256
+
+/* The smallest object we can generate, since each one starts with its 8 byte seed. */
+const DedupeMinSize = 8
+
+
+/*
+ * DedupeGenerator creates workloads for testing storage systems that de-duplicate data.
+ *
+ * Each object is built from fixed-size blocks.  The first block holds our header, and so is
+ * always unique.  Each block after that is either copied from a small pool of blocks (and so
+ * duplicates content found in other objects), or is filled with pseudorandom data (and so is
+ * unique).  The ratio determines what fraction of the blocks come from the pool.
+ *
+ * The pool is generated from the master seed, so all workers on all drivers share the same pool,
+ * and so will duplicate each other's content too.
+ *
+ * As with the slice generator, we write the object's seed into its header, which lets us
+ * recreate the object to verify reads.  The seed is derived from the master seed and the
+ * object's id and cycle, so that we can also detect stale objects.
+ */
+type DedupeGenerator struct {
+    seed uint64
+    ratio float64
+    pool [][]byte
+}
+
+
+
+func CreateDedupeGenerator(seed uint64, config GeneratorConfig) (*DedupeGenerator, error) {
+    var dg DedupeGenerator
+    var err error
+
+    dg.seed = seed
+    dg.ratio, err = strconv.ParseFloat(config["ratio"], 64)
+    if err != nil {
+        return nil, fmt.Errorf("Bad dedupe ratio %v: %v", config["ratio"], err)
+    }
+
+    dg.pool = make([][]byte, dedupePoolSize)
+    for i := range dg.pool {
+        dg.pool[i] = make([]byte, dedupeBlockSize)
+        dg.fill(dg.pool[i], prng(seed ^ uint64(i + 1)))
+    }
+
+    return &dg, nil
+}
+
+
+/* Fill a buffer with pseudorandom data from a seed, returning the state of the prng afterwards. */
+func (dg *DedupeGenerator) fill(buf []byte, next uint64) uint64 {
+    pos := 0
+
+    for ; pos + 8 <= len(buf); pos += 8 {
+        next = prng(next)
+        binary.LittleEndian.PutUint64(buf[pos:], next)
+    }
+
+    for ; pos < len(buf); pos++ {
+        buf[pos] = 0
+    }
+
+    return next
+}
+
+
+/* Derive the seed for a particular object from our master seed. */
+func (dg *DedupeGenerator) objectSeed(size uint64, id uint64, cycle uint64) uint64 {
+    next := dg.seed
+    next = prng(next ^ size)
+    next = prng(next ^ cycle)
+    next = prng(next ^ id)
+    return next
+}
+
+
+
+func (dg *DedupeGenerator) Generate(size uint64, id uint64, cycle uint64, buffer *[]byte) {
+    dg.generateFromSeed(size, dg.objectSeed(size, id, cycle), buffer)
+}
+
+
+
+func (dg *DedupeGenerator) generateFromSeed(size uint64, seed uint64, buffer *[]byte) {
+    buf := (*buffer)[:size]
+
+    // The first block is our header, followed by unique data.
+    end := uint64(dedupeBlockSize)
+    if end > size {
+        end = size
+    }
+
+    binary.LittleEndian.PutUint64(buf, seed)
+    next := dg.fill(buf[DedupeMinSize:end], seed)
+
+    // Then every other block is either from the pool or unique.
+    for start := end; start < size; start += dedupeBlockSize {
+        next = prng(next)
+        block := buf[start:]
+
+        // Use the top 53 bits of our prng as a float in [0, 1).
+        if float64(next >> 11) / (1 << 53) < dg.ratio {
+            copy(block, dg.pool[next % dedupePoolSize])
+        } else {
+            if len(block) > dedupeBlockSize {
+                block = block[:dedupeBlockSize]
+            }
+
+            next = dg.fill(block, next)
+        }
+    }
+}
+
+
+
+/*
+ * As generateFromSeed, but only for the part of the object in the buffer.  We generate each block
+ * that overlaps it, and just step our prng over the unique blocks before it.
+ */
+func (dg *DedupeGenerator) GenerateRange(size uint64, id uint64, cycle uint64, offset uint64, buffer []byte) {
+    var block [dedupeBlockSize]byte
+    seed := dg.objectSeed(size, id, cycle)
+    end := offset + uint64(len(buffer))
+
+    // The first block is our header, followed by unique data.
+    blockEnd := uint64(dedupeBlockSize)
+    if blockEnd > size {
+        blockEnd = size
+    }
+
+    next := seed
+    if offset < blockEnd {
+        binary.LittleEndian.PutUint64(block[:], seed)
+        next = dg.fill(block[DedupeMinSize:blockEnd], seed)
+        copyOverlap(buffer, offset, block[:blockEnd], 0)
+    } else {
+        next = prngSkip(seed, (blockEnd - DedupeMinSize) / 8)
+    }
+
+    // Then every other block is either from the pool or unique.
+    for start := blockEnd; start < end; start += dedupeBlockSize {
+        next = prng(next)
+
+        length := size - start
+        if length > dedupeBlockSize {
+            length = dedupeBlockSize
+        }
+
+        fromPool := float64(next >> 11) / (1 << 53) < dg.ratio
+
+        switch {
+            case fromPool:
+                copyOverlap(buffer, offset, dg.pool[next % dedupePoolSize][:length], start)
+
+            case start + length <= offset:
+                next = prngSkip(next, length / 8)
+
+            default:
+                next = dg.fill(block[:length], next)
+                copyOverlap(buffer, offset, block[:length], start)
+        }
+    }
+}
+
+
+
+func (dg *DedupeGenerator) Verify(size uint64, id uint64, cycle uint64, buffer *[]byte, scratch *[]byte) error {
+    if uint64(len(*buffer)) != size {
+        return fmt.Errorf("Incorrect size: expected %v but got %v\n", size, len(*buffer))
+    }
+
+    // Read the seed from the header of the payload
+    seed := binary.LittleEndian.Uint64(*buffer)
+
+    if (cycle != AnyCycle) && (seed != dg.objectSeed(size, id, cycle)) {
+        return fmt.Errorf("Stale object: not written in expected cycle %v\n", cycle)
+    }
+
+    // Now we can generate the expected buffer to compare against.
+    dg.generateFromSeed(size, seed, scratch)
+
+    if bytes.Compare(*buffer, *scratch) != 0 {
+        return fmt.Errorf("Buffers do not match\n")
+    }
+
+    return nil
+}
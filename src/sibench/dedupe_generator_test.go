@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the dedupe generator.
+
+package main
+
+import "math"
+import "strconv"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+func makeTestDedupeGenerator(t *testing.T, ratio float64) *DedupeGenerator {
+    config := GeneratorConfig{ "ratio": strconv.FormatFloat(ratio, 'f', -1, 64) }
+    dg, err := CreateDedupeGenerator(42, config)
+    testutil.CheckNoError(t, err)
+
+    return dg
+}
+
+
+// Generate count objects of the given size, and return the fraction of their blocks which are
+// copies of a block from the generator's pool.  The first block of each object holds its header,
+// and so is always unique: we leave it out.
+func measureDedupeRatio(dg *DedupeGenerator, count uint64, size uint64) float64 {
+    pool := make(map[string]bool)
+    for _, block := range dg.pool {
+        pool[string(block)] = true
+    }
+
+    buffer := make([]byte, size)
+    blocks := 0
+    pooled := 0
+
+    for id := uint64(0); id < count; id++ {
+        dg.Generate(size, id, 1, &buffer)
+
+        for start := uint64(dedupeBlockSize); start < size; start += dedupeBlockSize {
+            blocks++
+            if pool[string(buffer[start:start + dedupeBlockSize])] {
+                pooled++
+            }
+        }
+    }
+
+    return float64(pooled) / float64(blocks)
+}
+
+
+// Test functions.
+
+// Objects of any size must verify, but not if they are from another cycle or have been corrupted.
+func TestDedupeGeneratorRoundTrip(t *testing.T) {
+    dg := makeTestDedupeGenerator(t, 0.5)
+
+    tests := []struct {
+        size uint64
+        id uint64
+        cycle uint64
+    } {
+        { DedupeMinSize, 0, 0 },
+        { 100, 1, 2 },
+        { dedupeBlockSize, 7, 3 },
+        { dedupeBlockSize + 1, 7, 3 },
+        { 10001, 99, 1 },
+        { 1024 * 1024, 12345, 6 },
+    }
+
+    for _, test := range tests {
+        buffer := make([]byte, test.size)
+        scratch := make([]byte, test.size)
+
+        dg.Generate(test.size, test.id, test.cycle, &buffer)
+
+        testutil.CheckNoError(t, dg.Verify(test.size, test.id, test.cycle, &buffer, &scratch))
+        testutil.CheckNoError(t, dg.Verify(test.size, test.id, AnyCycle, &buffer, &scratch))
+        testutil.CheckError(t, dg.Verify(test.size, test.id, test.cycle + 1, &buffer, &scratch))
+
+        buffer[test.size - 1]++
+        testutil.CheckError(t, dg.Verify(test.size, test.id, test.cycle, &buffer, &scratch))
+    }
+}
+
+
+// The fraction of blocks which duplicate the pool must be the ratio that we asked for.
+func TestDedupeGeneratorAchievesRatio(t *testing.T) {
+    tests := []struct {
+        ratio float64
+        size uint64
+    } {
+        { 0, 1024 * 1024 },
+        { 0.25, 1024 * 1024 },
+        { 0.5, 1024 * 1024 },
+        { 0.9, 1024 * 1024 },
+        { 1, 1024 * 1024 },
+        { 0.5, 64 * 1024 },
+    }
+
+    for _, test := range tests {
+        dg := makeTestDedupeGenerator(t, test.ratio)
+        count := 16 * 1024 * 1024 / test.size
+
+        achieved := measureDedupeRatio(dg, count, test.size)
+        if math.Abs(achieved - test.ratio) > 0.02 {
+            t.Errorf("Asked for a dedupe ratio of %v with %v byte objects, but got %v", test.ratio, test.size, achieved)
+        }
+    }
+}
+
+
+// Every worker builds its pool from the same seed, so that they duplicate each other's content.
+func TestDedupeGeneratorSharedPool(t *testing.T) {
+    a := makeTestDedupeGenerator(t, 0.5)
+    b := makeTestDedupeGenerator(t, 0.9)
+
+    for i := range a.pool {
+        testutil.CheckBytes(t, a.pool[i], b.pool[i])
+    }
+}
+
+
+func TestDedupeGeneratorBadConfig(t *testing.T) {
+    for _, ratio := range []string{ "", "lots" } {
+        _, err := CreateDedupeGenerator(42, GeneratorConfig{ "ratio": ratio })
+        testutil.CheckError(t, err)
+    }
+}
@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "bytes"
+import "encoding/binary"
+import "fmt"
+import "math"
+import "strconv"
+
+
+/* The most entropy that a byte can have, in bits. */
+const maxByteEntropy = 8.0
+
+/* Our chance of writing a random byte is a fraction of this, so that we can compare it with 16 bits of prng output. */
+const entropyThresholdScale = 1 << 16
+
+
+/*
+ * EntropyGenerator creates objects whose contents have a given entropy, in bits per byte, so that
+ * compression engines can be driven more precisely than by a ratio.
+ *
+ * Each byte of an object is either a pseudorandom byte, with probability p, or a zero.  The
+ * entropy that we target is the Shannon entropy of the distribution that the bytes are drawn from.
+ * A zero byte has a chance of (1 - p) + p/256, since random bytes can be zero too, and each of the
+ * other 255 values has a chance of p/256.  That gives an entropy of:
+ *
+ *     H(p) = -(1 - p + p/256) * log2(1 - p + p/256) - 255 * (p/256) * log2(p/256)
+ *
+ * which rises steadily from 0 bits (all zeroes) when p is 0, to 8 bits (all random) when p is 1.
+ * We solve this for the p that gives the entropy we were asked for.  Since the bytes are sampled
+ * from that distribution, the entropy measured from the bytes of an object is very close to the
+ * target for objects of more than a few KB, but a little lower for small ones.
+ *
+ * This only controls the frequencies of byte values.  A compressor which also looks at the order
+ * of the bytes may find runs of zeroes to exploit, and so do better than the entropy suggests.
+ *
+ * As with the dedupe generator, we write the object's seed into its header, which lets us
+ * recreate the object to verify reads, and detect stale objects.
+ */
+type EntropyGenerator struct {
+    seed uint64
+    entropy float64
+    threshold uint64    // A byte is random if 16 bits of prng output are below this.
+}
+
+
+
+func CreateEntropyGenerator(seed uint64, config GeneratorConfig) (*EntropyGenerator, error) {
+    var eg EntropyGenerator
+    var err error
+
+    eg.seed = seed
+    eg.entropy, err = strconv.ParseFloat(config["entropy"], 64)
+    if err != nil {
+        return nil, fmt.Errorf("Bad entropy %v: %v", config["entropy"], err)
+    }
+
+    if (eg.entropy < 0) || (eg.entropy > maxByteEntropy) {
+        return nil, fmt.Errorf("Bad entropy %v: must be between 0 and %v bits per byte", eg.entropy, maxByteEntropy)
+    }
+
+    eg.threshold = uint64(math.Round(randomByteChance(eg.entropy) * entropyThresholdScale))
+    return &eg, nil
+}
+
+
+/* The entropy of our bytes, in bits per byte, if each one is random with probability p, and zero otherwise. */
+func mixedByteEntropy(p float64) float64 {
+    h := 0.0
+
+    zero := 1 - p + (p / 256)
+    if zero > 0 {
+        h -= zero * math.Log2(zero)
+    }
+
+    other := p / 256
+    if other > 0 {
+        h -= 255 * other * math.Log2(other)
+    }
+
+    return h
+}
+
+
+/* Find the chance of a byte being random which gives us a target entropy, using a binary search. */
+func randomByteChance(entropy float64) float64 {
+    lo := 0.0
+    hi := 1.0
+
+    for i := 0; i < 64; i++ {
+        mid := (lo + hi) / 2
+        if mixedByteEntropy(mid) < entropy {
+            lo = mid
+        } else {
+            hi = mid
+        }
+    }
+
+    return (lo + hi) / 2
+}
+
+
+/* Derive the seed for a particular object from our master seed. */
+func (eg *EntropyGenerator) objectSeed(size uint64, id uint64, cycle uint64) uint64 {
+    next := eg.seed
+    next = prng(next ^ size)
+    next = prng(next ^ cycle)
+    next = prng(next ^ id)
+    return next
+}
+
+
+
+func (eg *EntropyGenerator) Generate(size uint64, id uint64, cycle uint64, buffer *[]byte) {
+    eg.generateFromSeed(size, eg.objectSeed(size, id, cycle), buffer)
+}
+
+
+
+func (eg *EntropyGenerator) generateFromSeed(size uint64, seed uint64, buffer *[]byte) {
+    buf := (*buffer)[:size]
+    binary.LittleEndian.PutUint64(buf, seed)
+
+    // Each prng value makes two bytes: 16 bits to choose whether each is random, and 8 for its value.
+    next := seed
+    for pos := uint64(8); pos < size; pos += 2 {
+        next = prng(next)
+        buf[pos] = eg.mixedByte(next)
+
+        if pos + 1 < size {
+            buf[pos + 1] = eg.mixedByte(next >> 24)
+        }
+    }
+}
+
+
+/*
+ * As generateFromSeed, but only for the part of the object in the buffer.  Each prng value makes
+ * two bytes, so we can skip straight to the pair that holds the start of the range.
+ */
+func (eg *EntropyGenerator) GenerateRange(size uint64, id uint64, cycle uint64, offset uint64, buffer []byte) {
+    var header [8]byte
+    seed := eg.objectSeed(size, id, cycle)
+    end := offset + uint64(len(buffer))
+
+    binary.LittleEndian.PutUint64(header[:], seed)
+    copyOverlap(buffer, offset, header[:], 0)
+
+    pos := uint64(8)
+    next := seed
+    if offset > pos {
+        pairs := (offset - pos) / 2
+        next = prngSkip(next, pairs)
+        pos += pairs * 2
+    }
+
+    for ; pos < end; pos += 2 {
+        next = prng(next)
+        pair := []byte{ eg.mixedByte(next), eg.mixedByte(next >> 24) }
+
+        if pos + 1 >= size {
+            pair = pair[:1]
+        }
+
+        copyOverlap(buffer, offset, pair, pos)
+    }
+}
+
+
+/* Turn the bottom 24 bits of a prng value into either a random byte or a zero. */
+func (eg *EntropyGenerator) mixedByte(bits uint64) byte {
+    if (bits & 0xffff) < eg.threshold {
+        return byte(bits >> 16)
+    }
+
+    return 0
+}
+
+
+
+func (eg *EntropyGenerator) Verify(size uint64, id uint64, cycle uint64, buffer *[]byte, scratch *[]byte) error {
+    if uint64(len(*buffer)) != size {
+        return fmt.Errorf("Incorrect size: expected %v but got %v\n", size, len(*buffer))
+    }
+
+    // Read the seed from the header of the payload
+    seed := binary.LittleEndian.Uint64(*buffer)
+
+    if (cycle != AnyCycle) && (seed != eg.objectSeed(size, id, cycle)) {
+        return fmt.Errorf("Stale object: not written in expected cycle %v\n", cycle)
+    }
+
+    // Now we can generate the expected buffer to compare against.
+    eg.generateFromSeed(size, seed, scratch)
+
+    if bytes.Compare(*buffer, *scratch) != 0 {
+        return fmt.Errorf("Buffers do not match\n")
+    }
+
+    return nil
+}
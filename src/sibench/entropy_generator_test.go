@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the entropy generator.
+
+package main
+
+import "math"
+import "strconv"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+// Measure the Shannon entropy of a buffer, in bits per byte, from the frequencies of its byte values.
+func measureEntropy(buf []byte) float64 {
+    var counts [256]uint64
+    for _, b := range buf {
+        counts[b]++
+    }
+
+    h := 0.0
+    for _, c := range counts {
+        if c > 0 {
+            p := float64(c) / float64(len(buf))
+            h -= p * math.Log2(p)
+        }
+    }
+
+    return h
+}
+
+
+func makeTestEntropyGenerator(t *testing.T, entropy float64) Generator {
+    config := GeneratorConfig{ "entropy": strconv.FormatFloat(entropy, 'f', -1, 64) }
+    eg, err := CreateEntropyGenerator(42, config)
+    testutil.CheckNoError(t, err)
+
+    return eg
+}
+
+
+// Test functions.
+
+// The objects we generate must have the entropy that we asked for.
+func TestEntropyGeneratorAchievesTarget(t *testing.T) {
+    size := uint64(1024 * 1024)
+    buffer := make([]byte, size)
+
+    for _, target := range []float64{ 0, 0.5, 1, 2.5, 4, 6, 7.5, 8 } {
+        g := makeTestEntropyGenerator(t, target)
+        g.Generate(size, 7, 3, &buffer)
+
+        // Leave out the header, which is the only part that isn't drawn from our distribution.
+        achieved := measureEntropy(buffer[8:])
+        if math.Abs(achieved - target) > 0.02 {
+            t.Errorf("Asked for an entropy of %v bits per byte, but got %v", target, achieved)
+        }
+    }
+}
+
+
+// Generate an object, then verify it, including an odd size which doesn't fill the last prng value.
+func TestEntropyGeneratorRoundTrip(t *testing.T) {
+    g := makeTestEntropyGenerator(t, 3)
+
+    for _, size := range []uint64{ 4096, 10001 } {
+        buffer := make([]byte, size)
+        scratch := make([]byte, size)
+
+        g.Generate(size, 7, 3, &buffer)
+
+        testutil.CheckNoError(t, g.Verify(size, 7, 3, &buffer, &scratch))
+        testutil.CheckNoError(t, g.Verify(size, 7, AnyCycle, &buffer, &scratch))
+        testutil.CheckError(t, g.Verify(size, 7, 4, &buffer, &scratch))
+
+        buffer[size - 1]++
+        testutil.CheckError(t, g.Verify(size, 7, 3, &buffer, &scratch))
+    }
+}
+
+
+func TestEntropyGeneratorBadConfig(t *testing.T) {
+    for _, entropy := range []string{ "", "high", "-0.1", "8.1" } {
+        _, err := CreateEntropyGenerator(42, GeneratorConfig{ "entropy": entropy })
+        testutil.CheckError(t, err)
+    }
+}
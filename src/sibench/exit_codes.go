@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "errors"
+import "fmt"
+import "strings"
+
+
+/*
+ * The exit codes we use, so that automation can tell different kinds of failure apart.
+ * Code 1 is used by DocOpt when it can't parse the command line.
+ */
+const (
+    ExitSuccess = 0
+    ExitSloFailure = 2          // The run completed, but its results breached one of the --slo thresholds.
+    ExitConnectionFailure = 3   // We couldn't connect to (or lost touch with) a target or a server.
+    ExitValidationFailure = 4   // The arguments were parsed, but are not valid.
+    ExitInternalError = 5       // Anything else, including failures reported by the servers.
+    ExitVerifyFailure = 6       // A verification pass found objects which failed verification.
+)
+
+
+/* Wraps an error to mark it as a failure to connect to, or talk to, a target or server. */
+type ConnectionError struct {
+    err error
+}
+
+
+func (e *ConnectionError) Error() string {
+    return e.err.Error()
+}
+
+
+func (e *ConnectionError) Unwrap() error {
+    return e.err
+}
+
+
+func connectionError(err error) error {
+    return &ConnectionError{ err }
+}
+
+
+/* Reports that a verification pass completed, but found objects which failed verification. */
+type VerifyError struct {
+    count uint64
+}
+
+
+func (e *VerifyError) Error() string {
+    return fmt.Sprintf("Verification failed for %v objects", e.count)
+}
+
+
+/* Reports that a run completed, but that its results breached one or more service level thresholds. */
+type SloError struct {
+    breaches []string
+}
+
+
+func (e *SloError) Error() string {
+    return fmt.Sprintf("Service level check failed: %v", strings.Join(e.breaches, "; "))
+}
+
+
+/* Return the exit code we should use for an error from a run. */
+func exitCode(err error) int {
+    var ce *ConnectionError
+    var ve *VerifyError
+    var se *SloError
+
+    switch {
+        case err == nil:            return ExitSuccess
+        case errors.As(err, &ce):   return ExitConnectionFailure
+        case errors.As(err, &ve):   return ExitVerifyFailure
+        case errors.As(err, &se):   return ExitSloFailure
+        default:                    return ExitInternalError
+    }
+}
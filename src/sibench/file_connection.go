@@ -24,7 +24,7 @@ type FileConnection struct {
 
 func NewFileConnection(target string, protocol ProtocolConfig, worker WorkerConnectionConfig) (*FileConnection, error) {
     var conn FileConnection
-    conn.InitFileConnectionBase(".", target)
+    conn.InitFileConnectionBase(".", target, worker.ExplicitFlush)
     return &conn, nil
 }
 
@@ -47,7 +47,7 @@ func (conn *FileConnection) ManagerClose(cleanup bool) error {
 
 func (conn *FileConnection) WorkerConnect() error {
     path := filepath.Join(conn.root, conn.dir)
-    logger.Infof("Creating file connection to %v\n", path)
+    logger.Debugf("Creating file connection to %v\n", path)
 
     // Check the directory is exists.
     info, err := os.Stat(path);
@@ -69,7 +69,7 @@ func (conn *FileConnection) WorkerConnect() error {
 
 func (conn *FileConnection) WorkerClose(cleanup bool) error {
     path := filepath.Join(conn.root, conn.dir)
-    logger.Infof("Closing file connection to %v\n", path)
+    logger.Debugf("Closing file connection to %v\n", path)
     return nil
 }
 
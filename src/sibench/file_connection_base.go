@@ -4,14 +4,13 @@
 package main
 
 
+import "context"
 import "path/filepath"
 import "fmt"
 import "logger"
 import "os"
 import "strings"
 import "syscall"
-import "errors"
-import "io"
 
 
 /* 
@@ -29,13 +28,17 @@ type FileConnectionBase struct {
     root string
     dir string
     dirsCreated []string
+
+    /* If true, we write without syncing, and leave it to FlushObject */
+    explicitFlush bool
 }
 
 
-func (conn *FileConnectionBase) InitFileConnectionBase(root string, dir string) {
+func (conn *FileConnectionBase) InitFileConnectionBase(root string, dir string, explicitFlush bool) {
     logger.Debugf("Initialising file connection on %v with dir %v\n", root, dir)
     conn.root = root
     conn.dir = dir
+    conn.explicitFlush = explicitFlush
 }
 
 
@@ -69,7 +72,7 @@ func (conn *FileConnectionBase) CreateDirectories() error {
         if err != nil { return err }
 
         if !exists {
-            err = os.Mkdir(path, 0644)
+            err = os.Mkdir(path, 0755)
             if err != nil { return err }
 
             logger.Infof("Created dir: %v\n", path)
@@ -103,30 +106,88 @@ func (conn *FileConnectionBase) CanDelete() bool {
 }
 
 
-func (conn *FileConnectionBase) PutObject(key string, id uint64, buffer []byte) error {
+func (conn *FileConnectionBase) PutObject(ctx context.Context, key string, id uint64, buffer []byte) error {
     filename := filepath.Join(conn.root, conn.dir, key)
+    mode := syscall.O_WRONLY | syscall.O_CREAT | syscall.O_TRUNC
+
+    var fd FileDescriptor
+    var err error
+
+    if conn.explicitFlush {
+        fd, err = OpenUnsynced(filename, mode, 0644)
+    } else {
+        fd, err = Open(filename, mode, 0644)
+    }
 
-    fd, err := Open(filename, syscall.O_WRONLY | syscall.O_CREAT | syscall.O_TRUNC, 0644)
     if err != nil {
         return err
     }
 
     defer fd.Close()
 
-    for len(buffer) > 0 {
-        n, err := fd.Write(buffer)
-        if (err != nil) && (!errors.Is(err, io.ErrShortWrite)) {
-            return err
-        }
+    // A second write could land after another process's append, splitting our record in two, so
+    // we make a single write, and treat a short one as a failure.
+    n, err := fd.Write(buffer)
+    if err != nil {
+        return err
+    }
 
-        buffer = buffer[n:]
+    if n != len(buffer) {
+        return fmt.Errorf("Short append to %v: wrote %v of %v bytes", key, n, len(buffer))
     }
 
     return nil
 }
 
 
-func (conn *FileConnectionBase) GetObject(key string, id uint64, buffer []byte) error {
+/*
+ * Syncs an object written without syncing.  The sync applies to the file, and not just to the
+ * descriptor that wrote it, so we can open the file afresh to do it.
+ */
+func (conn *FileConnectionBase) FlushObject(key string, id uint64) error {
+    filename := filepath.Join(conn.root, conn.dir, key)
+
+    fd, err := OpenUnsynced(filename, syscall.O_WRONLY, 0644)
+    if err != nil {
+        return err
+    }
+
+    defer fd.Close()
+    return fd.Sync()
+}
+
+
+/*
+ * With O_APPEND, the kernel moves to the end of the file and writes as one step, so appends from
+ * the processes on a single machine can't overwrite each other.  Network filesystems such as NFS
+ * and CephFS don't promise the same between machines.
+ */
+func (conn *FileConnectionBase) AppendObject(key string, id uint64, buffer []byte) error {
+    filename := filepath.Join(conn.root, conn.dir, key)
+
+    fd, err := Open(filename, syscall.O_WRONLY | syscall.O_CREAT | syscall.O_APPEND, 0644)
+    if err != nil {
+        return err
+    }
+
+    defer fd.Close()
+
+    // A second write could land after another process's append, splitting our record in two, so
+    // we make a single write, and treat a short one as a failure.
+    n, err := fd.Write(buffer)
+    if err != nil {
+        return err
+    }
+
+    if n != len(buffer) {
+        return fmt.Errorf("Short append to %v: wrote %v of %v bytes", key, n, len(buffer))
+    }
+
+    return nil
+}
+
+
+func (conn *FileConnectionBase) GetObject(ctx context.Context, key string, id uint64, buffer []byte) error {
     filename := filepath.Join(conn.root, conn.dir, key)
 
     fd, err := Open(filename, syscall.O_RDONLY, 0644)
@@ -161,7 +222,44 @@ func (conn *FileConnectionBase) GetObject(key string, id uint64, buffer []byte)
 }
 
 
-func (conn *FileConnectionBase) DeleteObject(key string, id uint64) error {
+func (conn *FileConnectionBase) GetObjectRange(ctx context.Context, key string, id uint64, offset uint64, buffer []byte) error {
+    filename := filepath.Join(conn.root, conn.dir, key)
+
+    fd, err := Open(filename, syscall.O_RDONLY, 0644)
+    if err != nil {
+        return err
+    }
+
+    defer fd.Close()
+
+    start := int64(offset)
+
+    for len(buffer) > 0 {
+        n, err := fd.Pread(buffer, start)
+        if err != nil {
+            return err
+        }
+
+        if n == 0 {
+            return fmt.Errorf("Short read: file ended at offset %v", start)
+        }
+
+        buffer = buffer[n:]
+        start += int64(n)
+    }
+
+    return nil
+}
+
+
+func (conn *FileConnectionBase) StatObject(key string, id uint64) error {
+    filename := filepath.Join(conn.root, conn.dir, key)
+    _, err := os.Stat(filename)
+    return err
+}
+
+
+func (conn *FileConnectionBase) DeleteObject(ctx context.Context, key string, id uint64) error {
     filename := filepath.Join(conn.root, conn.dir, key)
     return os.Remove(filename)
 }
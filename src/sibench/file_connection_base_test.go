@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for FileConnectionBase's directory handling and object operations.
+
+package main
+
+import "bytes"
+import "os"
+import "path/filepath"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+func exists(path string) bool {
+    _, err := os.Stat(path)
+    return err == nil
+}
+
+
+// Test functions.
+
+// Create a nested dir, some of which already exists, then clean it up again.
+func TestFileConnectionBaseNestedDirectories(t *testing.T) {
+    root := t.TempDir()
+    err := os.Mkdir(filepath.Join(root, "a"), 0755)
+    testutil.CheckNoError(t, err)
+
+    var conn FileConnectionBase
+    conn.InitFileConnectionBase(root, "a/b/c", false)
+
+    err = conn.CreateDirectories()
+
+    testutil.CheckNoError(t, err)
+    testutil.CheckBool(t, true, exists(filepath.Join(root, "a", "b", "c")))
+    testutil.CheckInt(t, 2, len(conn.dirsCreated))
+
+    // We must be able to use the new directory.
+    err = os.WriteFile(filepath.Join(root, "a", "b", "c", "obj"), []byte{1, 2, 3}, 0644)
+    testutil.CheckNoError(t, err)
+    err = os.Remove(filepath.Join(root, "a", "b", "c", "obj"))
+    testutil.CheckNoError(t, err)
+
+    err = conn.DeleteDirectories()
+
+    testutil.CheckNoError(t, err)
+    testutil.CheckBool(t, false, exists(filepath.Join(root, "a", "b")))
+    testutil.CheckBool(t, true, exists(filepath.Join(root, "a")))
+}
+
+
+// Cleanup must remove the deepest directories first.
+func TestFileConnectionBaseDeleteOrder(t *testing.T) {
+    root := t.TempDir()
+
+    var conn FileConnectionBase
+    conn.InitFileConnectionBase(root, "x/y/z", false)
+
+    err := conn.CreateDirectories()
+
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 3, len(conn.dirsCreated))
+    testutil.CheckBytes(t, []byte(filepath.Join(root, "x", "y", "z")), []byte(conn.dirsCreated[0]))
+    testutil.CheckBytes(t, []byte(filepath.Join(root, "x")), []byte(conn.dirsCreated[2]))
+
+    err = conn.DeleteDirectories()
+
+    testutil.CheckNoError(t, err)
+    testutil.CheckBool(t, false, exists(filepath.Join(root, "x")))
+    testutil.CheckBool(t, true, exists(root))
+}
+
+
+// Directories that already existed must be left alone.
+func TestFileConnectionBaseExistingDirectories(t *testing.T) {
+    root := t.TempDir()
+    err := os.MkdirAll(filepath.Join(root, "p", "q"), 0755)
+    testutil.CheckNoError(t, err)
+
+    var conn FileConnectionBase
+    conn.InitFileConnectionBase(root, "p/q", false)
+
+    err = conn.CreateDirectories()
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 0, len(conn.dirsCreated))
+
+    err = conn.DeleteDirectories()
+    testutil.CheckNoError(t, err)
+    testutil.CheckBool(t, true, exists(filepath.Join(root, "p", "q")))
+}
+
+
+// Stat must find objects which exist, and fail for those which don't.
+func TestFileConnectionBaseStatObject(t *testing.T) {
+    root := t.TempDir()
+
+    var conn FileConnectionBase
+    conn.InitFileConnectionBase(root, "d", false)
+
+    err := conn.CreateDirectories()
+    testutil.CheckNoError(t, err)
+
+    err = os.WriteFile(filepath.Join(root, "d", "obj"), []byte{1, 2, 3}, 0644)
+    testutil.CheckNoError(t, err)
+
+    testutil.CheckNoError(t, conn.StatObject("obj", 0))
+    testutil.CheckError(t, conn.StatObject("missing", 1))
+}
+
+
+// Each append is added whole to the end of the object, creating it if need be.  Our files use
+// O_DIRECT, so the appends are whole, aligned blocks.
+func TestFileConnectionBaseAppendObject(t *testing.T) {
+    root := t.TempDir()
+
+    var conn FileConnectionBase
+    conn.InitFileConnectionBase(root, "d", false)
+
+    err := conn.CreateDirectories()
+    testutil.CheckNoError(t, err)
+
+    first := bytes.Repeat([]byte{1}, readRangeAlignment)
+    second := bytes.Repeat([]byte{2}, readRangeAlignment)
+
+    testutil.CheckNoError(t, conn.AppendObject("obj", 0, first))
+    testutil.CheckNoError(t, conn.AppendObject("obj", 0, second))
+
+    data, err := os.ReadFile(filepath.Join(root, "d", "obj"))
+    testutil.CheckNoError(t, err)
+    testutil.CheckBytes(t, append(first, second...), data)
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "strings"
+import "time"
+
+
+/*
+ * A report in a subset of fio's JSON output format (as written by fio --output-format=json), so
+ * that tooling which parses fio results can read ours.
+ *
+ * Each fio job is one of our analysis subjects: first the totals, then each target, then each
+ * server.  A job's write and read sections come from the Write (or Append) and Read analyses of
+ * that subject.  We have no equivalent of fio's trim, so that section is always empty, as it is
+ * for a fio job which does no trims.  Bucket and churn ops have no home in fio's schema, and are
+ * left out.
+ *
+ * fio's field names don't follow Go's conventions, so these types need json tags.
+ */
+type fioReport struct {
+    FioVersion string                       `json:"fio version"`
+    Timestamp int64                         `json:"timestamp"`
+    TimestampMs int64                       `json:"timestamp_ms"`
+    Time string                             `json:"time"`
+    GlobalOptions map[string]string         `json:"global options"`
+    Jobs []*fioJob                          `json:"jobs"`
+    DiskUtil []interface{}                  `json:"disk_util"`
+}
+
+
+type fioJob struct {
+    JobName string                          `json:"jobname"`
+    GroupId int                             `json:"groupid"`
+    Error int                               `json:"error"`
+    TotalErr uint64                         `json:"total_err"`
+    Read fioDirection                       `json:"read"`
+    Write fioDirection                      `json:"write"`
+    Trim fioDirection                       `json:"trim"`
+}
+
+
+/* The results for one direction (read, write or trim) of a job. */
+type fioDirection struct {
+    IoBytes uint64                          `json:"io_bytes"`
+    IoKBytes uint64                         `json:"io_kbytes"`
+    BwBytes uint64                          `json:"bw_bytes"`
+    Bw uint64                               `json:"bw"`
+    Iops float64                            `json:"iops"`
+    Runtime uint64                          `json:"runtime"`
+    TotalIos uint64                         `json:"total_ios"`
+    ShortIos uint64                         `json:"short_ios"`
+    DropIos uint64                          `json:"drop_ios"`
+    ClatNs fioLatency                       `json:"clat_ns"`
+    LatNs fioLatency                        `json:"lat_ns"`
+}
+
+
+/* Latencies in nanoseconds.  Percentile is keyed by fio's formatting of each percentile, such as "99.900000". */
+type fioLatency struct {
+    Min uint64                              `json:"min"`
+    Max uint64                              `json:"max"`
+    Mean float64                            `json:"mean"`
+    N uint64                                `json:"N"`
+    Percentile map[string]uint64            `json:"percentile,omitempty"`
+}
+
+
+/* Build a fio report from a job and the analyses of its results. */
+func newFioReport(job *Job, analyses []*Analysis) *fioReport {
+    now := time.Now()
+
+    r := fioReport {
+        FioVersion: "sibench-" + Version,
+        Timestamp: now.Unix(),
+        TimestampMs: now.UnixNano() / int64(time.Millisecond),
+        Time: now.Format(time.ANSIC),
+        GlobalOptions: map[string]string {
+            "bs": fmt.Sprintf("%v", job.order.ObjectSize),
+            "runtime": fmt.Sprintf("%v", job.runTime),
+            "ramp_time": fmt.Sprintf("%v", job.rampUp) },
+        DiskUtil: []interface{}{} }
+
+    // Our analyses are named for their subject and then their phase, such as "Total Write".
+    jobs := make(map[string]*fioJob)
+    var names []string
+
+    for _, a := range analyses {
+        read := a.Phase == SP_Read.ToString()
+        if !read && (a.Phase != SP_Write.ToString()) && (a.Phase != SP_Append.ToString()) {
+            continue
+        }
+
+        name := strings.TrimSuffix(a.Name, " " + a.Phase)
+        j, ok := jobs[name]
+        if !ok {
+            j = &fioJob{ JobName: name }
+            jobs[name] = j
+            names = append(names, name)
+        }
+
+        j.TotalErr += a.Failures()
+
+        if read {
+            j.Read = newFioDirection(a, job.runTime)
+        } else {
+            j.Write = newFioDirection(a, job.runTime)
+        }
+    }
+
+    // Our analyses have the totals last, but fio tooling usually only looks at the first job.
+    for _, name := range names {
+        if strings.HasPrefix(name, "Total") {
+            r.Jobs = append(r.Jobs, jobs[name])
+        }
+    }
+
+    for _, name := range names {
+        if !strings.HasPrefix(name, "Total") {
+            r.Jobs = append(r.Jobs, jobs[name])
+        }
+    }
+
+    return &r
+}
+
+
+func newFioDirection(a *Analysis, runTime uint64) fioDirection {
+    d := fioDirection {
+        IoBytes: a.BandwidthBytes * runTime,
+        BwBytes: a.BandwidthBytes,
+        Runtime: runTime * 1000,
+        TotalIos: a.Successes }
+
+    d.IoKBytes = d.IoBytes / 1024
+    d.Bw = d.BwBytes / 1024
+    d.Iops = float64(a.Successes) / float64(runTime)
+
+    if a.Successes > 0 {
+        d.LatNs = fioLatency {
+            Min: a.ResTimeMin * 1000,
+            Max: a.ResTimeMax * 1000,
+            Mean: float64(a.ResTimeAvg * 1000),
+            N: a.Successes }
+
+        // We only time whole ops, so our completion latencies are the same as our total latencies.
+        d.ClatNs = d.LatNs
+        d.ClatNs.Percentile = make(map[string]uint64)
+        for _, p := range a.ResTimePercentiles {
+            d.ClatNs.Percentile[fmt.Sprintf("%f", p.Percentile)] = p.ResTime * 1000
+        }
+    }
+
+    return d
+}
@@ -24,6 +24,9 @@ const InitialHangTimeoutSecs = 90
 // The value below which our dynamically adjusted hang timeout will not drop.
 const MinHangTimeoutSecs = 60
 
+// How many stats each worker pre-allocates when we can't tell how much memory we have.
+const defaultStatPreallocationCount = 64 * 1024
+
 
 /*
  * All the states a Foreman can be in.
@@ -54,6 +57,20 @@ const(
     FS_ReadWriteStopDone
     FS_Delete
     FS_DeleteDone
+    FS_BucketOpsStart
+    FS_BucketOpsStartDone
+    FS_BucketOpsStop
+    FS_BucketOpsStopDone
+    FS_Verify
+    FS_VerifyDone
+    FS_StatOpsStart
+    FS_StatOpsStartDone
+    FS_StatOpsStop
+    FS_StatOpsStopDone
+    FS_HoldConnectionsStart
+    FS_HoldConnectionsStartDone
+    FS_HoldConnectionsStop
+    FS_HoldConnectionsStopDone
     FS_Terminate
     FS_Hung
 )
@@ -99,6 +116,20 @@ var stateDetails = map[foremanState]foremanStateDetails {
     FS_ReadWriteStopDone:  { "ReadWriteStopDone",   false,  "",             "" },
     FS_Delete:             { "Delete",              true,   "",             "" },
     FS_DeleteDone:         { "DeleteDone",          false,  "",             "" },
+    FS_BucketOpsStart:     { "BucketOpsStart",      true,   "bucket_ops",   "" },
+    FS_BucketOpsStartDone: { "BucketOpsStartDone",  false,  "",             "" },
+    FS_BucketOpsStop:      { "BucketOpsStop",       false,  "",             "bucket_ops" },
+    FS_BucketOpsStopDone:  { "BucketOpsStopDone",   false,  "",             "" },
+    FS_Verify:             { "Verify",              true,   "",             "" },
+    FS_VerifyDone:         { "VerifyDone",          false,  "",             "" },
+    FS_StatOpsStart:       { "StatOpsStart",        true,   "stat",         "" },
+    FS_StatOpsStartDone:   { "StatOpsStartDone",    false,  "",             "" },
+    FS_StatOpsStop:        { "StatOpsStop",         false,  "",             "stat" },
+    FS_StatOpsStopDone:    { "StatOpsStopDone",     false,  "",             "" },
+    FS_HoldConnectionsStart:     { "HoldConnectionsStart",      true,   "hold_connections", "" },
+    FS_HoldConnectionsStartDone: { "HoldConnectionsStartDone",  false,  "",                 "" },
+    FS_HoldConnectionsStop:      { "HoldConnectionsStop",       false,  "",                 "hold_connections" },
+    FS_HoldConnectionsStopDone:  { "HoldConnectionsStopDone",   false,  "",                 "" },
     FS_Terminate:          { "Terminate",           false,  "",             "" },
     FS_Hung:               { "Hung",                false,  "",             "" },
 }
@@ -123,17 +154,42 @@ var validTcpTransitions = map[Opcode]map[foremanState]foremanState {
     OP_WriteStop:           { FS_WriteStartDone:        FS_WriteStop },
     OP_Prepare:             { FS_ConnectDone:           FS_Prepare,
                               FS_WriteStopDone:         FS_Prepare },
-    OP_ReadStart:           { FS_PrepareDone:           FS_ReadStart },
+    OP_ReadStart:           { FS_PrepareDone:           FS_ReadStart,
+                              FS_StatOpsStopDone:       FS_ReadStart,
+                              FS_VerifyDone:            FS_ReadStart },
     OP_ReadStop:            { FS_ReadStartDone:         FS_ReadStop },
     OP_ReadWriteStart:      { FS_PrepareDone:           FS_ReadWriteStart },
     OP_ReadWriteStop:       { FS_ReadWriteStartDone:    FS_ReadWriteStop },
-    OP_Delete:              { FS_ReadStopDone:          FS_Delete,
-                              FS_ReadWriteStopDone:     FS_Delete },
+    OP_Delete:              { FS_WriteStopDone:         FS_Delete,
+                              FS_ReadStopDone:          FS_Delete,
+                              FS_ReadWriteStopDone:     FS_Delete,
+                              FS_VerifyDone:            FS_Delete },
+    OP_BucketOpsStart:      { FS_ConnectDone:           FS_BucketOpsStart },
+    OP_BucketOpsStop:       { FS_BucketOpsStartDone:    FS_BucketOpsStop },
+    OP_Verify:              { FS_PrepareDone:           FS_Verify },
+    OP_StatOpsStart:        { FS_PrepareDone:           FS_StatOpsStart,
+                              FS_VerifyDone:            FS_StatOpsStart },
+    OP_StatOpsStop:         { FS_StatOpsStartDone:      FS_StatOpsStop },
+    OP_HoldConnectionsStart:  { FS_ConnectDone:               FS_HoldConnectionsStart },
+    OP_HoldConnectionsStop:   { FS_HoldConnectionsStartDone:  FS_HoldConnectionsStop },
     OP_StatDetails:         { FS_WriteStopDone:         FS_WriteStopDone,
                               FS_PrepareDone:           FS_PrepareDone,
                               FS_ReadStopDone:          FS_ReadStopDone,
                               FS_ReadWriteStopDone:     FS_ReadWriteStopDone,
-                              FS_DeleteDone:            FS_DeleteDone },
+                              FS_DeleteDone:            FS_DeleteDone,
+                              FS_BucketOpsStopDone:     FS_BucketOpsStopDone,
+                              FS_VerifyDone:            FS_VerifyDone,
+                              FS_StatOpsStopDone:       FS_StatOpsStopDone,
+                              FS_HoldConnectionsStopDone:   FS_HoldConnectionsStopDone },
+    OP_StatDetailsAck:        { FS_WriteStopDone:         FS_WriteStopDone,
+                              FS_PrepareDone:           FS_PrepareDone,
+                              FS_ReadStopDone:          FS_ReadStopDone,
+                              FS_ReadWriteStopDone:     FS_ReadWriteStopDone,
+                              FS_DeleteDone:            FS_DeleteDone,
+                              FS_BucketOpsStopDone:     FS_BucketOpsStopDone,
+                              FS_VerifyDone:            FS_VerifyDone,
+                              FS_StatOpsStopDone:       FS_StatOpsStopDone,
+                              FS_HoldConnectionsStopDone:   FS_HoldConnectionsStopDone },
     OP_StatSummaryStart:    { FS_ConnectDone:           FS_ConnectDone,
                               FS_WriteStart:            FS_WriteStart,
                               FS_WriteStartDone:        FS_WriteStartDone,
@@ -150,7 +206,21 @@ var validTcpTransitions = map[Opcode]map[foremanState]foremanState {
                               FS_ReadWriteStop:         FS_ReadWriteStop,
                               FS_ReadWriteStopDone:     FS_ReadWriteStopDone,
                               FS_Delete:                FS_Delete,
-                              FS_DeleteDone:            FS_DeleteDone },
+                              FS_DeleteDone:            FS_DeleteDone,
+                              FS_BucketOpsStart:        FS_BucketOpsStart,
+                              FS_BucketOpsStartDone:    FS_BucketOpsStartDone,
+                              FS_BucketOpsStop:         FS_BucketOpsStop,
+                              FS_BucketOpsStopDone:     FS_BucketOpsStopDone,
+                              FS_Verify:                FS_Verify,
+                              FS_VerifyDone:            FS_VerifyDone,
+                              FS_StatOpsStart:          FS_StatOpsStart,
+                              FS_StatOpsStartDone:      FS_StatOpsStartDone,
+                              FS_StatOpsStop:           FS_StatOpsStop,
+                              FS_StatOpsStopDone:       FS_StatOpsStopDone,
+                              FS_HoldConnectionsStart:      FS_HoldConnectionsStart,
+                              FS_HoldConnectionsStartDone:  FS_HoldConnectionsStartDone,
+                              FS_HoldConnectionsStop:       FS_HoldConnectionsStop,
+                              FS_HoldConnectionsStopDone:   FS_HoldConnectionsStopDone },
     OP_StatSummaryStop:     { FS_WriteStart:            FS_WriteStart,
                               FS_WriteStartDone:        FS_WriteStartDone,
                               FS_WriteStop:             FS_WriteStop,
@@ -166,7 +236,21 @@ var validTcpTransitions = map[Opcode]map[foremanState]foremanState {
                               FS_ReadWriteStop:         FS_ReadWriteStop,
                               FS_ReadWriteStopDone:     FS_ReadWriteStopDone,
                               FS_Delete:                FS_Delete,
-                              FS_DeleteDone:            FS_DeleteDone },
+                              FS_DeleteDone:            FS_DeleteDone,
+                              FS_BucketOpsStart:        FS_BucketOpsStart,
+                              FS_BucketOpsStartDone:    FS_BucketOpsStartDone,
+                              FS_BucketOpsStop:         FS_BucketOpsStop,
+                              FS_BucketOpsStopDone:     FS_BucketOpsStopDone,
+                              FS_Verify:                FS_Verify,
+                              FS_VerifyDone:            FS_VerifyDone,
+                              FS_StatOpsStart:          FS_StatOpsStart,
+                              FS_StatOpsStartDone:      FS_StatOpsStartDone,
+                              FS_StatOpsStop:           FS_StatOpsStop,
+                              FS_StatOpsStopDone:       FS_StatOpsStopDone,
+                              FS_HoldConnectionsStart:      FS_HoldConnectionsStart,
+                              FS_HoldConnectionsStartDone:  FS_HoldConnectionsStartDone,
+                              FS_HoldConnectionsStop:       FS_HoldConnectionsStop,
+                              FS_HoldConnectionsStopDone:   FS_HoldConnectionsStopDone },
     OP_Terminate:           { FS_Idle:                  FS_Terminate,
                               FS_Connect:               FS_Terminate,
                               FS_ConnectDone:           FS_Terminate,
@@ -186,6 +270,20 @@ var validTcpTransitions = map[Opcode]map[foremanState]foremanState {
                               FS_ReadWriteStopDone:     FS_Terminate,
                               FS_Delete:                FS_Terminate,
                               FS_DeleteDone:            FS_Terminate,
+                              FS_BucketOpsStart:        FS_Terminate,
+                              FS_BucketOpsStartDone:    FS_Terminate,
+                              FS_BucketOpsStop:         FS_Terminate,
+                              FS_BucketOpsStopDone:     FS_Terminate,
+                              FS_Verify:                FS_Terminate,
+                              FS_VerifyDone:            FS_Terminate,
+                              FS_StatOpsStart:          FS_Terminate,
+                              FS_StatOpsStartDone:      FS_Terminate,
+                              FS_StatOpsStop:           FS_Terminate,
+                              FS_StatOpsStopDone:       FS_Terminate,
+                              FS_HoldConnectionsStart:      FS_Terminate,
+                              FS_HoldConnectionsStartDone:  FS_Terminate,
+                              FS_HoldConnectionsStop:       FS_Terminate,
+                              FS_HoldConnectionsStopDone:   FS_Terminate,
                               FS_Terminate:             FS_Terminate,
                               FS_Hung:                  FS_Hung },
 }
@@ -203,6 +301,13 @@ var validWorkerTransitions = map[Opcode]map[foremanState]foremanState {
     OP_ReadWriteStart:  { FS_ReadWriteStart:    FS_ReadWriteStartDone },
     OP_ReadWriteStop:   { FS_ReadWriteStop:     FS_ReadWriteStopDone },
     OP_Delete:          { FS_Delete:            FS_DeleteDone },
+    OP_BucketOpsStart:  { FS_BucketOpsStart:    FS_BucketOpsStartDone },
+    OP_BucketOpsStop:   { FS_BucketOpsStop:     FS_BucketOpsStopDone },
+    OP_Verify:          { FS_Verify:            FS_VerifyDone },
+    OP_StatOpsStart:    { FS_StatOpsStart:      FS_StatOpsStartDone },
+    OP_StatOpsStop:     { FS_StatOpsStop:       FS_StatOpsStopDone },
+    OP_HoldConnectionsStart:  { FS_HoldConnectionsStart:  FS_HoldConnectionsStartDone },
+    OP_HoldConnectionsStop:   { FS_HoldConnectionsStop:   FS_HoldConnectionsStopDone },
     OP_Terminate:       { FS_Terminate:         FS_Idle },
     OP_Fail:            { FS_Connect:           FS_Terminate,
                           FS_WriteStart:        FS_Terminate,
@@ -212,6 +317,13 @@ var validWorkerTransitions = map[Opcode]map[foremanState]foremanState {
                           FS_ReadStop:          FS_Terminate,
                           FS_ReadWriteStart:    FS_Terminate,
                           FS_ReadWriteStop:     FS_Terminate,
+                          FS_BucketOpsStart:    FS_Terminate,
+                          FS_BucketOpsStop:     FS_Terminate,
+                          FS_Verify:            FS_Terminate,
+                          FS_StatOpsStart:      FS_Terminate,
+                          FS_StatOpsStop:       FS_Terminate,
+                          FS_HoldConnectionsStart:  FS_Terminate,
+                          FS_HoldConnectionsStop:   FS_Terminate,
                           FS_Terminate:         FS_Terminate },
 }
 
@@ -283,6 +395,9 @@ type Foreman struct {
     /* Channel used by our stats processing go-routine to indicate that it's completed a control request */
     statResponseChannel chan statControl
 
+    /* Limits how many StatDetails messages we have in flight to the Manager. */
+    statWindow *StatWindow
+
     /* The channel on which new TCP connections are given to us by our listening socket. */
     tcpControlChannel chan *comms.MessageConnection
 
@@ -295,6 +410,12 @@ type Foreman struct {
     /* How many workers have yet to respond to the last opcode we sent them */
     responsePending int
 
+    /* How many workers have reached their share of the current phase's cap. */
+    cappedWorkers int
+
+    /* The objects which our workers have found to fail verification, in a verification pass. */
+    verifyFailures []VerifyFailure
+
     /* Our current state. */
     state foremanState
 
@@ -309,6 +430,9 @@ type Foreman struct {
 
     /* The dynamically adjusted timeout value for workers */
     hangTimeout time.Duration
+
+    /* Our current status, which can be served over HTTP */
+    status foremanStatusTracker
 }
 
 
@@ -327,6 +451,13 @@ func StartForeman(profileFilename string) error {
     f.setState(FS_Idle)
     f.profilePrefix = profileFilename
 
+    if globalConfig.StatusPort != 0 {
+        err = f.status.Serve(globalConfig.StatusPort)
+        if err != nil {
+            return err
+        }
+    }
+
     endpoint := fmt.Sprintf(":%v", globalConfig.ListenPort)
     f.tcpControlChannel = make(chan *comms.MessageConnection, 100)
     _, err = comms.ListenTCP(endpoint, comms.MakeEncoderFactory(), f.tcpControlChannel)
@@ -334,6 +465,13 @@ func StartForeman(profileFilename string) error {
         return err
     }
 
+    if globalConfig.GrpcPort != 0 {
+        err = ServeGrpcControl(globalConfig.GrpcPort, globalConfig.ListenPort)
+        if err != nil {
+            return err
+        }
+    }
+
     // Start our event loop in the current goroutine
     f.eventLoop()
 
@@ -430,13 +568,16 @@ func (f *Foreman) handleTcpMsg(msgInfo *comms.ReceivedMessageInfo) {
             d.Cores = uint64(runtime.NumCPU())
             d.Ram = GetPhysicalMemorySize()
             d.Version = fmt.Sprintf("%s - %s", Version, BuildDate)
+            d.GoVersion = runtime.Version()
+            d.LibradosVersion, d.LibrbdVersion = CephLibraryVersions()
             f.tcpConnection.Send(OP_Discovery, d)
 
         case OP_Connect:
             msg.Data(&f.order)
             f.connect()
 
-        case OP_StatDetails:       f.setStatControl(SC_SendDetails)
+        case OP_StatDetails:       f.statControlChannel <- SC_SendDetails
+        case OP_StatDetailsAck:    f.statWindow.Ack()
         case OP_StatSummaryStart:  f.setStatControl(SC_StartSummaries)
         case OP_StatSummaryStop:   f.setStatControl(SC_StopSummaries)
 
@@ -458,12 +599,21 @@ func (f *Foreman) sendOpcodeToManager(op Opcode, err error) {
     }
 
     var resp ForemanGenericResponse
+    resp.VerifyFailures = f.verifyFailures
+    f.verifyFailures = nil
+
+    // Once we are connected, let our manager know how we divided our range between our workers.
+    if (op == OP_Connect) && (err == nil) {
+        for _, wi := range f.workerInfos {
+            resp.WorkerRanges = append(resp.WorkerRanges, ObjectRange{ wi.Worker.order.RangeStart, wi.Worker.order.RangeEnd })
+        }
+    }
 
     if err != nil {
         resp.Error = err.Error()
     }
 
-    logger.Debugf("Send response to manager: %v, %v\n", op.ToString(), err)
+    logger.Debugf("Send response to manager: %v, %v (%v verify failures)\n", op.ToString(), err, len(resp.VerifyFailures))
 
     f.tcpConnection.Send(uint8(op), &resp)
 }
@@ -483,6 +633,13 @@ func (f *Foreman) handleWorkerResponse(resp *WorkerResponse) {
         case OP_Fail: f.fail(resp.Error)
         case OP_Hung: f.hung(resp.Error)
 
+        // Once all our workers have reached their share of a phase's cap, our manager may end it early.
+        case OP_PhaseCapped:
+            f.cappedWorkers++
+            if f.cappedWorkers == len(f.workerInfos) {
+                f.sendOpcodeToManager(OP_PhaseCapped, nil)
+            }
+
         // Everything wlse is handled the same way.
         default:
             nextState := validWorkerTransitions[resp.Op][f.state]
@@ -492,9 +649,11 @@ func (f *Foreman) handleWorkerResponse(resp *WorkerResponse) {
             }
 
             f.responsePending--
+            f.verifyFailures = append(f.verifyFailures, resp.VerifyFailures...)
 
             if f.responsePending == 0 {
                 f.setState(nextState)
+
                 f.sendOpcodeToManager(resp.Op, nil)
             }
     }
@@ -507,6 +666,7 @@ func (f *Foreman) sendOpcodeToWorkers(op Opcode) {
 
     // When we send out this message, we expect to see each of our workers acknowledge it.
     f.responsePending = len(f.workerInfos)
+    f.cappedWorkers = 0
 
     for _, wi := range f.workerInfos {
         wi.OpChannel <- op
@@ -523,6 +683,8 @@ func (f *Foreman) connect() {
     f.summaryChannel = make(chan WorkerSummary, 1000)
     f.statControlChannel = make(chan statControl)
     f.statResponseChannel = make(chan statControl)
+    f.statWindow = NewStatWindow(int(f.order.StatUploadWindow))
+    f.verifyFailures = nil
 
     // Work out how many workers we need to create.
 
@@ -538,9 +700,14 @@ func (f *Foreman) connect() {
     // (They can allocate more than this, but we'll pick something usable to start with).
     // We'll take a quarter of the physical memory on the box and then divide it between the
     // workers. Then we round down to the nearest power of two.  Finally, we limit it to a
-    // million stats.
+    // million stats.  If we can't tell how much memory we have, then we use a fixed default.
     var stat Stat
-    statPreallocationCount := previousPowerOfTwo(GetPhysicalMemorySize() / (4 * uint64(unsafe.Sizeof(stat)) * nWorkers))
+    statPreallocationCount := uint64(defaultStatPreallocationCount)
+    ram := GetPhysicalMemorySize()
+    if (ram > 0) && (nWorkers > 0) {
+        statPreallocationCount = previousPowerOfTwo(ram / (4 * uint64(unsafe.Sizeof(stat)) * nWorkers))
+    }
+
     if statPreallocationCount > (1024 * 1024) {
         statPreallocationCount = 1024 * 1024
     }
@@ -551,6 +718,7 @@ func (f *Foreman) connect() {
     // We divvy up our object range between them.
 
     f.workerInfos = make([]*WorkerInfo, 0, nWorkers)
+    targetLimiter := NewTargetLimiter(len(f.order.Targets), f.order.MaxConcurrencyPerTarget)
     rangeStride := float32(rangeLen) / float32(nWorkers)
 
     hostname, err := os.Hostname()
@@ -569,6 +737,7 @@ func (f *Foreman) connect() {
             ResponseChannel: f.workerResponseChannel,
             SummaryChannel: f.summaryChannel,
             StatPreallocationCount: statPreallocationCount,
+            TargetLimiter: targetLimiter,
         }
 
         rangeEnd := rangeStart + rangeStride
@@ -577,6 +746,8 @@ func (f *Foreman) connect() {
         o.Bandwidth = f.order.Bandwidth / nWorkers
         o.RangeStart = uint64(rangeStart)
         o.RangeEnd = uint64(rangeEnd)
+        o.HotSetSize = hotSetShare(f.order.HotSetSize, f.order.RangeStart, f.order.RangeEnd, o.RangeStart, o.RangeEnd)
+        o.PhaseCaps = f.order.PhaseCaps.Share(f.order.RangeStart, f.order.RangeEnd, o.RangeStart, o.RangeEnd)
 
         rangeStart = rangeEnd
 
@@ -587,9 +758,13 @@ func (f *Foreman) connect() {
             ForemanRangeStart: f.order.RangeStart,
             ForemanRangeEnd: f.order.RangeEnd,
             WorkerRangeStart: o.RangeStart,
-            WorkerRangeEnd: o.RangeEnd }
+            WorkerRangeEnd: o.RangeEnd,
+            ConnectionReuse: o.ConnectionReuse,
+            OpTimeout: time.Duration(o.OpTimeoutMillis) * time.Millisecond,
+            ExplicitFlush: o.ExplicitFlush }
 
-        w, err := NewWorker(s, &o)
+        var w *Worker
+        w, err = NewWorker(s, &o)
         if err == nil {
             info := WorkerInfo{OpChannel: opChannel, Worker: w, lastSummary: time.Now()}
             f.workerInfos = append(f.workerInfos, &info)
@@ -601,6 +776,17 @@ func (f *Foreman) connect() {
         return
     }
 
+    // If our manager wants our stats compressed, then compress everything large that we send it.
+    if f.order.StatCompression != "" {
+        err = f.tcpConnection.SetCompression(f.order.StatCompression)
+        if err != nil {
+            f.fail(err)
+            return
+        }
+    }
+
+    f.status.SetJob(f.order.JobId, len(f.workerInfos))
+
     go f.processStats()
 
     // We're all good.  Time to connect.
@@ -624,7 +810,7 @@ func (f *Foreman) hung(err error) {
 
     logger.Infof("Exiting process to allow daemon to restart\n")
     time.Sleep(10)
-    os.Exit(-1)
+    os.Exit(ExitInternalError)
 }
 
 
@@ -632,6 +818,7 @@ func (f *Foreman) hung(err error) {
 func (f *Foreman) setState(state foremanState) {
     logger.Debugf("Foreman changing state: %v -> %v\n", foremanStateToStr(f.state), foremanStateToStr(state))
     f.state = state
+    f.status.SetState(state)
 
     details := stateDetails[state]
 
@@ -693,6 +880,12 @@ func (f *Foreman) terminateTCP() {
  * next connection. */
 func (f *Foreman) terminate() {
     f.setState(FS_Terminate)
+
+    // Abandon any ops still in flight first, so that a worker stuck in one can see the opcode.
+    for _, wi := range f.workerInfos {
+        wi.Worker.Cancel()
+    }
+
     f.sendOpcodeToWorkers(OP_Terminate)
 
     timeout := time.NewTimer(f.hangTimeout)
@@ -712,8 +905,9 @@ func (f *Foreman) terminate() {
         }
     }
 
-    // Tell the stats channel to terminate
+    // Tell the stats channel to terminate, waking it first if it's blocked uploading stats
     logger.Debugf("Waiting for Stats termination\n")
+    f.statWindow.Abort()
     f.statControlChannel <- SC_Terminate
 
     // Then wait for it to finish sending stats back to the Manager on our TCP connection
@@ -764,12 +958,20 @@ func (f *Foreman) clearHangTimeouts() {
  *
  * We start a Ticker to trigger sending a summary back to the Manager once per second.
  * This can be enabled and disabled by using the controlChannel.
+ *
+ * Whilst summaries are enabled, we also sample our own CPU and memory usage on each tick.  These
+ * samples are sent back to the Manager along with the detailed stats.
  */
 func (f *Foreman) processStats() {
     ticker := time.NewTicker(1 * time.Second)
     var summary = new(StatSummary)
     sendSummaries := false
 
+    var usage = new(ResourceUsage)
+    var lastCpuTime time.Duration
+    var lastSampleTime time.Time
+    nCores := float64(runtime.NumCPU())
+
     for {
         select {
             case s := <-f.summaryChannel:
@@ -805,12 +1007,20 @@ func (f *Foreman) processStats() {
             case <-ticker.C:
                 if sendSummaries {
                     f.tcpConnection.Send(OP_StatSummary, summary)
+                    f.status.AddSummary(summary)
                     summary = new(StatSummary)
 
+                    // Sample our own resource usage since the last tick.
+                    now := time.Now()
+                    cpuTime := GetProcessCpuTime()
+                    cpuPercent := 100.0 * float64(cpuTime - lastCpuTime) / (float64(now.Sub(lastSampleTime)) * nCores)
+                    usage.AddSample(cpuPercent, GetProcessRss())
+                    lastCpuTime = cpuTime
+                    lastSampleTime = now
+
                     // And check for hung workers (defined as any worker that has not send a summary in the
                     // last 90 or so seconds, provided that it should be in the middle of running benchmark ops).
 
-                    now := time.Now()
                     for i, wi  := range f.workerInfos {
                         if wi.canTimeout {
                             if now.Sub(wi.lastSummary) > f.hangTimeout {
@@ -829,15 +1039,26 @@ func (f *Foreman) processStats() {
                     case SC_SendDetails:
                         // Tell each worker to send its stats back to the manager.
                         for i, _  := range f.workerInfos {
-                            f.workerInfos[i].Worker.UploadStats(f.tcpConnection)
+                            f.workerInfos[i].Worker.UploadStats(f.tcpConnection, f.statWindow)
+                        }
+
+                        // Only say we're done once the manager has processed everything.
+                        if f.statWindow.Drain() {
+                            f.tcpConnection.Send(OP_StatDetailsDone, usage)
                         }
 
-                        f.tcpConnection.Send(OP_StatDetailsDone, nil)
+                        usage = new(ResourceUsage)
+
+                        // The main loop doesn't wait for us to finish uploading, since it needs to
+                        // keep passing us acks from the Manager.
+                        continue
 
                     case SC_StartSummaries:
                         logger.Debugf("Enabling summaries\n")
                         summary = new(StatSummary)
                         sendSummaries = true
+                        lastCpuTime = GetProcessCpuTime()
+                        lastSampleTime = time.Now()
                         f.tcpConnection.Send(OP_StatSummaryStart, nil)
 
                     case SC_StopSummaries:
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "encoding/json"
+import "fmt"
+import "logger"
+import "net"
+import "net/http"
+import "sync"
+import "time"
+
+
+/*
+ * A snapshot of what a Foreman is currently doing.
+ *
+ * This is served as JSON over HTTP (if a status port has been configured) so that headless
+ * drivers can be inspected without going through the Manager - which is handy for debugging
+ * stuck runs.
+ */
+type ForemanStatus struct {
+    State string
+    JobId uint64
+    Workers int
+
+    /* Op counts for each phase and error type, since the current job started. */
+    OpCounts map[string]map[string]uint64
+
+    /* The last summary we sent to the Manager, and when we sent it. */
+    LastSummary map[string]map[string]uint64
+    LastSummaryTime time.Time
+}
+
+
+/*
+ * Holds a Foreman's status in a thread-safe manner.
+ *
+ * The Foreman's event loop and its stats go-routine both update the status, and the HTTP server
+ * reads it from its own go-routines, so everything is done under a lock.
+ */
+type foremanStatusTracker struct {
+    mutex sync.Mutex
+    state string
+    jobId uint64
+    workers int
+    opCounts StatSummary
+    lastSummary StatSummary
+    lastSummaryTime time.Time
+}
+
+
+/*
+ * Start an HTTP server on the given port, which will serve our status at /status.
+ *
+ * We create the listening socket before returning, so that we can report any error with it.
+ * The server itself runs in its own go-routine.
+ */
+func (t *foremanStatusTracker) Serve(port uint16) error {
+    listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
+    if err != nil {
+        return err
+    }
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/status", t.handleStatus)
+
+    logger.Infof("Serving status over HTTP on :%v\n", port)
+
+    go func() {
+        err := http.Serve(listener, mux)
+        logger.Errorf("Status server failed: %v\n", err)
+    }()
+
+    return nil
+}
+
+
+func (t *foremanStatusTracker) handleStatus(w http.ResponseWriter, req *http.Request) {
+    data, err := json.MarshalIndent(t.Status(), "", "  ")
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.Write(data)
+}
+
+
+/* Return a snapshot of the current status. */
+func (t *foremanStatusTracker) Status() *ForemanStatus {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+
+    return &ForemanStatus {
+        State: t.state,
+        JobId: t.jobId,
+        Workers: t.workers,
+        OpCounts: t.opCounts.ToMap(),
+        LastSummary: t.lastSummary.ToMap(),
+        LastSummaryTime: t.lastSummaryTime }
+}
+
+
+func (t *foremanStatusTracker) SetState(state foremanState) {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+
+    t.state = foremanStateToStr(state)
+}
+
+
+/* Record the start of a new job, which resets all of our counters. */
+func (t *foremanStatusTracker) SetJob(jobId uint64, workers int) {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+
+    t.jobId = jobId
+    t.workers = workers
+    t.opCounts.Zero()
+    t.lastSummary.Zero()
+    t.lastSummaryTime = time.Time{}
+}
+
+
+/* Record a summary that we have just sent to the Manager. */
+func (t *foremanStatusTracker) AddSummary(summary *StatSummary) {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+
+    t.opCounts.Add(summary)
+    t.lastSummary = *summary
+    t.lastSummaryTime = time.Now()
+}
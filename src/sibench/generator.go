@@ -4,6 +4,11 @@
 package main
 
 import "fmt"
+import "math"
+
+
+/* Passed to Verify when we do not know which cycle an object was last written in. */
+const AnyCycle uint64 = math.MaxUint64
 
 
 /* 
@@ -14,6 +19,13 @@ import "fmt"
  * be verified WITHOUT storing the expected data in memory for comparison.  Typically
  * this means that the data should be generated algorithmically from a seed written
  * into (or derivable from) the header of the object.
+ *
+ * The contents of an object must depend only on the master seed and the object's size, id
+ * and cycle, and not on which worker made it.  Each worker has its own range of ids, so its
+ * objects differ from everyone else's anyway, but a read may come from a different worker
+ * to the write: a --no-write run with a different number of workers, say.  Range reads
+ * generate just the part of the object that they fetch, without seeing its header, so they depend
+ * on this too.
  */
 type Generator interface {
     /* 
@@ -26,15 +38,24 @@ type Generator interface {
      */
     Generate(size uint64, id uint64, cycle uint64, buffer *[]byte)
 
+    /*
+     * GenerateRange creates just part of the payload that Generate would, starting at offset, and
+     * filling the buffer.  The result must be identical to that part of Generate's output.
+     */
+    GenerateRange(size uint64, id uint64, cycle uint64, offset uint64, buffer []byte)
+
     /*
      * Verify checks if the contents of a payload are well-formed.
      *
      * size is the size of the payload in bytes.
+     * cycle is the cycle in which we expect the object to have last been written, or AnyCycle if
+     * we don't know.  Generators which can determine the cycle from the object should fail if it
+     * was written in a different one, since that means the backend has served a stale version.
      * buffer is the actual contents of the object.
      * Scratch is a scratch buffer, and should be of size 'size'.
      * Returns nil on success, or an error on failure.
      */
-    Verify(size uint64, id uint64, buffer *[]byte, scratch *[]byte) error
+    Verify(size uint64, id uint64, cycle uint64, buffer *[]byte, scratch *[]byte) error
 }
 
 
@@ -45,8 +66,52 @@ func CreateGenerator(generatorType string, seed uint64, config GeneratorConfig)
     switch generatorType {
         case "prng": return CreatePrngGenerator(seed, config)
         case "slice": return CreateSliceGenerator(seed, config)
+        case "dedupe": return CreateDedupeGenerator(seed, config)
+        case "entropy": return CreateEntropyGenerator(seed, config)
     }
 
     return nil, fmt.Errorf("Unknown generatorType: %v", generatorType)
 }
 
+
+
+/*
+ * Check part of an object, starting at offset, by generating just that part of the object into
+ * scratch and comparing it with the buffer.  Since the range may not include the object's header,
+ * we need to know which cycle the object was written in.
+ *
+ * scratch should be at least as large as the buffer.
+ */
+func VerifyRange(g Generator, size uint64, id uint64, cycle uint64, offset uint64, buffer []byte, scratch *[]byte) error {
+    expected := (*scratch)[:len(buffer)]
+    g.GenerateRange(size, id, cycle, offset, expected)
+
+    for i, b := range buffer {
+        if b != expected[i] {
+            return fmt.Errorf("Verify failure in range at offset %v: expected %v, but got %v", offset + uint64(i), expected[i], b)
+        }
+    }
+
+    return nil
+}
+
+
+/*
+ * Copy whatever part of a chunk of an object, which starts at chunkStart, falls within the buffer
+ * for a range of the same object, which starts at offset.  Generators use this to build ranges.
+ */
+func copyOverlap(buffer []byte, offset uint64, chunk []byte, chunkStart uint64) {
+    end := offset + uint64(len(buffer))
+    chunkEnd := chunkStart + uint64(len(chunk))
+
+    if (chunkEnd <= offset) || (chunkStart >= end) {
+        return
+    }
+
+    if chunkStart < offset {
+        chunk = chunk[offset - chunkStart:]
+        chunkStart = offset
+    }
+
+    copy(buffer[chunkStart - offset:], chunk)
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the parts of the generators which they all share.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+// Make one of each type of generator, all with the same seed.
+func makeTestGenerators(t *testing.T) map[string]Generator {
+    generators := map[string]Generator{ "slice": makeTestSliceGenerator(t) }
+
+    var err error
+    generators["prng"], err = CreatePrngGenerator(42, GeneratorConfig{})
+    testutil.CheckNoError(t, err)
+
+    generators["dedupe"], err = CreateDedupeGenerator(42, GeneratorConfig{ "ratio": "0.5" })
+    testutil.CheckNoError(t, err)
+
+    generators["entropy"], err = CreateEntropyGenerator(42, GeneratorConfig{ "entropy": "4" })
+    testutil.CheckNoError(t, err)
+
+    return generators
+}
+
+
+// Test functions.
+
+// Every part of an object that we generate on its own must match the same part of the whole object.
+func TestGenerateRange(t *testing.T) {
+    ranges := []struct {
+        offset uint64
+        length uint64
+    }{
+        { 0, 1 },
+        { 0, 40 },
+        { 1, 7 },
+        { 30, 40 },
+        { 4095, 4098 },
+        { 8192, 4096 },
+    }
+
+    for name, g := range makeTestGenerators(t) {
+        for _, size := range []uint64{ 64, 4096 * 3, 4096 * 3 + 13 } {
+            whole := make([]byte, size)
+            g.Generate(size, 7, 3, &whole)
+
+            // Check the whole object, and its last few bytes, as well as our ranges.
+            checks := append(ranges, struct{ offset, length uint64 }{ 0, size }, struct{ offset, length uint64 }{ size - 5, 5 })
+
+            for _, r := range checks {
+                if r.offset + r.length > size {
+                    continue
+                }
+
+                part := make([]byte, r.length)
+                g.GenerateRange(size, 7, 3, r.offset, part)
+
+                if string(part) != string(whole[r.offset:r.offset + r.length]) {
+                    t.Errorf("%v generator: range %v:%v of a %v byte object differs from the whole object", name, r.offset, r.length, size)
+                }
+            }
+        }
+    }
+}
+
+
+// A range is verified against its own part of the object, and any difference is found.
+func TestVerifyRange(t *testing.T) {
+    for name, g := range makeTestGenerators(t) {
+        size := uint64(4096 * 3)
+        whole := make([]byte, size)
+        g.Generate(size, 7, 3, &whole)
+
+        part := append([]byte{}, whole[5000:9000]...)
+        scratch := make([]byte, len(part))
+        testutil.CheckNoError(t, VerifyRange(g, size, 7, 3, 5000, part, &scratch))
+
+        // The wrong cycle, or a changed byte, must fail.
+        if VerifyRange(g, size, 7, 4, 5000, part, &scratch) == nil {
+            t.Errorf("%v generator: range verified against the wrong cycle", name)
+        }
+
+        part[100]++
+        testutil.CheckError(t, VerifyRange(g, size, 7, 3, 5000, part, &scratch))
+    }
+}
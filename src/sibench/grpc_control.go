@@ -0,0 +1,431 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "comms"
+import "context"
+import "fmt"
+import "logger"
+import "net"
+import "sync"
+import "sibench/controlpb"
+import "google.golang.org/grpc"
+import "google.golang.org/grpc/codes"
+import "google.golang.org/grpc/status"
+
+
+/*
+ * The gRPC control plane lets other tooling drive a foreman without speaking our own TCP protocol.
+ * If a gRPC port is configured, the server offers the sibench.Foreman service described in
+ * controlpb/control.proto, with Connect, Start, Stop, Stats and Disconnect RPCs.
+ *
+ * Rather than teaching the foreman a second protocol, the service is a bridge: each RPC is turned
+ * into the opcodes that a manager would send, over an ordinary connection to the foreman's own
+ * port.  The foreman can't tell the difference, and so its state machine still checks that the
+ * RPCs come in a sensible order.  It also means that a foreman driven over gRPC is busy as far as
+ * any manager is concerned, and vice versa.
+ *
+ * The protobuf messages mirror our own WorkOrder, Discovery and StatSummary types, and we convert
+ * between the two in grpc_messages.go.
+ */
+
+
+/* The opcodes which start and stop each phase.  OP_None means that the phase has no stop. */
+var grpcPhaseOpcodes = map[controlpb.Phase][2]Opcode {
+    controlpb.Phase_PHASE_WRITE:            { OP_WriteStart,           OP_WriteStop },
+    controlpb.Phase_PHASE_PREPARE:          { OP_Prepare,              OP_None },
+    controlpb.Phase_PHASE_READ:             { OP_ReadStart,            OP_ReadStop },
+    controlpb.Phase_PHASE_READ_WRITE:       { OP_ReadWriteStart,       OP_ReadWriteStop },
+    controlpb.Phase_PHASE_DELETE:           { OP_Delete,               OP_None },
+    controlpb.Phase_PHASE_BUCKET_OPS:       { OP_BucketOpsStart,       OP_BucketOpsStop },
+    controlpb.Phase_PHASE_VERIFY:           { OP_Verify,               OP_None },
+    controlpb.Phase_PHASE_STAT:             { OP_StatOpsStart,         OP_StatOpsStop },
+    controlpb.Phase_PHASE_HOLD_CONNECTIONS: { OP_HoldConnectionsStart, OP_HoldConnectionsStop },
+}
+
+
+/*
+ * Our connection to the foreman, on behalf of our gRPC callers.  We make a new one for each
+ * Connect, since the foreman closes its end whenever a work order terminates.
+ */
+type grpcBridge struct {
+    conn *comms.MessageConnection
+
+    /* The replies to the opcodes we send, along with any failures and errors. */
+    responses chan *comms.ReceivedMessageInfo
+
+    mutex sync.Mutex
+    closed bool                     // Whether our connection has been closed, by either end.
+    summaries chan *StatSummary     // Where we send summaries whilst a Stats call is running, or nil.
+}
+
+
+/* Implements the sibench.Foreman gRPC service. */
+type GrpcControl struct {
+    controlpb.UnimplementedForemanServer
+
+    /* Serialises our RPCs, so that we only ever have one opcode waiting for a reply. */
+    mutex sync.Mutex
+
+    /* The port of the foreman we are bridging to. */
+    foremanPort uint16
+
+    /* Our current connection to the foreman, or nil if we don't have one. */
+    bridge *grpcBridge
+}
+
+
+/*
+ * Start serving the gRPC control plane on the given port, for the foreman listening on foremanPort.
+ *
+ * As with our status server, we create the listening socket before returning so that we can
+ * report any error with it, and the server itself runs in its own go-routine.
+ */
+func ServeGrpcControl(port uint16, foremanPort uint16) error {
+    listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
+    if err != nil {
+        return err
+    }
+
+    server := grpc.NewServer()
+    controlpb.RegisterForemanServer(server, &GrpcControl{ foremanPort: foremanPort })
+
+    logger.Infof("Serving gRPC control plane on :%v\n", port)
+
+    go func() {
+        err := server.Serve(listener)
+        logger.Errorf("gRPC control plane failed: %v\n", err)
+    }()
+
+    return nil
+}
+
+
+func (g *GrpcControl) Connect(ctx context.Context, order *controlpb.WorkOrder) (*controlpb.Discovery, error) {
+    g.mutex.Lock()
+    defer g.mutex.Unlock()
+
+    if (g.bridge != nil) && !g.bridge.isClosed() {
+        return nil, status.Errorf(codes.FailedPrecondition, "Already connected: call Disconnect first")
+    }
+
+    endpoint := fmt.Sprintf("localhost:%v", g.foremanPort)
+    conn, err := comms.ConnectTCP(endpoint, comms.MakeEncoderFactory(), 0)
+    if err != nil {
+        return nil, status.Errorf(codes.Unavailable, "%v", err)
+    }
+
+    g.bridge = newGrpcBridge(conn)
+
+    msg, err := g.bridge.request(OP_Discovery, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    var d Discovery
+    msg.Data(&d)
+
+    _, err = g.bridge.request(OP_Connect, workOrderFromProto(order))
+    if err != nil {
+        return nil, err
+    }
+
+    return discoveryToProto(&d), nil
+}
+
+
+func (g *GrpcControl) Start(ctx context.Context, req *controlpb.PhaseRequest) (*controlpb.Empty, error) {
+    return g.sendPhaseOpcode(req.Phase, 0)
+}
+
+
+func (g *GrpcControl) Stop(ctx context.Context, req *controlpb.PhaseRequest) (*controlpb.Empty, error) {
+    return g.sendPhaseOpcode(req.Phase, 1)
+}
+
+
+func (g *GrpcControl) sendPhaseOpcode(phase controlpb.Phase, which int) (*controlpb.Empty, error) {
+    ops, ok := grpcPhaseOpcodes[phase]
+    if !ok {
+        return nil, status.Errorf(codes.InvalidArgument, "Unknown phase: %v", phase)
+    }
+
+    op := ops[which]
+    if op == OP_None {
+        return nil, status.Errorf(codes.InvalidArgument, "The %v phase runs to completion, and can't be stopped", phase)
+    }
+
+    g.mutex.Lock()
+    defer g.mutex.Unlock()
+
+    _, err := g.request(op, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    return &controlpb.Empty{}, nil
+}
+
+
+func (g *GrpcControl) Stats(req *controlpb.Empty, stream controlpb.Foreman_StatsServer) error {
+    g.mutex.Lock()
+    bridge := g.bridge
+    summaries, err := g.startSummaries()
+    g.mutex.Unlock()
+
+    if err != nil {
+        return err
+    }
+
+    // Stop the summaries again once our caller goes away, unless the connection has already gone.
+    defer func() {
+        g.mutex.Lock()
+        defer g.mutex.Unlock()
+
+        if bridge.setSummaries(nil) && (g.bridge == bridge) {
+            g.request(OP_StatSummaryStop, nil)
+        }
+    }()
+
+    for {
+        select {
+            case s, ok := <-summaries:
+                if !ok {
+                    return status.Errorf(codes.Aborted, "Connection to foreman closed")
+                }
+
+                err = stream.Send(statSummaryToProto(s))
+                if err != nil {
+                    return err
+                }
+
+            case <-stream.Context().Done():
+                return nil
+        }
+    }
+}
+
+
+/* Ask the foreman for summaries, returning the channel on which we will receive them. */
+func (g *GrpcControl) startSummaries() (chan *StatSummary, error) {
+    if g.bridge == nil {
+        return nil, status.Errorf(codes.FailedPrecondition, "Not connected")
+    }
+
+    summaries := make(chan *StatSummary, 10)
+    if !g.bridge.setSummaries(summaries) {
+        return nil, status.Errorf(codes.FailedPrecondition, "Connection to foreman closed")
+    }
+
+    _, err := g.request(OP_StatSummaryStart, nil)
+    if err != nil {
+        g.bridge.setSummaries(nil)
+        return nil, err
+    }
+
+    return summaries, nil
+}
+
+
+func (g *GrpcControl) Disconnect(ctx context.Context, req *controlpb.Empty) (*controlpb.Empty, error) {
+    g.mutex.Lock()
+    defer g.mutex.Unlock()
+
+    if g.bridge == nil {
+        return &controlpb.Empty{}, nil
+    }
+
+    // If the foreman has already closed the connection, then it has already terminated.
+    var err error
+    if !g.bridge.isClosed() {
+        _, err = g.bridge.request(OP_Terminate, nil)
+    }
+
+    g.bridge.close()
+    g.bridge = nil
+
+    if err != nil {
+        return nil, err
+    }
+
+    return &controlpb.Empty{}, nil
+}
+
+
+/* Send an opcode over our current connection.  Must be called with our mutex held. */
+func (g *GrpcControl) request(op Opcode, data interface{}) (comms.ReceivedMessage, error) {
+    if g.bridge == nil {
+        return nil, status.Errorf(codes.FailedPrecondition, "Not connected")
+    }
+
+    return g.bridge.request(op, data)
+}
+
+
+func newGrpcBridge(conn *comms.MessageConnection) *grpcBridge {
+    b := &grpcBridge{ conn: conn, responses: make(chan *comms.ReceivedMessageInfo, 10) }
+
+    messages := make(chan *comms.ReceivedMessageInfo, 10)
+    conn.ReceiveToChannel(messages)
+    go b.dispatch(messages)
+
+    return b
+}
+
+
+/*
+ * Sort the messages from the foreman: summaries go to any Stats call, and everything else is
+ * a response for whoever is waiting in request.
+ */
+func (b *grpcBridge) dispatch(messages chan *comms.ReceivedMessageInfo) {
+    for {
+        var info *comms.ReceivedMessageInfo
+
+        // Nothing is sent on the channel when we close the connection ourselves.
+        select {
+            case info = <-messages:
+
+            case <-b.conn.Closed():
+                b.markClosed()
+                return
+        }
+
+        if info.Error != nil {
+            b.markClosed()
+            b.responses <- info
+            return
+        }
+
+        if Opcode(info.Message.ID()) == OP_StatSummary {
+            var s StatSummary
+            info.Message.Data(&s)
+            b.publishSummary(&s)
+            continue
+        }
+
+        select {
+            case b.responses <- info:
+
+            case <-b.conn.Closed():
+                b.markClosed()
+                return
+        }
+    }
+}
+
+
+/*
+ * Send an opcode to the foreman, and wait for its reply.
+ *
+ * If the foreman failed since our last request, then we may see its failure here instead, which
+ * is how our callers find out about failures part way through a phase.
+ */
+func (b *grpcBridge) request(op Opcode, data interface{}) (comms.ReceivedMessage, error) {
+    if b.isClosed() {
+        return nil, status.Errorf(codes.FailedPrecondition, "Connection to foreman closed")
+    }
+
+    logger.Debugf("gRPC control sending: %v\n", op.ToString())
+
+    err := b.conn.Send(uint8(op), data)
+    if err != nil {
+        return nil, status.Errorf(codes.Unavailable, "Failure sending %v to foreman: %v", op.ToString(), err)
+    }
+
+    for {
+        info := <-b.responses
+        if info.Error != nil {
+            return nil, status.Errorf(codes.Unavailable, "Connection to foreman lost: %v", info.Error)
+        }
+
+        msg := info.Message
+        reply := Opcode(msg.ID())
+
+        switch reply {
+            case OP_Fail, OP_Hung:
+                var resp ForemanGenericResponse
+                msg.Data(&resp)
+                return nil, status.Errorf(codes.Aborted, "%v", resp.Error)
+
+            case OP_Busy:
+                return nil, status.Errorf(codes.Unavailable, "Foreman is busy with another manager")
+
+            case op:
+                // Discovery replies have their own type, but everything else uses the generic response.
+                if op != OP_Discovery {
+                    var resp ForemanGenericResponse
+                    msg.Data(&resp)
+                    if resp.Error != "" {
+                        return nil, status.Errorf(codes.Aborted, "%v", resp.Error)
+                    }
+                }
+
+                return msg, nil
+
+            default:
+                logger.Debugf("gRPC control ignoring unexpected %v while waiting for %v\n", reply.ToString(), op.ToString())
+        }
+    }
+}
+
+
+func (b *grpcBridge) isClosed() bool {
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+    return b.closed
+}
+
+
+/* Note that our connection has gone, and end any Stats call. */
+func (b *grpcBridge) markClosed() {
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+
+    b.closed = true
+    if b.summaries != nil {
+        close(b.summaries)
+        b.summaries = nil
+    }
+}
+
+
+func (b *grpcBridge) close() {
+    if !b.isClosed() {
+        b.conn.Close()
+    }
+}
+
+
+/*
+ * Set (or clear) the channel for a Stats call.  Returns false if our connection has closed, in
+ * which case the channel will never receive anything.
+ */
+func (b *grpcBridge) setSummaries(summaries chan *StatSummary) bool {
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+
+    if b.closed {
+        return false
+    }
+
+    b.summaries = summaries
+    return true
+}
+
+
+/* Pass a summary to any Stats call.  If the caller is too slow to keep up, then we drop it. */
+func (b *grpcBridge) publishSummary(s *StatSummary) {
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+
+    if b.summaries == nil {
+        return
+    }
+
+    select {
+        case b.summaries <- s:
+        default:
+            logger.Warnf("gRPC control dropping a summary: Stats caller is not keeping up\n")
+    }
+}
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the gRPC control plane.
+
+package main
+
+import "context"
+import "net"
+import "reflect"
+import "testing"
+import "sibench/controlpb"
+import "silib/testutil"
+import "google.golang.org/grpc"
+import "google.golang.org/grpc/codes"
+import "google.golang.org/grpc/credentials/insecure"
+import "google.golang.org/grpc/status"
+
+
+// Helper functions.
+
+/* Set every field of a value to something other than its zero value, following pointers. */
+func fillNonZero(v reflect.Value, n *int) {
+    *n++
+
+    switch v.Kind() {
+        case reflect.Uint64:    v.SetUint(uint64(*n))
+        case reflect.Int64:     v.SetInt(int64(*n))
+        case reflect.Float64:   v.SetFloat(float64(*n) + 0.5)
+        case reflect.Bool:      v.SetBool(true)
+        case reflect.String:    v.SetString(reflect.ValueOf(*n).String())
+
+        case reflect.Ptr:
+            v.Set(reflect.New(v.Type().Elem()))
+            fillNonZero(v.Elem(), n)
+
+        case reflect.Struct:
+            for i := 0; i < v.NumField(); i++ {
+                fillNonZero(v.Field(i), n)
+            }
+
+        case reflect.Slice:
+            v.Set(reflect.MakeSlice(v.Type(), 2, 2))
+            fillNonZero(v.Index(0), n)
+            fillNonZero(v.Index(1), n)
+
+        case reflect.Map:
+            v.Set(reflect.MakeMap(v.Type()))
+            key := reflect.New(v.Type().Key()).Elem()
+            val := reflect.New(v.Type().Elem()).Elem()
+            fillNonZero(key, n)
+            fillNonZero(val, n)
+            v.SetMapIndex(key, val)
+    }
+}
+
+
+/* Start a gRPC control plane with no foreman behind it, and return a client for it. */
+func startGrpcControl(t *testing.T) controlpb.ForemanClient {
+    listener, err := net.Listen("tcp", "localhost:0")
+    testutil.CheckNoError(t, err)
+
+    server := grpc.NewServer()
+    controlpb.RegisterForemanServer(server, &GrpcControl{})
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+    testutil.CheckNoError(t, err)
+    t.Cleanup(func() { conn.Close() })
+
+    return controlpb.NewForemanClient(conn)
+}
+
+
+/* Check that an RPC failed with the given code. */
+func checkGrpcCode(t *testing.T, expected codes.Code, err error) {
+    t.Helper()
+    testutil.CheckError(t, err)
+    testutil.CheckString(t, expected.String(), status.Code(err).String())
+}
+
+
+// Test functions.
+
+// Every field of a work order must survive the trip through protobuf, so that none are forgotten.
+func TestWorkOrderProtoRoundTrip(t *testing.T) {
+    var order WorkOrder
+    n := 0
+    fillNonZero(reflect.ValueOf(&order).Elem(), &n)
+
+    result := workOrderFromProto(workOrderToProto(&order))
+    testutil.CheckBool(t, true, reflect.DeepEqual(&order, result))
+}
+
+
+// Summaries are keyed by phase and error names.
+func TestStatSummaryToProto(t *testing.T) {
+    var s StatSummary
+    s[SP_Write][SE_None] = 7
+
+    p := statSummaryToProto(&s)
+    testutil.CheckInt(t, int(SP_Len), len(p.Phases))
+    testutil.CheckInt(t, 7, int(p.Phases[SP_Write.ToString()].Counts[StatError(SE_None).ToString()]))
+}
+
+
+// Phases must be ones we know about, and only those which don't run to completion can be stopped.
+func TestGrpcControlBadPhase(t *testing.T) {
+    client := startGrpcControl(t)
+    ctx := context.Background()
+
+    _, err := client.Start(ctx, &controlpb.PhaseRequest{ Phase: controlpb.Phase_PHASE_UNSPECIFIED })
+    checkGrpcCode(t, codes.InvalidArgument, err)
+
+    _, err = client.Stop(ctx, &controlpb.PhaseRequest{ Phase: controlpb.Phase_PHASE_PREPARE })
+    checkGrpcCode(t, codes.InvalidArgument, err)
+}
+
+
+// Nothing but Connect and Disconnect makes sense before we have connected.
+func TestGrpcControlNotConnected(t *testing.T) {
+    client := startGrpcControl(t)
+    ctx := context.Background()
+
+    _, err := client.Start(ctx, &controlpb.PhaseRequest{ Phase: controlpb.Phase_PHASE_WRITE })
+    checkGrpcCode(t, codes.FailedPrecondition, err)
+
+    stream, err := client.Stats(ctx, &controlpb.Empty{})
+    testutil.CheckNoError(t, err)
+    _, err = stream.Recv()
+    checkGrpcCode(t, codes.FailedPrecondition, err)
+
+    _, err = client.Disconnect(ctx, &controlpb.Empty{})
+    testutil.CheckNoError(t, err)
+}
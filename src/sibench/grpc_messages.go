@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "sibench/controlpb"
+import "time"
+
+
+/*
+ * Conversions between our own message types and the protobuf messages of the gRPC control plane.
+ *
+ * The protobuf messages mirror ours field for field, so whenever a field is added to WorkOrder, it
+ * needs adding to controlpb/control.proto and to both of the WorkOrder conversions here.
+ */
+
+
+func workOrderFromProto(p *controlpb.WorkOrder) *WorkOrder {
+    o := WorkOrder {
+        JobId: p.JobId,
+        Bandwidth: p.Bandwidth,
+        WorkerFactor: p.WorkerFactor,
+        SkipReadValidation: p.SkipReadValidation,
+        ReadWriteMix: p.ReadWriteMix,
+        ConnectionReuse: p.ConnectionReuse,
+        HttpErrorStats: p.HttpErrorStats,
+        VerifyOverwrites: p.VerifyOverwrites,
+        HotSetSize: p.HotSetSize,
+        HotSetRandom: p.HotSetRandom,
+        ReadRandom: p.ReadRandom,
+        NoWrite: p.NoWrite,
+        StatUploadWindow: p.StatUploadWindow,
+        BurstOps: p.BurstOps,
+        BurstIdleMillis: p.BurstIdleMillis,
+        QueueDepth: p.QueueDepth,
+        ReadAhead: p.ReadAhead,
+        PinWorkers: p.PinWorkers,
+        OpTimeoutMillis: p.OpTimeoutMillis,
+        MaxRetries: p.MaxRetries,
+        BucketOps: p.BucketOps,
+        Churn: p.Churn,
+        RampShape: p.RampShape,
+        RampUp: p.RampUp,
+        StatCompression: p.StatCompression,
+        MaxConcurrencyPerTarget: p.MaxConcurrencyPerTarget,
+        AppendObjects: p.AppendObjects,
+        ExplicitFlush: p.ExplicitFlush,
+        VerifyOnly: p.VerifyOnly,
+        PrewarmReads: p.PrewarmReads,
+        StatObjects: p.StatObjects,
+        HoldConnections: p.HoldConnections,
+        KeepaliveInterval: p.KeepaliveInterval,
+        ObjectKeyPrefix: p.ObjectKeyPrefix,
+        ObjectKeyScheme: p.ObjectKeyScheme,
+        ObjectSize: p.ObjectSize,
+        PadSize: p.PadSize,
+        Seed: p.Seed,
+        GeneratorType: p.GeneratorType,
+        RangeStart: p.RangeStart,
+        RangeEnd: p.RangeEnd,
+        ConnectionType: p.ConnectionType,
+        Targets: p.Targets,
+        TargetWeights: p.TargetWeights,
+        TargetSizes: p.TargetSizes,
+        ReferenceTarget: p.ReferenceTarget,
+        ReferenceProtocolConfig: ProtocolConfig(p.ReferenceProtocolConfig),
+        ConsistencyStride: p.ConsistencyStride,
+        ProtocolConfig: ProtocolConfig(p.ProtocolConfig),
+        GeneratorConfig: GeneratorConfig(p.GeneratorConfig),
+        CleanUpOnClose: p.CleanUpOnClose }
+
+    if p.ThinkTime != nil {
+        o.ThinkTime = &ThinkTime {
+            Min: time.Duration(p.ThinkTime.Min),
+            Max: time.Duration(p.ThinkTime.Max),
+            Mean: time.Duration(p.ThinkTime.Mean) }
+    }
+
+    if p.PhaseCaps != nil {
+        o.PhaseCaps = &PhaseCaps {
+            Write: p.PhaseCaps.Write,
+            Read: p.PhaseCaps.Read,
+            ReadWrite: p.PhaseCaps.ReadWrite,
+            Stat: p.PhaseCaps.Stat }
+    }
+
+    if p.SizeMix != nil {
+        o.SizeMix = &SizeMix{ Sizes: p.SizeMix.Sizes, Cumulative: p.SizeMix.Cumulative }
+    }
+
+    if p.ReadRange != nil {
+        o.ReadRange = &ReadRange{ Offset: p.ReadRange.Offset, Length: p.ReadRange.Length, Fraction: p.ReadRange.Fraction }
+    }
+
+    return &o
+}
+
+
+/* The inverse of workOrderFromProto, for clients written in Go. */
+func workOrderToProto(o *WorkOrder) *controlpb.WorkOrder {
+    p := controlpb.WorkOrder {
+        JobId: o.JobId,
+        Bandwidth: o.Bandwidth,
+        WorkerFactor: o.WorkerFactor,
+        SkipReadValidation: o.SkipReadValidation,
+        ReadWriteMix: o.ReadWriteMix,
+        ConnectionReuse: o.ConnectionReuse,
+        HttpErrorStats: o.HttpErrorStats,
+        VerifyOverwrites: o.VerifyOverwrites,
+        HotSetSize: o.HotSetSize,
+        HotSetRandom: o.HotSetRandom,
+        ReadRandom: o.ReadRandom,
+        NoWrite: o.NoWrite,
+        StatUploadWindow: o.StatUploadWindow,
+        BurstOps: o.BurstOps,
+        BurstIdleMillis: o.BurstIdleMillis,
+        QueueDepth: o.QueueDepth,
+        ReadAhead: o.ReadAhead,
+        PinWorkers: o.PinWorkers,
+        OpTimeoutMillis: o.OpTimeoutMillis,
+        MaxRetries: o.MaxRetries,
+        BucketOps: o.BucketOps,
+        Churn: o.Churn,
+        RampShape: o.RampShape,
+        RampUp: o.RampUp,
+        StatCompression: o.StatCompression,
+        MaxConcurrencyPerTarget: o.MaxConcurrencyPerTarget,
+        AppendObjects: o.AppendObjects,
+        ExplicitFlush: o.ExplicitFlush,
+        VerifyOnly: o.VerifyOnly,
+        PrewarmReads: o.PrewarmReads,
+        StatObjects: o.StatObjects,
+        HoldConnections: o.HoldConnections,
+        KeepaliveInterval: o.KeepaliveInterval,
+        ObjectKeyPrefix: o.ObjectKeyPrefix,
+        ObjectKeyScheme: o.ObjectKeyScheme,
+        ObjectSize: o.ObjectSize,
+        PadSize: o.PadSize,
+        Seed: o.Seed,
+        GeneratorType: o.GeneratorType,
+        RangeStart: o.RangeStart,
+        RangeEnd: o.RangeEnd,
+        ConnectionType: o.ConnectionType,
+        Targets: o.Targets,
+        TargetWeights: o.TargetWeights,
+        TargetSizes: o.TargetSizes,
+        ReferenceTarget: o.ReferenceTarget,
+        ReferenceProtocolConfig: o.ReferenceProtocolConfig,
+        ConsistencyStride: o.ConsistencyStride,
+        ProtocolConfig: o.ProtocolConfig,
+        GeneratorConfig: o.GeneratorConfig,
+        CleanUpOnClose: o.CleanUpOnClose }
+
+    if o.ThinkTime != nil {
+        p.ThinkTime = &controlpb.ThinkTime {
+            Min: int64(o.ThinkTime.Min),
+            Max: int64(o.ThinkTime.Max),
+            Mean: int64(o.ThinkTime.Mean) }
+    }
+
+    if o.PhaseCaps != nil {
+        p.PhaseCaps = &controlpb.PhaseCaps {
+            Write: o.PhaseCaps.Write,
+            Read: o.PhaseCaps.Read,
+            ReadWrite: o.PhaseCaps.ReadWrite,
+            Stat: o.PhaseCaps.Stat }
+    }
+
+    if o.SizeMix != nil {
+        p.SizeMix = &controlpb.SizeMix{ Sizes: o.SizeMix.Sizes, Cumulative: o.SizeMix.Cumulative }
+    }
+
+    if o.ReadRange != nil {
+        p.ReadRange = &controlpb.ReadRange{ Offset: o.ReadRange.Offset, Length: o.ReadRange.Length, Fraction: o.ReadRange.Fraction }
+    }
+
+    return &p
+}
+
+
+func discoveryToProto(d *Discovery) *controlpb.Discovery {
+    return &controlpb.Discovery {
+        Cores: d.Cores,
+        Ram: d.Ram,
+        Version: d.Version,
+        GoVersion: d.GoVersion,
+        LibradosVersion: d.LibradosVersion,
+        LibrbdVersion: d.LibrbdVersion }
+}
+
+
+/* Summaries are keyed by phase and error names, the same as in our status server. */
+func statSummaryToProto(s *StatSummary) *controlpb.StatSummary {
+    p := controlpb.StatSummary{ Phases: make(map[string]*controlpb.PhaseSummary) }
+
+    for phase, counts := range s.ToMap() {
+        p.Phases[phase] = &controlpb.PhaseSummary{ Counts: counts }
+    }
+
+    return &p
+}
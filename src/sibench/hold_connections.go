@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "logger"
+import "time"
+
+
+/*
+ * Rather than benchmarking IO, we can find out how many connections a gateway can sustain.  Each
+ * worker opens HoldConnections connections to each target, and then leaves them idle, apart from
+ * a keepalive request on each one every KeepaliveInterval seconds.
+ *
+ * We open the connections one at a time from our event loop, so that we still handle opcodes
+ * whilst we do it.  Each open is timed as a HoldConnect op, which includes the first keepalive,
+ * since for most protocols it is the first request that actually makes the network connection.
+ * Each keepalive after that is timed as a Keepalive op, which fails if the backend dropped the
+ * connection since the last one, even if the request then succeeded on a new connection.
+ */
+
+
+/* How long we wait after failing to open a connection before we try again, so that we don't hammer a gateway that is refusing us. */
+const heldConnectionRetryDelay = time.Second
+
+
+/* A connection that we are holding open, and when it next needs a keepalive. */
+type heldConnection struct {
+    conn Connection
+    keepalive KeepaliveConnection
+    targetIndex uint64
+    nextKeepalive time.Time
+}
+
+
+/*
+ * Open another connection, or make a keepalive request on the one that has waited longest for
+ * one.  Our held connections are kept in the order that their keepalives are due, so that we
+ * only ever need to look at the first one.
+ */
+func onHoldConnectionsEvent(w *Worker) {
+    if w.thinking() {
+        return
+    }
+
+    now := time.Now()
+
+    if (len(w.held) > 0) && !now.Before(w.held[0].nextKeepalive) {
+        hc := w.held[0]
+        w.keepaliveHeldConnection(hc)
+        w.held = append(w.held[1:], hc)
+        return
+    }
+
+    if len(w.held) < int(w.order.HoldConnections) * len(w.order.Targets) {
+        w.openHeldConnection(uint64(len(w.held) % len(w.order.Targets)))
+        return
+    }
+
+    // Nothing to do until the next keepalive, but our foreman still needs to hear from us, or it
+    // will think that we have hung.
+    w.sendSummary(&now, false)
+    w.thinkUntil = w.held[0].nextKeepalive
+}
+
+
+func onHoldConnectionsDone(w *Worker) {
+    w.closeHeldConnections()
+}
+
+
+/* Open a new connection to a target, and make our first request on it, so that it is really open. */
+func (w *Worker) openHeldConnection(targetIndex uint64) {
+    target := w.order.Targets[targetIndex]
+
+    // Each connection must have a network connection of its own, rather than sharing a pool.
+    config := w.spec.ConnConfig
+    config.HoldConnection = true
+
+    conn, err := NewConnection(w.order.ConnectionType, target, w.order.ProtocolConfig, config)
+    if err != nil {
+        w.fail(fmt.Errorf("[worker %v] failure creating connection to %v: %v", w.spec.Id, target, err))
+        return
+    }
+
+    kc, ok := conn.(KeepaliveConnection)
+    if !ok {
+        w.fail(fmt.Errorf("[worker %v] connection to %v does not support keepalives", w.spec.Id, target))
+        return
+    }
+
+    logger.Tracef("[worker %v] opening held connection %v to %v\n", w.spec.Id, len(w.held), target)
+
+    w.spec.TargetLimiter.Acquire(targetIndex)
+    start := time.Now()
+    err = conn.WorkerConnect()
+    if err == nil {
+        err = kc.Keepalive()
+    }
+    end := time.Now()
+    w.spec.TargetLimiter.Release(targetIndex)
+
+    w.recordHeldConnectionStat(SP_HoldConnect, targetIndex, start, end, err)
+
+    if err != nil {
+        logger.Warnf("[worker %v] failure opening held connection to %v: %v\n", w.spec.Id, target, err)
+        conn.WorkerClose(false)
+        w.thinkUntil = end.Add(heldConnectionRetryDelay)
+        return
+    }
+
+    interval := time.Duration(w.order.KeepaliveInterval) * time.Second
+    w.held = append(w.held, &heldConnection{ conn: conn, keepalive: kc, targetIndex: targetIndex, nextKeepalive: end.Add(interval) })
+}
+
+
+/* Make a keepalive request on a held connection, and work out when it needs the next one. */
+func (w *Worker) keepaliveHeldConnection(hc *heldConnection) {
+    logger.Tracef("[worker %v] starting keepalive on %v\n", w.spec.Id, hc.conn.Target())
+
+    w.spec.TargetLimiter.Acquire(hc.targetIndex)
+    start := time.Now()
+    err := hc.keepalive.Keepalive()
+    end := time.Now()
+    w.spec.TargetLimiter.Release(hc.targetIndex)
+
+    if err != nil {
+        // We keep the connection even so: the next keepalive will try to open it again.
+        logger.Warnf("[worker %v] failure in keepalive on %v: %v\n", w.spec.Id, hc.conn.Target(), err)
+    }
+
+    w.recordHeldConnectionStat(SP_Keepalive, hc.targetIndex, start, end, err)
+    hc.nextKeepalive = end.Add(time.Duration(w.order.KeepaliveInterval) * time.Second)
+}
+
+
+func (w *Worker) recordHeldConnectionStat(phase StatPhase, targetIndex uint64, start time.Time, end time.Time, err error) {
+    s := w.nextStat()
+    s.Error = SE_None
+    s.Phase = phase
+    s.TimeSincePhaseStartMillis = uint32(start.Sub(w.phaseStart) / (1000 * 1000))
+    s.DurationMicros = uint32(end.Sub(start) / 1000)
+    s.TargetIndex = uint16(targetIndex)
+    s.Size = 0
+
+    if err != nil {
+        s.Error = w.errorType(err)
+    }
+
+    w.summary.data[phase][s.Error]++
+    w.sendSummary(&end, true)
+}
+
+
+/* Close all of the connections we are holding. */
+func (w *Worker) closeHeldConnections() {
+    for _, hc := range w.held {
+        err := hc.conn.WorkerClose(false)
+        if err != nil {
+            logger.Warnf("[worker %v] failure closing held connection to %v: %v\n", w.spec.Id, hc.conn.Target(), err)
+        }
+    }
+
+    w.held = nil
+}
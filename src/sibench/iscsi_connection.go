@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "bytes"
+import "errors"
+import "fmt"
+import "logger"
+import "net"
+import "os"
+import "os/exec"
+import "strings"
+import "sync"
+import "time"
+
+
+/* The port that iSCSI portals listen on, unless we are told otherwise. */
+const iscsiDefaultPort = "3260"
+
+/* How long we wait for udev to create a target's device once we have logged in to it. */
+const iscsiDeviceTimeout = 30 * time.Second
+
+/* The exit status with which iscsiadm tells us that we are already logged in to a target. */
+const iscsiErrSessionExists = 15
+
+
+/*
+ * The logins which we made ourselves, keyed as for the mount manager.  Only these do we log out of
+ * when we are done: a session which was already there when we logged in belongs to someone else.
+ */
+var iscsiLogins = struct {
+    mutex sync.Mutex
+    ours map[string]bool
+}{ ours: make(map[string]bool) }
+
+
+/*
+ * IscsiConnection is a BlockConnection which logs in to an iSCSI target itself (using iscsiadm),
+ * rather than expecting the device to be there already.  This makes the login and logout part of
+ * the run, so that their cost shows up when connections are not reused.
+ *
+ * Our target is the portal, as HOST or HOST:PORT.  The target's IQN and the LUN to use come from
+ * our protocol config.  Like a CephFS mount, the login is shared by all the workers on a foreman:
+ * the first to connect logs in, and the last to close logs out, unless the login was already there.
+ */
+type IscsiConnection struct {
+    BlockConnection
+    portal string
+    iqn string
+}
+
+
+func NewIscsiConnection(target string, protocol ProtocolConfig, worker WorkerConnectionConfig) (*IscsiConnection, error) {
+    var conn IscsiConnection
+    conn.portal = target
+    conn.iqn = protocol["iqn"]
+
+    if _, _, err := net.SplitHostPort(target); err != nil {
+        conn.portal = net.JoinHostPort(target, iscsiDefaultPort)
+    }
+
+    // udev names the device after the portal, target and LUN that it came from.
+    device := fmt.Sprintf("/dev/disk/by-path/ip-%v-iscsi-%v-lun-%v", conn.portal, conn.iqn, protocol["lun"])
+
+    block, err := NewBlockConnection(device, protocol, worker)
+    if err != nil {
+        return nil, err
+    }
+
+    conn.BlockConnection = *block
+    return &conn, nil
+}
+
+
+func (conn *IscsiConnection) Target() string {
+    return conn.portal
+}
+
+
+func (conn *IscsiConnection) WorkerConnect() error {
+    if mountManager.Acquire(conn.loginKey()) {
+        // Nobody on this foreman is logged in yet, and we've been told to do it.
+        err := conn.login()
+        mountManager.MountComplete(conn.loginKey(), err == nil)
+        if err != nil {
+            return err
+        }
+    }
+
+    err := conn.BlockConnection.WorkerConnect()
+    if err != nil {
+        // Our caller won't close a connection that failed to open, so we must give up our share of the login here.
+        if conn.fd != 0 {
+            conn.fd.Close()
+        }
+
+        conn.release()
+        return err
+    }
+
+    return nil
+}
+
+
+func (conn *IscsiConnection) WorkerClose(cleanup bool) error {
+    err := conn.BlockConnection.WorkerClose(cleanup)
+    err2 := conn.release()
+    if err != nil {
+        return err
+    }
+
+    return err2
+}
+
+
+/* The key under which we share our login with the mount manager. */
+func (conn *IscsiConnection) loginKey() string {
+    return "iscsi:" + conn.portal + "/" + conn.iqn
+}
+
+
+/* Log in to our target, and wait for its device to appear. */
+func (conn *IscsiConnection) login() error {
+    logger.Debugf("Logging in to iSCSI target %v at %v\n", conn.iqn, conn.portal)
+
+    // Discovery creates the node record that iscsiadm needs before it can log in.
+    err := iscsiadm("-m", "discovery", "-t", "sendtargets", "-p", conn.portal)
+    if err != nil {
+        logger.Errorf("Failure discovering iSCSI targets at %v: %v\n", conn.portal, err)
+        return err
+    }
+
+    err = iscsiadm("-m", "node", "-T", conn.iqn, "-p", conn.portal, "--login")
+    ours := true
+
+    var exitErr *exec.ExitError
+    if errors.As(err, &exitErr) && (exitErr.ExitCode() == iscsiErrSessionExists) {
+        logger.Debugf("Already logged in to iSCSI target %v, so using the existing session\n", conn.iqn)
+        err = nil
+        ours = false
+    }
+
+    if err != nil {
+        logger.Errorf("Failure logging in to iSCSI target %v at %v: %v\n", conn.iqn, conn.portal, err)
+        return err
+    }
+
+    conn.setOurLogin(ours)
+
+    // udev creates the device in the background, so it may not be there yet.
+    deadline := time.Now().Add(iscsiDeviceTimeout)
+    for {
+        _, err = os.Stat(conn.device)
+        if err == nil {
+            return nil
+        }
+
+        if time.Now().After(deadline) {
+            if ours {
+                conn.logout()
+            }
+
+            return fmt.Errorf("Device %v did not appear after logging in to iSCSI target %v", conn.device, conn.iqn)
+        }
+
+        time.Sleep(100 * time.Millisecond)
+    }
+}
+
+
+func (conn *IscsiConnection) logout() error {
+    logger.Debugf("Logging out of iSCSI target %v at %v\n", conn.iqn, conn.portal)
+
+    err := iscsiadm("-m", "node", "-T", conn.iqn, "-p", conn.portal, "--logout")
+    if err != nil {
+        logger.Errorf("Failure logging out of iSCSI target %v at %v: %v\n", conn.iqn, conn.portal, err)
+    }
+
+    return err
+}
+
+
+/* Give up our share of the login, and log out if nobody else on this foreman is still using it. */
+func (conn *IscsiConnection) release() error {
+    if !mountManager.Release(conn.loginKey()) {
+        return nil
+    }
+
+    var err error
+    if conn.isOurLogin() {
+        err = conn.logout()
+    }
+
+    mountManager.UnmountComplete(conn.loginKey())
+    return err
+}
+
+
+/* Record whether we made our login ourselves, and so should log out of it. */
+func (conn *IscsiConnection) setOurLogin(ours bool) {
+    iscsiLogins.mutex.Lock()
+    defer iscsiLogins.mutex.Unlock()
+    iscsiLogins.ours[conn.loginKey()] = ours
+}
+
+
+func (conn *IscsiConnection) isOurLogin() bool {
+    iscsiLogins.mutex.Lock()
+    defer iscsiLogins.mutex.Unlock()
+    return iscsiLogins.ours[conn.loginKey()]
+}
+
+
+/* Run iscsiadm with the given arguments, returning an error which includes anything it printed to stderr. */
+func iscsiadm(args ...string) error {
+    var out bytes.Buffer
+
+    cmd := exec.Command("iscsiadm", args...)
+    cmd.Stderr = &out
+
+    err := cmd.Run()
+    if err != nil {
+        return fmt.Errorf("iscsiadm %v: %w: %v", strings.Join(args, " "), err, strings.TrimSpace(out.String()))
+    }
+
+    return nil
+}
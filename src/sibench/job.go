@@ -25,6 +25,11 @@ package main
  * be specified on the command line).  Typically RampUp is set to around 5-10 seconds,
  * the RunTime can be as long you want to smooth out any bumps in the numbers - say
  * 30 seconds to 10 minutes, and the RampDown is short - perhaps 5 secs maximum.
+ *
+ * Alternatively, the RampUp can be determined automatically: we watch the number of ops
+ * completed each second, and consider the backend to have reached a steady state once
+ * the coefficient of variation of those numbers over a window drops below a threshold
+ * (or once we have waited for too long).
  */
 type Job struct {
     /* The command line arguments with which we were created */
@@ -42,6 +47,35 @@ type Job struct {
     runTime uint64      // The length of the main part of the run where we record results.
     rampDown uint64     // Time at the end of the run where we throw away the results again.
 
+    /* Automatic steady state detection (durations in seconds) */
+    autoSteadyState bool        // Whether to detect the RampUp time automatically
+    steadyStateWindow uint64    // The number of seconds over which we look for a plateau
+    steadyStateCV float64       // The coefficient of variation below which we are steady
+    steadyStateMaxWait uint64   // The longest RampUp we will allow before giving up on a plateau
+
+    /* The RampUp used for the current phase.  This is only different from rampUp when we are
+     * automatically detecting steady state. */
+    phaseRampUp uint64
+
+    /* How long to wait for a lost server to restart, in seconds, or zero to abort the run instead. */
+    reconnectTimeout uint64
+
+    /* How long to wait when first connecting to each server, in seconds, or zero for no limit. */
+    connectTimeout uint64
+
+    /* What we are benchmarking, for tracking results across releases */
+    lineage Lineage
+
+    /* The report of an earlier run to compare our results with, or nil if we don't have one. */
+    baseline *Baseline
+
+    /* The capabilities of servers that we have already discovered, keyed by server name, or nil
+     * if we should always ask.  The jobs in a sequence share this, so that we only ask once. */
+    discoveries map[string]Discovery
+
+    /* Set if the user interrupted the run, so that a sequence of jobs knows not to go on. */
+    interrupted bool
+
     /* extra */
     useBytes bool       // Boolean value to specify if you want the output in Bytes and not Bits
     script string       // An optional script to be invoked at key points within each phase
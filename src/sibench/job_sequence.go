@@ -0,0 +1,349 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "bufio"
+import "bytes"
+import "encoding/json"
+import "errors"
+import "github.com/docopt/docopt-go"
+import "fmt"
+import "io"
+import "logger"
+import "os"
+import "path/filepath"
+import "strings"
+
+
+/*
+ * A job sequence runs several jobs, one after another, against the same servers, so that a sweep
+ * of workloads needs only one command.  The jobs are listed in a JSON file, such as:
+ *
+ *     { "Jobs": [ { "Name": "small", "Arguments": [ "s3", "run", "-s", "4K", ..., "gateway" ] },
+ *                 { "Name": "large", "Arguments": [ "s3", "run", "-s", "64M", ..., "gateway" ] } ] }
+ *
+ * Each job's arguments are a sibench command line, without the "sibench", so that every option
+ * works just as it does for a single run.  The servers, port and verbosity are shared by all the
+ * jobs, so they come from the sequence's own command line instead.  We check every job before we
+ * run any of them, so that a mistake in the last job doesn't waste the time spent on the others.
+ *
+ * Only the servers' discovered capabilities are shared between jobs: each job connects to the
+ * servers, and they to the targets, afresh, just as a separate run would.
+ *
+ * Each job writes its report to a temporary file, which we copy into the combined report under
+ * the job's name when the job is done.  We stop at the first job which fails or is interrupted.
+ *
+ * A size sweep is a sequence too, which we make from a single command line rather than a file:
+ * one job for each size, named for that size.
+ */
+type JobSequence struct {
+    Jobs []*SequenceJob
+}
+
+
+/* One named job in a sequence. */
+type SequenceJob struct {
+    Name string
+    Arguments []string
+
+    job *Job                // The job built from the arguments, once we have validated them.
+    analyses []*Analysis    // The analyses of the job's results, once it has run.
+}
+
+
+/* Load a job sequence from a file, and build and validate each of its jobs. */
+func LoadJobSequence(args *Arguments) (*JobSequence, error) {
+    data, err := os.ReadFile(args.Config)
+    if err != nil {
+        return nil, fmt.Errorf("Failure reading job sequence: %v", err)
+    }
+
+    // Complain about fields we don't know, since they are most likely typos.
+    var seq JobSequence
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.DisallowUnknownFields()
+
+    err = decoder.Decode(&seq)
+    if err != nil {
+        return nil, fmt.Errorf("Failure parsing job sequence %v: %v", args.Config, err)
+    }
+
+    if len(seq.Jobs) == 0 {
+        return nil, fmt.Errorf("Job sequence %v has no jobs", args.Config)
+    }
+
+    // Every job in the sequence shares what we find out about the servers.
+    discoveries := make(map[string]Discovery)
+    names := make(map[string]bool)
+
+    for i, sj := range seq.Jobs {
+        if sj.Name == "" {
+            return nil, fmt.Errorf("Job %v in %v has no name", i + 1, args.Config)
+        }
+
+        if names[sj.Name] {
+            return nil, fmt.Errorf("More than one job in %v is named %v", args.Config, sj.Name)
+        }
+
+        names[sj.Name] = true
+
+        jobArgs, err := parseSequenceJobArguments(args, sj)
+        if err != nil {
+            return nil, err
+        }
+
+        sj.job, err = makeJob(jobArgs)
+        if err != nil {
+            return nil, fmt.Errorf("Bad job %v: %v", sj.Name, err)
+        }
+
+        sj.job.discoveries = discoveries
+    }
+
+    return &seq, nil
+}
+
+
+/*
+ * Make a sequence which runs the job described by our arguments once for each size in its size
+ * sweep.  The arguments must already have been validated.
+ */
+func NewSizeSweep(args *Arguments) (*JobSequence, error) {
+    var seq JobSequence
+    discoveries := make(map[string]Discovery)
+    names := strings.Split(args.SizeSweep, ",")
+
+    for i, size := range args.SizeSweepValues {
+        jobArgs := *args
+        jobArgs.ObjectSize = names[i]
+        jobArgs.ObjectSizeInBits = size
+
+        j, err := makeJob(&jobArgs)
+        if err != nil {
+            return nil, err
+        }
+
+        j.discoveries = discoveries
+        seq.Jobs = append(seq.Jobs, &SequenceJob{ Name: names[i], job: j })
+    }
+
+    return &seq, nil
+}
+
+
+/* Parse and validate the command line of one job in a sequence, as though it were our own. */
+func parseSequenceJobArguments(seqArgs *Arguments, sj *SequenceJob) (*Arguments, error) {
+    // Docopt falls back to our own command line if it isn't given one.
+    if len(sj.Arguments) == 0 {
+        return nil, fmt.Errorf("Job %v has no arguments", sj.Name)
+    }
+
+    parser := &docopt.Parser{ HelpHandler: docopt.NoHelpHandler }
+    opts, err := parser.ParseArgs(usage(), sj.Arguments, "")
+    if err != nil {
+        return nil, fmt.Errorf("Bad arguments for job %v: they do not match any sibench command", sj.Name)
+    }
+
+    var args Arguments
+    err = opts.Bind(&args)
+    if err != nil {
+        return nil, fmt.Errorf("Bad arguments for job %v: %v", sj.Name, err)
+    }
+
+    if !args.Run || (args.Config != "") {
+        return nil, fmt.Errorf("Job %v must run a single benchmark, such as \"s3 run ...\"", sj.Name)
+    }
+
+    args.Servers = seqArgs.Servers
+    args.Port = seqArgs.Port
+    args.Verbosity = seqArgs.Verbosity
+    args.LogFormat = seqArgs.LogFormat
+
+    err = applyTuningProfile(&args, sj.Arguments)
+    if err == nil {
+        err = validateArguments(&args)
+    }
+
+    if err != nil {
+        return nil, fmt.Errorf("Bad arguments for job %v: %v", sj.Name, err)
+    }
+
+    return &args, nil
+}
+
+
+/*
+ * Run each job in turn, and write the combined report to a file.  Any error has already been
+ * logged.
+ */
+func (seq *JobSequence) Run(output string) error {
+    logger.Infof("Creating report: %s\n", output)
+
+    file := os.Stdout
+    if output != "-" {
+        var err error
+        file, err = os.Create(output)
+        if err != nil {
+            logger.Errorf("Failure creating file: %s, %v\n", output, err)
+            return err
+        }
+    }
+
+    // A bufio.Writer remembers its first error, so we only need to check it when we flush.
+    w := bufio.NewWriter(file)
+    w.WriteString("{\n  \"Jobs\": [")
+
+    var runErr error
+    var sloErr error
+
+    separator := ""
+
+    for i, sj := range seq.Jobs {
+        logger.Infof("%v", banner(fmt.Sprintf("JOB %v of %v: %v", i + 1, len(seq.Jobs), sj.Name), '='))
+        err := sj.run(output, w, separator)
+        separator = ","
+
+        // A job which breaches a service level threshold still has its results, so we carry on,
+        // and fail once the rest of the jobs have run.
+        var se *SloError
+        if errors.As(err, &se) {
+            sloErr = err
+        } else {
+            runErr = err
+        }
+
+        if sj.job.interrupted {
+            logger.Infof("Interrupted: skipping the rest of the jobs\n")
+            break
+        }
+
+        if runErr != nil {
+            break
+        }
+    }
+
+    w.WriteString("\n  ]\n}\n")
+
+    seq.DisplaySummary()
+
+    // We leave stdout open, since it isn't ours.
+    err := w.Flush()
+    if file != os.Stdout {
+        if err == nil {
+            err = file.Close()
+        } else {
+            file.Close()
+        }
+    }
+
+    if err != nil {
+        logger.Errorf("Failure writing report: %s, %v\n", output, err)
+        if runErr == nil {
+            runErr = err
+        }
+    }
+
+    if runErr == nil {
+        runErr = sloErr
+    }
+
+    return runErr
+}
+
+
+/* Run a single job, and add its report to the combined report, after the given separator. */
+func (sj *SequenceJob) run(output string, w *bufio.Writer, separator string) error {
+    // Our job's report goes alongside the combined report until we can copy it in, or in the
+    // temporary directory if the combined report is going to stdout.
+    dir := filepath.Dir(output)
+    if output == "-" {
+        dir = os.TempDir()
+    }
+
+    tmp, err := os.CreateTemp(dir, "." + filepath.Base(output) + ".*.tmp")
+    if err != nil {
+        logger.Errorf("Failure creating report for job %v: %v\n", sj.Name, err)
+        return err
+    }
+
+    tmp.Close()
+    defer os.Remove(tmp.Name())
+
+    sj.job.arguments.Output = tmp.Name()
+
+    // Each job can have its own wire format and TCP settings for talking to the servers.
+    err = buildConfig(sj.job.arguments)
+    if err != nil {
+        logger.Errorf("Failure building config for job %v: %v\n", sj.Name, err)
+        return err
+    }
+
+    var jobErr error
+    sj.analyses, jobErr = runJob(sj.job)
+
+    name, _ := json.Marshal(sj.Name)
+    fmt.Fprintf(w, "%s\n    {\n      \"Name\": %s,\n", separator, name)
+
+    if jobErr != nil {
+        msg, _ := json.Marshal(jobErr.Error())
+        fmt.Fprintf(w, "      \"Error\": %s,\n", msg)
+    }
+
+    w.WriteString("      \"Report\": ")
+
+    err = copyJobReport(tmp.Name(), w, jobErr == nil)
+    if err != nil {
+        logger.Errorf("Failure reading report for job %v: %v\n", sj.Name, err)
+        if jobErr == nil {
+            jobErr = err
+        }
+    }
+
+    w.WriteString("\n    }")
+    return jobErr
+}
+
+
+/* Prints the totals of each job that ran, so that they can be compared at a glance. */
+func (seq *JobSequence) DisplaySummary() {
+    logger.Infof("%v", banner("SEQUENCE SUMMARY", '='))
+
+    for _, sj := range seq.Jobs {
+        for _, a := range sj.analyses {
+            if a.IsTotal {
+                fmt.Fprintf(display, "%-14v %v\n", limit(sj.Name, 12), a.String(sj.job.useBytes))
+            }
+        }
+    }
+}
+
+
+/*
+ * Copy a job's report into the combined report.  A job which failed may have left its report
+ * unfinished, so we only copy that if it is valid JSON, and write a null otherwise.  We don't
+ * check a successful job's report, since it may be too big to hold in memory.
+ */
+func copyJobReport(path string, w *bufio.Writer, succeeded bool) error {
+    if !succeeded {
+        data, err := os.ReadFile(path)
+        if (err != nil) || !json.Valid(data) {
+            w.WriteString("null")
+            return nil
+        }
+
+        _, err = w.Write(data)
+        return err
+    }
+
+    f, err := os.Open(path)
+    if err != nil {
+        w.WriteString("null")
+        return err
+    }
+
+    defer f.Close()
+
+    _, err = io.Copy(w, f)
+    return err
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for loading sequences of jobs.
+
+package main
+
+import "os"
+import "path/filepath"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+/* Write a job sequence to a file, and try to load it, as "sibench run --config" would. */
+func loadTestSequence(t *testing.T, contents string) (*JobSequence, error) {
+    path := filepath.Join(t.TempDir(), "jobs.json")
+    testutil.CheckNoError(t, os.WriteFile(path, []byte(contents), 0644))
+
+    args := &Arguments{ Run: true, Config: path, Servers: "alpha,beta", Port: 5151, Verbosity: "off", LogFormat: "plain" }
+    return LoadJobSequence(args)
+}
+
+
+// Test functions.
+
+// Each job is built from its own arguments, but shares the sequence's servers and discoveries.
+func TestLoadJobSequence(t *testing.T) {
+    seq, err := loadTestSequence(t, `{ "Jobs": [
+        { "Name": "small", "Arguments": [ "s3", "run", "--s3-access-key", "k", "--s3-secret-key", "s", "-s", "4K", "gw" ] },
+        { "Name": "large", "Arguments": [ "rados", "run", "--ceph-key", "k", "-s", "4M", "--servers", "gamma", "mon" ] } ] }`)
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 2, len(seq.Jobs))
+
+    small := seq.Jobs[0].job
+    large := seq.Jobs[1].job
+    testutil.CheckString(t, "s3", small.order.ConnectionType)
+    testutil.CheckString(t, "rados", large.order.ConnectionType)
+    testutil.CheckInt(t, 4 * 1024, int(small.order.ObjectSize))
+    testutil.CheckInt(t, 4 * 1024 * 1024, int(large.order.ObjectSize))
+    testutil.CheckInt(t, 2, len(large.servers))
+    testutil.CheckString(t, "alpha", large.servers[0])
+    testutil.CheckInt(t, 5151, int(large.serverPort))
+
+    small.discoveries["alpha"] = Discovery{ Cores: 4 }
+    testutil.CheckInt(t, 4, int(large.discoveries["alpha"].Cores))
+}
+
+
+// Mistakes in any job are found before we run anything.
+func TestLoadJobSequenceBad(t *testing.T) {
+    s3 := `"s3", "run", "--s3-access-key", "k", "--s3-secret-key", "s"`
+
+    bad := []string{
+        `{ "Jobs": [] }`,
+        `{ "Jobs": [ { "Name": "a", "Arguments": [ ` + s3 + `, "gw" ] }, { "Name": "a", "Arguments": [ ` + s3 + `, "gw" ] } ] }`,
+        `{ "Jobs": [ { "Arguments": [ ` + s3 + `, "gw" ] } ] }`,
+        `{ "Jobs": [ { "Name": "a", "Args": [ ` + s3 + `, "gw" ] } ] }`,
+        `{ "Jobs": [ { "Name": "a" } ] }`,
+        `{ "Jobs": [ { "Name": "a", "Arguments": [ ` + s3 + ` ] } ] }`,
+        `{ "Jobs": [ { "Name": "a", "Arguments": [ "s3", "calibrate", "--s3-access-key", "k", "--s3-secret-key", "s", "gw" ] } ] }`,
+        `{ "Jobs": [ { "Name": "a", "Arguments": [ "run", "--config", "other.json" ] } ] }`,
+        `{ "Jobs": [ { "Name": "a", "Arguments": [ ` + s3 + `, "-r", "0", "gw" ] } ] }`,
+    }
+
+    for _, contents := range bad {
+        _, err := loadTestSequence(t, contents)
+        testutil.CheckError(t, err)
+    }
+}
+
+
+// A size sweep has one job for each size, named for it.
+func TestNewSizeSweep(t *testing.T) {
+    args := &Arguments{ Run: true, S3: true, SizeSweep: "4K,1M", SizeSweepValues: []uint64{ 4096, 1024 * 1024 },
+                        Servers: "alpha", ObjectCount: 1000, Generator: "prng", Targets: []string{ "gw" } }
+
+    seq, err := NewSizeSweep(args)
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 2, len(seq.Jobs))
+    testutil.CheckString(t, "4K", seq.Jobs[0].Name)
+    testutil.CheckString(t, "1M", seq.Jobs[1].Name)
+    testutil.CheckInt(t, 4096, int(seq.Jobs[0].job.order.ObjectSize))
+    testutil.CheckInt(t, 1024 * 1024, int(seq.Jobs[1].job.order.ObjectSize))
+
+    // Each job has arguments of its own, since we write a different report file for each.
+    testutil.CheckBool(t, false, seq.Jobs[0].job.arguments == seq.Jobs[1].job.arguments)
+}
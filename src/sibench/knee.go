@@ -0,0 +1,251 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "encoding/json"
+import "fmt"
+import "logger"
+import "os"
+
+
+/*
+ * Finding the knee is a search for the most bandwidth that a backend can sustain before it starts
+ * to struggle, so that we don't have to guess a value for --bandwidth.
+ *
+ * We start with a short probe benchmark with no bandwidth limit, to find the most that the backend
+ * can do at all.  Then we run more probes, offering an increasing fraction of that bandwidth with
+ * the usual bandwidth limiter.  The first, lightest, probe gives us the response times of a
+ * backend with time to spare.  Once a probe's response times for any phase rise to more than a
+ * few times those, or too many of its operations fail, we have passed the knee, and the last
+ * probe before it had the most bandwidth that we consider sustainable.
+ *
+ * Our per-second summaries only count operations, and don't have their response times, so we
+ * look at the analyses of each probe instead.  As with calibration, the probes are short and so
+ * the results are noisy.  It's a starting point, not a measurement.
+ */
+
+/* The fractions of the unlimited bandwidth that we offer in each probe, in order. */
+var kneeLoadFractions = []float64{ 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1.0 }
+
+/* The durations (in seconds) of each phase of a probe. */
+const kneeRampUp = 2
+const kneeRunTime = 5
+const kneeRampDown = 1
+
+/* How many times the lightest probe's res-95 a phase's res-95 can rise to before we say we are past the knee. */
+const kneeLatencyFactor = 2.0
+
+/* The res-95, in microseconds, below which we ignore rises in response time, since they are mostly noise. */
+const kneeMinResTime = 1000
+
+/* The fraction of operations that can fail before we say we are past the knee. */
+const kneeMaxFailureRate = 0.01
+
+
+/* The results of a single probe in a search for the knee. */
+type KneeProbe struct {
+    OfferedBandwidth uint64         // The bandwidth limit we set, in bits per second, or zero for none.
+    OfferedBandwidthBytes uint64
+    Bandwidth uint64                // The highest bandwidth of any phase, in bits per second.
+    BandwidthBytes uint64
+    ResTime95 map[string]uint64     // The res-95 of each phase, in microseconds.
+    Operations uint64
+    Failures uint64
+}
+
+
+/* The report we write when finding the knee, in place of the usual benchmark report. */
+type KneeReport struct {
+    Arguments *Arguments
+    Lineage Lineage
+    UnlimitedProbe *KneeProbe
+    Probes []*KneeProbe
+    KneeBandwidth uint64            // The highest bandwidth we could sustain, in bits per second, or 0 if none.
+    KneeBandwidthBytes uint64
+    Reason string                   // Why we stopped.
+}
+
+
+/*
+ * Run the probes to find the knee for a job, and report the most bandwidth we think the backend
+ * can sustain.  The job's own bandwidth limit and durations are ignored.
+ */
+func RunKneeSearch(j *Job) error {
+    var report KneeReport
+    report.Arguments = j.arguments
+    report.Lineage = j.lineage
+
+    // Our probes all run on the same servers, so there is no need to discover them every time.
+    if j.discoveries == nil {
+        j.discoveries = make(map[string]Discovery)
+    }
+
+    logger.Infof("%v", banner("KNEE PROBE: unlimited bandwidth", '='))
+
+    var err error
+    report.UnlimitedProbe, err = runKneeProbe(j, 0)
+    if err != nil {
+        return err
+    }
+
+    max := report.UnlimitedProbe.BandwidthBytes
+    if max == 0 {
+        return fmt.Errorf("No bandwidth in the unlimited knee probe, so there is no knee to find")
+    }
+
+    var baseline *KneeProbe
+    var knee *KneeProbe
+
+    for _, fraction := range kneeLoadFractions {
+        offered := uint64(fraction * float64(max))
+        logger.Infof("%v", banner(fmt.Sprintf("KNEE PROBE: %.0f%% of unlimited bandwidth", fraction * 100), '='))
+
+        probe, err := runKneeProbe(j, offered)
+        if err != nil {
+            return err
+        }
+
+        report.Probes = append(report.Probes, probe)
+
+        // The lightest probe gives us the response times of a backend which isn't struggling.
+        if baseline == nil {
+            baseline = probe
+        }
+
+        report.Reason = probe.pastKnee(baseline)
+        if report.Reason != "" {
+            break
+        }
+
+        knee = probe
+    }
+
+    if report.Reason == "" {
+        report.Reason = "Response times and failures stayed low up to the unlimited bandwidth"
+    }
+
+    if knee != nil {
+        report.KneeBandwidth = knee.OfferedBandwidth
+        report.KneeBandwidthBytes = knee.OfferedBandwidthBytes
+    }
+
+    logger.Infof("%v", banner("KNEE RESULTS", '='))
+    for _, p := range append([]*KneeProbe{ report.UnlimitedProbe }, report.Probes...) {
+        logger.Infof("Offered: %7v   bandwidth: %7v,  res-95: %6v ms,  fail: %6v\n",
+            kneeBandwidthString(p.OfferedBandwidth, p.OfferedBandwidthBytes, j.useBytes),
+            kneeBandwidthString(p.Bandwidth, p.BandwidthBytes, j.useBytes),
+            p.maxResTime95() / 1000,
+            p.Failures)
+    }
+
+    logger.Infof("\n%v\n", report.Reason)
+    if knee != nil {
+        logger.Infof("Sustainable bandwidth: %v\n", kneeBandwidthString(report.KneeBandwidth, report.KneeBandwidthBytes, j.useBytes))
+    } else {
+        logger.Infof("Sustainable bandwidth: none found, since even the lightest probe was past the knee\n")
+    }
+
+    logger.Infof("This is a heuristic from short probe runs: treat it as a starting point.\n")
+
+    data, err := json.MarshalIndent(report, "", "  ")
+    if err == nil {
+        err = writeOutput(j.arguments.Output, data)
+    }
+
+    if err != nil {
+        return fmt.Errorf("Failure writing knee report %v: %v", j.arguments.Output, err)
+    }
+
+    return nil
+}
+
+
+/* Run a single short benchmark with a given bandwidth limit in bytes per second, and summarise its results. */
+func runKneeProbe(j *Job, bandwidth uint64) (*KneeProbe, error) {
+    // Our probes don't write the usual report, so give them their own arguments.
+    args := *j.arguments
+    args.Output = os.DevNull
+    args.AtomicReport = false
+
+    probe := *j
+    probe.arguments = &args
+    probe.order.Bandwidth = bandwidth
+    probe.rampUp = kneeRampUp
+    probe.runTime = kneeRunTime
+    probe.rampDown = kneeRampDown
+    probe.phaseRampUp = probe.rampUp
+
+    analyses, err := RunBenchmark(&probe)
+    if err != nil {
+        return nil, err
+    }
+
+    result := &KneeProbe{ OfferedBandwidth: bandwidth * 8, OfferedBandwidthBytes: bandwidth, ResTime95: make(map[string]uint64) }
+
+    for _, a := range analyses {
+        if !a.IsTotal {
+            continue
+        }
+
+        if a.BandwidthBytes > result.BandwidthBytes {
+            result.Bandwidth = a.Bandwidth
+            result.BandwidthBytes = a.BandwidthBytes
+        }
+
+        result.ResTime95[a.Phase] = a.ResTime95
+        result.Operations += a.Successes + a.Failures()
+        result.Failures += a.Failures()
+    }
+
+    // We can end up with no results if we were interrupted.
+    if len(result.ResTime95) == 0 {
+        return nil, fmt.Errorf("No results from knee probe with bandwidth %vB/s", ToUnits(bandwidth))
+    }
+
+    return result, nil
+}
+
+
+/* Returns why this probe is past the knee, compared with our lightest probe, or "" if it isn't. */
+func (p *KneeProbe) pastKnee(baseline *KneeProbe) string {
+    if float64(p.Failures) > kneeMaxFailureRate * float64(p.Operations) {
+        return fmt.Sprintf("Stopped: %v of %v operations failed", p.Failures, p.Operations)
+    }
+
+    for phase, resTime := range p.ResTime95 {
+        base := baseline.ResTime95[phase]
+        if base < kneeMinResTime {
+            base = kneeMinResTime
+        }
+
+        if float64(resTime) > kneeLatencyFactor * float64(base) {
+            return fmt.Sprintf("Stopped: %v res-95 rose from %v ms to %v ms", phase, baseline.ResTime95[phase] / 1000, resTime / 1000)
+        }
+    }
+
+    return ""
+}
+
+
+/* The slowest res-95 of any of our phases, in microseconds. */
+func (p *KneeProbe) maxResTime95() uint64 {
+    max := uint64(0)
+    for _, resTime := range p.ResTime95 {
+        if resTime > max {
+            max = resTime
+        }
+    }
+
+    return max
+}
+
+
+/* Format a bandwidth for our results, with zero meaning that there was no limit. */
+func kneeBandwidthString(bits uint64, bytes uint64, useBytes bool) string {
+    switch {
+        case bytes == 0:    return "none"
+        case useBytes:      return fmt.Sprintf("%vB/s", ToUnits(bytes))
+        default:            return fmt.Sprintf("%vb/s", ToUnits(bits))
+    }
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for deciding when a knee probe has gone past the knee.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Test functions.
+
+// Response times may rise a little, or stay below our noise floor, without passing the knee.
+func TestPastKneeResTime(t *testing.T) {
+    baseline := &KneeProbe{ ResTime95: map[string]uint64{ "Write": 4000, "Read": 100 }, Operations: 1000 }
+
+    probe := &KneeProbe{ ResTime95: map[string]uint64{ "Write": 8000, "Read": 1900 }, Operations: 1000 }
+    testutil.CheckString(t, "", probe.pastKnee(baseline))
+
+    probe.ResTime95["Write"] = 8001
+    testutil.CheckBool(t, true, probe.pastKnee(baseline) != "")
+
+    probe.ResTime95["Write"] = 8000
+    probe.ResTime95["Read"] = 2001
+    testutil.CheckBool(t, true, probe.pastKnee(baseline) != "")
+}
+
+
+// A few failures are tolerated, but more than our threshold passes the knee.
+func TestPastKneeFailures(t *testing.T) {
+    baseline := &KneeProbe{ ResTime95: map[string]uint64{ "Write": 4000 }, Operations: 1000 }
+
+    probe := &KneeProbe{ ResTime95: map[string]uint64{ "Write": 4000 }, Operations: 1000, Failures: 10 }
+    testutil.CheckString(t, "", probe.pastKnee(baseline))
+
+    probe.Failures = 11
+    testutil.CheckBool(t, true, probe.pastKnee(baseline) != "")
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "html"
+import "os"
+import "strings"
+
+
+/*
+ * A standalone SVG file of bar charts of our response time histograms, for a quick look at the
+ * shape of the latency distribution without needing any other tools.
+ *
+ * There is one chart for each phase, drawn from that phase's total analysis.  Each bar is one
+ * bucket of the histogram, and the buckets get wider as the response times grow (see
+ * histogramBucket), so the x axis is roughly logarithmic.  Hovering over a bar shows its range and
+ * count.  We draw everything ourselves, so that we don't need a plotting library.
+ */
+
+
+/* The size of each chart, and the space around its plot area, in pixels. */
+const svgWidth = 800
+const svgChartHeight = 300
+const svgMarginLeft = 60
+const svgMarginRight = 20
+const svgMarginTop = 40
+const svgMarginBottom = 40
+
+/* The closest that we put two labels on the x axis, in pixels. */
+const svgLabelSpacing = 60
+
+
+/* Write the charts of the response times in the given analyses to a new SVG file. */
+func WriteLatencySvg(path string, analyses []*Analysis) error {
+    var charts []*Analysis
+    for _, a := range analyses {
+        if a.IsTotal && (len(a.ResTimeHistogram) > 0) {
+            charts = append(charts, a)
+        }
+    }
+
+    height := svgChartHeight * len(charts)
+    if len(charts) == 0 {
+        height = svgMarginTop
+    }
+
+    var b strings.Builder
+    fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%v\" height=\"%v\" font-family=\"sans-serif\" font-size=\"12\">\n", svgWidth, height)
+    fmt.Fprintf(&b, "<rect width=\"100%%\" height=\"100%%\" fill=\"white\"/>\n")
+
+    if len(charts) == 0 {
+        fmt.Fprintf(&b, "<text x=\"%v\" y=\"%v\">No successful operations to chart</text>\n", svgMarginLeft, svgMarginTop / 2)
+    }
+
+    for i, a := range charts {
+        writeLatencyChart(&b, a, i * svgChartHeight)
+    }
+
+    b.WriteString("</svg>\n")
+
+    return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+
+/* Draw the chart for one analysis, with its top edge at the given y coordinate. */
+func writeLatencyChart(b *strings.Builder, a *Analysis, top int) {
+    plotWidth := svgWidth - svgMarginLeft - svgMarginRight
+    plotHeight := svgChartHeight - svgMarginTop - svgMarginBottom
+    plotBottom := top + svgMarginTop + plotHeight
+
+    maxCount := uint64(0)
+    for _, bucket := range a.ResTimeHistogram {
+        if bucket.Count > maxCount {
+            maxCount = bucket.Count
+        }
+    }
+
+    barWidth := float64(plotWidth) / float64(len(a.ResTimeHistogram))
+
+    fmt.Fprintf(b, "<text x=\"%v\" y=\"%v\" font-size=\"14\" font-weight=\"bold\">%v: %v ops, res-95 %v</text>\n",
+        svgMarginLeft, top + (svgMarginTop / 2), html.EscapeString(a.Name), a.Successes, formatMicros(a.ResTime95))
+
+    // The y axis only needs its top value, since the bars are all relative to it.
+    fmt.Fprintf(b, "<text x=\"%v\" y=\"%v\" text-anchor=\"end\">%v</text>\n", svgMarginLeft - 5, top + svgMarginTop + 10, maxCount)
+    fmt.Fprintf(b, "<text x=\"%v\" y=\"%v\" text-anchor=\"end\">0</text>\n", svgMarginLeft - 5, plotBottom)
+
+    lastLabel := -svgLabelSpacing
+    for i, bucket := range a.ResTimeHistogram {
+        x := float64(svgMarginLeft) + (float64(i) * barWidth)
+        h := float64(plotHeight) * float64(bucket.Count) / float64(maxCount)
+
+        fmt.Fprintf(b, "<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"steelblue\" stroke=\"white\" stroke-width=\"0.5\"><title>%v - %v: %v</title></rect>\n",
+            x, float64(plotBottom) - h, barWidth, h, formatMicros(bucket.ResTimeLow), formatMicros(bucket.ResTimeHigh), bucket.Count)
+
+        // Label each doubling of the response time, as long as there is room.
+        isDoubling := (bucket.ResTimeLow & (bucket.ResTimeLow - 1)) == 0
+        if isDoubling && (int(x) - lastLabel >= svgLabelSpacing) {
+            fmt.Fprintf(b, "<line x1=\"%.1f\" y1=\"%v\" x2=\"%.1f\" y2=\"%v\" stroke=\"black\"/>\n", x, plotBottom, x, plotBottom + 5)
+            fmt.Fprintf(b, "<text x=\"%.1f\" y=\"%v\" text-anchor=\"middle\">%v</text>\n", x, plotBottom + 18, formatMicros(bucket.ResTimeLow))
+            lastLabel = int(x)
+        }
+    }
+
+    fmt.Fprintf(b, "<line x1=\"%v\" y1=\"%v\" x2=\"%v\" y2=\"%v\" stroke=\"black\"/>\n", svgMarginLeft, plotBottom, svgMarginLeft + plotWidth, plotBottom)
+    fmt.Fprintf(b, "<text x=\"%v\" y=\"%v\" text-anchor=\"middle\">response time</text>\n", svgMarginLeft + (plotWidth / 2), plotBottom + 34)
+}
+
+
+/* Format a time in microseconds with whichever unit suits it best. */
+func formatMicros(micros uint64) string {
+    switch {
+        case micros < 1000:     return fmt.Sprintf("%vus", micros)
+        case micros < 1000000:  return fmt.Sprintf("%.1fms", float64(micros) / 1000)
+        default:                return fmt.Sprintf("%.2fs", float64(micros) / 1000000)
+    }
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "regexp"
+
+
+/*
+ * Lineage identifies what a run was benchmarking, so that results can be tracked across releases
+ * on trend dashboards.  It is written into the report, and attached as labels to every metric we
+ * export.
+ *
+ * The fields are fixed rather than free-form, so that every run is tagged in the same way:
+ *
+ *   BuildId      The build of the software under test: a git sha, a version or a build number.
+ *   ClusterId    The cluster (or other system) under test.
+ *   Environment  Where the cluster lives - "lab", "staging" and so on.
+ *
+ * BuildId and ClusterId are required when exporting metrics to a time-series database, since
+ * series without them can't be attributed to anything.  Environment is always optional.
+ */
+type Lineage struct {
+    BuildId string
+    ClusterId string
+    Environment string
+}
+
+
+/* The characters we allow in lineage values, which keeps them safe to use as metric labels. */
+var lineageValueRegex = regexp.MustCompile(`^[A-Za-z0-9._:/+-]*$`)
+
+
+/* Check that all of our fields are well formed.  If required is set, then all required fields must be present. */
+func (l *Lineage) Validate(required bool) error {
+    fields := []struct {
+        name string
+        value string
+        required bool
+    } {
+        { "build-id", l.BuildId, true },
+        { "cluster-id", l.ClusterId, true },
+        { "environment", l.Environment, false },
+    }
+
+    for _, f := range fields {
+        if !lineageValueRegex.MatchString(f.value) {
+            return fmt.Errorf("Bad %v: %v.  May only contain letters, digits and . _ : / + -", f.name, f.value)
+        }
+
+        if required && f.required && (f.value == "") {
+            return fmt.Errorf("Missing %v: required when exporting metrics", f.name)
+        }
+    }
+
+    return nil
+}
+
+
+/* Return our fields as Prometheus labels, ready to be appended to a metric's label list. */
+func (l *Lineage) Labels() string {
+    return fmt.Sprintf("build_id=%q,cluster_id=%q,environment=%q", l.BuildId, l.ClusterId, l.Environment)
+}
@@ -3,11 +3,13 @@
 
 package main
 
+import "comms"
 import "encoding/json"
 import "github.com/docopt/docopt-go"
 import "fmt"
 import "logger"
 import "math"
+import "net/url"
 import "math/rand"
 import "os"
 import "regexp"
@@ -22,6 +24,13 @@ var Version = "Development build"
 var BuildDate = "not set"
 
 
+/*
+ * The key prefixes (once lower-cased) that we can use for bucket names with --bucket-ops.  We add a
+ * tag and a counter of up to 31 characters, and S3 bucket names must be at most 63 characters long.
+ */
+var bucketPrefixRegex = regexp.MustCompile(`^([a-z0-9][a-z0-9-]{0,31})?$`)
+
+
 /* Struct type into which DocOpt can put our command line options. */
 type Arguments struct {
     // Command selection bools
@@ -31,14 +40,25 @@ type Arguments struct {
     Rados bool
     Rbd bool
     Cephfs bool
+    Nfs bool
     Block bool
+    Iscsi bool
     File bool
     Run bool
+    Calibrate bool
+    Config string
     CleanUp bool
 
     // Common options
     Verbosity string
+    LogFormat string
+    LogFile string
     Port int
+    TcpNodelay string
+    TcpKeepalive int
+    WireFormat string
+    MaxFrameSize string
+    StatCompression string
     MountsDir string
     ObjectSize string
     ObjectCount int
@@ -49,20 +69,93 @@ type Arguments struct {
     Bandwidth string
     ReadWriteMix int
     Output string
+    OutputFormat string
+    Baseline string
+    SloRes95 string
+    SloBandwidth string
+    LatencySvg string
     IndividualStats bool
+    WorkerStats bool
     Targets []string
+    TargetWeights string
+    TargetSizes string
+    SizeSweep string
+    FindKnee bool
+    ReferenceTarget string
+    ReferenceBucket string
+    ReferenceAccessKey string
+    ReferenceSecretKey string
+    ReferencePool string
+    ReferenceCephUser string
+    ReferenceCephKey string
+    ConsistencyCheck int
     Workers float64
+    Profile string
     SkipReadVerification bool
     UseBytes bool
+    Manifest string
+    ConnectionReuse string
+    AutoSteadyState bool
+    SteadyStateWindow int
+    SteadyStateCv float64
+    SteadyStateMaxWait int
+    ReconnectTimeout int
+    ConnectTimeout int
+    MetricsPort int
+    BuildId string
+    ClusterId string
+    Environment string
 
     // Server options
     ProfilePrefix string
+    StatusPort int
+    GrpcPort int
 
     // S3 options
     S3AccessKey string
     S3SecretKey string
+    RgwAdminUrl string
+    RgwAdminAccessKey string
+    RgwAdminSecretKey string
     S3Bucket string
     S3Port int
+    S3MultipartThreshold string
+    S3MultipartPartSize string
+    HttpErrorStats bool
+    BucketOps int
+    HoldConnections int
+    KeepaliveInterval int
+    AppendObjects int
+    VerifyOverwrites bool
+    HotSet int
+    HotSetRandom bool
+    ReadOrder string
+    StatUploadWindow int
+    SizeMix string
+    SizeMixBasis string
+    PadTo string
+    ReadRange string
+    ThinkTime string
+    OpTimeout string
+    MaxRetries int
+    BurstOps int
+    BurstIdle string
+    QueueDepth int
+    ReadAhead int
+    PinWorkers bool
+    MaxConcurrencyPerTarget int
+    Churn float64
+    RampShape string
+    AtomicReport bool
+    NoWrite bool
+    VerifyOnly bool
+    StatObjects bool
+    PrewarmReads bool
+    ExplicitFlush bool
+    PhaseCaps string
+    KeyPrefix string
+    KeyScheme string
+    ProvisionRetries int
 
     // Rados and/or CephFS options
     CephPool     string
@@ -70,18 +163,34 @@ type Arguments struct {
     CephUser     string
     CephKey      string
     CephDir      string
+    CephfsMountOptions string
+
+    // NFS options
+    NfsDir string
+    NfsOptions string
 
     // Block options
     BlockDevice string
 
+    // iSCSI options
+    IscsiPortal string
+    IscsiIqn string
+    IscsiLun int
+
     // File options
     FileDir string
 
     // Generator options
     Generator string
+    Seed string
     SliceDir string
     SliceSize int
     SliceCount int
+    SliceRecursive bool
+    SliceCache int
+    SliceWarmup int
+    DedupeRatio float64
+    Entropy float64
 
     // Script options
     Script string
@@ -90,6 +199,24 @@ type Arguments struct {
     Bucket string
     BandwidthInBits uint64
     ObjectSizeInBits uint64
+    PadToInBytes uint64
+    S3MultipartThresholdInBytes uint64
+    S3MultipartPartSizeInBytes uint64
+    ConnectionReuseEnabled bool
+    TcpNodelayEnabled bool
+    SeedValue uint64
+    TargetWeightValues []uint64
+    TargetSizeValues []uint64
+    SizeSweepValues []uint64
+    SizeMixValue *SizeMix
+    ReadRangeValue *ReadRange
+    PhaseCapsValue *PhaseCaps
+    ThinkTimeValue *ThinkTime
+    OpTimeoutValue time.Duration
+    MaxFrameSizeInBytes uint64
+    SloRes95Value time.Duration
+    SloBandwidthInBits uint64
+    BurstIdleValue time.Duration
 }
 
 
@@ -98,81 +225,314 @@ func usage() string {
     s := `SoftIron Benchmark Tool.
 Usage:
   sibench version
-  sibench server     [-v LEVEL] [-p PORT] [-m DIR] [--profile-prefix FILE]
-  sibench s3 run     [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats]
-                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR]
+  sibench server     [-v LEVEL] [-p PORT] [-m DIR] [--profile-prefix FILE] [--status-port PORT] [--grpc-port PORT]
+                     [--tcp-nodelay BOOL] [--tcp-keepalive TIME] [--wire-format FORMAT]
+                     [--max-frame-size SIZE] [--log-format FORMAT] [--log-file FILE]
+  sibench s3 (run | calibrate)
+                     [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] [--atomic-report] [--latency-svg FILE]
+                     [--output-format FORMAT] [--max-concurrency-per-target N] [--baseline FILE] [--worker-stats]
+                     [--slo-res-95 TIME] [--slo-bandwidth BW]
+                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR] [--size-sweep SIZES]
                      [-g GEN] [--slice-dir DIR] [--slice-count COUNT] [--slice-size BYTES] [--use-bytes]
-                     [--s3-port PORT] [--s3-bucket BUCKET] (--s3-access-key KEY) (--s3-secret-key KEY) 
-                     [--clean-up] [--skip-read-verification] [--servers SERVERS] <targets> ...`
+                     [--manifest FILE] [--connection-reuse BOOL] [--auto-steady-state] [--reconnect-timeout TIME]
+                     [--steady-state-window TIME] [--steady-state-cv CV] [--steady-state-max-wait TIME] [--profile NAME]
+                     [--metrics-port PORT] [--verify-overwrites] [--tcp-nodelay BOOL] [--seed N] [--op-timeout TIME]
+                     [--tcp-keepalive TIME] [--connect-timeout TIME] [--log-format FORMAT] [--log-file FILE] [--read-ahead N]
+                     [--dedupe-ratio RATIO] [--entropy BITS] [--build-id ID] [--cluster-id ID] [--environment ENV]
+                     [--hot-set N] [--hot-set-random] [--stat-upload-window N] [--read-range RANGE] [--read-order ORDER]
+                     [--think-time TIME] [--queue-depth N] [--slice-recursive] [--slice-cache COUNT] [--slice-warmup COUNT]
+                     [--churn RATIO] [--ramp-shape SHAPE] [--wire-format FORMAT] [--stat-compression TYPE] [--max-retries N]
+                     [--burst-ops N] [--burst-idle TIME] [--max-frame-size SIZE]
+                     [--size-mix MIX] [--size-mix-basis BASIS] [--key-prefix PREFIX] [--key-scheme SCHEME] [--pad-to SIZE]
+                     [--s3-port PORT] [--s3-bucket BUCKET] [--s3-access-key KEY] [--s3-secret-key KEY]
+                     [--rgw-admin-url URL] [--rgw-admin-access-key KEY] [--rgw-admin-secret-key KEY]
+                     [--s3-multipart-threshold SIZE] [--s3-multipart-part-size SIZE] [--http-error-stats]
+                     [--bucket-ops N] [--hold-connections N] [--keepalive-interval TIME] [--provision-retries N]
+                     [--reference-bucket BUCKET] [--reference-access-key KEY] [--reference-secret-key KEY]
+                     [--clean-up] [--no-write] [--verify-only] [--skip-read-verification] [--servers SERVERS]
+                     [--stat-objects] [--phase-caps CAPS] [--target-weights WEIGHTS] [--target-sizes SIZES]
+                     [--reference-target TARGET] [--consistency-check N] [--find-knee] [--pin-workers] [--prewarm-reads]
+                     <targets> ...`
 
     if runtime.GOOS == "linux" {
         s += ` 
-  sibench rados run  [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats]
-                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR]
+  sibench rados (run | calibrate)
+                     [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] [--atomic-report] [--latency-svg FILE]
+                     [--output-format FORMAT] [--max-concurrency-per-target N] [--baseline FILE] [--worker-stats]
+                     [--slo-res-95 TIME] [--slo-bandwidth BW]
+                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR] [--size-sweep SIZES]
                      [-g GEN] [--slice-dir DIR] [--slice-count COUNT] [--slice-size BYTES] [--use-bytes]
+                     [--manifest FILE] [--connection-reuse BOOL] [--auto-steady-state] [--reconnect-timeout TIME]
+                     [--steady-state-window TIME] [--steady-state-cv CV] [--steady-state-max-wait TIME] [--profile NAME]
+                     [--metrics-port PORT] [--verify-overwrites] [--tcp-nodelay BOOL] [--seed N] [--op-timeout TIME]
+                     [--tcp-keepalive TIME] [--connect-timeout TIME] [--log-format FORMAT] [--log-file FILE] [--read-ahead N]
+                     [--dedupe-ratio RATIO] [--entropy BITS] [--build-id ID] [--cluster-id ID] [--environment ENV]
+                     [--hot-set N] [--hot-set-random] [--stat-upload-window N] [--read-range RANGE] [--read-order ORDER]
+                     [--think-time TIME] [--queue-depth N] [--slice-recursive] [--slice-cache COUNT] [--slice-warmup COUNT]
+                     [--churn RATIO] [--ramp-shape SHAPE] [--wire-format FORMAT] [--stat-compression TYPE] [--max-retries N]
+                     [--burst-ops N] [--burst-idle TIME] [--max-frame-size SIZE]
+                     [--size-mix MIX] [--size-mix-basis BASIS] [--key-prefix PREFIX] [--key-scheme SCHEME] [--pad-to SIZE]
                      [--ceph-pool POOL] [--ceph-user USER] (--ceph-key KEY) [--script SCRIPT]
-                     [--clean-up] [--skip-read-verification] [--servers SERVERS] <targets> ...
-  sibench cephfs run [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] 
-                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR]
+                     [--append-objects N] [--provision-retries N]
+                     [--reference-pool POOL] [--reference-ceph-user USER] [--reference-ceph-key KEY]
+                     [--clean-up] [--no-write] [--verify-only] [--skip-read-verification] [--servers SERVERS]
+                     [--stat-objects] [--phase-caps CAPS] [--target-weights WEIGHTS] [--target-sizes SIZES]
+                     [--reference-target TARGET] [--consistency-check N] [--find-knee] [--pin-workers] [--prewarm-reads]
+                     <targets> ...
+  sibench cephfs (run | calibrate)
+                     [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] [--atomic-report] [--latency-svg FILE]
+                     [--output-format FORMAT] [--max-concurrency-per-target N] [--baseline FILE] [--worker-stats]
+                     [--slo-res-95 TIME] [--slo-bandwidth BW]
+                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR] [--size-sweep SIZES]
                      [-g GEN] [--slice-dir DIR] [--slice-count COUNT] [--slice-size BYTES] [--use-bytes]
+                     [--manifest FILE] [--connection-reuse BOOL] [--auto-steady-state] [--reconnect-timeout TIME]
+                     [--steady-state-window TIME] [--steady-state-cv CV] [--steady-state-max-wait TIME] [--profile NAME]
+                     [--metrics-port PORT] [--verify-overwrites] [--tcp-nodelay BOOL] [--seed N] [--op-timeout TIME]
+                     [--tcp-keepalive TIME] [--connect-timeout TIME] [--log-format FORMAT] [--log-file FILE] [--read-ahead N]
+                     [--dedupe-ratio RATIO] [--entropy BITS] [--build-id ID] [--cluster-id ID] [--environment ENV]
+                     [--hot-set N] [--hot-set-random] [--stat-upload-window N] [--read-range RANGE] [--read-order ORDER]
+                     [--think-time TIME] [--queue-depth N] [--slice-recursive] [--slice-cache COUNT] [--slice-warmup COUNT]
+                     [--churn RATIO] [--ramp-shape SHAPE] [--wire-format FORMAT] [--stat-compression TYPE] [--max-retries N]
+                     [--burst-ops N] [--burst-idle TIME] [--max-frame-size SIZE]
+                     [--size-mix MIX] [--size-mix-basis BASIS] [--key-prefix PREFIX] [--key-scheme SCHEME] [--pad-to SIZE]
                      [-m DIR] [--ceph-dir DIR] [--ceph-user USER] (--ceph-key KEY) [--script SCRIPT]
-                     [--clean-up] [--skip-read-verification] [--servers SERVERS] <targets> ...
-  sibench rbd run    [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] 
-                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR]
+                     [--cephfs-mount-options OPTS] [--append-objects N] [--explicit-flush]
+                     [--reference-ceph-user USER] [--reference-ceph-key KEY]
+                     [--clean-up] [--no-write] [--verify-only] [--skip-read-verification] [--servers SERVERS]
+                     [--stat-objects] [--phase-caps CAPS] [--target-weights WEIGHTS] [--target-sizes SIZES]
+                     [--reference-target TARGET] [--consistency-check N] [--find-knee] [--pin-workers] [--prewarm-reads]
+                     <targets> ...
+  sibench nfs (run | calibrate)
+                     [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] [--atomic-report] [--latency-svg FILE]
+                     [--output-format FORMAT] [--max-concurrency-per-target N] [--baseline FILE] [--worker-stats]
+                     [--slo-res-95 TIME] [--slo-bandwidth BW]
+                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR] [--size-sweep SIZES]
                      [-g GEN] [--slice-dir DIR] [--slice-count COUNT] [--slice-size BYTES] [--use-bytes]
-                     [--ceph-pool POOL] [--ceph-datapool POOL] [--ceph-user USER] (--ceph-key KEY)
-                     [--script SCRIPT] [--clean-up] [--skip-read-verification] [--servers SERVERS] 
-                     <targets> ...`
+                     [--manifest FILE] [--connection-reuse BOOL] [--auto-steady-state] [--reconnect-timeout TIME]
+                     [--steady-state-window TIME] [--steady-state-cv CV] [--steady-state-max-wait TIME] [--profile NAME]
+                     [--metrics-port PORT] [--verify-overwrites] [--tcp-nodelay BOOL] [--seed N] [--op-timeout TIME]
+                     [--tcp-keepalive TIME] [--connect-timeout TIME] [--log-format FORMAT] [--log-file FILE] [--read-ahead N]
+                     [--dedupe-ratio RATIO] [--entropy BITS] [--build-id ID] [--cluster-id ID] [--environment ENV]
+                     [--hot-set N] [--hot-set-random] [--stat-upload-window N] [--read-range RANGE] [--read-order ORDER]
+                     [--think-time TIME] [--queue-depth N] [--slice-recursive] [--slice-cache COUNT] [--slice-warmup COUNT]
+                     [--churn RATIO] [--ramp-shape SHAPE] [--wire-format FORMAT] [--stat-compression TYPE] [--max-retries N]
+                     [--burst-ops N] [--burst-idle TIME] [--max-frame-size SIZE]
+                     [--size-mix MIX] [--size-mix-basis BASIS] [--key-prefix PREFIX] [--key-scheme SCHEME] [--pad-to SIZE]
+                     [-m DIR] [--nfs-dir DIR] [--nfs-options OPTS] [--script SCRIPT] [--append-objects N]
+                     [--explicit-flush]
+                     [--clean-up] [--no-write] [--verify-only] [--skip-read-verification] [--servers SERVERS]
+                     [--stat-objects] [--phase-caps CAPS] [--target-weights WEIGHTS] [--target-sizes SIZES]
+                     [--reference-target TARGET] [--consistency-check N] [--find-knee] [--pin-workers] [--prewarm-reads]
+                     <targets> ...
+  sibench rbd (run | calibrate)
+                     [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] [--atomic-report] [--latency-svg FILE]
+                     [--output-format FORMAT] [--max-concurrency-per-target N] [--baseline FILE] [--worker-stats]
+                     [--slo-res-95 TIME] [--slo-bandwidth BW]
+                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR] [--size-sweep SIZES]
+                     [-g GEN] [--slice-dir DIR] [--slice-count COUNT] [--slice-size BYTES] [--use-bytes]
+                     [--manifest FILE] [--connection-reuse BOOL] [--auto-steady-state] [--reconnect-timeout TIME]
+                     [--steady-state-window TIME] [--steady-state-cv CV] [--steady-state-max-wait TIME] [--profile NAME]
+                     [--metrics-port PORT] [--verify-overwrites] [--tcp-nodelay BOOL] [--seed N] [--op-timeout TIME]
+                     [--tcp-keepalive TIME] [--connect-timeout TIME] [--log-format FORMAT] [--log-file FILE] [--read-ahead N]
+                     [--dedupe-ratio RATIO] [--entropy BITS] [--build-id ID] [--cluster-id ID] [--environment ENV]
+                     [--hot-set N] [--hot-set-random] [--stat-upload-window N] [--read-range RANGE] [--read-order ORDER]
+                     [--think-time TIME] [--queue-depth N] [--slice-recursive] [--slice-cache COUNT] [--slice-warmup COUNT]
+                     [--churn RATIO] [--ramp-shape SHAPE] [--wire-format FORMAT] [--stat-compression TYPE] [--max-retries N]
+                     [--burst-ops N] [--burst-idle TIME] [--max-frame-size SIZE]
+                     [--ceph-pool POOL] [--ceph-datapool POOL] [--ceph-user USER] (--ceph-key KEY) [--provision-retries N]
+                     [--script SCRIPT] [--clean-up] [--verify-only] [--skip-read-verification] [--servers SERVERS]
+                     [--stat-objects] [--phase-caps CAPS] [--target-weights WEIGHTS] [--target-sizes SIZES]
+                     [--find-knee] [--pin-workers] [--prewarm-reads] [--explicit-flush] <targets> ...
+  sibench iscsi (run | calibrate)
+                     [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] [--atomic-report] [--latency-svg FILE]
+                     [--output-format FORMAT] [--max-concurrency-per-target N] [--baseline FILE] [--worker-stats]
+                     [--slo-res-95 TIME] [--slo-bandwidth BW]
+                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR] [--size-sweep SIZES]
+                     [-g GEN] [--slice-dir DIR] [--slice-count COUNT] [--slice-size BYTES] [--use-bytes]
+                     [--manifest FILE] [--connection-reuse BOOL] [--auto-steady-state] [--reconnect-timeout TIME]
+                     [--steady-state-window TIME] [--steady-state-cv CV] [--steady-state-max-wait TIME] [--profile NAME]
+                     [--metrics-port PORT] [--verify-overwrites] [--tcp-nodelay BOOL] [--seed N] [--op-timeout TIME]
+                     [--tcp-keepalive TIME] [--connect-timeout TIME] [--log-format FORMAT] [--log-file FILE] [--read-ahead N]
+                     [--dedupe-ratio RATIO] [--entropy BITS] [--build-id ID] [--cluster-id ID] [--environment ENV]
+                     [--hot-set N] [--hot-set-random] [--stat-upload-window N] [--read-range RANGE] [--read-order ORDER]
+                     [--think-time TIME] [--queue-depth N] [--slice-recursive] [--slice-cache COUNT] [--slice-warmup COUNT]
+                     [--churn RATIO] [--ramp-shape SHAPE] [--wire-format FORMAT] [--stat-compression TYPE] [--max-retries N]
+                     [--burst-ops N] [--burst-idle TIME] [--max-frame-size SIZE]
+                     (--iscsi-portal PORTAL) (--iscsi-iqn IQN) [--iscsi-lun LUN]
+                     [--script SCRIPT] [--clean-up] [--no-write] [--verify-only]
+                     [--skip-read-verification] [--servers SERVERS] [--stat-objects] [--phase-caps CAPS]
+                     [--find-knee] [--pin-workers] [--prewarm-reads] [--explicit-flush]`
     }
 
     s += ` 
-  sibench block run  [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] 
-                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR]
+  sibench block (run | calibrate)
+                     [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] [--atomic-report] [--latency-svg FILE]
+                     [--output-format FORMAT] [--max-concurrency-per-target N] [--baseline FILE] [--worker-stats]
+                     [--slo-res-95 TIME] [--slo-bandwidth BW]
+                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR] [--size-sweep SIZES]
                      [-g GEN] [--slice-dir DIR] [--slice-count COUNT] [--slice-size BYTES] [--use-bytes]
-                     [--block-device DEVICE] [--script SCRIPT] [--clean-up] 
-                     [--skip-read-verification] [--servers SERVERS] 
-  sibench file run   [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] 
-                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR]
+                     [--manifest FILE] [--connection-reuse BOOL] [--auto-steady-state] [--reconnect-timeout TIME]
+                     [--steady-state-window TIME] [--steady-state-cv CV] [--steady-state-max-wait TIME] [--profile NAME]
+                     [--metrics-port PORT] [--verify-overwrites] [--tcp-nodelay BOOL] [--seed N] [--op-timeout TIME]
+                     [--tcp-keepalive TIME] [--connect-timeout TIME] [--log-format FORMAT] [--log-file FILE] [--read-ahead N]
+                     [--dedupe-ratio RATIO] [--entropy BITS] [--build-id ID] [--cluster-id ID] [--environment ENV]
+                     [--hot-set N] [--hot-set-random] [--stat-upload-window N] [--read-range RANGE] [--read-order ORDER]
+                     [--think-time TIME] [--queue-depth N] [--slice-recursive] [--slice-cache COUNT] [--slice-warmup COUNT]
+                     [--churn RATIO] [--ramp-shape SHAPE] [--wire-format FORMAT] [--stat-compression TYPE] [--max-retries N]
+                     [--burst-ops N] [--burst-idle TIME] [--max-frame-size SIZE]
+                     [--block-device DEVICE] [--script SCRIPT] [--clean-up] [--no-write] [--verify-only]
+                     [--skip-read-verification] [--servers SERVERS] [--stat-objects] [--phase-caps CAPS]
+                     [--find-knee] [--pin-workers] [--prewarm-reads] [--explicit-flush]
+  sibench file (run | calibrate)
+                     [-v LEVEL] [-p PORT] [-o FILE] [--individual-stats] [--atomic-report] [--latency-svg FILE]
+                     [--output-format FORMAT] [--max-concurrency-per-target N] [--baseline FILE] [--worker-stats]
+                     [--slo-res-95 TIME] [--slo-bandwidth BW]
+                     [-s SIZE] [-c COUNT] [-b BW] [-x MIX] [-r TIME] [-u TIME] [-d TIME] [-w FACTOR] [--size-sweep SIZES]
                      [-g GEN] [--slice-dir DIR] [--slice-count COUNT] [--slice-size BYTES] [--use-bytes]
-                     [--script SCRIPT] [--file-dir DIR] [--clean-up] [--skip-read-verification] 
-                     [--servers SERVERS] 
+                     [--manifest FILE] [--connection-reuse BOOL] [--auto-steady-state] [--reconnect-timeout TIME]
+                     [--steady-state-window TIME] [--steady-state-cv CV] [--steady-state-max-wait TIME] [--profile NAME]
+                     [--metrics-port PORT] [--verify-overwrites] [--tcp-nodelay BOOL] [--seed N] [--op-timeout TIME]
+                     [--tcp-keepalive TIME] [--connect-timeout TIME] [--log-format FORMAT] [--log-file FILE] [--read-ahead N]
+                     [--dedupe-ratio RATIO] [--entropy BITS] [--build-id ID] [--cluster-id ID] [--environment ENV]
+                     [--hot-set N] [--hot-set-random] [--stat-upload-window N] [--read-range RANGE] [--read-order ORDER]
+                     [--think-time TIME] [--queue-depth N] [--slice-recursive] [--slice-cache COUNT] [--slice-warmup COUNT]
+                     [--churn RATIO] [--ramp-shape SHAPE] [--wire-format FORMAT] [--stat-compression TYPE] [--max-retries N]
+                     [--burst-ops N] [--burst-idle TIME] [--max-frame-size SIZE]
+                     [--size-mix MIX] [--size-mix-basis BASIS] [--key-prefix PREFIX] [--key-scheme SCHEME] [--pad-to SIZE]
+                     [--script SCRIPT] [--file-dir DIR] [--clean-up] [--no-write] [--skip-read-verification]
+                     [--servers SERVERS] [--append-objects N] [--verify-only] [--stat-objects] [--phase-caps CAPS]
+                     [--find-knee] [--pin-workers] [--prewarm-reads] [--explicit-flush]
+  sibench run (--config FILE) [-v LEVEL] [-p PORT] [-o FILE] [--servers SERVERS] [--log-format FORMAT] [--log-file FILE]
   sibench -h | --help
 
 Options:
   -h, --help                      Show full usage
   -v LEVEL, --verbosity LEVEL     Turn on debug output at level "off", "debug" or "trace"          [default: off]
+  --log-format FORMAT             Log as "plain" text, or "json" with timestamps, levels and host. [default: plain]
+  --log-file FILE                 Write log messages to this file, rather than to stderr.
   -p PORT, --port PORT            The port on which sibench communicates.                          [default: 5150]
   -m DIR, --mounts-dir DIR        The directory in which we should create any filesystem mounts.   [default: /tmp/sibench_mnt]
   -s SIZE, --object-size SIZE     Object size to test, in units of K or M.                         [default: 1M]
   -c COUNT, --object-count COUNT  The number of objects to use as our working set.                 [default: 1000]
+  --size-sweep SIZES              Run once for each of a comma-separated list of object sizes.
+  --size-mix MIX                  Use a mix of object sizes, as SIZE:WEIGHT pairs: 4K:50,1M:50
+  --size-mix-basis BASIS          Whether size mix weights are by object "count" or "bytes".       [default: count]
+  --pad-to SIZE                   Pad smaller objects with zeroes to SIZE, for allocation studies. [default: 0]
+  --key-prefix PREFIX             The prefix for object keys.  Random by default, so each run is new.
+  --key-scheme SCHEME             How to build keys: "sequential", "hashed" or "uuid".             [default: sequential]
   -r TIME, --run-time TIME        Seconds spent on each phase of the benchmark.                    [default: 30]
   -u TIME, --ramp-up TIME         Seconds at the start of each phase where we don't record data.   [default: 5]
   -d TIME, --ramp-down TIME       Seconds at the end of each phase where we don't record data.     [default: 2]
+  --ramp-shape SHAPE              How ramp-up works: "discard" or "linear" (needs --bandwidth).    [default: discard]
   -w FACTOR, --workers FACTOR     Number of workers per server as a factor x number of CPU cores   [default: 1.0]
+  --profile NAME                  Use defaults tuned for the backend: "none" or "auto".            [default: none]
   -b BW, --bandwidth BW           Benchmark at a fixed bandwidth, in units of K, M or G bits/s..   [default: 0]
+  --find-knee                     Probe rising bandwidths to find the most the backend can sustain.
   -x MIX, --read-write-mix MIX    Do a mix of read and writes, giving the percentage of reads.     [default: 0]
-  -g GEN, --generator GEN         Which object generator to use: "prng" or "slice"                 [default: prng]
-  -o FILE, --output FILE          The file to which we write our json results.                     [default: sibench.json]
+  -g GEN, --generator GEN         Which generator to use: "prng", "slice", "dedupe" or "entropy".  [default: prng]
+  --config FILE                   Run each of the jobs in a JSON file in turn, on the same servers.
+  -o FILE, --output FILE          The file to which we write our json results, or - for stdout.    [default: sibench.json]
+  --output-format FORMAT          Write results as "sibench" or as "fio" compatible JSON.          [default: sibench]
+  --baseline FILE                 Compare our results with those in the report of an earlier run.
+  --slo-res-95 TIME               Exit with code 2 if a write or read phase has a slower 95th %ile.[default: 0]
+  --slo-bandwidth BW              Exit with code 2 if a write or read phase has less bandwidth.    [default: 0]
+  --latency-svg FILE              Also write a histogram of each phase's response times as SVG.
   --individual-stats              Write full stats to the output file - may be big.
+  --worker-stats                  Add an analysis of each worker to the report, to find stragglers.
+  --atomic-report                 Write the output file under a temporary name, and rename it when done.
   --clean-up                      Delete the data at the end of the benchmark run.
+  --no-write                      Skip writing, and read the objects left by an earlier run instead.
+  --verify-only                   Instead of benchmarking, read and verify every object once.
+  --stat-objects                  Add a timed phase which stats objects before the read phase.
+  --prewarm-reads                 Read every object once, untimed, so that the timed reads are warm.
+  --phase-caps CAPS               Cap the ops in timed phases as PHASE:COUNT, eg: "write:10000".
   --use-bytes                     Bandwidth output in Bytes
   --skip-read-verification        Disable validation on reads (for when sibench CPU is a limit).
   --servers SERVERS               A comma-separated list of sibench servers to connect to.         [default: localhost]
+  --target-weights WEIGHTS        Comma-separated relative share of ops for each target.
+  --target-sizes SIZES            Comma-separated object size for each target, instead of -s.
+  --reference-target TARGET       Verify reads against the same objects read from this target.
+  --reference-bucket BUCKET       The S3 bucket to use on the reference target, if not --s3-bucket.
+  --reference-access-key KEY      The S3 access key for the reference target, if not --s3-access-key.
+  --reference-secret-key KEY      The S3 secret key for the reference target, if not --s3-secret-key.
+  --reference-pool POOL           The pool to use on the reference target, if not --ceph-pool.
+  --reference-ceph-user USER      The ceph user for the reference target, if not --ceph-user.
+  --reference-ceph-key KEY        The ceph key for the reference target, if not --ceph-key.
+  --consistency-check N           Compare a sample of N objects across every target, on each read.
+  --manifest FILE                 Write a manifest of the objects used, for later clean-up.
+  --connection-reuse BOOL         Set to false to open a new connection for every operation.       [default: true]
+  --auto-steady-state             Detect the ramp-up time by waiting for a plateau in ops/s.
+  --steady-state-window TIME      Seconds over which we look for a plateau in auto steady state.   [default: 5]
+  --steady-state-cv CV            The coefficient of variation below which ops/s is a plateau.     [default: 0.05]
+  --steady-state-max-wait TIME    The longest we wait for a plateau before recording anyway.       [default: 60]
+  --metrics-port PORT             Serve live Prometheus metrics on this port during the run.       [default: 0]
+  --reconnect-timeout TIME        Seconds to wait for a lost server to restart, or 0 to abort.     [default: 0]
+  --connect-timeout TIME          Seconds to wait connecting to each server, or 0 for no limit.    [default: 0]
+  --verify-overwrites             Check reads return the latest cycle of overwritten objects.
+  --tcp-nodelay BOOL              Disable Nagle's algorithm on sibench's own control connections.  [default: true]
+  --tcp-keepalive TIME            Seconds between TCP keepalives on control connections, or 0.     [default: 15]
+  --wire-format FORMAT            Encoding for sibench's own connections: "gob" or "json".         [default: gob]
+  --max-frame-size SIZE           Largest message we accept on sibench's own connections.          [default: 256M]
+  --seed N                        Seed for object content, to repeat a run. Generated if not given.
+  --build-id ID                   The build under test (eg: a git sha), for tracking results.
+  --cluster-id ID                 The cluster under test, for tracking results.
+  --environment ENV               The environment of the cluster under test (eg: lab).
+  --hot-set N                     Only read this many objects, to test caching.  0 means all of them.[default: 0]
+  --hot-set-random                Read the hot set in random order rather than cycling through it.
+  --read-order ORDER              Read each worker's objects in "sequential" or "random" order.   [default: sequential]
+  --stat-upload-window N          Max unacked stat messages per server when retrieving stats.      [default: 16]
+  --stat-compression TYPE         Compress the stats sent by servers: "none" or "gzip".            [default: none]
+  --read-range RANGE              Read only part of each object: OFFSET:LENGTH, or a fraction.
+  --think-time TIME               Pause between each worker's ops: 10ms, 5ms-20ms or exp:10ms.
+  --op-timeout TIME               Fail any op which takes longer than this, such as 10s or 500ms.  [default: 0]
+  --max-retries N                 Retry an op that fails transiently up to N times, with backoff.  [default: 0]
+  --burst-ops N                   Run in bursts of this many ops per worker, idling between them.  [default: 0]
+  --burst-idle TIME               How long each worker idles between bursts, such as 5s.           [default: 5s]
+  --queue-depth N                 The number of ops each worker keeps in flight at once.           [default: 1]
+  --read-ahead N                  The number of reads each worker fetches ahead of verifying them. [default: 1]
+  --pin-workers                   Pin each worker to one CPU of its server, to reduce jitter (Linux).
+  --max-concurrency-per-target N  The most ops each server has in flight to one target, or 0.      [default: 0]
+  --churn RATIO                   The fraction of read phase ops which delete an object instead.   [default: 0]
+  --explicit-flush                Write without syncing, then time a separate flush of each write.
+  --append-objects N              Instead of writing, append to N objects shared by all workers.   [default: 0]
   --s3-port PORT                  The port on which to connect to S3.                              [default: 7480]
   --s3-bucket BUCKET              The name of the bucket we wish to use for S3 operations.         [default: sibench]
-  --s3-access-key KEY             S3 access key.
-  --s3-secret-key KEY             S3 secret key.
+  --s3-access-key KEY             S3 access key.  Required unless --rgw-admin-url is given.
+  --s3-secret-key KEY             S3 secret key.  Required unless --rgw-admin-url is given.
+  --rgw-admin-url URL             Create a throwaway S3 user for the run with this RGW admin API.
+  --rgw-admin-access-key KEY      Access key of an RGW user with the users=* admin capability.
+  --rgw-admin-secret-key KEY      Secret key of the RGW admin user.
+  --s3-multipart-threshold SIZE   Objects larger than this are uploaded with S3 multipart uploads. [default: 64M]
+  --s3-multipart-part-size SIZE   The part size to use for S3 multipart uploads.                   [default: 16M]
+  --http-error-stats              Break down HTTP failures into client errors, server errors and throttling.
+  --bucket-ops N                  Benchmark creating and deleting buckets, keeping N per worker.   [default: 0]
+  --hold-connections N            Hold N idle connections per target in each worker, instead of IO.[default: 0]
+  --keepalive-interval TIME       Seconds between keepalive requests on each held connection.      [default: 10]
+  --provision-retries N           Retries, with backoff, when creating a bucket or finding a pool. [default: 3]
   --ceph-pool POOL                The pool we use for benchmarking.                                [default: sibench]
   --ceph-datapool POOL            Optional pool used for RBD.  If set, ceph-pool is for metadata.
   --ceph-user USER                The ceph username we use.                                        [default: admin]
   --ceph-key KEY                  The secret key belonging to the ceph user.
   --ceph-dir DIR                  The CephFS directory which we should use for a benchmark.        [default: sibench]
+  --cephfs-mount-options OPTS     Extra CephFS mount options, such as "mds_namespace=fs2,noatime".
+  --nfs-dir DIR                   The directory within the NFS export to use for a benchmark.      [default: sibench]
+  --nfs-options OPTS              Extra NFS mount options, such as "vers=4.1".
   --block-device DEVICE           The block device to use for a benchmark.                         [default: /tmp/sibench_block]
+  --iscsi-portal PORTAL           The iSCSI portal to log in to, as HOST or HOST:PORT.
+  --iscsi-iqn IQN                 The IQN of the iSCSI target to log in to.
+  --iscsi-lun LUN                 The LUN of the iSCSI target to use for a benchmark.              [default: 0]
   --file-dir DIR                  The directory to use (must already exist).
   --slice-dir DIR                 The directory of files to be sliced up to form new workload objects.
   --slice-count COUNT             The number of slices to construct for workload generation        [default: 10000]
   --slice-size BYTES              The size of each slice in bytes.                                 [default: 4097]
+  --slice-recursive               Also load slices from files in subdirectories of the slice directory.
+  --slice-cache COUNT             Slices kept in memory (0 for all), with others read from disk.   [default: 0]
+  --slice-warmup COUNT            Objects each worker generates up front to warm its slices.       [default: 0]
+  --dedupe-ratio RATIO            The fraction of blocks duplicated across objects by dedupe.      [default: 0.5]
+  --entropy BITS                  Bits of entropy per byte in objects from the entropy generator.  [default: 4]
   --profile-prefix FILE           Enable profiling, using tne given prefix for any output.
+  --status-port PORT              Serve the server's status as JSON over HTTP on this port.        [default: 0]
+  --grpc-port PORT                Serve a gRPC control plane on this port.                         [default: 0]
   --script SCRIPT                 Specifies a script to be run at key points in each phase.
 `
     return s
@@ -191,23 +551,23 @@ func prettyPrint(i interface{}) string {
 
 
 /*
- * Quit with an error message.
+ * Quit with an error message and one of our exit codes.
  */
-func die(format string, a ...interface{}) {
-    fmt.Printf(format, a)
-    os.Exit(-1)
+func die(code int, format string, a ...interface{}) {
+    fmt.Fprintf(os.Stderr, format, a...)
+    os.Exit(code)
 }
 
 
 /* 
  * Helper to simplify our error handling.  
- * If err is not nil, then we print an error message and die (with a non-zero exit code).
+ * If err is not nil, then we print an error message and die with the given exit code.
  */
-func dieOnError(err error, format string, a ...interface{}) {
+func dieOnError(err error, code int, format string, a ...interface{}) {
     if err != nil {
-        fmt.Printf(format, a)
-        fmt.Printf(": %v\n", err)
-        os.Exit(-1)
+        fmt.Fprintf(os.Stderr, format, a...)
+        fmt.Fprintf(os.Stderr, ": %v\n", err)
+        os.Exit(code)
     }
 }
 
@@ -241,6 +601,30 @@ func expandUnits(val string) (uint64, error) {
 }
 
 
+/*
+ * Check that each timed phase will record something, and that the ramps are not negative.
+ * The ramp-up and ramp-down are run either side of the run time, so they don't eat into it.
+ */
+func validateDurations(args *Arguments) error {
+    if (args.RampUp < 0) || (args.RampDown < 0) {
+        return fmt.Errorf("Bad ramp times: up %v, down %v.  May not be negative", args.RampUp, args.RampDown)
+    }
+
+    if args.RunTime < 1 {
+        return fmt.Errorf("Bad run time: %vs.  Must be at least 1", args.RunTime)
+    }
+
+    return nil
+}
+
+
+/*
+ * The smallest --max-frame-size we accept.  The stats that servers upload are the largest messages
+ * on our own connections, and come to less than 10MB each, even in JSON.
+ */
+const minMaxFrameSize = 16 * 1024 * 1024
+
+
 /* 
  * Do any argument checking that can not be done inherently by DocOpt (such as 
  * ensuring a port number is < 65535, or that a string has a particular form.
@@ -250,20 +634,129 @@ func validateArguments(args *Arguments) error {
         return fmt.Errorf("Port not in range: %v", args.Port)
     }
 
+    if (args.MetricsPort < 0) || ( args.MetricsPort > int(math.MaxUint16)) {
+        return fmt.Errorf("Metrics port not in range: %v", args.MetricsPort)
+    }
+
+    if (args.StatusPort < 0) || ( args.StatusPort > int(math.MaxUint16)) {
+        return fmt.Errorf("Status port not in range: %v", args.StatusPort)
+    }
+
+    // S3 needs keys, which we either get from the user, or make for ourselves with the RGW admin API.
+    if args.S3 && (args.RgwAdminUrl == "") {
+        if (args.S3AccessKey == "") || (args.S3SecretKey == "") {
+            return fmt.Errorf("S3 needs --s3-access-key and --s3-secret-key, unless --rgw-admin-url is given")
+        }
+
+        if (args.RgwAdminAccessKey != "") || (args.RgwAdminSecretKey != "") {
+            return fmt.Errorf("--rgw-admin-access-key and --rgw-admin-secret-key need --rgw-admin-url")
+        }
+    }
+
+    if args.RgwAdminUrl != "" {
+        u, err := url.Parse(args.RgwAdminUrl)
+        if (err != nil) || ((u.Scheme != "http") && (u.Scheme != "https")) || (u.Host == "") {
+            return fmt.Errorf("Bad RGW admin URL: %v.  Should be like http://gateway:7480", args.RgwAdminUrl)
+        }
+
+        if (args.S3AccessKey != "") || (args.S3SecretKey != "") {
+            return fmt.Errorf("--rgw-admin-url creates its own S3 user, so can not be used with --s3-access-key or --s3-secret-key")
+        }
+
+        if (args.RgwAdminAccessKey == "") || (args.RgwAdminSecretKey == "") {
+            return fmt.Errorf("--rgw-admin-url needs --rgw-admin-access-key and --rgw-admin-secret-key")
+        }
+
+        // The user is deleted with everything it owns at the end of the run, and can't read anyone else's objects.
+        if args.NoWrite || args.VerifyOnly {
+            return fmt.Errorf("--rgw-admin-url can not be used with --no-write or --verify-only, since its user has no objects")
+        }
+    }
+
+    if (args.GrpcPort < 0) || ( args.GrpcPort > int(math.MaxUint16)) {
+        return fmt.Errorf("gRPC port not in range: %v", args.GrpcPort)
+    }
+
     if (args.S3Port < 0) || ( args.S3Port > int(math.MaxUint16)) {
         return fmt.Errorf("S3 Port not in range: %v", args.S3Port)
     }
 
+    if args.ProvisionRetries < 0 {
+        return fmt.Errorf("Bad provision retries: %v.  Should not be negative", args.ProvisionRetries)
+    }
+
+    if args.IscsiLun < 0 {
+        return fmt.Errorf("Bad iSCSI LUN: %v.  Should not be negative", args.IscsiLun)
+    }
+
+    if (args.HotSet < 0) || (args.HotSet > args.ObjectCount) {
+        return fmt.Errorf("Bad hot set size: %v.  Must be between 0 and the object count", args.HotSet)
+    }
+
+    if (args.ReadOrder != "sequential") && (args.ReadOrder != "random") {
+        return fmt.Errorf("Bad read order: %v.  Should be \"sequential\" or \"random\"", args.ReadOrder)
+    }
+
+    if (args.ReadOrder == "random") && (args.HotSet != 0) {
+        return fmt.Errorf("--read-order random can not be used with --hot-set: use --hot-set-random instead")
+    }
+
+    err := validateDurations(args)
+    if err != nil {
+        return err
+    }
+
+    if args.StatUploadWindow < 1 {
+        return fmt.Errorf("Bad stat upload window: %v.  Must be at least 1", args.StatUploadWindow)
+    }
+
+    if (args.DedupeRatio < 0) || (args.DedupeRatio > 1) {
+        return fmt.Errorf("Bad dedupe ratio: %v.  Must be between 0 and 1", args.DedupeRatio)
+    }
+
+    if (args.Entropy < 0) || (args.Entropy > maxByteEntropy) {
+        return fmt.Errorf("Bad entropy: %v.  Must be between 0 and %v bits per byte", args.Entropy, maxByteEntropy)
+    }
+
+    if args.SteadyStateWindow < 2 {
+        return fmt.Errorf("Steady state window too small: %v.  Must be at least 2 seconds", args.SteadyStateWindow)
+    }
+
+    if args.SteadyStateMaxWait < 0 {
+        return fmt.Errorf("Steady state max wait may not be negative: %v", args.SteadyStateMaxWait)
+    }
+
+    if args.ReconnectTimeout < 0 {
+        return fmt.Errorf("Reconnect timeout may not be negative: %v", args.ReconnectTimeout)
+    }
+
+    if args.ConnectTimeout < 0 {
+        return fmt.Errorf("Connect timeout may not be negative: %v", args.ConnectTimeout)
+    }
+
+    if args.TcpKeepalive < 0 {
+        return fmt.Errorf("TCP keepalive interval may not be negative: %v", args.TcpKeepalive)
+    }
+
+    args.MaxFrameSizeInBytes, err = expandUnits(args.MaxFrameSize)
+    if (err != nil) || (args.MaxFrameSizeInBytes < minMaxFrameSize) {
+        return fmt.Errorf("Bad max frame size: %v.  Should be at least 16M, so that uploads of stats fit", args.MaxFrameSize)
+    }
+
     if (args.Workers < 0.1) {
         args.Workers = 0.1
     }
 
-    var err error
     args.ObjectSizeInBits, err = expandUnits(args.ObjectSize)
     if err != nil {
         return err
     }
 
+    args.PadToInBytes, err = expandUnits(args.PadTo)
+    if err != nil {
+        return err
+    }
+
     args.BandwidthInBits, err = expandUnits(args.Bandwidth)
     if err != nil {
         return err
@@ -271,11 +764,512 @@ func validateArguments(args *Arguments) error {
 
     args.BandwidthInBits /= 8
 
+    args.S3MultipartThresholdInBytes, err = expandUnits(args.S3MultipartThreshold)
+    if err != nil {
+        return err
+    }
+
+    args.S3MultipartPartSizeInBytes, err = expandUnits(args.S3MultipartPartSize)
+    if err != nil {
+        return err
+    }
+
+    // S3 requires every part except the last to be at least 5 MB.
+    if args.S3MultipartPartSizeInBytes < 5 * 1024 * 1024 {
+        return fmt.Errorf("S3 multipart part size too small: %v.  Must be at least 5M", args.S3MultipartPartSize)
+    }
+
+    lineage := Lineage{ args.BuildId, args.ClusterId, args.Environment }
+    err = lineage.Validate(args.MetricsPort != 0)
+    if err != nil {
+        return err
+    }
+
+    if args.Seed != "" {
+        args.SeedValue, err = strconv.ParseUint(args.Seed, 10, 64)
+        if err != nil {
+            return fmt.Errorf("Bad seed: %v.  Should be a non-negative integer", args.Seed)
+        }
+    }
+
+    if strings.ContainsAny(args.KeyPrefix, "/\\") {
+        return fmt.Errorf("Bad key prefix: %v.  May not contain path separators", args.KeyPrefix)
+    }
+
+    err = validateKeyScheme(args.KeyScheme)
+    if err != nil {
+        return err
+    }
+
+    // A manifest describes keys with a printf format, which only the sequential scheme has.
+    if (args.Manifest != "") && (args.KeyScheme != keySchemeSequential) {
+        return fmt.Errorf("--manifest can only be used with the %v key scheme", keySchemeSequential)
+    }
+
+    // We set the CephFS user and key ourselves, so the mount options may not.
+    for _, opt := range strings.Split(args.CephfsMountOptions, ",") {
+        name, _, _ := strings.Cut(strings.TrimSpace(opt), "=")
+        if (name == "name") || (name == "secret") || (name == "secretfile") {
+            return fmt.Errorf("Bad CephFS mount option: %v.  Use --ceph-user and --ceph-key instead", opt)
+        }
+    }
+
+    if args.NoWrite {
+        if args.ReadWriteMix != 0 {
+            return fmt.Errorf("--no-write can not be used with a read/write mix")
+        }
+
+        if args.Rbd {
+            return fmt.Errorf("--no-write is not supported for rbd, since every run creates new images")
+        }
+
+        if !args.Block && !args.Iscsi && (args.KeyPrefix == "") {
+            return fmt.Errorf("--no-write requires --key-prefix, to find the objects from the earlier run")
+        }
+
+        // Reads compared with a reference target don't need our generator, and so don't need the seed.
+        // Nor does a verification pass which only reads the sample for a consistency check.
+        verifying := (!args.SkipReadVerification || args.VerifyOnly) && (args.ReferenceTarget == "")
+        verifying = verifying && !(args.VerifyOnly && (args.ConsistencyCheck > 0))
+        if verifying && (args.Seed == "") {
+            return fmt.Errorf("--no-write requires --seed, to verify the objects from the earlier run")
+        }
+    }
+
+    if args.SizeMix != "" {
+        args.SizeMixValue, err = ParseSizeMix(args.SizeMix, args.SizeMixBasis)
+        if err != nil {
+            return err
+        }
+    }
+
+    if args.ReadRange != "" {
+        args.ReadRangeValue, err = ParseReadRange(args.ReadRange)
+        if err != nil {
+            return err
+        }
+
+        // A range may miss the object's header, so our generator can only verify it if we know which cycle we wrote it in.
+        if args.NoWrite && !args.SkipReadVerification && (args.ReferenceTarget == "") {
+            return fmt.Errorf("--read-range can only verify objects written in the same run, so --no-write needs --skip-read-verification or --reference-target with it")
+        }
+    }
+
+    if args.PhaseCaps != "" {
+        args.PhaseCapsValue, err = ParsePhaseCaps(args.PhaseCaps)
+        if err != nil {
+            return err
+        }
+    }
+
+    if args.BucketOps < 0 {
+        return fmt.Errorf("Bad bucket ops: %v.  Should not be negative", args.BucketOps)
+    }
+
+    if args.BucketOps > 0 {
+        if args.Calibrate {
+            return fmt.Errorf("--bucket-ops can not be used with calibrate")
+        }
+
+        if args.NoWrite || (args.ReadWriteMix != 0) {
+            return fmt.Errorf("--bucket-ops can not be used with --no-write or a read/write mix")
+        }
+
+        // Our bucket names are built from the key prefix, and must be valid DNS labels.
+        if !bucketPrefixRegex.MatchString(strings.ToLower(args.KeyPrefix)) {
+            return fmt.Errorf("Bad key prefix for --bucket-ops: %v.  Should start with a letter or digit, use only letters, digits and '-', and be at most 32 characters", args.KeyPrefix)
+        }
+    }
+
+    if args.HoldConnections < 0 {
+        return fmt.Errorf("Bad hold connections: %v.  Should not be negative", args.HoldConnections)
+    }
+
+    if args.HoldConnections > 0 {
+        if args.Calibrate {
+            return fmt.Errorf("--hold-connections can not be used with calibrate")
+        }
+
+        // Holding connections replaces all of the other phases.
+        if args.NoWrite || (args.ReadWriteMix != 0) || (args.BucketOps != 0) || args.VerifyOnly || args.StatObjects {
+            return fmt.Errorf("--hold-connections can not be used with --no-write, a read/write mix, --bucket-ops, --verify-only or --stat-objects")
+        }
+
+        if args.KeepaliveInterval < 1 {
+            return fmt.Errorf("Bad keepalive interval: %v.  Should be at least 1 second", args.KeepaliveInterval)
+        }
+    }
+
+    if args.AppendObjects < 0 {
+        return fmt.Errorf("Bad append objects: %v.  Should not be negative", args.AppendObjects)
+    }
+
+    if args.AppendObjects > 0 {
+        if args.Calibrate {
+            return fmt.Errorf("--append-objects can not be used with calibrate")
+        }
+
+        // Appends only have a write phase, so nothing that reads makes sense with them.
+        if args.NoWrite || (args.ReadWriteMix != 0) || (args.Churn != 0) || args.VerifyOverwrites || (args.ReadRange != "") {
+            return fmt.Errorf("--append-objects can not be used with --no-write, a read/write mix, --churn, --verify-overwrites or --read-range")
+        }
+
+        // The shared objects are the first ones in the range, so that the delete phase cleans them up.
+        if args.AppendObjects > args.ObjectCount {
+            return fmt.Errorf("Bad append objects: %v.  Should not be more than the object count", args.AppendObjects)
+        }
+    }
+
+    if (args.Churn < 0) || (args.Churn >= 1) {
+        return fmt.Errorf("Bad churn: %v.  Should be at least 0, and less than 1", args.Churn)
+    }
+
+    if (args.Churn > 0) && (args.ReadWriteMix != 0) {
+        return fmt.Errorf("--churn can only be used with separate write and read phases, not a read/write mix")
+    }
+
+    if args.VerifyOnly {
+        if args.Calibrate {
+            return fmt.Errorf("--verify-only can not be used with calibrate")
+        }
+
+        // A verification pass reads every whole object exactly once, so nothing that changes that makes sense.
+        if (args.ReadWriteMix != 0) || (args.BucketOps != 0) || (args.AppendObjects != 0) || (args.Churn != 0) || (args.HotSet != 0) || (args.ReadRange != "") {
+            return fmt.Errorf("--verify-only can not be used with a read/write mix, --bucket-ops, --append-objects, --churn, --hot-set or --read-range")
+        }
+
+        if args.OutputFormat == "fio" {
+            return fmt.Errorf("--verify-only can not be used with --output-format fio, which has nowhere to list the failures")
+        }
+    }
+
+    if (args.PadToInBytes > 0) && (args.AppendObjects > 0) {
+        return fmt.Errorf("--pad-to can not be used with --append-objects")
+    }
+
+    // The stat phase runs just before the read phase, so we need to have one.
+    if args.StatObjects && ((args.ReadWriteMix != 0) || (args.BucketOps != 0) || (args.AppendObjects != 0) || args.VerifyOnly) {
+        return fmt.Errorf("--stat-objects can not be used with a read/write mix, --bucket-ops, --append-objects or --verify-only")
+    }
+
+    if args.PrewarmReads && ((args.ReadWriteMix != 0) || (args.BucketOps != 0) || (args.AppendObjects != 0) || args.VerifyOnly || (args.HoldConnections != 0)) {
+        return fmt.Errorf("--prewarm-reads can not be used with a read/write mix, --bucket-ops, --append-objects, --verify-only or --hold-connections")
+    }
+
+    if args.ExplicitFlush && (args.AppendObjects != 0) {
+        return fmt.Errorf("--explicit-flush can not be used with --append-objects")
+    }
+
+    if (args.RampShape != "discard") && (args.RampShape != "linear") {
+        return fmt.Errorf("Bad ramp shape: %v.  Should be \"discard\" or \"linear\"", args.RampShape)
+    }
+
+    if args.RampShape == "linear" {
+        if args.BandwidthInBits == 0 {
+            return fmt.Errorf("--ramp-shape linear ramps up the bandwidth limit, and so needs --bandwidth")
+        }
+
+        if args.AutoSteadyState {
+            return fmt.Errorf("--ramp-shape linear can not be used with --auto-steady-state, which detects the ramp-up time")
+        }
+    }
+
+    // There's nowhere to rename a temporary file to when we write to stdout.
+    if (args.Output == "-") && args.AtomicReport {
+        return fmt.Errorf("--atomic-report can not be used with --output -")
+    }
+
+    if (args.OutputFormat != "sibench") && (args.OutputFormat != "fio") {
+        return fmt.Errorf("Bad output format: %v.  Should be \"sibench\" or \"fio\"", args.OutputFormat)
+    }
+
+    if args.OutputFormat == "fio" {
+        if args.IndividualStats {
+            return fmt.Errorf("--individual-stats can not be used with --output-format fio, which has nowhere to put them")
+        }
+
+        if args.Calibrate {
+            return fmt.Errorf("--output-format fio can not be used with calibrate")
+        }
+
+        if args.Baseline != "" {
+            return fmt.Errorf("--baseline can not be used with --output-format fio, which has nowhere to put the comparison")
+        }
+    }
+
+    if (args.Baseline != "") && args.Calibrate {
+        return fmt.Errorf("--baseline can not be used with calibrate")
+    }
+
+    args.SloRes95Value, err = time.ParseDuration(args.SloRes95)
+    if (err != nil) || (args.SloRes95Value < 0) {
+        return fmt.Errorf("Bad SLO response time: %v.  Should be a duration such as 50ms, or 0 for none", args.SloRes95)
+    }
+
+    args.SloBandwidthInBits, err = expandUnits(args.SloBandwidth)
+    if err != nil {
+        return err
+    }
+
+    if ((args.SloRes95Value > 0) || (args.SloBandwidthInBits > 0)) && (args.Calibrate || args.FindKnee) {
+        return fmt.Errorf("--slo-res-95 and --slo-bandwidth can not be used with calibrate or --find-knee, which have no results to check")
+    }
+
+    if (args.LatencySvg != "") && args.Calibrate {
+        return fmt.Errorf("--latency-svg can not be used with calibrate")
+    }
+
+    if (args.StatCompression != comms.CompressionNone) && (args.StatCompression != comms.CompressionGzip) {
+        return fmt.Errorf("Bad stat compression: %v.  Should be %v or %v", args.StatCompression, comms.CompressionNone, comms.CompressionGzip)
+    }
+
+    if args.SliceCache < 0 {
+        return fmt.Errorf("Bad slice cache: %v.  Should not be negative", args.SliceCache)
+    }
+
+    if args.SliceWarmup < 0 {
+        return fmt.Errorf("Bad slice warmup: %v.  Should not be negative", args.SliceWarmup)
+    }
+
+    if args.ThinkTime != "" {
+        args.ThinkTimeValue, err = ParseThinkTime(args.ThinkTime)
+        if err != nil {
+            return err
+        }
+    }
+
+    args.OpTimeoutValue, err = time.ParseDuration(args.OpTimeout)
+    if (err != nil) || (args.OpTimeoutValue < 0) || ((args.OpTimeoutValue > 0) && (args.OpTimeoutValue < time.Millisecond)) {
+        return fmt.Errorf("Bad op timeout: %v.  Should be a duration of at least 1ms, such as 10s, or 0 for none", args.OpTimeout)
+    }
+
+    // A worker stuck in an op for longer than this is taken to have hung, which aborts the whole run.
+    if args.OpTimeoutValue >= MinHangTimeoutSecs * time.Second {
+        return fmt.Errorf("Op timeout too long: %v.  Should be less than %vs, after which we treat a worker as hung", args.OpTimeout, MinHangTimeoutSecs)
+    }
+
+    if (args.MaxRetries < 0) || (args.MaxRetries > maxOpRetries) {
+        return fmt.Errorf("Bad max retries: %v.  Should be from 0 to %v", args.MaxRetries, maxOpRetries)
+    }
+
+    if args.BurstOps < 0 {
+        return fmt.Errorf("Bad burst ops: %v.  Should not be negative", args.BurstOps)
+    }
+
+    args.BurstIdleValue, err = time.ParseDuration(args.BurstIdle)
+    if (err != nil) || (args.BurstIdleValue < time.Millisecond) {
+        return fmt.Errorf("Bad burst idle: %v.  Should be a duration of at least 1ms, such as 5s", args.BurstIdle)
+    }
+
+    // Bursts deliberately keep the throughput from settling, and would throw out any measure of its peak.
+    if (args.BurstOps > 0) && (args.Calibrate || args.FindKnee || args.AutoSteadyState) {
+        return fmt.Errorf("--burst-ops can not be used with calibrate, --find-knee or --auto-steady-state")
+    }
+
+    if args.TargetWeights != "" {
+        for _, w := range strings.Split(args.TargetWeights, ",") {
+            val, err := strconv.ParseUint(w, 10, 64)
+            if (err != nil) || (val == 0) {
+                return fmt.Errorf("Bad target weight: %v.  Should be a positive integer", w)
+            }
+
+            args.TargetWeightValues = append(args.TargetWeightValues, val)
+        }
+
+        if len(args.TargetWeightValues) != len(args.Targets) {
+            return fmt.Errorf("Wrong number of target weights: got %v for %v targets", len(args.TargetWeightValues), len(args.Targets))
+        }
+    }
+
+    if args.TargetSizes != "" {
+        for _, sizeStr := range strings.Split(args.TargetSizes, ",") {
+            size, err := expandUnits(sizeStr)
+            if (err != nil) || (size == 0) {
+                return fmt.Errorf("Bad target size: %v", sizeStr)
+            }
+
+            args.TargetSizeValues = append(args.TargetSizeValues, size)
+        }
+
+        if len(args.TargetSizeValues) != len(args.Targets) {
+            return fmt.Errorf("Wrong number of target sizes: got %v for %v targets", len(args.TargetSizeValues), len(args.Targets))
+        }
+
+        // Each object lives on one target at that target's size, so the object size can't vary in any other way.
+        if (args.SizeMix != "") || (args.AppendObjects != 0) {
+            return fmt.Errorf("--target-sizes can not be used with --size-mix or --append-objects")
+        }
+    }
+
+    if args.SizeSweep != "" {
+        seen := make(map[uint64]bool)
+        for _, sizeStr := range strings.Split(args.SizeSweep, ",") {
+            size, err := expandUnits(sizeStr)
+            if (err != nil) || (size == 0) {
+                return fmt.Errorf("Bad size in size sweep: %v", sizeStr)
+            }
+
+            if seen[size] {
+                return fmt.Errorf("Size sweep has %v more than once", sizeStr)
+            }
+
+            seen[size] = true
+            args.SizeSweepValues = append(args.SizeSweepValues, size)
+        }
+
+        // Each size is a separate run, which must write its own objects.
+        if args.Calibrate || (args.SizeMix != "") || (args.TargetSizes != "") || args.NoWrite || args.VerifyOnly {
+            return fmt.Errorf("--size-sweep can not be used with calibrate, --size-mix, --target-sizes, --no-write or --verify-only")
+        }
+
+        // These would have each size overwrite the file written for the last one.
+        if (args.Baseline != "") || (args.Manifest != "") || (args.LatencySvg != "") {
+            return fmt.Errorf("--size-sweep can not be used with --baseline, --manifest or --latency-svg")
+        }
+    }
+
+    if args.Generator == "dedupe" {
+        sizes := append([]uint64{ args.ObjectSizeInBits }, args.TargetSizeValues...)
+        sizes = append(sizes, args.SizeSweepValues...)
+        if args.SizeMixValue != nil {
+            sizes = append(sizes, args.SizeMixValue.Sizes...)
+        }
+
+        for _, size := range sizes {
+            if size < DedupeMinSize {
+                return fmt.Errorf("Object size too small for the dedupe generator: %v.  Must be at least %v bytes", size, DedupeMinSize)
+            }
+        }
+    }
+
+    if args.FindKnee {
+        // Finding the knee sets its own bandwidth limits and durations for each probe.
+        if args.Calibrate || (args.BandwidthInBits != 0) || (args.RampShape == "linear") || args.AutoSteadyState {
+            return fmt.Errorf("--find-knee can not be used with calibrate, --bandwidth, --ramp-shape linear or --auto-steady-state")
+        }
+
+        // Each probe must write and read its objects, and there is only one report.
+        if (args.SizeSweep != "") || args.VerifyOnly || (args.BucketOps != 0) || (args.HoldConnections != 0) {
+            return fmt.Errorf("--find-knee can not be used with --size-sweep, --verify-only, --bucket-ops or --hold-connections")
+        }
+
+        if (args.Baseline != "") || (args.LatencySvg != "") || (args.OutputFormat != "sibench") {
+            return fmt.Errorf("--find-knee can not be used with --baseline, --latency-svg or --output-format fio")
+        }
+    }
+
+    args.TcpNodelayEnabled, err = strconv.ParseBool(args.TcpNodelay)
+    if err != nil {
+        return fmt.Errorf("Bad tcp-nodelay value: %v.  Should be true or false", args.TcpNodelay)
+    }
+
+    args.ConnectionReuseEnabled, err = strconv.ParseBool(args.ConnectionReuse)
+    if err != nil {
+        return fmt.Errorf("Bad connection-reuse value: %v.  Should be true or false", args.ConnectionReuse)
+    }
+
+    if args.QueueDepth < 1 {
+        return fmt.Errorf("Bad queue depth: %v.  Should be at least 1", args.QueueDepth)
+    }
+
+    if args.MaxConcurrencyPerTarget < 0 {
+        return fmt.Errorf("Bad max concurrency per target: %v.  Should not be negative", args.MaxConcurrencyPerTarget)
+    }
+
+    if args.ReadAhead < 1 {
+        return fmt.Errorf("Bad read ahead: %v.  Should be at least 1", args.ReadAhead)
+    }
+
+    // Both keep several reads in flight, so there's no sense in having both.
+    if (args.QueueDepth > 1) && (args.ReadAhead > 1) {
+        return fmt.Errorf("--read-ahead can not be used with --queue-depth")
+    }
+
+    if (args.QueueDepth > 1) || (args.ReadAhead > 1) {
+        option := "--queue-depth"
+        if args.ReadAhead > 1 {
+            option = "--read-ahead"
+        }
+
+        // RBD connections seek then read, so can't be shared between ops in flight.
+        if args.Rbd {
+            return fmt.Errorf("%v is not supported for rbd", option)
+        }
+
+        // Reconnecting closes the connection, which would break any other ops using it.
+        if !args.ConnectionReuseEnabled {
+            return fmt.Errorf("%v can not be used with --connection-reuse false", option)
+        }
+    }
+
+    if args.ReferenceTarget != "" {
+        if args.Calibrate {
+            return fmt.Errorf("--reference-target can not be used with calibrate")
+        }
+
+        // Writing would replace the objects we are comparing with generated ones.
+        if !args.NoWrite {
+            return fmt.Errorf("--reference-target requires --no-write, to read objects that are already on the targets")
+        }
+
+        // Nothing that relies on the generator, or that changes the objects, makes sense either.
+        if args.SkipReadVerification || args.VerifyOverwrites || (args.Churn != 0) {
+            return fmt.Errorf("--reference-target can not be used with --skip-read-verification, --verify-overwrites or --churn")
+        }
+
+        for _, t := range args.Targets {
+            if t == args.ReferenceTarget {
+                return fmt.Errorf("Bad reference target: %v is also one of the targets", t)
+            }
+        }
+
+        if (args.ReferenceAccessKey == "") != (args.ReferenceSecretKey == "") {
+            return fmt.Errorf("--reference-access-key and --reference-secret-key must be given together")
+        }
+    } else if len(referenceOverrides(args)) > 0 {
+        return fmt.Errorf("The --reference-* protocol options need --reference-target")
+    }
+
+    if args.ConsistencyCheck < 0 {
+        return fmt.Errorf("Bad consistency check: %v.  Should not be negative", args.ConsistencyCheck)
+    }
+
+    if args.ConsistencyCheck > 0 {
+        if len(args.Targets) < 2 {
+            return fmt.Errorf("--consistency-check needs at least two targets to compare")
+        }
+
+        if args.Calibrate {
+            return fmt.Errorf("--consistency-check can not be used with calibrate")
+        }
+
+        // The sample is compared between the targets instead of verified, so nothing that changes
+        // how we verify makes sense, and a reference target would be a second way of comparing.
+        if args.SkipReadVerification || args.VerifyOverwrites || (args.Churn != 0) || (args.ReferenceTarget != "") {
+            return fmt.Errorf("--consistency-check can not be used with --skip-read-verification, --verify-overwrites, --churn or --reference-target")
+        }
+
+        if args.TargetSizes != "" {
+            return fmt.Errorf("--consistency-check can not be used with --target-sizes, since the targets hold different objects")
+        }
+    }
+
+    // A connection that is closed after every request can't be held.
+    if (args.HoldConnections > 0) && !args.ConnectionReuseEnabled {
+        return fmt.Errorf("--hold-connections can not be used with --connection-reuse false")
+    }
+
     switch args.Verbosity {
         case "off":
         case "debug": logger.SetLevel(logger.Debug)
         case "trace": logger.SetLevel(logger.Trace)
-        default: return fmt.Errorf("Bad verbosity level: %v.  Should be one of off, debug or trace")
+        default: return fmt.Errorf("Bad verbosity level: %v.  Should be one of off, debug or trace", args.Verbosity)
+    }
+
+    switch args.LogFormat {
+        case "plain":
+        case "json": logger.SetFormat(logger.JSON)
+        default: return fmt.Errorf("Bad log format: %v.  Should be plain or json", args.LogFormat)
     }
 
     return nil
@@ -290,7 +1284,34 @@ func validateArguments(args *Arguments) error {
  */
 func buildConfig(args *Arguments) error {
     globalConfig.ListenPort = uint16(args.Port)
+    globalConfig.StatusPort = uint16(args.StatusPort)
+    globalConfig.GrpcPort = uint16(args.GrpcPort)
+    comms.SetNoDelay(args.TcpNodelayEnabled)
+    comms.SetKeepAlive(time.Duration(args.TcpKeepalive) * time.Second)
+    comms.SetMaxFrameSize(uint(args.MaxFrameSizeInBytes))
+
+    err := comms.SetWireFormat(args.WireFormat)
+    if err != nil {
+        return err
+    }
+
     globalConfig.MountsDir = args.MountsDir
+
+    // Logs go to stderr unless we're told otherwise, so that stdout is left for our results.
+    if args.LogFile != "" {
+        file, err := os.OpenFile(args.LogFile, os.O_WRONLY | os.O_CREATE | os.O_APPEND, 0644)
+        if err != nil {
+            return fmt.Errorf("Failure opening log file %v: %v", args.LogFile, err)
+        }
+
+        logger.SetOutput(file)
+    }
+
+    // Our tables of results go to stdout, unless the report is going there.
+    if args.Output == "-" {
+        display = os.Stderr
+    }
+
     return nil
 }
 
@@ -298,23 +1319,27 @@ func buildConfig(args *Arguments) error {
 func main() {
     // Error should never happen outside of development, since docopt is complaining that our usage string has bad syntax.
     opts, err := docopt.ParseDoc(usage())
-    dieOnError(err, "Error parsing arguments")
+    dieOnError(err, ExitInternalError, "Error parsing arguments")
 
     // Error should never happen outside of development, since docopt is complaining that our type bindings are wrong.
     var args Arguments
     err = opts.Bind(&args)
-    dieOnError(err, "Failure binding arguments")
+    dieOnError(err, ExitInternalError, "Failure binding arguments")
+
+    // The defaults of a tuning profile replace our own, so they need to be in place before we check them.
+    err = applyTuningProfile(&args, os.Args[1:])
+    dieOnError(err, ExitValidationFailure, "Failure applying tuning profile")
 
     // This can error on bad user input.
     err = validateArguments(&args)
-    dieOnError(err, "Failure validating arguments")
+    dieOnError(err, ExitValidationFailure, "Failure validating arguments")
 
     // Build our config.  In the future, this may load json etc...
     err = buildConfig(&args)
-    dieOnError(err, "Failure building config")
+    dieOnError(err, ExitValidationFailure, "Failure building config")
 
     if logger.IsDebug() {
-        fmt.Printf("%v\n", prettyPrint(args))
+        fmt.Fprintf(display, "%v\n", prettyPrint(args))
     }
 
     switch {
@@ -324,7 +1349,10 @@ func main() {
         case args.Server:
             startServer(&args)
 
-        case args.Run:
+        case args.Config != "":
+            startJobSequence(&args)
+
+        case args.Run, args.Calibrate:
             startRun(&args)
     }
 
@@ -335,7 +1363,7 @@ func main() {
 /* Start a server, listening on a TCP port */
 func startServer(args *Arguments) {
     err := StartForeman(args.ProfilePrefix)
-    dieOnError(err, "Failure creating server")
+    dieOnError(err, ExitInternalError, "Failure creating server")
 }
 
 
@@ -350,6 +1378,94 @@ func createUniquePrefix() string {
 
 /* Create a job and execute it on some set of servers. */
 func startRun(args *Arguments) {
+    if args.SizeSweepValues != nil {
+        startSizeSweep(args)
+        return
+    }
+
+    j, err := makeJob(args)
+    if err != nil {
+        logger.Errorf("%v\n", err)
+        os.Exit(ExitValidationFailure)
+    }
+
+    _, err = runJob(j)
+
+    // Any error has already been logged, so we just need to exit with the right code.
+    if err != nil {
+        os.Exit(exitCode(err))
+    }
+}
+
+
+/* Run each of the jobs in a sequence, one after another, on the same servers. */
+func startJobSequence(args *Arguments) {
+    seq, err := LoadJobSequence(args)
+    runSequence(seq, err, args.Output)
+}
+
+
+/* Run the same job once for each object size in a sweep. */
+func startSizeSweep(args *Arguments) {
+    seq, err := NewSizeSweep(args)
+    runSequence(seq, err, args.Output)
+}
+
+
+/* Run a sequence of jobs, if we could create it, and exit if anything fails. */
+func runSequence(seq *JobSequence, err error, output string) {
+    if err != nil {
+        logger.Errorf("%v\n", err)
+        os.Exit(ExitValidationFailure)
+    }
+
+    err = seq.Run(output)
+
+    // Any error has already been logged, so we just need to exit with the right code.
+    if err != nil {
+        os.Exit(exitCode(err))
+    }
+}
+
+
+/* The protocol options which we were given for the reference target, keyed as in the ProtocolConfig. */
+func referenceOverrides(args *Arguments) map[string]string {
+    overrides := make(map[string]string)
+    options := map[string]string {
+        "bucket": args.ReferenceBucket,
+        "access_key": args.ReferenceAccessKey,
+        "secret_key": args.ReferenceSecretKey,
+        "pool": args.ReferencePool,
+        "username": args.ReferenceCephUser,
+        "key": args.ReferenceCephKey }
+
+    for k, v := range options {
+        if v != "" {
+            overrides[k] = v
+        }
+    }
+
+    return overrides
+}
+
+
+/* The protocol config for the reference target: the same as for our targets, apart from the overrides. */
+func referenceProtocolConfig(protocol ProtocolConfig, overrides map[string]string) ProtocolConfig {
+    result := make(ProtocolConfig)
+    for k, v := range protocol {
+        result[k] = v
+    }
+
+    for k, v := range overrides {
+        result[k] = v
+    }
+
+    return result
+}
+
+
+/* Create a job from our arguments.  Any error is a failure to validate them. */
+func makeJob(args *Arguments) (*Job, error) {
     var j Job
 
     j.arguments = args
@@ -359,21 +1475,107 @@ func startRun(args *Arguments) {
     j.runTime = uint64(args.RunTime)
     j.rampUp = uint64(args.RampUp)
     j.rampDown = uint64(args.RampDown)
+    j.phaseRampUp = j.rampUp
+    j.autoSteadyState = args.AutoSteadyState
+    j.steadyStateWindow = uint64(args.SteadyStateWindow)
+    j.steadyStateCV = args.SteadyStateCv
+    j.steadyStateMaxWait = uint64(args.SteadyStateMaxWait)
+    j.reconnectTimeout = uint64(args.ReconnectTimeout)
+    j.connectTimeout = uint64(args.ConnectTimeout)
     j.useBytes = args.UseBytes
+    j.lineage = Lineage{ args.BuildId, args.ClusterId, args.Environment }
     j.script = args.Script
 
     j.order.JobId = 1
     j.order.CleanUpOnClose = args.CleanUp
-    j.order.ObjectKeyPrefix = createUniquePrefix()
+    j.order.ObjectKeyPrefix = args.KeyPrefix
+    j.order.ObjectKeyScheme = args.KeyScheme
+
+    if args.KeyPrefix == "" {
+        j.order.ObjectKeyPrefix = createUniquePrefix()
+    }
+
     j.order.ObjectSize = args.ObjectSizeInBits
-    j.order.Seed = uint64(time.Now().Unix())
+    j.order.SizeMix = args.SizeMixValue
+    j.order.PadSize = args.PadToInBytes
+    j.order.ReadRange = args.ReadRangeValue
+    j.order.ThinkTime = args.ThinkTimeValue
+    j.order.QueueDepth = uint64(args.QueueDepth)
+    j.order.ReadAhead = uint64(args.ReadAhead)
+    j.order.PinWorkers = args.PinWorkers
+    j.order.OpTimeoutMillis = uint64(args.OpTimeoutValue / time.Millisecond)
+    j.order.MaxRetries = uint64(args.MaxRetries)
+    j.order.BurstOps = uint64(args.BurstOps)
+    j.order.BurstIdleMillis = uint64(args.BurstIdleValue / time.Millisecond)
+    j.order.MaxConcurrencyPerTarget = uint64(args.MaxConcurrencyPerTarget)
+    j.order.BucketOps = uint64(args.BucketOps)
+    j.order.HoldConnections = uint64(args.HoldConnections)
+    j.order.KeepaliveInterval = uint64(args.KeepaliveInterval)
+    j.order.AppendObjects = uint64(args.AppendObjects)
+    j.order.ExplicitFlush = args.ExplicitFlush
+    j.order.Churn = args.Churn
+    j.order.RampShape = args.RampShape
+    j.order.RampUp = uint64(args.RampUp)
+    j.order.StatCompression = args.StatCompression
+
+    j.order.TargetSizes = args.TargetSizeValues
+    j.order.ReferenceTarget = args.ReferenceTarget
+
+    // Buffers are sized by ObjectSize, so it needs to be the largest size we might use.
+    if j.order.SizeMix != nil {
+        j.order.ObjectSize = j.order.SizeMix.MaxSize()
+    }
+
+    if j.order.TargetSizes != nil {
+        j.order.ObjectSize = 0
+        for _, size := range j.order.TargetSizes {
+            if size > j.order.ObjectSize {
+                j.order.ObjectSize = size
+            }
+        }
+    }
+
+    j.order.Seed = args.SeedValue
+
+    if args.Seed == "" {
+        j.order.Seed = uint64(time.Now().Unix())
+        logger.Infof("Using generated seed: %v (use --seed to repeat this run's object content)\n", j.order.Seed)
+    }
+
+    if j.order.ReferenceTarget != "" {
+        logger.Infof("Comparing reads with the same objects on reference target %v\n", j.order.ReferenceTarget)
+    }
+
+    // Spread the sample evenly over the objects, by taking every object whose id is a multiple of the stride.
+    if args.ConsistencyCheck > 0 {
+        j.order.ConsistencyStride = 1
+        if args.ObjectCount > args.ConsistencyCheck {
+            j.order.ConsistencyStride = uint64(args.ObjectCount / args.ConsistencyCheck)
+        }
+
+        sample := (uint64(args.ObjectCount) + j.order.ConsistencyStride - 1) / j.order.ConsistencyStride
+        logger.Infof("Comparing reads of %v objects across all of the targets\n", sample)
+    }
     j.order.RangeStart = 0
     j.order.RangeEnd = uint64(args.ObjectCount)
     j.order.Targets = args.Targets
+    j.order.TargetWeights = args.TargetWeightValues
     j.order.Bandwidth = args.BandwidthInBits
     j.order.ReadWriteMix = uint64(args.ReadWriteMix)
     j.order.WorkerFactor = args.Workers
     j.order.SkipReadValidation = args.SkipReadVerification
+    j.order.ConnectionReuse = args.ConnectionReuseEnabled
+    j.order.HttpErrorStats = args.HttpErrorStats
+    j.order.VerifyOverwrites = args.VerifyOverwrites
+    j.order.HotSetSize = uint64(args.HotSet)
+    j.order.HotSetRandom = args.HotSetRandom
+    j.order.ReadRandom = args.ReadOrder == "random"
+    j.order.NoWrite = args.NoWrite
+    j.order.VerifyOnly = args.VerifyOnly
+    j.order.StatObjects = args.StatObjects
+    j.order.PrewarmReads = args.PrewarmReads
+    j.order.PhaseCaps = args.PhaseCapsValue
+    j.order.StatUploadWindow = uint64(args.StatUploadWindow)
     j.order.GeneratorType = args.Generator
 
     if uint64(len(j.servers)) > j.order.RangeEnd {
@@ -390,10 +1592,22 @@ func startRun(args *Arguments) {
             j.order.GeneratorConfig = GeneratorConfig {
                 "dir": args.SliceDir,
                 "size": strconv.Itoa(int(args.SliceSize)),
-                "count": strconv.Itoa(int(args.SliceCount)) }
+                "count": strconv.Itoa(int(args.SliceCount)),
+                "recursive": strconv.FormatBool(args.SliceRecursive),
+                "cache": strconv.Itoa(args.SliceCache),
+                "warmup": strconv.Itoa(args.SliceWarmup),
+                "warmup-size": strconv.FormatUint(j.order.ObjectSize, 10) }
+
+        case "dedupe":
+            j.order.GeneratorConfig = GeneratorConfig {
+                "ratio": strconv.FormatFloat(args.DedupeRatio, 'f', -1, 64) }
+
+        case "entropy":
+            j.order.GeneratorConfig = GeneratorConfig {
+                "entropy": strconv.FormatFloat(args.Entropy, 'f', -1, 64) }
 
         default:
-            die("Unknown generator type %v.  Expected one of [prng, slice]")
+            die(ExitInternalError, "Unknown generator type %v.  Expected one of [prng, slice, dedupe, entropy]\n", args.Generator)
     }
 
     // Detemrine our protocol configuration
@@ -404,21 +1618,32 @@ func startRun(args *Arguments) {
                 "access_key": args.S3AccessKey,
                 "secret_key": args.S3SecretKey,
                 "port": strconv.Itoa(args.S3Port),
-                "bucket": args.S3Bucket }
+                "bucket": args.S3Bucket,
+                "provision_retries": strconv.Itoa(args.ProvisionRetries),
+                "multipart_threshold": strconv.FormatUint(args.S3MultipartThresholdInBytes, 10),
+                "multipart_part_size": strconv.FormatUint(args.S3MultipartPartSizeInBytes, 10) }
 
         case args.Rados:
             j.order.ConnectionType = "rados"
             j.order.ProtocolConfig = ProtocolConfig {
                 "username": args.CephUser,
                 "key": args.CephKey,
-                "pool": args.CephPool }
+                "pool": args.CephPool,
+                "provision_retries": strconv.Itoa(args.ProvisionRetries) }
+
+        case args.Nfs:
+            j.order.ConnectionType = "nfs"
+            j.order.ProtocolConfig = ProtocolConfig {
+                "dir": args.NfsDir,
+                "options": args.NfsOptions }
 
         case args.Cephfs:
             j.order.ConnectionType = "cephfs"
             j.order.ProtocolConfig = ProtocolConfig {
                 "username": args.CephUser,
                 "key": args.CephKey,
-                "dir": args.CephDir }
+                "dir": args.CephDir,
+                "options": args.CephfsMountOptions }
 
         case args.Rbd:
             j.order.ConnectionType = "rbd"
@@ -427,20 +1652,67 @@ func startRun(args *Arguments) {
                 "key": args.CephKey,
                 "pool": args.CephPool,
                 "datapool": args.CephDatapool,
+                "provision_retries": strconv.Itoa(args.ProvisionRetries),
                 "image_prefix": createUniquePrefix() }
 
         case args.Block:
             j.order.ConnectionType = "block"
             j.order.Targets = append(j.order.Targets, args.BlockDevice)
 
+        case args.Iscsi:
+            j.order.ConnectionType = "iscsi"
+            j.order.Targets = append(j.order.Targets, args.IscsiPortal)
+            j.order.ProtocolConfig = ProtocolConfig {
+                "iqn": args.IscsiIqn,
+                "lun": strconv.Itoa(args.IscsiLun) }
+
         case args.File:
             j.order.ConnectionType = "file"
             j.order.Targets = append(j.order.Targets, args.FileDir)
 
         default:
-            die("No protocol specified")
+            die(ExitInternalError, "No protocol specified\n")
+    }
+
+    if args.ReferenceTarget != "" {
+        j.order.ReferenceProtocolConfig = referenceProtocolConfig(j.order.ProtocolConfig, referenceOverrides(args))
+    }
+
+    if args.Baseline != "" {
+        var err error
+        j.baseline, err = LoadBaseline(args.Baseline)
+        if err != nil {
+            return nil, fmt.Errorf("Failure loading baseline: %v", err)
+        }
+    }
+
+    return &j, nil
+}
+
+
+/*
+ * Execute a job on its servers, returning the analyses of its results, if it has any.  Any error
+ * has already been logged.
+ */
+func runJob(j *Job) ([]*Analysis, error) {
+    if j.arguments.Calibrate {
+        err := RunCalibration(j)
+        if err != nil {
+            logger.Errorf("%v\n", err)
+        }
+
+        return nil, err
+    }
+
+    if j.arguments.FindKnee {
+        err := RunKneeSearch(j)
+        if err != nil {
+            logger.Errorf("%v\n", err)
+        }
+
+        return nil, err
     }
 
-    RunBenchmark(&j)
+    return RunBenchmark(j)
 }
 
@@ -0,0 +1,327 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for command line argument validation.
+
+package main
+
+import "strings"
+import "testing"
+import "github.com/docopt/docopt-go"
+import "silib/testutil"
+
+
+// Helper functions.
+
+func makeDurationArgs(runTime int, rampUp int, rampDown int) *Arguments {
+    return &Arguments{ RunTime: runTime, RampUp: rampUp, RampDown: rampDown }
+}
+
+
+/* Parse and validate an s3 run command line, with the given options added to it, as main would. */
+func validateS3Run(t *testing.T, options ...string) error {
+    argv := append([]string{ "s3", "run", "--s3-access-key", "key", "--s3-secret-key", "secret" }, options...)
+    return validateCommandLine(t, append(argv, "gateway")...)
+}
+
+
+/* Parse and validate any command line, as main would. */
+func validateCommandLine(t *testing.T, argv ...string) error {
+    parser := &docopt.Parser{ HelpHandler: docopt.NoHelpHandler }
+    opts, err := parser.ParseArgs(usage(), argv, "")
+    testutil.CheckNoError(t, err)
+
+    var args Arguments
+    testutil.CheckNoError(t, opts.Bind(&args))
+
+    err = applyTuningProfile(&args, argv)
+    if err != nil {
+        return err
+    }
+
+    return validateArguments(&args)
+}
+
+
+// Test functions.
+
+// The defaults, and a run time no longer than the ramps, are fine.
+func TestValidateDurationsGood(t *testing.T) {
+    testutil.CheckNoError(t, validateDurations(makeDurationArgs(30, 5, 2)))
+    testutil.CheckNoError(t, validateDurations(makeDurationArgs(1, 5, 2)))
+    testutil.CheckNoError(t, validateDurations(makeDurationArgs(1, 0, 0)))
+}
+
+
+// A phase with no run time would record nothing.
+func TestValidateDurationsNoRunTime(t *testing.T) {
+    testutil.CheckError(t, validateDurations(makeDurationArgs(0, 5, 2)))
+    testutil.CheckError(t, validateDurations(makeDurationArgs(-1, 0, 0)))
+}
+
+
+// Negative ramps would wrap around when converted to the unsigned durations in the job.
+func TestValidateDurationsNegativeRamps(t *testing.T) {
+    testutil.CheckError(t, validateDurations(makeDurationArgs(30, -1, 0)))
+    testutil.CheckError(t, validateDurations(makeDurationArgs(30, 0, -1)))
+    testutil.CheckError(t, validateDurations(makeDurationArgs(30, -5, -2)))
+    testutil.CheckError(t, validateS3Run(t, "-u", "-1"))
+    testutil.CheckError(t, validateS3Run(t, "-d", "-1"))
+}
+
+
+// A plain command line with all the defaults is fine.
+func TestValidateArgumentsGood(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t))
+}
+
+
+// Errors must say what was wrong with the value they were given.
+func TestValidateArgumentsMessages(t *testing.T) {
+    err := validateS3Run(t, "-v", "loud")
+    testutil.CheckError(t, err)
+    testutil.CheckString(t, "Bad verbosity level: loud.  Should be one of off, debug or trace", err.Error())
+
+    err = validateS3Run(t, "-p", "70000")
+    testutil.CheckError(t, err)
+    testutil.CheckString(t, "Port not in range: 70000", err.Error())
+
+    err = validateS3Run(t, "--target-weights", "1,2")
+    testutil.CheckError(t, err)
+    testutil.CheckString(t, "Wrong number of target weights: got 2 for 1 targets", err.Error())
+
+    err = validateS3Run(t, "--log-format", "xml")
+    testutil.CheckError(t, err)
+    testutil.CheckString(t, "Bad log format: xml.  Should be plain or json", err.Error())
+
+    // Nothing should be left with a formatting error from a missing or mismatched argument.
+    for _, option := range [][]string{ { "-v", "loud" }, { "--tcp-nodelay", "maybe" }, { "--target-sizes", "0" }, { "--entropy", "9" } } {
+        err = validateS3Run(t, option...)
+        testutil.CheckError(t, err)
+        testutil.CheckBool(t, false, strings.Contains(err.Error(), "%!"))
+    }
+}
+
+
+// Holding connections replaces every other phase, and needs connections that stay open.
+func TestValidateHoldConnections(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--hold-connections", "10"))
+    testutil.CheckError(t, validateS3Run(t, "--hold-connections", "-1"))
+    testutil.CheckError(t, validateS3Run(t, "--hold-connections", "10", "--keepalive-interval", "0"))
+    testutil.CheckError(t, validateS3Run(t, "--hold-connections", "10", "--connection-reuse", "false"))
+    testutil.CheckError(t, validateS3Run(t, "--hold-connections", "10", "--bucket-ops", "5"))
+    testutil.CheckError(t, validateS3Run(t, "--hold-connections", "10", "-x", "50"))
+}
+
+
+// Each size in a sweep must be valid and different, and the sweep must be able to write its own objects.
+func TestValidateSizeSweep(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--size-sweep", "4K,64K,1M"))
+    testutil.CheckError(t, validateS3Run(t, "--size-sweep", "4K,0"))
+    testutil.CheckError(t, validateS3Run(t, "--size-sweep", "4K,big"))
+    testutil.CheckError(t, validateS3Run(t, "--size-sweep", "4K,4096"))
+    testutil.CheckError(t, validateS3Run(t, "--size-sweep", "4K,64K", "--size-mix", "4K:50,1M:50"))
+    testutil.CheckError(t, validateS3Run(t, "--size-sweep", "4K,64K", "--no-write", "--seed", "1"))
+    testutil.CheckError(t, validateS3Run(t, "--size-sweep", "4K,64K", "--manifest", "objects.json"))
+}
+
+
+// The dedupe generator needs room in every object for its seed, whichever option gives the size.
+func TestValidateDedupeSize(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "-g", "dedupe", "-s", "8"))
+    testutil.CheckError(t, validateS3Run(t, "-g", "dedupe", "-s", "4"))
+    testutil.CheckError(t, validateS3Run(t, "-g", "dedupe", "--size-mix", "4:50,1M:50"))
+    testutil.CheckError(t, validateS3Run(t, "-g", "dedupe", "--size-sweep", "4,1M"))
+}
+
+
+// Finding the knee sets its own bandwidth limits and durations, so those options can't be given too.
+func TestValidateFindKnee(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--find-knee"))
+    testutil.CheckNoError(t, validateS3Run(t, "--find-knee", "-r", "1"))
+    testutil.CheckError(t, validateS3Run(t, "--find-knee", "-b", "100M"))
+    testutil.CheckError(t, validateS3Run(t, "--find-knee", "--auto-steady-state"))
+    testutil.CheckError(t, validateS3Run(t, "--find-knee", "--size-sweep", "4K,64K"))
+    testutil.CheckError(t, validateS3Run(t, "--find-knee", "--hold-connections", "10"))
+    testutil.CheckError(t, validateS3Run(t, "--find-knee", "--baseline", "old.json"))
+}
+
+
+// Prewarming reads before the read phase needs a read phase of its own.
+func TestValidatePrewarmReads(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--prewarm-reads"))
+    testutil.CheckNoError(t, validateS3Run(t, "--prewarm-reads", "--no-write", "--seed", "1", "--key-prefix", "old", "--stat-objects"))
+    testutil.CheckError(t, validateS3Run(t, "--prewarm-reads", "-x", "50"))
+    testutil.CheckError(t, validateS3Run(t, "--prewarm-reads", "--verify-only"))
+    testutil.CheckError(t, validateS3Run(t, "--prewarm-reads", "--bucket-ops", "10"))
+}
+
+
+// An op timeout must be a duration, and short enough that a slow op fails before its worker looks hung.
+func TestValidateOpTimeout(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--op-timeout", "0"))
+    testutil.CheckNoError(t, validateS3Run(t, "--op-timeout", "500ms"))
+    testutil.CheckNoError(t, validateS3Run(t, "--op-timeout", "30s"))
+    testutil.CheckError(t, validateS3Run(t, "--op-timeout", "10"))
+    testutil.CheckError(t, validateS3Run(t, "--op-timeout", "-1s"))
+    testutil.CheckError(t, validateS3Run(t, "--op-timeout", "500us"))
+    testutil.CheckError(t, validateS3Run(t, "--op-timeout", "2m"))
+}
+
+
+func TestValidateMaxRetries(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--max-retries", "0"))
+    testutil.CheckNoError(t, validateS3Run(t, "--max-retries", "3"))
+    testutil.CheckError(t, validateS3Run(t, "--max-retries", "-1"))
+    testutil.CheckError(t, validateS3Run(t, "--max-retries", "6"))
+}
+
+
+func TestValidateBurstOps(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--burst-ops", "100"))
+    testutil.CheckNoError(t, validateS3Run(t, "--burst-ops", "100", "--burst-idle", "500ms"))
+    testutil.CheckError(t, validateS3Run(t, "--burst-ops", "-1"))
+    testutil.CheckError(t, validateS3Run(t, "--burst-ops", "100", "--burst-idle", "0"))
+    testutil.CheckError(t, validateS3Run(t, "--burst-ops", "100", "--burst-idle", "5"))
+    testutil.CheckError(t, validateS3Run(t, "--burst-ops", "100", "--auto-steady-state"))
+}
+
+
+// Explicit flushes are only for the backends that sync their writes, and not for appends.
+func TestValidateExplicitFlush(t *testing.T) {
+    parser := &docopt.Parser{ HelpHandler: docopt.NoHelpHandler }
+    _, err := parser.ParseArgs(usage(), []string{ "s3", "run", "--s3-access-key", "key", "--s3-secret-key", "secret", "--explicit-flush", "gateway" }, "")
+    testutil.CheckError(t, err)
+
+    testutil.CheckNoError(t, validateCommandLine(t, "file", "run", "--file-dir", "dir", "--explicit-flush"))
+    testutil.CheckError(t, validateCommandLine(t, "file", "run", "--file-dir", "dir", "--explicit-flush", "--append-objects", "10"))
+}
+
+
+// S3 needs either its own keys, or the RGW admin API and its keys to make some, but not both.
+func TestValidateRgwAdmin(t *testing.T) {
+    admin := []string{ "s3", "run", "--rgw-admin-url", "http://gw:7480", "--rgw-admin-access-key", "ak", "--rgw-admin-secret-key", "sk" }
+    testutil.CheckNoError(t, validateCommandLine(t, append(admin, "gw")...))
+    testutil.CheckError(t, validateCommandLine(t, append(admin, "--no-write", "--key-prefix", "old", "gw")...))
+    testutil.CheckError(t, validateS3Run(t, "--rgw-admin-url", "http://gw:7480", "--rgw-admin-access-key", "ak", "--rgw-admin-secret-key", "sk"))
+    testutil.CheckError(t, validateS3Run(t, "--rgw-admin-access-key", "ak"))
+
+    testutil.CheckError(t, validateCommandLine(t, "s3", "run", "gw"))
+    testutil.CheckError(t, validateCommandLine(t, "s3", "run", "--s3-access-key", "key", "gw"))
+    testutil.CheckError(t, validateCommandLine(t, "s3", "run", "--rgw-admin-url", "http://gw:7480", "--rgw-admin-access-key", "ak", "gw"))
+    testutil.CheckError(t, validateCommandLine(t, "s3", "run", "--rgw-admin-url", "gw:7480", "--rgw-admin-access-key", "ak", "--rgw-admin-secret-key", "sk", "gw"))
+}
+
+
+// Keepalives and connect timeouts may be turned off with 0, but not made negative.
+func TestValidateControlConnections(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--tcp-keepalive", "0", "--connect-timeout", "5"))
+    testutil.CheckNoError(t, validateCommandLine(t, "server", "--tcp-keepalive", "30"))
+    testutil.CheckError(t, validateS3Run(t, "--tcp-keepalive", "-1"))
+    testutil.CheckError(t, validateS3Run(t, "--connect-timeout", "-1"))
+}
+
+
+// Read ahead keeps reads in flight, so it has the same limits as a queue depth, and can't be used with one.
+func TestValidateReadAhead(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--read-ahead", "4"))
+    testutil.CheckError(t, validateS3Run(t, "--read-ahead", "0"))
+    testutil.CheckError(t, validateS3Run(t, "--read-ahead", "4", "--queue-depth", "4"))
+    testutil.CheckError(t, validateS3Run(t, "--read-ahead", "4", "--connection-reuse", "false"))
+}
+
+
+// S3 takes any number of gateways, and weights must match them.
+func TestValidateS3Gateways(t *testing.T) {
+    gateways := []string{ "s3", "run", "--s3-access-key", "key", "--s3-secret-key", "secret" }
+    testutil.CheckNoError(t, validateCommandLine(t, append(gateways, "gw1", "gw2", "gw3")...))
+    testutil.CheckNoError(t, validateCommandLine(t, append(gateways, "--target-weights", "2,1,1", "gw1", "gw2", "gw3")...))
+    testutil.CheckError(t, validateCommandLine(t, append(gateways, "--target-weights", "2,1", "gw1", "gw2", "gw3")...))
+}
+
+
+// A report written to stdout can't be renamed into place.
+func TestValidateOutputStdout(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "-o", "-"))
+    testutil.CheckError(t, validateS3Run(t, "-o", "-", "--atomic-report"))
+}
+
+
+// The reference target's own protocol options only make sense with a reference target, and S3 keys come in pairs.
+func TestValidateReferenceOverrides(t *testing.T) {
+    ref := []string{ "--reference-target", "old", "--no-write", "--key-prefix", "old" }
+    testutil.CheckNoError(t, validateS3Run(t, append(ref, "--reference-bucket", "old")...))
+    testutil.CheckNoError(t, validateS3Run(t, append(ref, "--reference-access-key", "k", "--reference-secret-key", "s")...))
+    testutil.CheckError(t, validateS3Run(t, append(ref, "--reference-access-key", "k")...))
+    testutil.CheckError(t, validateS3Run(t, "--reference-bucket", "old"))
+}
+
+
+// The reference target gets our protocol config, with only the options we were given replaced.
+func TestReferenceProtocolConfig(t *testing.T) {
+    protocol := ProtocolConfig{ "bucket": "new", "access_key": "a", "secret_key": "s", "port": "7480" }
+    args := &Arguments{ ReferenceBucket: "old", ReferenceAccessKey: "a2", ReferenceSecretKey: "s2" }
+
+    result := referenceProtocolConfig(protocol, referenceOverrides(args))
+    testutil.CheckString(t, "old", result["bucket"])
+    testutil.CheckString(t, "a2", result["access_key"])
+    testutil.CheckString(t, "s2", result["secret_key"])
+    testutil.CheckString(t, "7480", result["port"])
+    testutil.CheckInt(t, 4, len(result))
+
+    // Our own config must be left alone.
+    testutil.CheckString(t, "new", protocol["bucket"])
+}
+
+
+// Ranged reads of objects from an earlier run can't be verified by the generator, since we don't know their cycles.
+func TestValidateReadRangeNoWrite(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--read-range", "0.5"))
+    testutil.CheckError(t, validateS3Run(t, "--read-range", "0.5", "--no-write", "--key-prefix", "old"))
+    testutil.CheckNoError(t, validateS3Run(t, "--read-range", "0.5", "--no-write", "--key-prefix", "old", "--skip-read-verification"))
+    testutil.CheckNoError(t, validateS3Run(t, "--read-range", "0.5", "--no-write", "--key-prefix", "old", "--reference-target", "old-gw"))
+}
+
+
+// A consistency check needs several targets holding the same objects, and compares them rather than verifying them.
+func TestValidateConsistencyCheck(t *testing.T) {
+    s3 := []string{ "s3", "run", "--s3-access-key", "key", "--s3-secret-key", "secret", "--consistency-check", "100" }
+    testutil.CheckNoError(t, validateCommandLine(t, append(s3, "gw1", "gw2")...))
+    testutil.CheckNoError(t, validateCommandLine(t, append(s3, "--verify-only", "--no-write", "--key-prefix", "old", "gw1", "gw2")...))
+    testutil.CheckError(t, validateCommandLine(t, append(s3, "--no-write", "--key-prefix", "old", "gw1", "gw2")...))
+    testutil.CheckError(t, validateCommandLine(t, append(s3, "gw1")...))
+    testutil.CheckError(t, validateCommandLine(t, append(s3, "--reference-target", "gw3", "--no-write", "--key-prefix", "old", "gw1", "gw2")...))
+    testutil.CheckError(t, validateCommandLine(t, append(s3, "--target-sizes", "4K,8K", "gw1", "gw2")...))
+    testutil.CheckError(t, validateS3Run(t, "--consistency-check", "-1"))
+}
+
+
+// Service level thresholds need results to check, and must be a duration and a bandwidth.
+func TestValidateSlo(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--slo-res-95", "50ms", "--slo-bandwidth", "500M"))
+    testutil.CheckError(t, validateS3Run(t, "--slo-res-95", "50"))
+    testutil.CheckError(t, validateS3Run(t, "--slo-res-95", "-1s"))
+    testutil.CheckError(t, validateS3Run(t, "--slo-bandwidth", "fast"))
+    testutil.CheckError(t, validateS3Run(t, "--slo-res-95", "50ms", "--find-knee"))
+    testutil.CheckError(t, validateCommandLine(t, "s3", "calibrate", "--slo-bandwidth", "500M", "gw1"))
+}
+
+
+// The largest frame must be a size, and big enough for the stats that servers upload.
+func TestValidateMaxFrameSize(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--max-frame-size", "16M"))
+    testutil.CheckNoError(t, validateS3Run(t, "--max-frame-size", "1G"))
+    testutil.CheckError(t, validateS3Run(t, "--max-frame-size", "1M"))
+    testutil.CheckError(t, validateS3Run(t, "--max-frame-size", "big"))
+    testutil.CheckNoError(t, validateCommandLine(t, "server", "--max-frame-size", "512M"))
+}
+
+
+// A manifest can only describe sequential keys.
+func TestValidateManifestKeyScheme(t *testing.T) {
+    testutil.CheckNoError(t, validateS3Run(t, "--manifest", "objects.json"))
+    testutil.CheckNoError(t, validateS3Run(t, "--manifest", "objects.json", "--key-scheme", "sequential"))
+    testutil.CheckError(t, validateS3Run(t, "--manifest", "objects.json", "--key-scheme", "hashed"))
+    testutil.CheckError(t, validateS3Run(t, "--manifest", "objects.json", "--key-scheme", "uuid"))
+    testutil.CheckNoError(t, validateS3Run(t, "--key-scheme", "uuid"))
+}
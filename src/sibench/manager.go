@@ -23,6 +23,10 @@ type ServerDetails struct {
     Discovery
     Name string
     Index uint16
+    Usage ResourceUsage
+    order WorkOrder     // The server's share of the job, which we send again if it restarts.
+    loss *ServerLoss    // If we have lost the server, then the record of that in our report.
+    workerRanges []ObjectRange  // The objects that the server gave to each of its workers.
 }
 
 
@@ -47,13 +51,28 @@ type Manager struct {
     sigChan chan os.Signal
     isInterrupted bool
 
+    /* Prometheus exporter for our per-second stats, or nil if not enabled. */
+    metrics *MetricsExporter
+
+    /* Each server's stat summaries for the current second, keyed by server name */
+    serverSummaries map[string]*StatSummary
+
+    /* Servers we have lost, and will try to bring back before the next phase.  See checkLostServer. */
+    lostServers []*ServerDetails
+
+    /* The phase we are running, for the record of any servers that we lose. */
+    phase string
+
+    /* The servers which have reached their share of the current phase's cap. */
+    cappedServers map[*comms.MessageConnection]bool
+
     /* Most operations will be skipped after the first time we encounter an error */
     err error
 }
 
 
-/* Runs a single benchmark */
-func RunBenchmark(j *Job) error {
+/* Runs a single benchmark, returning the analyses of its results. */
+func RunBenchmark(j *Job) ([]*Analysis, error) {
     var m Manager;
     m.job = j
     m.report, m.err = MakeReport(j)
@@ -61,23 +80,61 @@ func RunBenchmark(j *Job) error {
     // Pull out the order, just to make the code more clear.
     o := &(j.order)
 
+    // A throwaway S3 user must exist before anything uses its keys, and outlive everything that does.
+    if j.arguments.RgwAdminUrl != "" {
+        admin, user, err := createRgwRunUser(j)
+        if err != nil {
+            logger.Errorf("%v\n", err)
+            return nil, connectionError(err)
+        }
+
+        defer admin.deleteRunUser(user)
+    }
+
     // Ensure that we can connect to at least the first target ourselves.  If we can't then
     // there's no need to bother the driver nodes about this at all.
     var wcc WorkerConnectionConfig
+    wcc.ConnectionReuse = true
     conn, err := NewConnection(o.ConnectionType, o.Targets[0], o.ProtocolConfig, wcc)
     if err != nil {
         logger.Errorf("Failure making new connection: %v\n", err)
-        return err
+        return nil, connectionError(err)
     }
 
     err = conn.ManagerConnect()
     if err != nil {
         logger.Errorf("Failure establishing new connection: %v\n", err)
-        return err
+        return nil, connectionError(err)
     }
 
     defer conn.ManagerClose(j.order.CleanUpOnClose)
 
+    if pc, ok := conn.(PlacementConnection); ok {
+        m.reportPlacement(pc)
+    }
+
+    // Write out our manifest before we create any objects, so that even an aborted run can be cleaned up.
+    if j.arguments.Manifest != "" {
+        logger.Infof("Writing object manifest: %s\n", j.arguments.Manifest)
+        err = NewManifest(j).Write(j.arguments.Manifest)
+        if err != nil {
+            logger.Errorf("Failure writing manifest %s: %v\n", j.arguments.Manifest, err)
+            return nil, err
+        }
+    }
+
+    if j.arguments.MetricsPort != 0 {
+        m.metrics, err = StartMetricsExporter(uint16(j.arguments.MetricsPort), o.MeanObjectSize(), o.MeanReadSize(), j.lineage)
+        if err != nil {
+            logger.Errorf("Failure starting metrics exporter: %v\n", err)
+            return nil, err
+        }
+
+        defer m.metrics.Close()
+    }
+
+    m.serverSummaries = make(map[string]*StatSummary)
+
     m.connectToServers()
     defer m.disconnectFromServers()
 
@@ -87,13 +144,37 @@ func RunBenchmark(j *Job) error {
     // Register for interrupts before we do the actual work
     m.sigChan = make(chan os.Signal, 1)
     signal.Notify(m.sigChan, syscall.SIGINT, syscall.SIGTERM)
+    defer signal.Stop(m.sigChan)
 
     phaseTime := j.runTime + j.rampUp + j.rampDown
 
-    if j.order.ReadWriteMix == 0 {
+    if j.order.BucketOps > 0 {
+        // Create and delete buckets, rather than working with objects.
+        m.runPhaseForTime("BUCKET OPS", phaseTime, OP_BucketOpsStart, OP_BucketOpsStop)
+    } else if j.order.HoldConnections > 0 {
+        // Open connections and leave them idle, to see how many the targets can sustain.
+        m.runPhaseForTime("HOLD CONNECTIONS", phaseTime, OP_HoldConnectionsStart, OP_HoldConnectionsStop)
+    } else if j.order.AppendObjects > 0 {
+        // Append to a few shared objects, rather than writing our own.  Appends leave objects that
+        // we can't verify, so there is no read phase.
+        m.runPhaseForTime("APPEND", phaseTime, OP_WriteStart, OP_WriteStop)
+    } else if j.order.VerifyOnly {
+        // Read every object once, to check that it is intact, rather than benchmarking.
+        m.runPhaseToCompletion("PREPARE", OP_Prepare)
+        m.runPhaseToCompletion("VERIFY", OP_Verify)
+    } else if j.order.NoWrite {
+        // Read objects from an earlier run.  The prepare phase has nothing to do, but takes the
+        // foremen and workers through to the state where they can start reading.
+        m.runPhaseToCompletion("PREPARE", OP_Prepare)
+        m.runPrewarmPhase()
+        m.runStatPhase(phaseTime)
+        m.runPhaseForTime("READ", phaseTime, OP_ReadStart, OP_ReadStop)
+    } else if j.order.ReadWriteMix == 0 {
         // Write/Prepare/Read
         m.runPhaseForTime("WRITE", phaseTime, OP_WriteStart, OP_WriteStop)
         m.runPhaseToCompletion("PREPARE", OP_Prepare)
+        m.runPrewarmPhase()
+        m.runStatPhase(phaseTime)
         m.runPhaseForTime("READ", phaseTime, OP_ReadStart, OP_ReadStop)
     } else {
         // Prepare/Read-Write-Mix
@@ -101,14 +182,46 @@ func RunBenchmark(j *Job) error {
         m.runPhaseForTime("READ/WRITE", phaseTime, OP_ReadWriteStart, OP_ReadWriteStop)
     }
 
-    if (conn.CanDelete() && j.order.CleanUpOnClose) {
+    // Workers delete their own buckets, and we haven't made any objects, with bucket ops or held connections.
+    if (conn.CanDelete() && j.order.CleanUpOnClose && (j.order.BucketOps == 0) && (j.order.HoldConnections == 0)) {
         m.runPhaseToCompletion("DELETE", OP_Delete)
     }
 
     // Process the stats.
+    var sloErr error
+
     if m.err == nil {
         logger.Infof("\n")
         m.report.DisplayAnalyses(m.job.useBytes)
+
+        if j.baseline != nil {
+            m.report.CompareWithBaseline(j.baseline)
+            m.report.DisplayBaselineComparisons()
+        }
+
+        for _, conn := range m.msgConns {
+            m.report.AddDriverUsage(m.connToServerDetails[conn])
+        }
+
+        m.report.DisplayDriverUsages()
+
+        if j.arguments.LatencySvg != "" {
+            logger.Infof("Writing latency chart: %s\n", j.arguments.LatencySvg)
+            err := WriteLatencySvg(j.arguments.LatencySvg, m.report.analyses)
+            if err != nil {
+                logger.Errorf("Failure writing latency chart %s: %v\n", j.arguments.LatencySvg, err)
+                m.report.AddError(err)
+            }
+        }
+
+        if j.order.VerifyOnly || (len(m.report.verifyFailures) > 0) {
+            m.report.DisplayVerifyFailures()
+        }
+
+        if breaches := m.report.SloBreaches(); len(breaches) > 0 {
+            sloErr = &SloError{ breaches }
+            m.report.AddError(sloErr)
+        }
     }
 
     // Terminate
@@ -121,7 +234,22 @@ func RunBenchmark(j *Job) error {
     }
 
     m.report.Close()
-    return m.err
+    j.interrupted = m.isInterrupted
+
+    // A verification pass which finds bad objects has done its job, but the run should still fail.
+    if (m.err == nil) && j.order.VerifyOnly && (len(m.report.verifyFailures) > 0) {
+        err = &VerifyError{ m.report.VerifyFailureCount() }
+        logger.Errorf("%v\n", err)
+        return m.report.analyses, err
+    }
+
+    // Likewise, a run which breaches a service level threshold has its results, but should fail.
+    if (m.err == nil) && (sloErr != nil) {
+        logger.Errorf("%v\n", sloErr)
+        return m.report.analyses, sloErr
+    }
+
+    return m.report.analyses, m.err
 }
 
 
@@ -152,7 +280,7 @@ func (m *Manager) runScript(phase string, event string) {
     err := cmd.Run()
 
     if err != nil {
-        logger.Errorf("Failure running phase script: '%s %s %ws' - %v\n", m.job.script, phase, event, err)
+        logger.Errorf("Failure running phase script: '%s %s %s' - %v\n", m.job.script, phase, event, err)
     }
 }
 
@@ -215,6 +343,182 @@ func (m *Manager) checkError(msgInfo *comms.ReceivedMessageInfo) {
 }
 
 
+/* Return the set of servers we are connected to, so that we can tick them off as they respond. */
+func (m *Manager) connectionSet() map[*comms.MessageConnection]bool {
+    set := make(map[*comms.MessageConnection]bool)
+    for _, conn := range m.msgConns {
+        set[conn] = true
+    }
+
+    return set
+}
+
+
+/*
+ * If we have been asked to wait for servers to restart, then check whether a message means that
+ * we've lost one: either because its connection has failed, or because it has told us that it has
+ * hung (and so is about to exit).  If so, we carry on with the rest of our servers, and try to
+ * bring the lost one back before our next phase.  Anything that the lost server did in the current
+ * phase is gone.
+ *
+ * Returns true if the caller should ignore the message, either because we've just lost its server,
+ * or because it comes from a server that we lost earlier.
+ */
+func (m *Manager) checkLostServer(msgInfo *comms.ReceivedMessageInfo) bool {
+    details, ok := m.connToServerDetails[msgInfo.Connection]
+    if !ok {
+        return true
+    }
+
+    if m.job.reconnectTimeout == 0 {
+        return false
+    }
+
+    err := msgInfo.Error
+    if (err == nil) && (Opcode(msgInfo.Message.ID()) == OP_Hung) {
+        var resp ForemanGenericResponse
+        msgInfo.Message.Data(&resp)
+        err = fmt.Errorf("Server has hung: %v", resp.Error)
+    }
+
+    if err == nil {
+        return false
+    }
+
+    logger.Warnf("Lost server %v during %v: %v\n", details.Name, m.phase, err)
+
+    index := slices.Index(m.msgConns, msgInfo.Connection)
+    m.msgConns = slices.Delete(m.msgConns, index, index + 1)
+    delete(m.connToServerDetails, msgInfo.Connection)
+    msgInfo.Connection.Close()
+
+    details.loss = m.report.AddServerLoss(details.Name, m.phase, err)
+    m.lostServers = append(m.lostServers, details)
+    return true
+}
+
+
+/*
+ * Try to bring back any servers that we have lost, before we start a phase.  We give each one up
+ * to our reconnect timeout to restart, and after that it sits out the rest of the run.  If none of
+ * our servers are left, then there's no point carrying on.
+ *
+ * A restarted server has forgotten our job, so we send it its share of the work order again.  Most
+ * phases need objects to have been prepared first, so for those we run a prepare on the server
+ * before it rejoins, which rewrites its objects.  A restarted server can't delete objects that it
+ * doesn't know it has written, so we don't try to bring any back for the delete phase.
+ */
+func (m *Manager) rejoinLostServers(phaseOp Opcode) {
+    if (len(m.lostServers) == 0) || (phaseOp == OP_Delete) { return }
+
+    prepare := (phaseOp == OP_ReadStart) || (phaseOp == OP_ReadWriteStart) || (phaseOp == OP_Verify) || (phaseOp == OP_StatOpsStart)
+    deadline := time.Now().Add(time.Duration(m.job.reconnectTimeout) * time.Second)
+
+    for _, details := range m.lostServers {
+        logger.Infof("Waiting for server %v to rejoin\n", details.Name)
+
+        conn, err := m.rejoinServer(details, prepare, deadline)
+        if err != nil {
+            logger.Warnf("Server %v has not rejoined, and will sit out the rest of the run: %v\n", details.Name, err)
+            continue
+        }
+
+        logger.Infof("Server %v has rejoined\n", details.Name)
+        conn.ReceiveToChannel(m.msgChannel)
+        m.msgConns = append(m.msgConns, conn)
+        m.connToServerDetails[conn] = details
+        details.loss.Rejoined = true
+    }
+
+    m.lostServers = nil
+
+    if len(m.msgConns) == 0 {
+        m.err = connectionError(fmt.Errorf("Lost all of our servers\n"))
+    }
+}
+
+
+/*
+ * Keep trying to reconnect to a lost server until it takes its share of our job again, or until
+ * the deadline passes.  We talk to it directly, rather than through our message channel, until
+ * it has caught up with the rest of the servers.
+ */
+func (m *Manager) rejoinServer(details *ServerDetails, prepare bool, deadline time.Time) (*comms.MessageConnection, error) {
+    endpoint := fmt.Sprintf("%v:%v", details.Name, m.job.serverPort)
+
+    for {
+        conn, err := comms.ConnectTCP(endpoint, comms.MakeEncoderFactory(), time.Second)
+        if err == nil {
+            err = m.restartServer(conn, details, prepare, time.Until(deadline))
+            if err == nil {
+                return conn, nil
+            }
+
+            conn.Close()
+        }
+
+        if time.Now().After(deadline) {
+            return nil, err
+        }
+
+        logger.Debugf("Unable to rejoin server %v yet: %v\n", details.Name, err)
+        time.Sleep(time.Second)
+    }
+}
+
+
+/* Take a server which has just restarted through discovery and connection, and prepare it if need be. */
+func (m *Manager) restartServer(conn *comms.MessageConnection, details *ServerDetails, prepare bool, timeout time.Duration) error {
+    if timeout < time.Second {
+        timeout = time.Second
+    }
+
+    msg, err := rejoinRequest(conn, OP_Discovery, nil, timeout)
+    if err != nil {
+        return err
+    }
+
+    msg.Data(&details.Discovery)
+
+    msg, err = rejoinRequest(conn, OP_Connect, &details.order, timeout)
+    if err != nil {
+        return err
+    }
+
+    m.setWorkerRanges(conn, msg)
+
+    if prepare {
+        // Preparing may take a while, and we would wait for as long in the prepare phase itself.
+        logger.Infof("Preparing objects on server %v\n", details.Name)
+        _, err = rejoinRequest(conn, OP_Prepare, nil, 0)
+    }
+
+    return err
+}
+
+
+/* Send an op to a server which is rejoining, and check that it responds in kind. */
+func rejoinRequest(conn *comms.MessageConnection, op Opcode, data interface{}, timeout time.Duration) (comms.ReceivedMessage, error) {
+    msg, err := conn.SendReceive(uint8(op), data, timeout)
+    if err != nil {
+        return nil, err
+    }
+
+    resp := Opcode(msg.ID())
+    switch resp {
+        case op:
+            return msg, nil
+
+        case OP_Fail:
+            var fail ForemanGenericResponse
+            msg.Data(&fail)
+            return nil, fmt.Errorf("%v failed: %v", op.ToString(), fail.Error)
+    }
+
+    return nil, fmt.Errorf("Unexpected Opcode received: expected %v but got %v", op.ToString(), resp.ToString())
+}
+
+
 /*
  * When we have complete a phase (or the whole run!) we can ask the servers to
  * send us all the detailed stats that they have been collecting (and to then
@@ -233,14 +537,20 @@ func (m* Manager) drainStats() {
     m.sendOpToServers(OP_StatDetails, false)
 
     count := 0
-    pending := len(m.msgConns)
+    waiting := m.connectionSet()
     start := time.Now()
 
-    for pending > 0 {
+    for len(waiting) > 0 {
         select {
             case msgInfo := <-m.msgChannel:
+                if m.checkLostServer(msgInfo) {
+                    delete(waiting, msgInfo.Connection)
+                    if m.err != nil { return }
+                    continue
+                }
+
                 if msgInfo.Error != nil {
-                    m.err = fmt.Errorf("Transport failure: %v\n", msgInfo.Error)
+                    m.err = connectionError(fmt.Errorf("Transport failure: %v\n", msgInfo.Error))
                     return
                 }
 
@@ -267,8 +577,14 @@ func (m* Manager) drainStats() {
                             count++
                         }
 
+                        // Let the foreman know it can send more.
+                        msgInfo.Connection.Send(OP_StatDetailsAck, nil)
+
                     case OP_StatDetailsDone:
-                        pending--
+                        var u ResourceUsage
+                        msg.Data(&u)
+                        m.connToServerDetails[msgInfo.Connection].Usage.Add(&u)
+                        delete(waiting, msgInfo.Connection)
 
                     case OP_StatSummary:
                         // Ignore this - we just received one a bit later than expected.
@@ -306,7 +622,14 @@ func (m* Manager) drainStats() {
 func (m *Manager) runPhaseToCompletion(msg string, phaseOp Opcode) {
     if (m.err != nil) || m.isInterrupted { return }
 
+    m.rejoinLostServers(phaseOp)
+    if (m.err != nil) || m.isInterrupted { return }
+
     logger.Infof(banner(msg, '-'))
+    m.phase = msg
+
+    timing := m.report.StartPhase(msg)
+    defer timing.Finish()
 
     m.sendOpToServers(OP_StatSummaryStart, true)
     m.sendOpToServers(phaseOp, false)
@@ -314,19 +637,25 @@ func (m *Manager) runPhaseToCompletion(msg string, phaseOp Opcode) {
     ticker := time.NewTicker(time.Second)
 
     var summary StatSummary
-    pending := len(m.msgConns)
+    waiting := m.connectionSet()
     i := 0
 
-    for {
+    for len(waiting) > 0 {
         select {
             case msgInfo := <-m.msgChannel:
+                if m.checkLostServer(msgInfo) {
+                    delete(waiting, msgInfo.Connection)
+                    if m.err != nil { return }
+                    continue
+                }
+
                 if msgInfo.Error != nil {
                     if msgInfo.Error == io.EOF {
-                        m.err = fmt.Errorf("Received remote close from %v\n", msgInfo.Connection.RemoteIP())
+                        m.err = connectionError(fmt.Errorf("Received remote close from %v\n", msgInfo.Connection.RemoteIP()))
                         return
                     }
 
-                    m.err = fmt.Errorf("Transport failure: %v\n", msgInfo.Error)
+                    m.err = connectionError(fmt.Errorf("Transport failure: %v\n", msgInfo.Error))
                     return
                 }
 
@@ -337,17 +666,14 @@ func (m *Manager) runPhaseToCompletion(msg string, phaseOp Opcode) {
                 op := Opcode(msg.ID())
                 switch op {
                     case phaseOp:
-                        pending--
-                        if pending == 0 {
-                            m.sendOpToServers(OP_StatSummaryStop, true)
-                            m.drainStats()
-                            return
-                        }
+                        m.addVerifyFailures(msgInfo)
+                        delete(waiting, msgInfo.Connection)
 
                     case OP_StatSummary:
                         var s StatSummary
                         msg.Data(&s)
                         summary.Add(&s)
+                        m.addServerSummary(msgInfo.Connection, &s)
 
                     default:
                         m.err = fmt.Errorf("Unexpected opcode %v\n", op.ToString())
@@ -355,7 +681,9 @@ func (m *Manager) runPhaseToCompletion(msg string, phaseOp Opcode) {
                 }
 
             case <-ticker.C:
-                logger.Infof("%v: %v\n", i, summary.String(m.job.order.ObjectSize, m.job.useBytes))
+                logger.Infof("%v: %v\n", i, summary.String(m.job.order.MeanObjectSize(), m.job.order.MeanReadSize(), m.job.useBytes))
+                m.report.AddTimeSamples(&summary)
+                m.publishMetrics()
                 i++
                 summary.Zero()
 
@@ -366,10 +694,35 @@ func (m *Manager) runPhaseToCompletion(msg string, phaseOp Opcode) {
                 return
         }
     }
+
+    ticker.Stop()
+    timing.Finish()
+    m.sendOpToServers(OP_StatSummaryStop, true)
+    m.drainStats()
 }
 
 
 
+/*
+ * If we've been asked to, then read every object once before the read phase, so that the caches
+ * are warm.  This is a verification pass under another name, except that the workers don't verify
+ * unless reads do, and we don't analyse the results.
+ */
+func (m *Manager) runPrewarmPhase() {
+    if m.job.order.PrewarmReads {
+        m.runPhaseToCompletion("PREWARM", OP_Verify)
+    }
+}
+
+
+/* If we've been asked to, then time how long it takes to stat objects, before we read them. */
+func (m *Manager) runStatPhase(secs uint64) {
+    if m.job.order.StatObjects {
+        m.runPhaseForTime("STAT", secs, OP_StatOpsStart, OP_StatOpsStop)
+    }
+}
+
+
 /*
  * Waits for the specified number of seconds whilst a benchmark executes.
  *
@@ -378,31 +731,68 @@ func (m *Manager) runPhaseToCompletion(msg string, phaseOp Opcode) {
  * see what the system is doing.
  *
  * This is used for Read, Write and Read/Write phases.
+ *
+ * If we are automatically detecting steady state, then we don't know how long the phase will be
+ * until we've seen a plateau in the number of ops per second.  Only then do we start our timer for
+ * the remainder of the phase.
  */
 func (m *Manager) runPhaseForTime(msg string, secs uint64, startOp Opcode, stopOp Opcode) {
     if (m.err != nil) || m.isInterrupted { return }
 
+    m.rejoinLostServers(startOp)
+    if (m.err != nil) || m.isInterrupted { return }
+
     logger.Infof(banner(msg, '-'))
+    m.phase = msg
 
+    timing := m.report.StartPhase(msg)
+    defer timing.Finish()
+
+    m.cappedServers = make(map[*comms.MessageConnection]bool)
     m.sendOpToServers(startOp, true)
     m.sendOpToServers(OP_StatSummaryStart, true)
 
-    timer := time.NewTimer(time.Duration(secs + 1) * time.Second)
+    // A nil channel never fires, so we won't time out until we've set a real timer.
+    var timerChan <-chan time.Time
+    var opsPerSec []uint64
+    steady := !m.job.autoSteadyState
+
+    if steady {
+        m.job.phaseRampUp = m.job.rampUp
+        timerChan = time.NewTimer(time.Duration(secs + 1) * time.Second).C
+
+        // Without a ramp-up, we never see a tick that marks its end.
+        if m.job.phaseRampUp == 0 {
+            timing.RampUpEnd = &timing.Start
+        }
+    }
+
     ticker := time.NewTicker(time.Second)
 
+    // If the phase is capped, then we end it early once every server has done its share.
+    if m.allServersCapped() {
+        m.stopCappedPhase(ticker, stopOp, timing)
+        return
+    }
+
     var summary StatSummary
     i := 0
 
     for {
         select {
             case msgInfo := <-m.msgChannel:
+                if m.checkLostServer(msgInfo) {
+                    if m.err != nil { return }
+                    continue
+                }
+
                 if msgInfo.Error != nil {
                     if msgInfo.Error == io.EOF {
-                        m.err = fmt.Errorf("Received remote close from %v\n", msgInfo.Connection.RemoteIP())
+                        m.err = connectionError(fmt.Errorf("Received remote close from %v\n", msgInfo.Connection.RemoteIP()))
                         return
                     }
 
-                    m.err = fmt.Errorf("Transport failure: %v\n", msgInfo.Error)
+                    m.err = connectionError(fmt.Errorf("Transport failure: %v\n", msgInfo.Error))
                     return
                 }
 
@@ -411,6 +801,16 @@ func (m *Manager) runPhaseForTime(msg string, secs uint64, startOp Opcode, stopO
                 if m.err != nil { return }
 
                 op := Opcode(msg.ID())
+                if op == OP_PhaseCapped {
+                    m.cappedServers[msgInfo.Connection] = true
+                    if m.allServersCapped() {
+                        m.stopCappedPhase(ticker, stopOp, timing)
+                        return
+                    }
+
+                    continue
+                }
+
                 if op != OP_StatSummary {
                     m.err = fmt.Errorf("Unexpected opcode %v\n", op.ToString())
                     return
@@ -419,13 +819,34 @@ func (m *Manager) runPhaseForTime(msg string, secs uint64, startOp Opcode, stopO
                 var s StatSummary
                 msg.Data(&s)
                 summary.Add(&s)
+                m.addServerSummary(msgInfo.Connection, &s)
 
             case <-ticker.C:
-                logger.Infof("%v: %v\n", i, summary.String(m.job.order.ObjectSize, m.job.useBytes))
+                logger.Infof("%v: %v\n", i, summary.String(m.job.order.MeanObjectSize(), m.job.order.MeanReadSize(), m.job.useBytes))
+                m.report.AddTimeSamples(&summary)
+                m.publishMetrics()
                 i++
 
-                isRampUp := (uint64(i) == m.job.rampUp)
-                isRampDown := (uint64(i) == m.job.rampUp + m.job.runTime)
+                if !steady {
+                    opsPerSec = append(opsPerSec, summary.Successes())
+                    steady = m.checkSteadyState(opsPerSec, uint64(i))
+
+                    if steady {
+                        m.job.phaseRampUp = uint64(i)
+                        timerChan = time.NewTimer(time.Duration(m.job.runTime + m.job.rampDown + 1) * time.Second).C
+                    }
+                }
+
+                isRampUp := steady && (uint64(i) == m.job.phaseRampUp)
+                isRampDown := steady && (uint64(i) == m.job.phaseRampUp + m.job.runTime)
+
+                if isRampUp {
+                    timing.RampUpEnd = phaseTimestamp()
+                }
+
+                if isRampDown {
+                    timing.RampDownStart = phaseTimestamp()
+                }
 
                 if isRampUp || isRampDown {
                     // Draw some lines to indicate the ramp-up/ramp-down demarcation.
@@ -441,12 +862,8 @@ func (m *Manager) runPhaseForTime(msg string, secs uint64, startOp Opcode, stopO
 
                 summary.Zero()
 
-            case <-timer.C:
-                ticker.Stop()
-                m.sendOpToServers(OP_StatSummaryStop, true)
-                logger.Infof("Waiting for all workers to complete their current operation\n");
-                m.sendOpToServers(stopOp, true)
-                m.drainStats()
+            case <-timerChan:
+                m.stopTimedPhase(ticker, stopOp, timing)
                 return
 
             case <-m.sigChan:
@@ -459,6 +876,139 @@ func (m *Manager) runPhaseForTime(msg string, secs uint64, startOp Opcode, stopO
 }
 
 
+/* Return true if every one of our servers has done its share of the current phase's cap. */
+func (m *Manager) allServersCapped() bool {
+    for _, conn := range m.msgConns {
+        if !m.cappedServers[conn] {
+            return false
+        }
+    }
+
+    return len(m.msgConns) > 0
+}
+
+
+/* End a timed phase early, since every server has done its share of the phase's cap. */
+func (m *Manager) stopCappedPhase(ticker *time.Ticker, stopOp Opcode, timing *PhaseTiming) {
+    logger.Infof("Every server has reached its share of the cap for this phase\n")
+
+    if timing.RampUpEnd == nil {
+        logger.Warnf("The phase reached its cap during its ramp-up, so none of its ops will be analysed\n")
+    }
+
+    m.stopTimedPhase(ticker, stopOp, timing)
+}
+
+
+/* End a timed phase, either because its time is up or because it has reached its cap. */
+func (m *Manager) stopTimedPhase(ticker *time.Ticker, stopOp Opcode, timing *PhaseTiming) {
+    ticker.Stop()
+    m.sendOpToServers(OP_StatSummaryStop, true)
+    logger.Infof("Waiting for all workers to complete their current operation\n");
+    m.sendOpToServers(stopOp, true)
+    timing.Finish()
+    m.drainStats()
+}
+
+
+/*
+ * Work out how our objects will spread across the backend's placement groups, and report it.
+ * This is only for information, so any failure is just logged.
+ */
+func (m *Manager) reportPlacement(pc PlacementConnection) {
+    pgCount, err := pc.PlacementGroupCount()
+    if err != nil {
+        logger.Warnf("Unable to report placement group spread: %v\n", err)
+        return
+    }
+
+    if pgCount == 0 {
+        return
+    }
+
+    // With appends, we only use a few objects.
+    o := &m.job.order
+    end := o.RangeEnd
+    if o.AppendObjects > 0 {
+        end = o.RangeStart + o.AppendObjects
+    }
+
+    ps := NewPlacementSpread(o.ObjectKeyScheme, o.ObjectKeyPrefix, o.RangeStart, end, pgCount)
+    logger.Infof("Placement: %v\n", ps)
+    m.report.SetPlacement(ps)
+}
+
+
+/* Record any objects which a server has found to fail verification since its last response. */
+func (m *Manager) addVerifyFailures(msgInfo *comms.ReceivedMessageInfo) {
+    var resp ForemanGenericResponse
+    msgInfo.Message.Data(&resp)
+
+    name := m.connToServerDetails[msgInfo.Connection].Name
+    for i := range resp.VerifyFailures {
+        resp.VerifyFailures[i].Server = name
+    }
+
+    m.report.AddVerifyFailures(resp.VerifyFailures)
+}
+
+
+/* Remember how a server divided its range between its workers, from its response to OP_Connect. */
+func (m *Manager) setWorkerRanges(conn *comms.MessageConnection, msg comms.ReceivedMessage) {
+    var resp ForemanGenericResponse
+    msg.Data(&resp)
+    m.connToServerDetails[conn].workerRanges = resp.WorkerRanges
+}
+
+
+/* Accumulate a summary from one of our servers for the current second. */
+func (m *Manager) addServerSummary(conn *comms.MessageConnection, s *StatSummary) {
+    name := m.connToServerDetails[conn].Name
+
+    ss, ok := m.serverSummaries[name]
+    if !ok {
+        ss = new(StatSummary)
+        m.serverSummaries[name] = ss
+    }
+
+    ss.Add(s)
+}
+
+
+/* At the end of each second, hand the per-server summaries to our exporter (if we have one). */
+func (m *Manager) publishMetrics() {
+    if m.metrics != nil {
+        m.metrics.Update(m.serverSummaries)
+    }
+
+    m.serverSummaries = make(map[string]*StatSummary)
+}
+
+
+/*
+ * Decides whether the ops per second we've seen so far in a phase have reached a plateau, or
+ * whether we've waited long enough that we should just give up and start recording anyway.
+ */
+func (m *Manager) checkSteadyState(opsPerSec []uint64, elapsed uint64) bool {
+    window := int(m.job.steadyStateWindow)
+
+    if len(opsPerSec) >= window {
+        cv := coefficientOfVariation(opsPerSec[len(opsPerSec) - window:])
+        if cv < m.job.steadyStateCV {
+            logger.Infof("Steady state reached after %v seconds (coefficient of variation %.3f)\n", elapsed, cv)
+            return true
+        }
+    }
+
+    if elapsed >= m.job.steadyStateMaxWait {
+        logger.Warnf("No steady state after %v seconds: recording anyway\n", elapsed)
+        return true
+    }
+
+    return false
+}
+
+
 /*
  * Blocks until all the servers have responded with the specified opcode.
  *
@@ -470,14 +1020,23 @@ func (m *Manager) waitForResponses(expectedOp Opcode) {
     if (m.err != nil) || m.isInterrupted { return }
 
     logger.Debugf("Waiting for %s\n", expectedOp.ToString())
-    pending := len(m.msgConns)
+    waiting := m.connectionSet()
 
-    for {
+    for len(waiting) > 0 {
         select {
             case msgInfo := <-m.msgChannel:
+                if m.checkLostServer(msgInfo) {
+                    delete(waiting, msgInfo.Connection)
+                    if m.err != nil { return }
+                    continue
+                }
+
+                // We can't carry on, but we can at least leave a report behind with what we have.
                 if msgInfo.Error != nil {
                     logger.Errorf("%v\n", msgInfo.Error)
-                    os.Exit(-1)
+                    m.report.AddError(msgInfo.Error)
+                    m.report.Close()
+                    os.Exit(ExitConnectionFailure)
                 }
 
                 m.checkError(msgInfo)
@@ -487,16 +1046,18 @@ func (m *Manager) waitForResponses(expectedOp Opcode) {
                 op := Opcode(msg.ID())
 
                 if op == expectedOp {
-                    var resp ForemanGenericResponse
-                    msg.Data(&resp)
-
-                    pending--
-                    if pending == 0 {
-                        logger.Debugf("Received %v, finished waiting\n", op.ToString())
-                        return
+                    m.addVerifyFailures(msgInfo)
+                    if op == OP_Connect {
+                        m.setWorkerRanges(msgInfo.Connection, msgInfo.Message)
                     }
+                    delete(waiting, msgInfo.Connection)
 
-                    logger.Debugf("Received %v, still waiting for %v more\n", op.ToString(), pending)
+                    if len(waiting) > 0 {
+                        logger.Debugf("Received %v, still waiting for %v more\n", op.ToString(), len(waiting))
+                    }
+                } else if op == OP_PhaseCapped {
+                    // A server can reach its cap whilst we are still starting (or stopping) a phase.
+                    m.cappedServers[msgInfo.Connection] = true
                 } else if op != OP_StatSummary {
                     // Stat Summary messages can arrive later than expected because they're asynchronous.
                     // If we see one when we don't want one, we just drop it.
@@ -511,6 +1072,8 @@ func (m *Manager) waitForResponses(expectedOp Opcode) {
                 return
         }
     }
+
+    logger.Debugf("Received %v, finished waiting\n", expectedOp.ToString())
 }
 
 
@@ -525,6 +1088,11 @@ func (m *Manager) terminate() {
     for pending := len(m.msgConns); pending > 0; {
         msgInfo := <-m.msgChannel
 
+        // Ignore anything from servers that we have lost.
+        if _, ok := m.connToServerDetails[msgInfo.Connection]; !ok {
+            continue
+        }
+
         switch msgInfo.Error {
             case nil:
                 if Opcode(msgInfo.Message.ID()) == OP_Terminate {
@@ -535,7 +1103,7 @@ func (m *Manager) terminate() {
                 // Ignore: the foreman has just closed the connection.
 
             default:
-                m.err = fmt.Errorf("Transport failure: %v\n", msgInfo.Error)
+                m.err = connectionError(fmt.Errorf("Transport failure: %v\n", msgInfo.Error))
         }
     }
 }
@@ -573,19 +1141,23 @@ func (m *Manager) sendJobToServers() {
         o.Bandwidth = (order.Bandwidth * details.Cores) / m.totalCoreCount
         o.RangeStart = uint64(rangeStart)
         o.RangeEnd = uint64(rangeEnd)
+        o.HotSetSize = hotSetShare(order.HotSetSize, order.RangeStart, order.RangeEnd, o.RangeStart, o.RangeEnd)
+        o.PhaseCaps = order.PhaseCaps.Share(order.RangeStart, order.RangeEnd, o.RangeStart, o.RangeEnd)
 
         rangeStart = rangeEnd
 
-        // Check if we should warn about memory usage for this server
-        if ((o.RangeEnd - o.RangeStart) * o.ObjectSize) * 10 > (details.Ram * 8) {
+        // Check if we should warn about memory usage for this server, if we know how much it has.
+        if (details.Ram > 0) && (((o.RangeEnd - o.RangeStart) * o.ObjectSize) * 10 > (details.Ram * 8)) {
             hostsWithLowRam = append(hostsWithLowRam, details.Name)
         }
 
         // Tell the server to connect...
         logger.Debugf("Sending job to %s with start: %v, end: %v, bandwidth: %v\n", details.Name, o.RangeStart, o.RangeEnd, o.Bandwidth)
+        details.order = o
         conn.Send(OP_Connect, &o)
     }
 
+    m.phase = "CONNECT"
     m.waitForResponses(OP_Connect)
 }
 
@@ -597,6 +1169,10 @@ func (m *Manager) sendJobToServers() {
 func (m *Manager) discoverServerCapabilities() {
     if (m.err != nil) || m.isInterrupted { return }
 
+    if m.useKnownDiscoveries() {
+        return
+    }
+
     logger.Debugf("Sending Server Capability Discovery requests\n")
     for _, conn := range m.msgConns {
         conn.Send(OP_Discovery, nil)
@@ -612,7 +1188,7 @@ func (m *Manager) discoverServerCapabilities() {
         msgInfo := <-m.msgChannel
 
         if msgInfo.Error != nil {
-            m.err = fmt.Errorf("Failure in driver discovery: %v\n", msgInfo.Error)
+            m.err = connectionError(fmt.Errorf("Failure in driver discovery: %v\n", msgInfo.Error))
             return
         }
 
@@ -630,15 +1206,77 @@ func (m *Manager) discoverServerCapabilities() {
         // Find our details object
 
         logger.Infof("%s: %v cores, %vB of RAM, sibench build %s\n", d.Name, d.Cores, ToUnits(d.Ram), d.Version)
+        if d.LibradosVersion != "" {
+            logger.Infof("%s: %s, librados %s, librbd %s\n", d.Name, d.GoVersion, d.LibradosVersion, d.LibrbdVersion)
+        } else {
+            logger.Infof("%s: %s\n", d.Name, d.GoVersion)
+        }
+
         m.totalCoreCount += d.Cores
 
+        if m.job.discoveries != nil {
+            m.job.discoveries[d.Name] = d.Discovery
+        }
+
         pending--
     }
 
+    m.checkServerVersions("sibench build", func(d *ServerDetails) string { return d.Version })
+    m.checkServerVersions("Go", func(d *ServerDetails) string { return d.GoVersion })
+    m.checkServerVersions("librados", func(d *ServerDetails) string { return d.LibradosVersion })
+    m.checkServerVersions("librbd", func(d *ServerDetails) string { return d.LibrbdVersion })
+
     logger.Debugf("Discovery complete\n\n")
 }
 
 
+/*
+ * If an earlier job in a sequence has already discovered the capabilities of all of our servers,
+ * then use those rather than asking again.  Returns whether we could.
+ */
+func (m *Manager) useKnownDiscoveries() bool {
+    if m.job.discoveries == nil {
+        return false
+    }
+
+    for _, conn := range m.msgConns {
+        if _, ok := m.job.discoveries[m.connToServerDetails[conn].Name]; !ok {
+            return false
+        }
+    }
+
+    m.totalCoreCount = 0
+
+    for _, conn := range m.msgConns {
+        d := m.connToServerDetails[conn]
+        d.Discovery = m.job.discoveries[d.Name]
+        m.totalCoreCount += d.Cores
+    }
+
+    logger.Debugf("Using the driver capabilities discovered for an earlier job\n")
+    return true
+}
+
+
+/*
+ * Warns if our servers don't all have the same version of something, since that may explain why
+ * one of them behaves differently to the rest.
+ */
+func (m *Manager) checkServerVersions(what string, version func(*ServerDetails) string) {
+    var first string
+
+    for i, conn := range m.msgConns {
+        v := version(m.connToServerDetails[conn])
+        if i == 0 {
+            first = v
+        } else if v != first {
+            logger.Warnf("Servers have different %v versions: see DriverUsages in the report for each server's versions\n", what)
+            return
+        }
+    }
+}
+
+
 /*
  * Attempts to connect to a set of servers (as specified in our current Job).
  *
@@ -659,9 +1297,9 @@ func (m *Manager) connectToServers() {
         endpoint := fmt.Sprintf("%v:%v", s, m.job.serverPort)
         logger.Infof("Connecting to sibench server at %v\n", endpoint)
 
-        conn, err := comms.ConnectTCP(endpoint, comms.MakeEncoderFactory(), 0)
+        conn, err := comms.ConnectTCP(endpoint, comms.MakeEncoderFactory(), time.Duration(m.job.connectTimeout) * time.Second)
         if err != nil {
-            m.err = fmt.Errorf("Could not connect to sibench server at %v: %v\n", endpoint, err)
+            m.err = connectionError(fmt.Errorf("Could not connect to sibench server at %v: %v\n", endpoint, err))
             return
         }
 
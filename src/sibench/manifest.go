@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "encoding/json"
+import "os"
+
+
+/*
+ * A Manifest describes every object that a job may have created, so that external tooling can
+ * find and delete them later.
+ *
+ * Rather than listing every key (which could be millions of them), we record the key scheme and
+ * the range of ids, from which all of the keys can be regenerated.  For the keyed backends (s3,
+ * rados, cephfs, nfs and file), each key is printf(KeyFormat, KeyPrefix, id) for every id in the
+ * range [RangeStart, RangeEnd).  That only holds for the sequential key scheme, so we refuse to
+ * write a manifest with any other.  For RBD, each worker creates an image named
+ * <image_prefix>-<hostname>-<worker id>, and the image_prefix can be found in the Config.
+ */
+type Manifest struct {
+    ConnectionType string
+    Targets []string
+    Config ProtocolConfig
+    KeyPrefix string
+    KeyScheme string
+    KeyFormat string
+    RangeStart uint64
+    RangeEnd uint64
+}
+
+
+/* Protocol config entries which are secrets, and so must not be written into a manifest. */
+var manifestSecrets = []string{ "secret_key", "key" }
+
+
+/* Build a Manifest describing the objects used by a Job. */
+func NewManifest(job *Job) *Manifest {
+    o := &(job.order)
+
+    var m Manifest
+    m.ConnectionType = o.ConnectionType
+    m.Targets = o.Targets
+    m.KeyPrefix = o.ObjectKeyPrefix
+    m.KeyScheme = o.ObjectKeyScheme
+    m.KeyFormat = "%s-%d"
+    m.RangeStart = o.RangeStart
+    m.RangeEnd = o.RangeEnd
+
+    m.Config = make(ProtocolConfig)
+    for k, v := range o.ProtocolConfig {
+        m.Config[k] = v
+    }
+
+    for _, k := range manifestSecrets {
+        delete(m.Config, k)
+    }
+
+    return &m
+}
+
+
+/* Write the manifest out as JSON to a file. */
+func (m *Manifest) Write(filename string) error {
+    data, err := json.MarshalIndent(m, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    return os.WriteFile(filename, append(data, '\n'), 0644)
+}
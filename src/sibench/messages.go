@@ -31,6 +31,7 @@ const(
     OP_Discovery
     OP_StatDetails
     OP_StatDetailsDone
+    OP_StatDetailsAck
     OP_StatSummaryStart
     OP_StatSummaryStop
 
@@ -45,6 +46,16 @@ const(
     OP_ReadWriteStop
     OP_Delete
     OP_Terminate
+    OP_BucketOpsStart
+    OP_BucketOpsStop
+    OP_Verify
+    OP_StatOpsStart
+    OP_StatOpsStop
+    OP_HoldConnectionsStart
+    OP_HoldConnectionsStop
+
+    // Opcode used between Worker->Foreman and Foreman->Manager when a phase reaches its cap
+    OP_PhaseCapped
 )
 
 
@@ -58,6 +69,7 @@ func (op Opcode) ToString() string {
         case OP_Discovery: return "Discovery"
         case OP_StatDetails: return "StatDetails"
         case OP_StatDetailsDone: return "StatDetailsDone"
+        case OP_StatDetailsAck: return "StatDetailsAck"
         case OP_StatSummaryStart: return "StatSummaryStart"
         case OP_StatSummaryStop: return "StatSummaryStop"
         case OP_Connect: return "Connect"
@@ -70,6 +82,14 @@ func (op Opcode) ToString() string {
         case OP_ReadWriteStop: return "ReadWriteStop"
         case OP_Delete: return "Delete"
         case OP_Terminate: return "Terminate"
+        case OP_BucketOpsStart: return "BucketOpsStart"
+        case OP_BucketOpsStop: return "BucketOpsStop"
+        case OP_Verify: return "Verify"
+        case OP_StatOpsStart: return "StatOpsStart"
+        case OP_StatOpsStop: return "StatOpsStop"
+        case OP_HoldConnectionsStart: return "HoldConnectionsStart"
+        case OP_HoldConnectionsStop: return "HoldConnectionsStop"
+        case OP_PhaseCapped: return "PhaseCapped"
         default: return "Unknown"
     }
 }
@@ -78,9 +98,30 @@ func (op Opcode) ToString() string {
 /* 
  * Standard response type for all TCP messages from the Foreman to the Manager that don't need special 
  * data (such as Stats).  
+ *
+ * Any objects which failed verification since the Foreman's last response come along with it, so
+ * that the report can say which objects they were, and not just how many.
  */
 type ForemanGenericResponse struct {
     Error string
+    VerifyFailures []VerifyFailure
+    WorkerRanges []ObjectRange     // Only in responses to OP_Connect: the objects given to each of our workers.
+}
+
+
+/* A range of object ids, from Start up to (but not including) End. */
+type ObjectRange struct {
+    Start uint64
+    End uint64
+}
+
+
+/* An object which we could not read back, or which did not have the content we expected. */
+type VerifyFailure struct {
+    Id uint64
+    Target string
+    Server string      // Filled in by the Manager, since the Foreman doesn't know its own name.
+    Error string
 }
 
 
@@ -94,27 +135,91 @@ const (
     SP_Prepare
     SP_Read
     SP_Delete
+    SP_BucketCreate
+    SP_BucketDelete
+    SP_ChurnDelete
+    SP_Append
+    SP_Verify
+    SP_Stat
+    SP_HoldConnect
+    SP_Keepalive
+    SP_Prewarm
+    SP_Flush
     SP_Len // Not a phase, but a count of how many phases we have
 )
 
 
 func (sp StatPhase) ToString() string {
     switch sp {
-        case SP_Write:    return "Write"
-        case SP_Prepare:  return "Prepare"
-        case SP_Read:     return "Read"
-        case SP_Delete:   return "Delete"
-        default:          return "Unknown"
+        case SP_Write:          return "Write"
+        case SP_Prepare:        return "Prepare"
+        case SP_Read:           return "Read"
+        case SP_Delete:         return "Delete"
+        case SP_BucketCreate:   return "BucketCreate"
+        case SP_BucketDelete:   return "BucketDelete"
+        case SP_ChurnDelete:    return "ChurnDelete"
+        case SP_Append:         return "Append"
+        case SP_Verify:         return "Verify"
+        case SP_Stat:           return "Stat"
+        case SP_HoldConnect:    return "HoldConnect"
+        case SP_Keepalive:      return "Keepalive"
+        case SP_Prewarm:        return "Prewarm"
+        case SP_Flush:          return "Flush"
+        default:                return "Unknown"
+    }
+}
+
+
+/* Whether the ops in a phase transfer object data, and so have a bandwidth. */
+func (sp StatPhase) MovesData() bool {
+    switch sp {
+        case SP_BucketCreate, SP_BucketDelete, SP_ChurnDelete, SP_Stat, SP_HoldConnect, SP_Keepalive, SP_Flush:
+            return false
+    }
+
+    return true
+}
+
+
+/*
+ * Whether the number of ops in a phase depends on how long it runs for.  Verification reads each
+ * object once, held connections are opened once each, and churn deletes and flushes only ever
+ * happen alongside other ops, so none of them are.
+ */
+func (sp StatPhase) IsTimed() bool {
+    switch sp {
+        case SP_Verify, SP_HoldConnect, SP_ChurnDelete, SP_Flush:
+            return false
+    }
+
+    return true
+}
+
+
+/* Return the number of bytes that each op in a phase transfers, given our mean object and read sizes. */
+func (sp StatPhase) OpSize(objectSize uint64, readSize uint64) uint64 {
+    switch {
+        case sp == SP_Read:     return readSize
+        case !sp.MovesData():   return 0
+        default:                return objectSize
     }
 }
 
 
-/* An enum of the types of errors we count for stats purposes. */
+/*
+ * An enum of the types of errors we count for stats purposes.
+ *
+ * The HTTP error types are only used if finer-grained HTTP stats have been requested: otherwise
+ * all failed operations are counted as SE_OperationFailure.
+ */
 type StatError uint8
 const (
     SE_None = iota
     SE_VerifyFailure    // When we read back data and get unexpected content
     SE_OperationFailure // When we hit a non-fatal error reading or writing
+    SE_HttpClientError  // When an HTTP request fails with a 4xx status (other than throttling)
+    SE_HttpServerError  // When an HTTP request fails with a 5xx status (other than throttling)
+    SE_HttpThrottled    // When an HTTP request fails with a 429 or 503 status
     SE_Len              // Not an error code, but a count of how many error codes we have
 )
 
@@ -124,6 +229,9 @@ func (se StatError) ToString() string {
         case SE_None:               return "None"
         case SE_VerifyFailure:      return "Verify"
         case SE_OperationFailure:   return "Operation"
+        case SE_HttpClientError:    return "HttpClient"
+        case SE_HttpServerError:    return "HttpServer"
+        case SE_HttpThrottled:      return "HttpThrottled"
         default:                    return "Unknown"
     }
 }
@@ -138,23 +246,48 @@ type StatSummary [SP_Len][SE_Len] uint64
 /*
  * The fully-detailed stats that we send on Job completion.
  * Each stat describes a single operation (such as a single object read or write).
+ *
+ * We may hold millions of these, so keep them small.
  */
 type Stat struct {
     Phase StatPhase
     Error StatError
+    Retries uint8       // How many times the op was retried after transient failures, before this attempt.
     TargetIndex uint16
+    WorkerIndex uint16  // The index of the worker which made the op, amongst those on its server.
     TimeSincePhaseStartMillis uint32
     DurationMicros uint32
+    Size uint32         // The size of the object in bytes, or 0 if it was too large to record.
 }
 
 
 /*
- * A Foreman's response to a discovery request
+ * A Foreman's response to a discovery request.  The library versions are empty on platforms
+ * where we don't link against the Ceph libraries.
  */
 type Discovery struct {
     Cores uint64
     Ram uint64
     Version string
+    GoVersion string
+    LibradosVersion string
+    LibrbdVersion string
+}
+
+
+/*
+ * A Foreman's usage of its own resources, sampled once per second whilst summaries are enabled.
+ * This is sent as the data of the StatDetailsDone message, so that we can tell when the sibench
+ * drivers themselves are the bottleneck, rather than the storage under test.
+ *
+ * We keep totals rather than averages so that the usage from several phases can be merged.
+ */
+type ResourceUsage struct {
+    Samples uint64
+    TotalCpuPercent float64     // CPU usage as a percentage of all the cores in the driver.
+    PeakCpuPercent float64
+    TotalRss uint64             // Resident set size in bytes.
+    PeakRss uint64
 }
 
 
@@ -171,10 +304,44 @@ type WorkOrder struct {
     WorkerFactor float64            // Number of workers to create for each core on a server.
     SkipReadValidation bool         // Whether to skip the validation step when we read objects.
     ReadWriteMix uint64             // Give the percentage of reads vs writes for combined ops. 
+    ConnectionReuse bool            // If false, we open a fresh connection for every operation.
+    HttpErrorStats bool             // Whether to break down HTTP failures by status code in our stats.
+    VerifyOverwrites bool           // Whether reads should check they see the latest cycle of each object.
+    HotSetSize uint64               // If non-zero, reads only touch this many objects from the start of the range.
+    HotSetRandom bool               // Whether to read the hot set in random order, rather than cycling through it.
+    ReadRandom bool                 // Whether each worker reads its range in a shuffled order (seeded), rather than by id.
+    NoWrite bool                    // Whether our objects were written by an earlier run, so that we only read them.
+    StatUploadWindow uint64         // The maximum number of StatDetails messages each foreman may have unacked.
+    ThinkTime *ThinkTime            // The pause each worker takes between ops, or nil for none.
+    BurstOps uint64                 // If non-zero, each worker runs this many ops flat out, then idles, and repeats.
+    BurstIdleMillis uint64          // How long each worker idles between bursts.
+    QueueDepth uint64               // The number of reads or writes each worker keeps in flight.
+    ReadAhead uint64                // The number of reads each worker keeps in flight, verifying each as it completes.
+    PinWorkers bool                 // Whether each worker's goroutines are locked to threads pinned to one CPU.
+    OpTimeoutMillis uint64          // If non-zero, ops which take longer than this many milliseconds are failures.
+    MaxRetries uint64               // How many times to retry an op, with backoff, after a transient failure.
+    BucketOps uint64                // If non-zero, we benchmark bucket creates and deletes, keeping this many buckets per worker.
+    Churn float64                   // The fraction of read phase ops which delete an object instead, to model churn.
+    RampShape string                // How timed phases ramp up: "discard" runs flat out, "linear" ramps the bandwidth limit.
+    RampUp uint64                   // The ramp-up time of timed phases, in seconds.
+    StatCompression string          // How foremen compress the stats they send us: "none" or "gzip".
+    MaxConcurrencyPerTarget uint64  // The most ops each foreman may have in flight to one target, or zero for no limit.
+    AppendObjects uint64            // If non-zero, the write phase appends to this many objects shared by every worker.
+    ExplicitFlush bool              // Whether writes are made without syncing, and then flushed as a separately timed op.
+    VerifyOnly bool                 // Whether to read every object once and verify it, rather than benchmarking.
+    PrewarmReads bool               // Whether to read every object once, untimed, before the timed read phase.
+    StatObjects bool                // Whether to run a timed phase which stats objects before the read phase.
+    PhaseCaps *PhaseCaps            // Limits on how many ops each timed phase may perform, or nil for none.
+    HoldConnections uint64          // If non-zero, each worker holds this many idle connections to each target, rather than benchmarking IO.
+    KeepaliveInterval uint64        // How often we make a keepalive request on each held connection, in seconds.
 
     // Object parameters
-    ObjectKeyPrefix string          // A random prefix to be used for object keys to ensure uniqueness across runs
-    ObjectSize uint64               // The size of the objects we read and write
+    ObjectKeyPrefix string          // A prefix to be used for object keys: random by default, to ensure uniqueness across runs
+    ObjectKeyScheme string          // How we build object keys from the prefix and id: see object_key.go.
+    ObjectSize uint64               // The size of the objects we read and write, or the largest size in a mix.
+    SizeMix *SizeMix                // The mix of object sizes to use, or nil if every object is ObjectSize.
+    PadSize uint64                  // If non-zero, smaller objects are padded with zeroes to this size when we write them.
+    ReadRange *ReadRange            // The part of each object that reads should fetch, or nil for the whole object.
     Seed uint64                     // A seed for any PRNGs in use. 
     GeneratorType string            // Which type of Generator we will use to create and verify object data.
     RangeStart uint64               // Start of the object range to be used.
@@ -183,8 +350,68 @@ type WorkOrder struct {
     // Connection parameters
     ConnectionType string           // The type of connection: s3, librados etc... 
     Targets []string                // The set of gateways, monitors, metadata servers or whatever we connect to. 
+    TargetWeights []uint64          // The relative share of ops for each target, or nil for an even split.
+    TargetSizes []uint64            // The size of the objects on each target, or nil if the targets don't differ.
+    ReferenceTarget string          // If set, reads are compared with the same objects read from here, rather than verified by the generator.
+    ReferenceProtocolConfig ProtocolConfig // The ProtocolConfig for connecting to the reference target, or nil to use ours.
+    ConsistencyStride uint64        // If non-zero, reads of objects whose ids are multiples of this are compared across every target instead.
     ProtocolConfig ProtocolConfig   // Protocol-specific key/value pairs for credential info for making new connection.
     GeneratorConfig GeneratorConfig // Generator-specific key/value pairs.
     CleanUpOnClose bool             // Whether we should clean up at the end of the job.
 }
 
+
+
+/* Return the average size of the objects we use, for working out bandwidth from op counts. */
+func (o *WorkOrder) MeanObjectSize() uint64 {
+    switch {
+        case o.SizeMix != nil:      return o.SizeMix.MeanSize()
+        case o.TargetSizes != nil:  return o.meanTargetSize()
+        default:                    return o.ObjectSize
+    }
+}
+
+
+/* Return the average size of our objects when each target has its own size, weighted by each target's share of the ops. */
+func (o *WorkOrder) meanTargetSize() uint64 {
+    total := uint64(0)
+    weights := uint64(0)
+
+    for i, size := range o.TargetSizes {
+        weight := uint64(1)
+        if o.TargetWeights != nil {
+            weight = o.TargetWeights[i]
+        }
+
+        total += size * weight
+        weights += weight
+    }
+
+    return total / weights
+}
+
+
+/*
+ * Return the size of an object once it has been padded to PadSize, which is how many bytes we
+ * actually put and get.
+ */
+func (o *WorkOrder) PaddedSize(size uint64) uint64 {
+    if size < o.PadSize {
+        return o.PadSize
+    }
+
+    return size
+}
+
+
+/* Return the average number of bytes fetched by each read, which is less than the object size for ranged reads. */
+func (o *WorkOrder) MeanReadSize() uint64 {
+    size := o.MeanObjectSize()
+
+    switch {
+        case o.ReadRange == nil:            return size
+        case o.ReadRange.Fraction > 0:      return uint64(o.ReadRange.Fraction * float64(size))
+        case o.ReadRange.Length < size:     return o.ReadRange.Length
+        default:                            return size
+    }
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "logger"
+import "net"
+import "net/http"
+import "sort"
+import "strings"
+import "sync"
+
+
+/*
+ * A MetricsExporter serves the Manager's per-second stats in the Prometheus text format, so that
+ * live throughput can be graphed (in Grafana, say) whilst a run is in progress.
+ *
+ * All of our metrics are gauges, describing the last second of the run, and labelled with the
+ * phase, the name of the sibench server and the run's lineage.  We only serve metrics whilst a run
+ * is active.
+ */
+type MetricsExporter struct {
+    mutex sync.Mutex
+    listener net.Listener
+    objectSize uint64
+    readSize uint64
+    lineage Lineage
+
+    /* The summaries for the last complete second of the run, keyed by server name. */
+    summaries map[string]StatSummary
+}
+
+
+/* Description of each of the gauges that we export. */
+type metricDetails struct {
+    name string
+    help string
+    value func(s *StatSummary, phase StatPhase, objectSize uint64) uint64
+}
+
+
+var metrics = []metricDetails {
+    { "sibench_bandwidth_bytes", "Bytes per second transferred by successful operations.",
+        func(s *StatSummary, phase StatPhase, objectSize uint64) uint64 { return s[phase][SE_None] * objectSize } },
+    { "sibench_ops", "Successful operations per second.",
+        func(s *StatSummary, phase StatPhase, objectSize uint64) uint64 { return s[phase][SE_None] } },
+    { "sibench_failures", "Failed operations (including verification failures) per second.",
+        func(s *StatSummary, phase StatPhase, objectSize uint64) uint64 {
+            return s.OperationFailures(phase) + s[phase][SE_VerifyFailure]
+        } },
+}
+
+
+/*
+ * Start serving metrics over HTTP on the given port.
+ *
+ * We create the listening socket before returning so that we can report any error with it.
+ * The server itself runs in its own go-routine until Close is called.
+ */
+func StartMetricsExporter(port uint16, objectSize uint64, readSize uint64, lineage Lineage) (*MetricsExporter, error) {
+    var e MetricsExporter
+    var err error
+
+    e.objectSize = objectSize
+    e.readSize = readSize
+    e.lineage = lineage
+    e.summaries = make(map[string]StatSummary)
+
+    e.listener, err = net.Listen("tcp", fmt.Sprintf(":%v", port))
+    if err != nil {
+        return nil, err
+    }
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/metrics", e.handleMetrics)
+
+    logger.Infof("Serving Prometheus metrics on :%v\n", port)
+    go http.Serve(e.listener, mux)
+
+    return &e, nil
+}
+
+
+/* Stop serving metrics. */
+func (e *MetricsExporter) Close() {
+    e.listener.Close()
+}
+
+
+/* Replace our metrics with those from the latest second of the run. */
+func (e *MetricsExporter) Update(summaries map[string]*StatSummary) {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+
+    e.summaries = make(map[string]StatSummary)
+    for name, s := range summaries {
+        e.summaries[name] = *s
+    }
+}
+
+
+func (e *MetricsExporter) handleMetrics(w http.ResponseWriter, req *http.Request) {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+
+    // Sort the servers so that our output is stable.
+    servers := make([]string, 0, len(e.summaries))
+    for name, _ := range e.summaries {
+        servers = append(servers, name)
+    }
+
+    sort.Strings(servers)
+
+    var b strings.Builder
+    lineage := e.lineage.Labels()
+
+    for _, m := range metrics {
+        fmt.Fprintf(&b, "# HELP %v %v\n", m.name, m.help)
+        fmt.Fprintf(&b, "# TYPE %v gauge\n", m.name)
+
+        for _, server := range servers {
+            s := e.summaries[server]
+            for phase := StatPhase(0); phase < SP_Len; phase++ {
+                size := phase.OpSize(e.objectSize, e.readSize)
+
+                fmt.Fprintf(&b, "%v{phase=%q,server=%q,%v} %v\n", m.name, phase.ToString(), server, lineage, m.value(&s, phase, size))
+            }
+        }
+    }
+
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+    w.Write([]byte(b.String()))
+}
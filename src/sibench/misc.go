@@ -22,6 +22,33 @@ func previousPowerOfTwo(x uint64) uint64 {
 }
 
 
+/**
+ * When dividing an object range between servers or workers, work out how much of a hot set of
+ * objects belongs to the sub-range [start, end) of the range [rangeStart, rangeEnd).
+ *
+ * The shares add up to the whole hot set, except that every non-empty sub-range gets at least one
+ * object, so that no worker is left with nothing to read.
+ */
+func hotSetShare(hotSet uint64, rangeStart uint64, rangeEnd uint64, start uint64, end uint64) uint64 {
+    if (hotSet == 0) || (end <= start) {
+        return 0
+    }
+
+    rangeLen := rangeEnd - rangeStart
+    share := (hotSet * (end - rangeStart) / rangeLen) - (hotSet * (start - rangeStart) / rangeLen)
+
+    if share < 1 {
+        share = 1
+    }
+
+    if share > end - start {
+        share = end - start
+    }
+
+    return share
+}
+
+
 /* Convert values into to K, G, M etc. units */
 func ToUnits(val uint64) string {
     const unit = 1024
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for our miscellaneous helpers.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+// Split the range [rangeStart, rangeEnd) into n sub-ranges, and add up their shares of a hot set.
+func sumHotSetShares(hotSet uint64, rangeStart uint64, rangeEnd uint64, n uint64) uint64 {
+    var sum uint64
+    rangeLen := rangeEnd - rangeStart
+
+    for i := uint64(0); i < n; i++ {
+        start := rangeStart + (i * rangeLen / n)
+        end := rangeStart + ((i + 1) * rangeLen / n)
+        sum += hotSetShare(hotSet, rangeStart, rangeEnd, start, end)
+    }
+
+    return sum
+}
+
+
+// Test functions.
+
+// Each sub-range gets its proportion of the hot set, but at least one object, and no more than it holds.
+func TestHotSetShare(t *testing.T) {
+    tests := []struct {
+        hotSet uint64
+        rangeStart uint64
+        rangeEnd uint64
+        start uint64
+        end uint64
+        expected uint64
+    } {
+        { 0, 0, 100, 0, 100, 0 },           // No hot set.
+        { 10, 0, 100, 50, 50, 0 },          // An empty sub-range.
+        { 10, 0, 100, 0, 100, 10 },         // The whole range.
+        { 10, 0, 100, 0, 50, 5 },
+        { 10, 0, 100, 50, 100, 5 },
+        { 10, 0, 100, 0, 33, 3 },           // Thirds, where the rounding has to come out somewhere.
+        { 10, 0, 100, 33, 66, 3 },
+        { 10, 0, 100, 66, 100, 4 },
+        { 10, 1000, 1100, 1050, 1100, 5 },  // A range which doesn't start at zero.
+        { 2, 0, 100, 10, 20, 1 },           // Too small a share still gets one object.
+        { 200, 0, 100, 0, 5, 5 },           // Too large a share is capped at the sub-range.
+    }
+
+    for _, test := range tests {
+        share := hotSetShare(test.hotSet, test.rangeStart, test.rangeEnd, test.start, test.end)
+        testutil.CheckInt(t, int(test.expected), int(share))
+    }
+}
+
+
+// When every sub-range's share comes to at least one object anyway, the shares add up to the hot set.
+func TestHotSetShareSum(t *testing.T) {
+    for _, hotSet := range []uint64{ 10, 64, 500 } {
+        for _, n := range []uint64{ 1, 3, 7 } {
+            testutil.CheckInt(t, int(hotSet), int(sumHotSetShares(hotSet, 100, 1100, n)))
+        }
+    }
+}
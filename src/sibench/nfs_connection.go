@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "logger"
+import "net"
+import "os"
+import "path/filepath"
+import "strings"
+
+
+/*
+ * A Connection for testing NFS exports.
+ *
+ * Targets are of the form server:/export.  We mount the export ourselves, and then hand over to
+ * FileConnectionBase, in the same way as CephFSConnection.
+ */
+type NFSConnection struct {
+    FileConnectionBase
+    protocol ProtocolConfig
+    worker WorkerConnectionConfig
+    target string
+    server string
+    export string
+    mountPoint string
+}
+
+
+func NewNFSConnection(target string, protocol ProtocolConfig, worker WorkerConnectionConfig) (*NFSConnection, error) {
+    server, export, found := strings.Cut(target, ":")
+    if !found || (server == "") || (export == "") {
+        return nil, fmt.Errorf("Bad NFS target: %v.  Should be of the form server:/export", target)
+    }
+
+    var conn NFSConnection
+    conn.protocol = protocol
+    conn.worker = worker
+    conn.target = target
+    conn.server = server
+    conn.export = export
+    conn.mountPoint = filepath.Join(globalConfig.MountsDir, target)
+    return &conn, nil
+}
+
+
+func (conn *NFSConnection) Target() string {
+    return conn.target
+}
+
+
+func (conn *NFSConnection) ManagerConnect() error {
+    err := conn.WorkerConnect()
+    if err != nil {
+        return err
+    }
+
+    // As with CephFS, we unmount again as soon as we've created our directories, rather than holding
+    // the mount open until ManagerClose().
+
+    err1 := conn.CreateDirectories()
+    err2 := conn.WorkerClose(false)
+    if err1 != nil {
+        return err1
+    }
+
+    return err2
+}
+
+
+func (conn *NFSConnection) ManagerClose(cleanup bool) error {
+    err := conn.WorkerConnect()
+    if err != nil {
+        return err
+    }
+
+    if cleanup {
+        err = conn.DeleteDirectories()
+    }
+
+    err2 := conn.WorkerClose(cleanup)
+    if err != nil {
+        return err
+    }
+
+    return err2
+}
+
+
+func (conn *NFSConnection) WorkerConnect() error {
+    logger.Debugf("Creating nfs connection to %v in %v\n", conn.target, conn.mountPoint)
+
+    if mountManager.Acquire(conn.mountPoint) {
+        // The mount doesn't exist yet, and we've been told to create it.
+
+        // First ensure our mount point exists
+        _, err := os.Stat(conn.mountPoint)
+        if os.IsNotExist(err) {
+            err = os.MkdirAll(conn.mountPoint, 0755)
+            if err != nil {
+                logger.Errorf("Unable to create mount point %v: %v\n", conn.mountPoint, err)
+                mountManager.MountComplete(conn.mountPoint, false)
+                return err
+            }
+        }
+
+        // The kernel's NFS client can't resolve names, so we have to look up the server's address
+        // and pass it in the options.
+
+        server_ips, err := net.LookupHost(conn.server)
+        if err != nil {
+            logger.Errorf("Failure resolving %v: %v\n", conn.server, err)
+            mountManager.MountComplete(conn.mountPoint, false)
+            return err
+        }
+
+        options := "addr=" + server_ips[0]
+        if conn.protocol["options"] != "" {
+            options += "," + conn.protocol["options"]
+        }
+
+        logger.Debugf("NFSConnection mounting with server: %v, export: %v, mountpoint: %v, options: %v\n", server_ips[0], conn.export, conn.mountPoint, options)
+
+        err = Mount(server_ips[0] + ":" + conn.export, conn.mountPoint, "nfs", 0, options)
+        if err != nil {
+            logger.Errorf("Failure mounting NFS: %v\n", err)
+            mountManager.MountComplete(conn.mountPoint, false)
+            return err
+        }
+
+        mountManager.MountComplete(conn.mountPoint, true)
+    }
+
+    // Tell our FileConnection delegate which directories to use for its root and its dir within that root.
+    conn.InitFileConnectionBase(conn.mountPoint, conn.protocol["dir"], conn.worker.ExplicitFlush)
+    return nil
+}
+
+
+func (conn *NFSConnection) WorkerClose(cleanup bool) error {
+    logger.Debugf("Closing nfs connection to %v\n", conn.target)
+
+    if mountManager.Release(conn.mountPoint) {
+        logger.Debugf("Unmounting %v\n", conn.mountPoint)
+        Unmount(conn.mountPoint, 0)
+        mountManager.UnmountComplete(conn.mountPoint)
+    }
+
+    return nil
+}
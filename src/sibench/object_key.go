@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+
+
+/*
+ * Key schemes determine how we turn an object's id into its key, for the backends that use keys.
+ *
+ *   sequential  <prefix>-<id>.  Keys sort in id order, which is easy to read, but some object
+ *               stores split their index by key range, and so concentrate our load on one shard.
+ *   hashed      <hash>-<prefix>-<id>, where hash is 8 hex digits derived from the id.  This
+ *               spreads consecutive ids across the key space.
+ *   uuid        <prefix>-<uuid>, where the uuid-style string is derived from the id.
+ *
+ * The hash depends only on the id, so any worker (or external tool) can work out the key for an
+ * object.  It is three rounds of our xorshift prng, starting from id + 1 (since the prng maps 0 to
+ * itself).  The prng is a bijection, so every id still gets a different key.
+ */
+const keySchemeSequential = "sequential"
+const keySchemeHashed = "hashed"
+const keySchemeUuid = "uuid"
+
+
+func validateKeyScheme(scheme string) error {
+    switch scheme {
+        case keySchemeSequential, keySchemeHashed, keySchemeUuid:
+            return nil
+    }
+
+    return fmt.Errorf("Bad key scheme: %v.  Should be %v, %v or %v", scheme, keySchemeSequential, keySchemeHashed, keySchemeUuid)
+}
+
+
+/* Return the hash of an object's id that we use to build its key. */
+func keyHash(id uint64) uint64 {
+    next := prng(id + 1)
+    next = prng(next)
+    next = prng(next)
+    return next
+}
+
+
+/* Return the key for an object. */
+func ObjectKey(scheme string, prefix string, id uint64) string {
+    switch scheme {
+        case keySchemeHashed:
+            return fmt.Sprintf("%08x-%v-%v", keyHash(id) >> 32, prefix, id)
+
+        case keySchemeUuid:
+            h1 := keyHash(id)
+            h2 := prng(h1)
+            return fmt.Sprintf("%v-%08x-%04x-%04x-%04x-%012x", prefix, h1 >> 32, (h1 >> 16) & 0xffff, h1 & 0xffff, h2 >> 48, h2 & 0xffffffffffff)
+
+        default:
+            return fmt.Sprintf("%v-%v", prefix, id)
+    }
+}
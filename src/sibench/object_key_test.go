@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for building object keys from their ids.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Test functions.
+
+func TestValidateKeyScheme(t *testing.T) {
+    tests := []struct {
+        scheme string
+        good bool
+    } {
+        { "sequential", true },
+        { "hashed", true },
+        { "uuid", true },
+        { "", false },
+        { "random", false },
+        { "Hashed", false },
+    }
+
+    for _, test := range tests {
+        err := validateKeyScheme(test.scheme)
+        testutil.CheckBool(t, test.good, err == nil)
+    }
+}
+
+
+// External tools regenerate keys from a manifest, so the keys for a given id must never change.
+func TestObjectKey(t *testing.T) {
+    tests := []struct {
+        scheme string
+        prefix string
+        id uint64
+        expected string
+    } {
+        { "sequential", "obj", 0, "obj-0" },
+        { "sequential", "obj", 12345, "obj-12345" },
+        { "hashed", "obj", 0, "9b1e842f-obj-0" },
+        { "hashed", "obj", 1, "363d895a-obj-1" },
+        { "hashed", "run7", 12345, "0f0b047f-run7-12345" },
+        { "uuid", "obj", 0, "obj-9b1e842f-6e86-2629-f554-f503555d8025" },
+        { "uuid", "obj", 2, "obj-ad230d75-bb88-4e3b-8ff5-d727dbe5b067" },
+        { "uuid", "run7", 12345, "run7-0f0b047f-6c09-ba8a-af00-aff131104b7f" },
+    }
+
+    for _, test := range tests {
+        testutil.CheckString(t, test.expected, ObjectKey(test.scheme, test.prefix, test.id))
+    }
+}
+
+
+// Every id must get its own key, whichever scheme we use.
+func TestObjectKeyUnique(t *testing.T) {
+    for _, scheme := range []string{ keySchemeSequential, keySchemeHashed, keySchemeUuid } {
+        seen := make(map[string]bool)
+
+        for id := uint64(0); id < 100000; id++ {
+            key := ObjectKey(scheme, "obj", id)
+            if seen[key] {
+                t.Errorf("Key scheme %v gave key %v to more than one id", scheme, key)
+            }
+
+            seen[key] = true
+        }
+    }
+}
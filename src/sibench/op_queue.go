@@ -0,0 +1,591 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "context"
+import "errors"
+import "fmt"
+import "logger"
+import "net"
+import "os"
+import "syscall"
+import "time"
+
+
+/* How long we wait before retrying a failed op for the first time.  This doubles with each retry. */
+const opRetryBackoff = 100 * time.Millisecond
+
+/* The most retries we allow, so that an op's backoff can't approach the time after which its worker is taken to have hung. */
+const maxOpRetries = 5
+
+
+/*
+ * A workerOp holds everything needed to perform a single read, write or delete, and its results, so that
+ * the op can be run on a lane rather than on the worker's own goroutine.
+ */
+type workerOp struct {
+    phase StatPhase
+    id uint64
+    key string
+    conn Connection
+    connIndex uint64
+    size uint64             // The size of the object.
+    padded uint64           // The size of the object on the wire, which is larger if we pad it.
+    offset uint64           // Where a ranged read starts.
+    length uint64           // How many bytes we transfer.
+    cycle uint64            // The cycle we write, or the cycle we expect to read (which may be AnyCycle).
+
+    start time.Time
+    end time.Time
+    err error               // Any error from the connection.
+    verifyErr error         // Any error from verifying a read.
+
+    flushStart time.Time    // When we started flushing a write, or zero if we didn't.
+    flushEnd time.Time
+    flushErr error          // Any error from flushing a write.
+    retries uint64          // How many times we retried the op after transient failures.
+
+    buffer []byte           // With read ahead, the buffer that a read fetches its object into.
+}
+
+
+/*
+ * With a queue depth of more than one, each worker keeps that many ops in flight by handing them
+ * to a pool of goroutines, which we call lanes.  Each lane has its own buffers.
+ *
+ * Only the work that needs the buffers - generating, transferring and verifying the data - is
+ * done on a lane.  Choosing the next object, and recording stats and summaries once an op
+ * completes, are still done on the worker's own goroutine, so that none of the worker's state
+ * (including the preallocated stats that nextStat hands out) is ever shared.
+ *
+ * Read ahead uses lanes too, but they only fetch the data for reads.  Each read is verified on
+ * the worker's own goroutine once it completes, while the lanes carry on with the reads after it,
+ * so that the network latency of our reads overlaps with the CPU time of our verification.  Since
+ * a read's data has to outlive its lane's part in it, the buffers belong to the reads rather than
+ * to the lanes.  Anything other than a read waits for the reads in flight, and then runs
+ * synchronously.
+ *
+ * With a queue depth and read ahead of one, we don't start any lanes, and ops run synchronously
+ * as before.
+ */
+func (w *Worker) startLanes() {
+    if w.laneCount() <= 1 {
+        return
+    }
+
+    w.laneOps = make(chan *workerOp)
+    w.completions = make(chan *workerOp, w.laneCount())
+    w.inFlightIds = make(map[uint64]bool)
+
+    if w.order.ReadAhead > 1 {
+        // The worker's own buffer is free for a read whenever a synchronous op isn't using it.
+        w.readBuffers = [][]byte{ w.objectBuffer }
+
+        for i := uint64(1); i < w.order.ReadAhead; i++ {
+            w.readBuffers = append(w.readBuffers, make([]byte, w.order.PaddedSize(w.order.ObjectSize)))
+        }
+
+        for i := uint64(0); i < w.order.ReadAhead; i++ {
+            go w.runFetchLane()
+        }
+
+        return
+    }
+
+    // The first lane uses the worker's own buffers, since it doesn't need them itself.
+    go w.runLane(w.objectBuffer, w.verifyBuffer)
+
+    for i := uint64(1); i < w.order.QueueDepth; i++ {
+        go w.runLane(make([]byte, w.order.PaddedSize(w.order.ObjectSize)), make([]byte, w.order.PaddedSize(w.order.ObjectSize)))
+    }
+}
+
+
+/* The number of ops we keep in flight at once. */
+func (w *Worker) laneCount() uint64 {
+    if w.order.ReadAhead > 1 {
+        return w.order.ReadAhead
+    }
+
+    return w.order.QueueDepth
+}
+
+
+/* Tell our lanes to exit.  We must have drained them first. */
+func (w *Worker) stopLanes() {
+    if w.laneOps != nil {
+        close(w.laneOps)
+    }
+}
+
+
+func (w *Worker) runLane(objectBuffer []byte, verifyBuffer []byte) {
+    // Our lanes share the worker's CPU.  The worker's own goroutine reports any failure to pin.
+    if w.order.PinWorkers {
+        PinThreadToCpu(w.cpu)
+    }
+
+    for op := range w.laneOps {
+        w.performOp(op, objectBuffer, verifyBuffer)
+        w.completions <- op
+    }
+}
+
+
+/* Run a lane for read ahead, which only fetches the data for reads, into the buffers that they bring. */
+func (w *Worker) runFetchLane() {
+    if w.order.PinWorkers {
+        PinThreadToCpu(w.cpu)
+    }
+
+    for op := range w.laneOps {
+        w.retryOp(op, func() { w.fetchRead(op, op.buffer) })
+        w.completions <- op
+    }
+}
+
+
+/*
+ * Run an op: either straight away, or by handing it to a lane.  If all our lanes are busy, then we
+ * wait for one of them to complete an op first.
+ *
+ * We also wait if we already have an op in flight for the same object.  That can happen if one op
+ * is slow enough for the others to wrap around our range, and the two ops would then race.  The
+ * exception is appends, which are meant to contend for the same objects.
+ */
+func (w *Worker) runOp(op *workerOp) {
+    if w.phaseOpsLeft != Uncapped {
+        w.phaseOpsLeft--
+    }
+
+    synchronous := w.laneOps == nil
+    if (w.order.ReadAhead > 1) && !isReadPhase(op.phase) {
+        w.drainOps()
+        synchronous = true
+    }
+
+    if synchronous {
+        w.startOpCycle(op)
+        w.performOp(op, w.objectBuffer, w.verifyBuffer)
+        w.completeOp(op)
+        return
+    }
+
+    for (w.inFlight >= w.laneCount()) || (w.inFlightIds[op.id] && (op.phase != SP_Append)) {
+        w.completeLaneOp(<-w.completions)
+    }
+
+    if w.order.ReadAhead > 1 {
+        op.buffer = w.readBuffers[len(w.readBuffers) - 1]
+        w.readBuffers = w.readBuffers[:len(w.readBuffers) - 1]
+    }
+
+    w.startOpCycle(op)
+    w.inFlight++
+    w.inFlightIds[op.id] = true
+    w.laneOps <- op
+}
+
+
+/* Record an op that one of our lanes has completed, verifying it first if it was read ahead. */
+func (w *Worker) completeLaneOp(op *workerOp) {
+    w.inFlight--
+    delete(w.inFlightIds, op.id)
+
+    if op.buffer != nil {
+        w.verifyRead(op, op.buffer, w.verifyBuffer)
+        w.readBuffers = append(w.readBuffers, op.buffer)
+        op.buffer = nil
+    }
+
+    w.completeOp(op)
+}
+
+
+/* Wait for all our in-flight ops to complete, so that we can change state. */
+func (w *Worker) drainOps() {
+    for w.inFlight > 0 {
+        w.completeLaneOp(<-w.completions)
+    }
+}
+
+
+/* Whether ops in a phase are reads, which fetch an object's data (and usually verify it). */
+func isReadPhase(phase StatPhase) bool {
+    return (phase == SP_Read) || (phase == SP_Verify) || (phase == SP_Prewarm)
+}
+
+
+/* Do the timed part of an op.  This may run on a lane, so must not touch the worker's state. */
+func (w *Worker) performOp(op *workerOp, objectBuffer []byte, verifyBuffer []byte) {
+    switch op.phase {
+        case SP_Read, SP_Verify, SP_Prewarm:    w.performRead(op, objectBuffer, verifyBuffer)
+        case SP_ChurnDelete:        w.retryOp(op, func() { w.performDelete(op) })
+        case SP_Append:             w.performAppend(op, objectBuffer)
+        case SP_Stat:               w.retryOp(op, func() { w.performStat(op) })
+        default:                    w.retryOp(op, func() { w.performWrite(op, objectBuffer) })
+    }
+}
+
+
+/*
+ * Make an attempt at the timed part of an op, and then retry it after any transient failure, up to
+ * our max retries.  We back off before each retry, doubling the wait each time, so that a throttled
+ * or overloaded target has a chance to recover.
+ *
+ * Each attempt sets the op's start and end afresh, so that only the last attempt is timed, and the
+ * backoff is left out of the response time.  The retries are counted on the op instead.  Reads are
+ * only retried if the fetch fails: data which fails verification is never retried, since that would
+ * hide exactly what we are looking for.
+ *
+ * Appends are never retried: an attempt which timed out may still have landed, and appending the
+ * record a second time would corrupt the object.
+ */
+func (w *Worker) retryOp(op *workerOp, attempt func()) {
+    backoff := opRetryBackoff
+
+    attempt()
+    for (op.err != nil) && (op.retries < w.order.MaxRetries) && isTransient(w.ctx, op.err) {
+        logger.Debugf("[worker %v] failure %v on object<%v> on %v: %v.  Retrying in %v\n", w.spec.Id, op.retries + 1, op.id, op.conn.Target(), op.err, backoff)
+
+        time.Sleep(backoff)
+        backoff *= 2
+
+        op.retries++
+        op.err = nil
+        attempt()
+    }
+}
+
+
+/*
+ * Whether a failed op might succeed if we try it again.  We only retry failures which are down to
+ * the network or to a busy target: timeouts, refused or reset connections, EAGAIN, and HTTP server
+ * errors or throttling.  Ceph reports the same errnos as negative error codes of its own.  Anything
+ * else, such as a missing object or a rejected request, would only fail again.  Once the worker's
+ * context has been cancelled, the phase is over, and nothing is retried.
+ */
+func isTransient(ctx context.Context, err error) bool {
+    if ctx.Err() != nil {
+        return false
+    }
+
+    for ; err != nil; err = unwrapOpError(err) {
+        if err == context.Canceled {
+            return false
+        }
+
+        if httpErr, ok := err.(HttpStatusError); ok {
+            code := httpErr.StatusCode()
+            return (code == 429) || ((code >= 500) && (code < 600))
+        }
+
+        if errno, ok := err.(syscall.Errno); ok {
+            return isTransientErrno(errno)
+        }
+
+        if cephErr, ok := err.(interface{ ErrorCode() int }); ok {
+            return isTransientErrno(syscall.Errno(-cephErr.ErrorCode()))
+        }
+
+        // This includes an op timeout, which is a context.DeadlineExceeded.
+        if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+            return true
+        }
+    }
+
+    return false
+}
+
+
+/* Whether an errno means that the target was briefly unreachable, or too busy to answer. */
+func isTransientErrno(errno syscall.Errno) bool {
+    return (errno == syscall.ECONNRESET) || (errno == syscall.ECONNREFUSED) || (errno == syscall.ETIMEDOUT) || (errno == syscall.EAGAIN)
+}
+
+
+/*
+ * Whether an op failed because its object doesn't exist.  Connections report this in their own
+ * ways: a filesystem's ENOENT, an HTTP 404, or a Ceph error code of -ENOENT.
+ */
+func isNotFound(err error) bool {
+    if errors.Is(err, os.ErrNotExist) {
+        return true
+    }
+
+    for ; err != nil; err = unwrapOpError(err) {
+        if httpErr, ok := err.(HttpStatusError); ok {
+            return httpErr.StatusCode() == 404
+        }
+
+        if cephErr, ok := err.(interface{ ErrorCode() int }); ok {
+            return cephErr.ErrorCode() == -int(syscall.ENOENT)
+        }
+    }
+
+    return false
+}
+
+
+/*
+ * Return the error that an op error wraps, or nil if it doesn't wrap one.  The AWS SDK's errors
+ * don't support errors.Unwrap, and so we also follow their OrigErr.
+ */
+func unwrapOpError(err error) error {
+    switch e := err.(type) {
+        case interface{ Unwrap() error }:   return e.Unwrap()
+        case interface{ OrigErr() error }:  return e.OrigErr()
+        default:                            return nil
+    }
+}
+
+
+func (w *Worker) performWrite(op *workerOp, objectBuffer []byte) {
+    buffer := objectBuffer[:op.padded:op.padded]
+    data := buffer[:op.size:op.size]
+    w.generator.Generate(op.size, op.id, op.cycle, &data)
+    zeroPadding(buffer, op.size)
+
+    logger.Tracef("[worker %v] starting put for object<%v> on %v at %v\n", w.spec.Id, op.id, op.conn.Target(), time.Now())
+
+    w.spec.TargetLimiter.Acquire(op.connIndex)
+    op.start = time.Now()
+    op.err = w.reconnect(op.conn)
+    if op.err == nil {
+        op.err = op.conn.PutObject(w.ctx, op.key, op.id, buffer)
+    }
+    op.end = time.Now()
+
+    if (op.err == nil) && w.order.ExplicitFlush {
+        op.flushStart = op.end
+        op.flushErr = op.conn.(FlushConnection).FlushObject(op.key, op.id)
+        op.flushEnd = time.Now()
+    }
+    w.spec.TargetLimiter.Release(op.connIndex)
+
+    logger.Tracef("[worker %v] completed put for object<%v> on %v\n", w.spec.Id, op.id, op.conn.Target())
+}
+
+
+func (w *Worker) performAppend(op *workerOp, objectBuffer []byte) {
+    buffer := objectBuffer[:op.size:op.size]
+    w.generator.Generate(op.size, op.id, op.cycle, &buffer)
+
+    logger.Tracef("[worker %v] starting append for object<%v> on %v\n", w.spec.Id, op.id, op.conn.Target())
+
+    w.spec.TargetLimiter.Acquire(op.connIndex)
+    op.start = time.Now()
+    op.err = w.reconnect(op.conn)
+    if op.err == nil {
+        op.err = op.conn.(AppendConnection).AppendObject(op.key, op.id, buffer)
+    }
+    op.end = time.Now()
+    w.spec.TargetLimiter.Release(op.connIndex)
+
+    logger.Tracef("[worker %v] completed append for object<%v> on %v\n", w.spec.Id, op.id, op.conn.Target())
+}
+
+
+func (w *Worker) performDelete(op *workerOp) {
+    logger.Tracef("[worker %v] starting churn delete for object<%v> on %v\n", w.spec.Id, op.id, op.conn.Target())
+
+    w.spec.TargetLimiter.Acquire(op.connIndex)
+    op.start = time.Now()
+    op.err = w.reconnect(op.conn)
+    if op.err == nil {
+        op.err = op.conn.DeleteObject(w.ctx, op.key, op.id)
+    }
+    op.end = time.Now()
+
+    // An earlier attempt which failed may still have deleted the object, such as one which timed
+    // out waiting for the reply, so a retry which finds it gone has done its job.
+    if (op.err != nil) && (op.retries > 0) && isNotFound(op.err) {
+        op.err = nil
+    }
+    w.spec.TargetLimiter.Release(op.connIndex)
+
+    logger.Tracef("[worker %v] completed churn delete for object<%v> on %v\n", w.spec.Id, op.id, op.conn.Target())
+}
+
+
+func (w *Worker) performStat(op *workerOp) {
+    logger.Tracef("[worker %v] starting stat for object<%v> on %v\n", w.spec.Id, op.id, op.conn.Target())
+
+    w.spec.TargetLimiter.Acquire(op.connIndex)
+    op.start = time.Now()
+    op.err = w.reconnect(op.conn)
+    if op.err == nil {
+        op.err = op.conn.StatObject(op.key, op.id)
+    }
+    op.end = time.Now()
+    w.spec.TargetLimiter.Release(op.connIndex)
+
+    logger.Tracef("[worker %v] completed stat for object<%v> on %v\n", w.spec.Id, op.id, op.conn.Target())
+}
+
+
+func (w *Worker) performRead(op *workerOp, objectBuffer []byte, verifyBuffer []byte) {
+    w.retryOp(op, func() { w.fetchRead(op, objectBuffer) })
+    w.verifyRead(op, objectBuffer, verifyBuffer)
+}
+
+
+/* The part of our object buffer that a read fills. */
+func (w *Worker) readBuffer(op *workerOp, objectBuffer []byte) []byte {
+    // Connections check the size of the object they read against the capacity of the buffer.
+    if w.order.ReadRange != nil {
+        return objectBuffer[:op.length:op.length]
+    }
+
+    return objectBuffer[:op.padded:op.padded]
+}
+
+
+/* The timed part of a read. */
+func (w *Worker) fetchRead(op *workerOp, objectBuffer []byte) {
+    buffer := w.readBuffer(op, objectBuffer)
+
+    logger.Tracef("[worker %v] starting get for object<%v> on %v\n", w.spec.Id, op.id, op.conn.Target())
+
+    w.spec.TargetLimiter.Acquire(op.connIndex)
+    op.start = time.Now()
+    op.err = w.reconnect(op.conn)
+    if op.err == nil {
+        if w.order.ReadRange != nil {
+            op.err = op.conn.GetObjectRange(w.ctx, op.key, op.id, op.offset, buffer)
+        } else {
+            op.err = op.conn.GetObject(w.ctx, op.key, op.id, buffer)
+        }
+    }
+    op.end = time.Now()
+    w.spec.TargetLimiter.Release(op.connIndex)
+
+    logger.Tracef("[worker %v] completed get for object<%v> on %v\n", w.spec.Id, op.id, op.conn.Target())
+}
+
+
+/* Check the data that a read fetched, unless we are skipping verification. */
+func (w *Worker) verifyRead(op *workerOp, objectBuffer []byte, verifyBuffer []byte) {
+    if (op.err != nil) || (w.order.SkipReadValidation && (op.phase != SP_Verify)) {
+        return
+    }
+
+    buffer := w.readBuffer(op, objectBuffer)
+    scratch := verifyBuffer[:op.size:op.size]
+
+    if w.reference != nil {
+        op.verifyErr = w.compareWithReference(op, buffer, verifyBuffer)
+        return
+    }
+
+    if w.inConsistencySample(op.id) {
+        op.verifyErr = w.compareAcrossTargets(op, buffer, verifyBuffer)
+        return
+    }
+
+    if w.order.ReadRange == nil {
+        data := buffer[:op.size:op.size]
+        op.verifyErr = w.generator.Verify(op.size, op.id, op.cycle, &data, &scratch)
+        if op.verifyErr == nil {
+            op.verifyErr = verifyPadding(buffer, op.size)
+        }
+    } else if op.cycle != AnyCycle {
+        op.verifyErr = VerifyRange(w.generator, op.size, op.id, op.cycle, op.offset, buffer, &scratch)
+    }
+}
+
+
+/*
+ * Check the data from a read against the same part of the same object, read from our reference
+ * target, rather than against our generator.  The reference read isn't timed.  If it fails, then
+ * we count a verify failure, since we couldn't show that the object was intact.
+ */
+func (w *Worker) compareWithReference(op *workerOp, buffer []byte, verifyBuffer []byte) error {
+    reference := verifyBuffer[:len(buffer):len(buffer)]
+
+    var err error
+    if w.order.ReadRange != nil {
+        err = w.reference.GetObjectRange(w.ctx, op.key, op.id, op.offset, reference)
+    } else {
+        err = w.reference.GetObject(w.ctx, op.key, op.id, reference)
+    }
+
+    if err != nil {
+        return fmt.Errorf("Failure reading reference copy from %v: %v", w.reference.Target(), err)
+    }
+
+    for i := range buffer {
+        if buffer[i] != reference[i] {
+            return fmt.Errorf("Differs from reference copy on %v at offset %v", w.reference.Target(), op.offset + uint64(i))
+        }
+    }
+
+    return nil
+}
+
+
+/* Whether an object is one of those which a consistency check compares across every target. */
+func (w *Worker) inConsistencySample(id uint64) bool {
+    stride := w.order.ConsistencyStride
+    return (stride != 0) && (id % stride == 0)
+}
+
+
+/*
+ * Check the data from a read against the same part of the same object, read from each of our
+ * other targets, for a consistency check.  As with a reference target, only the first read is
+ * timed, and a failure to read any of the other copies counts as a verify failure.
+ */
+func (w *Worker) compareAcrossTargets(op *workerOp, buffer []byte, verifyBuffer []byte) error {
+    other := verifyBuffer[:len(buffer):len(buffer)]
+
+    for i, conn := range w.connections {
+        if uint64(i) == op.connIndex {
+            continue
+        }
+
+        w.spec.TargetLimiter.Acquire(uint64(i))
+        err := w.reconnect(conn)
+        if err == nil {
+            if w.order.ReadRange != nil {
+                err = conn.GetObjectRange(w.ctx, op.key, op.id, op.offset, other)
+            } else {
+                err = conn.GetObject(w.ctx, op.key, op.id, other)
+            }
+        }
+        w.spec.TargetLimiter.Release(uint64(i))
+
+        if err != nil {
+            return fmt.Errorf("Failure reading copy from %v: %v", conn.Target(), err)
+        }
+
+        for j := range buffer {
+            if buffer[j] != other[j] {
+                return fmt.Errorf("Differs between %v and %v at offset %v", op.conn.Target(), conn.Target(), op.offset + uint64(j))
+            }
+        }
+    }
+
+    return nil
+}
+
+
+/* Fill the end of a padded object, from the end of its data, with zeroes. */
+func zeroPadding(buffer []byte, size uint64) {
+    for i := size; i < uint64(len(buffer)); i++ {
+        buffer[i] = 0
+    }
+}
+
+
+/* Check that the end of a padded object, from the end of its data, is all zeroes. */
+func verifyPadding(buffer []byte, size uint64) error {
+    for i := size; i < uint64(len(buffer)); i++ {
+        if buffer[i] != 0 {
+            return fmt.Errorf("Verify failure in padding at offset %v: expected 0, but got %v", i, buffer[i])
+        }
+    }
+
+    return nil
+}
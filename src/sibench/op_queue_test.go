@@ -0,0 +1,483 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for comparing reads with a reference target, and across targets, for retrying ops, and
+// for reading ahead.
+
+package main
+
+import "context"
+import "fmt"
+import "net"
+import "os"
+import "strings"
+import "sync"
+import "syscall"
+import "testing"
+import "time"
+import "silib/testutil"
+
+
+// Helper functions.
+
+/* A connection which holds one object, "obj", in memory.  Only reads are supported. */
+type referenceConnection struct {
+    FileConnection
+    contents []byte
+}
+
+
+func (conn *referenceConnection) GetObject(ctx context.Context, key string, id uint64, buffer []byte) error {
+    if key != "obj" {
+        return fmt.Errorf("No such object: %v", key)
+    }
+
+    if cap(buffer) != len(conn.contents) {
+        return fmt.Errorf("Object has wrong size: expected %v, but got %v", cap(buffer), len(conn.contents))
+    }
+
+    copy(buffer, conn.contents)
+    return nil
+}
+
+
+func (conn *referenceConnection) GetObjectRange(ctx context.Context, key string, id uint64, offset uint64, buffer []byte) error {
+    if key != "obj" {
+        return fmt.Errorf("No such object: %v", key)
+    }
+
+    copy(buffer, conn.contents[offset:])
+    return nil
+}
+
+
+/* Make a worker whose reference target holds one object with the given contents. */
+func makeReferenceWorker(contents []byte) *Worker {
+    return &Worker{ reference: &referenceConnection{ contents: contents } }
+}
+
+
+/* Make a worker with a connection to each target, each holding one object with the given contents. */
+func makeConsistencyWorker(contents ...[]byte) *Worker {
+    w := &Worker{}
+    w.ctx, w.cancel = context.WithCancel(context.Background())
+    w.order.ConnectionReuse = true
+    w.order.ConsistencyStride = 10
+
+    for _, c := range contents {
+        w.connections = append(w.connections, &referenceConnection{ contents: c })
+    }
+
+    return w
+}
+
+
+/* An error from an HTTP-based connection, with its status code. */
+type statusError int
+
+
+func (e statusError) Error() string {
+    return fmt.Sprintf("HTTP status %v", int(e))
+}
+
+
+func (e statusError) StatusCode() int {
+    return int(e)
+}
+
+
+/* Make an attempt function for an op, which fails with each of the given errors in turn, and then succeeds. */
+func failingAttempt(op *workerOp, attempts *int, errs ...error) func() {
+    return func() {
+        op.err = nil
+        if *attempts < len(errs) {
+            op.err = errs[*attempts]
+        }
+
+        *attempts++
+    }
+}
+
+
+/* A connection whose deletes and appends fail with each of the given errors in turn, and then succeed. */
+type flakyConnection struct {
+    FileConnection
+    errs []error
+    attempts int
+}
+
+
+func (conn *flakyConnection) nextError() error {
+    var err error
+    if conn.attempts < len(conn.errs) {
+        err = conn.errs[conn.attempts]
+    }
+
+    conn.attempts++
+    return err
+}
+
+
+func (conn *flakyConnection) DeleteObject(ctx context.Context, key string, id uint64) error {
+    return conn.nextError()
+}
+
+
+func (conn *flakyConnection) AppendObject(key string, id uint64, buffer []byte) error {
+    return conn.nextError()
+}
+
+
+/* An error from Ceph, with its negated errno. */
+type cephError int
+
+
+func (e cephError) Error() string {
+    return fmt.Sprintf("Ceph error %v", int(e))
+}
+
+
+func (e cephError) ErrorCode() int {
+    return int(e)
+}
+
+
+/*
+ * A connection which serves each object's contents after a delay which varies from object to
+ * object, so that reads complete out of order.  It notes whether two reads in flight ever share a
+ * buffer, and how many reads were in flight when each write was made.
+ */
+type readAheadConnection struct {
+    FileConnection
+    contents map[uint64][]byte
+    mutex sync.Mutex
+    buffers map[*byte]bool      // The buffers of the reads in flight.
+    sharedBuffer bool
+    readsInFlight int
+    readsAtWrite []int
+}
+
+
+func (conn *readAheadConnection) GetObject(ctx context.Context, key string, id uint64, buffer []byte) error {
+    conn.mutex.Lock()
+    conn.sharedBuffer = conn.sharedBuffer || conn.buffers[&buffer[0]]
+    conn.buffers[&buffer[0]] = true
+    conn.readsInFlight++
+    conn.mutex.Unlock()
+
+    time.Sleep(time.Duration(id % 3) * time.Millisecond)
+    copy(buffer, conn.contents[id])
+
+    conn.mutex.Lock()
+    delete(conn.buffers, &buffer[0])
+    conn.readsInFlight--
+    conn.mutex.Unlock()
+
+    return nil
+}
+
+
+func (conn *readAheadConnection) PutObject(ctx context.Context, key string, id uint64, buffer []byte) error {
+    conn.mutex.Lock()
+    conn.readsAtWrite = append(conn.readsAtWrite, conn.readsInFlight)
+    conn.mutex.Unlock()
+
+    return nil
+}
+
+
+/* Make a connection holding count objects of the given size, with the last byte of one of them corrupted. */
+func makeReadAheadConnection(t *testing.T, count uint64, size uint64, corrupt uint64) *readAheadConnection {
+    g, err := CreatePrngGenerator(42, GeneratorConfig{})
+    testutil.CheckNoError(t, err)
+
+    conn := &readAheadConnection{ contents: make(map[uint64][]byte), buffers: make(map[*byte]bool) }
+    for id := uint64(0); id < count; id++ {
+        data := make([]byte, size)
+        g.Generate(size, id, 0, &data)
+        conn.contents[id] = data
+    }
+
+    conn.contents[corrupt][size - 1]++
+    return conn
+}
+
+
+/* Make a worker which reads ahead by the given number of objects, with its lanes running. */
+func makeReadAheadWorker(t *testing.T, readAhead uint64, size uint64) *Worker {
+    w := &Worker{}
+    w.ctx, w.cancel = context.WithCancel(context.Background())
+    w.order = WorkOrder{ ObjectSize: size, ReadAhead: readAhead, ConnectionReuse: true, Targets: []string{ "t" } }
+    w.phaseOpsLeft = Uncapped
+    w.objectBuffer = make([]byte, size)
+    w.verifyBuffer = make([]byte, size)
+    w.stats = [][]Stat{ make([]Stat, 100) }
+    w.spec.SummaryChannel = make(chan WorkerSummary, 100)
+
+    var err error
+    w.generator, err = CreatePrngGenerator(42, GeneratorConfig{})
+    testutil.CheckNoError(t, err)
+
+    w.startLanes()
+    t.Cleanup(w.stopLanes)
+    return w
+}
+
+
+/* Make an op of the given phase for an object. */
+func makeOp(phase StatPhase, id uint64, size uint64, conn Connection) *workerOp {
+    return &workerOp{ phase: phase, id: id, key: fmt.Sprint(id), conn: conn, size: size, padded: size, length: size }
+}
+
+
+// Test functions.
+
+// A whole object must match its reference copy byte for byte.
+func TestCompareWithReference(t *testing.T) {
+    w := makeReferenceWorker([]byte{ 1, 2, 3, 4 })
+    scratch := make([]byte, 8)
+    op := &workerOp{ key: "obj" }
+
+    testutil.CheckNoError(t, w.compareWithReference(op, []byte{ 1, 2, 3, 4 }, scratch))
+
+    err := w.compareWithReference(op, []byte{ 1, 2, 9, 4 }, scratch)
+    testutil.CheckError(t, err)
+    testutil.CheckBool(t, true, strings.HasSuffix(err.Error(), "at offset 2"))
+
+    // A reference copy of a different size, or none at all, is a failure too.
+    testutil.CheckError(t, w.compareWithReference(op, []byte{ 1, 2, 3 }, scratch))
+
+    op.key = "missing"
+    testutil.CheckError(t, w.compareWithReference(op, []byte{ 1, 2, 3, 4 }, scratch))
+}
+
+
+// A ranged read is compared with the same range of the reference copy.
+func TestCompareWithReferenceRange(t *testing.T) {
+    w := makeReferenceWorker([]byte{ 1, 2, 3, 4 })
+    w.order.ReadRange = &ReadRange{ Offset: 1, Length: 2 }
+    scratch := make([]byte, 8)
+    op := &workerOp{ key: "obj", offset: 1 }
+
+    testutil.CheckNoError(t, w.compareWithReference(op, []byte{ 2, 3 }, scratch))
+
+    err := w.compareWithReference(op, []byte{ 2, 9 }, scratch)
+    testutil.CheckError(t, err)
+    testutil.CheckBool(t, true, strings.HasSuffix(err.Error(), "at offset 2"))
+}
+
+
+// Only objects whose ids are multiples of the stride are in the sample, and there is none without one.
+func TestInConsistencySample(t *testing.T) {
+    w := makeConsistencyWorker()
+    testutil.CheckBool(t, true, w.inConsistencySample(0))
+    testutil.CheckBool(t, true, w.inConsistencySample(20))
+    testutil.CheckBool(t, false, w.inConsistencySample(21))
+
+    w.order.ConsistencyStride = 0
+    testutil.CheckBool(t, false, w.inConsistencySample(0))
+}
+
+
+// A read must match the same object on every other target, but isn't read again from its own.
+func TestCompareAcrossTargets(t *testing.T) {
+    w := makeConsistencyWorker([]byte{ 9, 9, 9, 9 }, []byte{ 1, 2, 3, 4 }, []byte{ 1, 2, 3, 4 })
+    scratch := make([]byte, 8)
+    op := &workerOp{ key: "obj", connIndex: 0, conn: w.connections[0] }
+
+    testutil.CheckNoError(t, w.compareAcrossTargets(op, []byte{ 1, 2, 3, 4 }, scratch))
+
+    op.connIndex = 1
+    op.conn = w.connections[1]
+    err := w.compareAcrossTargets(op, []byte{ 1, 2, 3, 4 }, scratch)
+    testutil.CheckError(t, err)
+    testutil.CheckBool(t, true, strings.HasSuffix(err.Error(), "at offset 0"))
+
+    // Missing copies, and copies of a different size, are failures too.
+    op.key = "missing"
+    testutil.CheckError(t, w.compareAcrossTargets(op, []byte{ 1, 2, 3, 4 }, scratch))
+}
+
+
+// Transient failures are retried until an attempt succeeds, and we count the retries.
+func TestRetryOp(t *testing.T) {
+    w := makeConsistencyWorker([]byte{})
+    w.order.MaxRetries = 2
+    op := &workerOp{ conn: w.connections[0] }
+    attempts := 0
+
+    w.retryOp(op, failingAttempt(op, &attempts, fmt.Errorf("Put failed: %w", syscall.ECONNRESET), statusError(503)))
+    testutil.CheckNoError(t, op.err)
+    testutil.CheckInt(t, 3, attempts)
+    testutil.CheckInt(t, 2, int(op.retries))
+}
+
+
+// Once we run out of retries, the op fails with its last error.
+func TestRetryOpGivesUp(t *testing.T) {
+    w := makeConsistencyWorker([]byte{})
+    w.order.MaxRetries = 1
+    op := &workerOp{ conn: w.connections[0] }
+    attempts := 0
+
+    w.retryOp(op, failingAttempt(op, &attempts, statusError(500), statusError(429), statusError(500)))
+    testutil.CheckError(t, op.err)
+    testutil.CheckString(t, "HTTP status 429", op.err.Error())
+    testutil.CheckInt(t, 2, attempts)
+    testutil.CheckInt(t, 1, int(op.retries))
+
+    // Without any retries, we only make the one attempt.
+    w.order.MaxRetries = 0
+    op = &workerOp{ conn: w.connections[0] }
+    attempts = 0
+
+    w.retryOp(op, failingAttempt(op, &attempts, statusError(500)))
+    testutil.CheckError(t, op.err)
+    testutil.CheckInt(t, 1, attempts)
+}
+
+
+/* An error from the AWS SDK, which wraps its cause without supporting errors.Unwrap. */
+type awsError struct {
+    orig error
+}
+
+
+func (e awsError) Error() string {
+    return "RequestError: send request failed"
+}
+
+
+func (e awsError) OrigErr() error {
+    return e.orig
+}
+
+
+// Only timeouts, refused or reset connections, EAGAIN, and server errors or throttling are worth retrying.
+func TestIsTransient(t *testing.T) {
+    ctx := context.Background()
+
+    testutil.CheckBool(t, true, isTransient(ctx, statusError(429)))
+    testutil.CheckBool(t, true, isTransient(ctx, statusError(503)))
+    testutil.CheckBool(t, true, isTransient(ctx, fmt.Errorf("Put failed: %w", statusError(500))))
+    testutil.CheckBool(t, true, isTransient(ctx, &net.OpError{ Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED) }))
+    testutil.CheckBool(t, true, isTransient(ctx, awsError{ fmt.Errorf("Put failed: %w", syscall.ECONNRESET) }))
+    testutil.CheckBool(t, true, isTransient(ctx, awsError{ context.DeadlineExceeded }))
+    testutil.CheckBool(t, true, isTransient(ctx, fmt.Errorf("Read failed: %w", syscall.EAGAIN)))
+    testutil.CheckBool(t, true, isTransient(ctx, fmt.Errorf("Failure listing Ceph pools: %w", cephError(-int(syscall.ETIMEDOUT)))))
+
+    testutil.CheckBool(t, false, isTransient(ctx, statusError(404)))
+    testutil.CheckBool(t, false, isTransient(ctx, fmt.Errorf("Put failed: %w", statusError(403))))
+    testutil.CheckBool(t, false, isTransient(ctx, fmt.Errorf("Get failed: %w", syscall.ENOENT)))
+    testutil.CheckBool(t, false, isTransient(ctx, cephError(-int(syscall.ENOENT))))
+    testutil.CheckBool(t, false, isTransient(ctx, awsError{ context.Canceled }))
+    testutil.CheckBool(t, false, isTransient(ctx, fmt.Errorf("Connection reset")))
+}
+
+
+// Once the phase has been cancelled, not even a timeout is retried.
+func TestIsTransientCancelled(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    testutil.CheckBool(t, false, isTransient(ctx, context.DeadlineExceeded))
+    testutil.CheckBool(t, false, isTransient(ctx, statusError(503)))
+}
+
+
+// Only failures which say that the object doesn't exist count as not found, however they are wrapped.
+func TestIsNotFound(t *testing.T) {
+    testutil.CheckBool(t, true, isNotFound(&os.PathError{ Op: "unlink", Path: "obj", Err: syscall.ENOENT }))
+    testutil.CheckBool(t, true, isNotFound(fmt.Errorf("Delete failed: %w", statusError(404))))
+    testutil.CheckBool(t, true, isNotFound(awsError{ statusError(404) }))
+    testutil.CheckBool(t, true, isNotFound(cephError(-int(syscall.ENOENT))))
+
+    testutil.CheckBool(t, false, isNotFound(nil))
+    testutil.CheckBool(t, false, isNotFound(statusError(503)))
+    testutil.CheckBool(t, false, isNotFound(cephError(-int(syscall.EIO))))
+    testutil.CheckBool(t, false, isNotFound(fmt.Errorf("Delete failed: %w", syscall.ETIMEDOUT)))
+}
+
+
+// A retried delete which finds its object gone has succeeded, since the failed attempt may have
+// deleted it.  A first attempt which finds nothing there has still failed.
+func TestChurnDeleteRetryNotFound(t *testing.T) {
+    w := makeConsistencyWorker()
+    w.order.MaxRetries = 2
+    conn := &flakyConnection{ errs: []error{ syscall.ETIMEDOUT, &os.PathError{ Op: "unlink", Path: "obj", Err: syscall.ENOENT } } }
+    op := &workerOp{ phase: SP_ChurnDelete, conn: conn }
+
+    w.performOp(op, nil, nil)
+    testutil.CheckNoError(t, op.err)
+    testutil.CheckInt(t, 2, conn.attempts)
+    testutil.CheckInt(t, 1, int(op.retries))
+
+    conn = &flakyConnection{ errs: []error{ &os.PathError{ Op: "unlink", Path: "obj", Err: syscall.ENOENT } } }
+    op = &workerOp{ phase: SP_ChurnDelete, conn: conn }
+
+    w.performOp(op, nil, nil)
+    testutil.CheckError(t, op.err)
+    testutil.CheckInt(t, 1, conn.attempts)
+}
+
+
+// An append which may have landed must not be made again, so even a timeout is not retried.
+func TestAppendNotRetried(t *testing.T) {
+    w := makeConsistencyWorker()
+    w.order.MaxRetries = 2
+
+    var err error
+    w.generator, err = CreatePrngGenerator(42, GeneratorConfig{})
+    testutil.CheckNoError(t, err)
+
+    conn := &flakyConnection{ errs: []error{ syscall.ETIMEDOUT } }
+    op := &workerOp{ phase: SP_Append, conn: conn, size: 64 }
+
+    w.performOp(op, make([]byte, 64), nil)
+    testutil.CheckError(t, op.err)
+    testutil.CheckInt(t, 1, conn.attempts)
+    testutil.CheckInt(t, 0, int(op.retries))
+}
+
+
+// With read ahead, each read must be verified against the buffer that it was read into, every
+// buffer must come back to the ring, and anything other than a read must wait for the reads in flight.
+func TestReadAhead(t *testing.T) {
+    const readAhead = 4
+    const count = 20
+    const size = 256
+
+    conn := makeReadAheadConnection(t, count, size, 5)
+    w := makeReadAheadWorker(t, readAhead, size)
+
+    ring := make(map[*byte]bool)
+    for _, b := range w.readBuffers {
+        ring[&b[0]] = true
+    }
+
+    testutil.CheckInt(t, readAhead, len(ring))
+
+    for id := uint64(0); id < count; id++ {
+        w.runOp(makeOp(SP_Read, id, size, conn))
+    }
+
+    w.runOp(makeOp(SP_Write, count, size, conn))
+
+    // The write saw no reads in flight, and was recorded after all of them.
+    testutil.CheckInt(t, 1, len(conn.readsAtWrite))
+    testutil.CheckInt(t, 0, conn.readsAtWrite[0])
+    testutil.CheckInt(t, 0, int(w.inFlight))
+    testutil.CheckInt(t, count + 1, w.nextStatIndex)
+    testutil.CheckBool(t, true, w.stats[0][count].Phase == SP_Write)
+
+    // No two reads in flight shared a buffer, and every buffer is back in the ring.
+    testutil.CheckBool(t, false, conn.sharedBuffer)
+    testutil.CheckInt(t, readAhead, len(w.readBuffers))
+    for _, b := range w.readBuffers {
+        testutil.CheckBool(t, true, ring[&b[0]])
+    }
+
+    // Only the object which we corrupted failed verification.
+    testutil.CheckInt(t, 1, len(w.verifyFailures))
+    testutil.CheckInt(t, 5, int(w.verifyFailures[0].Id))
+}
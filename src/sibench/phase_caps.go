@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "math"
+import "strconv"
+import "strings"
+
+
+/* The value of a phase's cap when it has none. */
+const Uncapped uint64 = math.MaxUint64
+
+
+/*
+ * PhaseCaps limit how many ops each timed phase may perform, across all of our servers, so that
+ * a phase ends either when its time is up or when it reaches its cap, whichever comes first.  The
+ * write cap is the most useful, since it bounds how much a run fills the cluster.
+ *
+ * They are given as comma-separated PHASE:COUNT pairs, such as "write:10000,read:50000", where
+ * PHASE is one of write, read, readwrite or stat.  Phases which aren't given are uncapped.
+ *
+ * Each server is given a share of each cap in proportion to its share of the objects, and then
+ * shares it between its workers in the same way, so the shares always add up to the whole cap.
+ */
+type PhaseCaps struct {
+    Write uint64
+    Read uint64
+    ReadWrite uint64
+    Stat uint64
+}
+
+
+func ParsePhaseCaps(spec string) (*PhaseCaps, error) {
+    pc := PhaseCaps{ Write: Uncapped, Read: Uncapped, ReadWrite: Uncapped, Stat: Uncapped }
+
+    for _, pair := range strings.Split(spec, ",") {
+        phase, countStr, found := strings.Cut(pair, ":")
+        if !found {
+            return nil, fmt.Errorf("Bad phase cap: %v.  Should be PHASE:COUNT", pair)
+        }
+
+        count, err := strconv.ParseUint(countStr, 10, 64)
+        if (err != nil) || (count == 0) || (count == Uncapped) {
+            return nil, fmt.Errorf("Bad count in phase cap: %v.  Should be a positive integer", pair)
+        }
+
+        var limit *uint64
+        switch phase {
+            case "write":       limit = &pc.Write
+            case "read":        limit = &pc.Read
+            case "readwrite":   limit = &pc.ReadWrite
+            case "stat":        limit = &pc.Stat
+            default:
+                return nil, fmt.Errorf("Bad phase in phase cap: %v.  Should be write, read, readwrite or stat", phase)
+        }
+
+        if *limit != Uncapped {
+            return nil, fmt.Errorf("The %v phase is capped more than once", phase)
+        }
+
+        *limit = count
+    }
+
+    return &pc, nil
+}
+
+
+/*
+ * Return the cap for the phase that is started by the given opcode, or Uncapped if it has none.
+ * This is safe to call on nil, which means that no phase is capped.
+ */
+func (pc *PhaseCaps) For(startOp Opcode) uint64 {
+    if pc == nil {
+        return Uncapped
+    }
+
+    switch startOp {
+        case OP_WriteStart:       return pc.Write
+        case OP_ReadStart:        return pc.Read
+        case OP_ReadWriteStart:   return pc.ReadWrite
+        case OP_StatOpsStart:     return pc.Stat
+    }
+
+    return Uncapped
+}
+
+
+/*
+ * Return the share of our caps for the part of our range from start to end.  Shares may be zero,
+ * in which case that part of the range does nothing in the phase.
+ */
+func (pc *PhaseCaps) Share(rangeStart uint64, rangeEnd uint64, start uint64, end uint64) *PhaseCaps {
+    if pc == nil {
+        return nil
+    }
+
+    share := func(limit uint64) uint64 {
+        if limit == Uncapped {
+            return Uncapped
+        }
+
+        if end <= start {
+            return 0
+        }
+
+        // Work in floating point, since a large cap times a large range could overflow.
+        rangeLen := float64(rangeEnd - rangeStart)
+        return uint64(float64(limit) * float64(end - rangeStart) / rangeLen) - uint64(float64(limit) * float64(start - rangeStart) / rangeLen)
+    }
+
+    return &PhaseCaps{ Write: share(pc.Write), Read: share(pc.Read), ReadWrite: share(pc.ReadWrite), Stat: share(pc.Stat) }
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for capping the number of ops in timed phases.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Test functions.
+
+func TestParsePhaseCaps(t *testing.T) {
+    pc, err := ParsePhaseCaps("write:10000,stat:50")
+    testutil.CheckNoError(t, err)
+    testutil.CheckBool(t, true, pc.For(OP_WriteStart) == 10000)
+    testutil.CheckBool(t, true, pc.For(OP_StatOpsStart) == 50)
+    testutil.CheckBool(t, true, pc.For(OP_ReadStart) == Uncapped)
+    testutil.CheckBool(t, true, pc.For(OP_Prepare) == Uncapped)
+
+    var none *PhaseCaps
+    testutil.CheckBool(t, true, none.For(OP_WriteStart) == Uncapped)
+
+    for _, spec := range []string{ "", "write", "write:0", "write:-1", "write:1K", "delete:10", "read:1,read:2" } {
+        _, err = ParsePhaseCaps(spec)
+        testutil.CheckError(t, err)
+    }
+}
+
+
+// However a range is split up, the shares of a cap must add up to the whole of it.
+func TestPhaseCapsShare(t *testing.T) {
+    pc, err := ParsePhaseCaps("write:1000,read:7")
+    testutil.CheckNoError(t, err)
+
+    for _, parts := range []uint64{ 1, 3, 10, 64 } {
+        writes := uint64(0)
+        reads := uint64(0)
+
+        for i := uint64(0); i < parts; i++ {
+            share := pc.Share(100, 1100, 100 + (i * 1000 / parts), 100 + ((i + 1) * 1000 / parts))
+            testutil.CheckBool(t, true, share.For(OP_StatOpsStart) == Uncapped)
+            writes += share.Write
+            reads += share.Read
+        }
+
+        testutil.CheckInt(t, 1000, int(writes))
+        testutil.CheckInt(t, 7, int(reads))
+    }
+
+    share := pc.Share(100, 1100, 500, 500)
+    testutil.CheckInt(t, 0, int(share.Write))
+}
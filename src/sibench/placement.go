@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "math/bits"
+
+
+/*
+ * Connections to backends which place objects into placement groups implement this as well, so
+ * that we can report how evenly a run's keys spread across them.
+ */
+type PlacementConnection interface {
+    /* Return the number of placement groups in the pool we are using. */
+    PlacementGroupCount() (uint64, error)
+}
+
+
+/*
+ * How evenly a run's objects are spread across the placement groups of a Ceph pool.
+ *
+ * Ceph picks an object's placement group by hashing its name, so this depends on our key scheme
+ * and prefix, and we can work it out for ourselves before the run starts.  We use the same hash as
+ * Ceph (rjenkins, which is the default for pools), and the same "stable mod" to fold the hash down
+ * to the pool's pg_num.  Pools using any other hash will see a different spread.
+ */
+type PlacementSpread struct {
+    KeyScheme string
+    Objects uint64
+    PgCount uint64
+    PgsUsed uint64              // How many placement groups hold at least one of our objects.
+    MinObjectsPerPg uint64
+    MaxObjectsPerPg uint64
+    MeanObjectsPerPg float64
+    Cv float64                  // The coefficient of variation of the objects per placement group.
+}
+
+
+/* Work out how the objects with the given ids spread across a pool's placement groups. */
+func NewPlacementSpread(scheme string, prefix string, rangeStart uint64, rangeEnd uint64, pgCount uint64) *PlacementSpread {
+    counts := make([]uint64, pgCount)
+    mask := cephPgMask(uint32(pgCount))
+
+    for id := rangeStart; id < rangeEnd; id++ {
+        pg := cephStableMod(cephStrHashRjenkins(ObjectKey(scheme, prefix, id)), uint32(pgCount), mask)
+        counts[pg]++
+    }
+
+    ps := PlacementSpread {
+        KeyScheme: scheme,
+        Objects: rangeEnd - rangeStart,
+        PgCount: pgCount,
+        MinObjectsPerPg: counts[0],
+        MeanObjectsPerPg: float64(rangeEnd - rangeStart) / float64(pgCount),
+        Cv: coefficientOfVariation(counts) }
+
+    for _, c := range counts {
+        if c > 0 {
+            ps.PgsUsed++
+        }
+
+        if c < ps.MinObjectsPerPg {
+            ps.MinObjectsPerPg = c
+        }
+
+        if c > ps.MaxObjectsPerPg {
+            ps.MaxObjectsPerPg = c
+        }
+    }
+
+    return &ps
+}
+
+
+func (ps *PlacementSpread) String() string {
+    return fmt.Sprintf("%v objects with %v keys use %v of %v placement groups: min %v, max %v, mean %.1f objects per group (cv %.2f)",
+        ps.Objects,
+        ps.KeyScheme,
+        ps.PgsUsed,
+        ps.PgCount,
+        ps.MinObjectsPerPg,
+        ps.MaxObjectsPerPg,
+        ps.MeanObjectsPerPg,
+        ps.Cv)
+}
+
+
+/* Return the mask that Ceph uses with cephStableMod for a pool with the given pg_num. */
+func cephPgMask(pgCount uint32) uint32 {
+    return (uint32(1) << bits.Len32(pgCount - 1)) - 1
+}
+
+
+/*
+ * Fold a hash down to fewer than b buckets, as Ceph does.  Unlike a plain modulus, this moves as
+ * few objects as possible when b grows.
+ */
+func cephStableMod(x uint32, b uint32, bmask uint32) uint32 {
+    if (x & bmask) < b {
+        return x & bmask
+    }
+
+    return x & (bmask >> 1)
+}
+
+
+/* Ceph's version of Bob Jenkins' lookup2 hash, which it uses to hash object names. */
+func cephStrHashRjenkins(s string) uint32 {
+    k := []byte(s)
+    length := uint32(len(k))
+
+    a := uint32(0x9e3779b9)
+    b := a
+    c := uint32(0)
+
+    for len(k) >= 12 {
+        a += uint32(k[0]) + (uint32(k[1]) << 8) + (uint32(k[2]) << 16) + (uint32(k[3]) << 24)
+        b += uint32(k[4]) + (uint32(k[5]) << 8) + (uint32(k[6]) << 16) + (uint32(k[7]) << 24)
+        c += uint32(k[8]) + (uint32(k[9]) << 8) + (uint32(k[10]) << 16) + (uint32(k[11]) << 24)
+        a, b, c = rjenkinsMix(a, b, c)
+        k = k[12:]
+    }
+
+    // Add in the last 11 bytes.  The first byte of c is reserved for the length.
+    c += length
+
+    switch len(k) {
+        case 11: c += uint32(k[10]) << 24;  fallthrough
+        case 10: c += uint32(k[9]) << 16;   fallthrough
+        case 9:  c += uint32(k[8]) << 8;    fallthrough
+        case 8:  b += uint32(k[7]) << 24;   fallthrough
+        case 7:  b += uint32(k[6]) << 16;   fallthrough
+        case 6:  b += uint32(k[5]) << 8;    fallthrough
+        case 5:  b += uint32(k[4]);         fallthrough
+        case 4:  a += uint32(k[3]) << 24;   fallthrough
+        case 3:  a += uint32(k[2]) << 16;   fallthrough
+        case 2:  a += uint32(k[1]) << 8;    fallthrough
+        case 1:  a += uint32(k[0])
+    }
+
+    _, _, c = rjenkinsMix(a, b, c)
+    return c
+}
+
+
+func rjenkinsMix(a uint32, b uint32, c uint32) (uint32, uint32, uint32) {
+    a -= b; a -= c; a ^= c >> 13
+    b -= c; b -= a; b ^= a << 8
+    c -= a; c -= b; c ^= b >> 13
+    a -= b; a -= c; a ^= c >> 12
+    b -= c; b -= a; b ^= a << 16
+    c -= a; c -= b; c ^= b >> 5
+    a -= b; a -= c; a ^= c >> 3
+    b -= c; b -= a; b ^= a << 10
+    c -= a; c -= b; c ^= b >> 15
+    return a, b, c
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for working out how our objects spread across Ceph placement groups.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Test functions.
+
+// Ceph's documentation maps an object called "foo" to pg 0.7fc1f406, which is its hash.
+func TestCephStrHashRjenkins(t *testing.T) {
+    testutil.CheckInt(t, 0x7fc1f406, int(cephStrHashRjenkins("foo")))
+
+    // Every length of tail must change the hash.
+    key := "abcdefghijklmnopqrstuvwxyz"
+    for i := 1; i <= len(key); i++ {
+        testutil.CheckBool(t, true, cephStrHashRjenkins(key[:i]) != cephStrHashRjenkins(key[:i - 1]))
+    }
+}
+
+
+func TestCephPgMask(t *testing.T) {
+    testutil.CheckInt(t, 0, int(cephPgMask(1)))
+    testutil.CheckInt(t, 7, int(cephPgMask(8)))
+    testutil.CheckInt(t, 15, int(cephPgMask(12)))
+    testutil.CheckInt(t, 127, int(cephPgMask(65)))
+}
+
+
+// With a pg_num that isn't a power of two, hashes beyond it fold into the lower half.
+func TestCephStableMod(t *testing.T) {
+    mask := cephPgMask(12)
+
+    testutil.CheckInt(t, 6, int(cephStableMod(0x7fc1f406, 8, cephPgMask(8))))
+    testutil.CheckInt(t, 11, int(cephStableMod(11, 12, mask)))
+    testutil.CheckInt(t, 4, int(cephStableMod(12, 12, mask)))
+    testutil.CheckInt(t, 7, int(cephStableMod(15, 12, mask)))
+    testutil.CheckInt(t, 3, int(cephStableMod(19, 12, mask)))
+
+    for x := uint32(0); x < 1000; x++ {
+        testutil.CheckBool(t, true, cephStableMod(x, 12, mask) < 12)
+    }
+}
+
+
+// Both of our key schemes should use every placement group, with no more than a modest spread.
+func TestPlacementSpread(t *testing.T) {
+    for _, scheme := range []string{ keySchemeSequential, keySchemeHashed } {
+        ps := NewPlacementSpread(scheme, "prefix", 0, 6400, 64)
+
+        testutil.CheckInt(t, 6400, int(ps.Objects))
+        testutil.CheckInt(t, 64, int(ps.PgsUsed))
+        testutil.CheckBool(t, true, ps.MeanObjectsPerPg == 100)
+        testutil.CheckBool(t, true, ps.MinObjectsPerPg <= 100)
+        testutil.CheckBool(t, true, ps.MaxObjectsPerPg >= 100)
+        testutil.CheckBool(t, true, ps.Cv < 0.25)
+    }
+}
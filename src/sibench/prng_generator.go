@@ -18,6 +18,16 @@ func prng(lastValue uint64) uint64 {
 }
 
 
+/* Step our hash function on n times, which is how we skip over data that we don't need to generate. */
+func prngSkip(lastValue uint64, n uint64) uint64 {
+	for i := uint64(0); i < n; i++ {
+		lastValue = prng(lastValue)
+	}
+
+	return lastValue
+}
+
+
 /*
  * The PRNG generator is the default content generator for sibench.
  *
@@ -83,7 +93,46 @@ func (pg *PrngGenerator) Generate(size uint64, id uint64, cycle uint64, buf *[]b
 }
 
 
-func (pg *PrngGenerator) Verify(size uint64, id uint64, buffer *[]byte, scratch *[]byte) error {
+/*
+ * Our objects are a header of four words, then words from our prng, then zeroes to pad out any
+ * partial word at the end.  We generate a range a word at a time, skipping the prng past any
+ * words before it.
+ */
+func (pg *PrngGenerator) GenerateRange(size uint64, id uint64, cycle uint64, offset uint64, buffer []byte) {
+    var word [8]byte
+    header := []uint64{ size, cycle, pg.seed, id }
+    fullWords := size / 8
+    end := offset + uint64(len(buffer))
+
+    next := pg.seed
+    next = prng(next ^ size)
+    next = prng(next ^ cycle)
+    next = prng(next ^ id)
+
+    first := offset / 8
+    if first > uint64(len(header)) {
+        next = prngSkip(next, first - uint64(len(header)))
+    }
+
+    for i := first; i * 8 < end; i++ {
+        switch {
+            case i < uint64(len(header)):
+                binary.LittleEndian.PutUint64(word[:], header[i])
+
+            case i < fullWords:
+                binary.LittleEndian.PutUint64(word[:], next)
+                next = prng(next)
+
+            default:
+                word = [8]byte{}
+        }
+
+        copyOverlap(buffer, offset, word[:], i * 8)
+    }
+}
+
+
+func (pg *PrngGenerator) Verify(size uint64, id uint64, expectedCycle uint64, buffer *[]byte, scratch *[]byte) error {
     if uint64(len(*buffer)) != size {
         return fmt.Errorf("Incorrect size: expected %v but got %v\n", size, len(*buffer))
     }
@@ -91,6 +140,10 @@ func (pg *PrngGenerator) Verify(size uint64, id uint64, buffer *[]byte, scratch
     // Read the cycle from the header of the payload: it's the only bit we don't necessarily know. 
     cycle := binary.LittleEndian.Uint64((*buffer)[8:])
 
+    if (expectedCycle != AnyCycle) && (cycle != expectedCycle) {
+        return fmt.Errorf("Stale object: expected cycle %v but got %v\n", expectedCycle, cycle)
+    }
+
     // Now we can generate the expected buffer to compare against.
     pg.Generate(size, id, cycle, scratch)
 
@@ -5,6 +5,8 @@
 
 package main
 
+import "context"
+import "encoding/json"
 import "fmt"
 import "github.com/ceph/go-ceph/rados"
 
@@ -73,13 +75,19 @@ func (conn *RadosConnection) CanDelete() bool {
 }
 
 
-func (conn *RadosConnection) PutObject(key string, id uint64, buffer []byte) error {
+func (conn *RadosConnection) PutObject(ctx context.Context, key string, id uint64, buffer []byte) error {
     err := conn.ioctx.WriteFull(key, buffer)
     return err
 }
 
 
-func (conn *RadosConnection) GetObject(key string, id uint64, buffer []byte) error {
+/* Each append is a single op on the object's primary OSD, so concurrent appends are serialised there. */
+func (conn *RadosConnection) AppendObject(key string, id uint64, buffer []byte) error {
+    return conn.ioctx.Append(key, buffer)
+}
+
+
+func (conn *RadosConnection) GetObject(ctx context.Context, key string, id uint64, buffer []byte) error {
     stat, err := conn.ioctx.Stat(key)
     if err != nil {
         return err
@@ -104,12 +112,59 @@ func (conn *RadosConnection) GetObject(key string, id uint64, buffer []byte) err
 }
 
 
-func (conn *RadosConnection) DeleteObject(key string, id uint64) error {
+func (conn *RadosConnection) GetObjectRange(ctx context.Context, key string, id uint64, offset uint64, buffer []byte) error {
+    nread, err := conn.ioctx.Read(key, buffer, offset)
+    if err != nil {
+        return err
+    }
+
+    if nread != len(buffer) {
+        return fmt.Errorf("Short read: wanted %v bytes, but got %v", len(buffer), nread)
+    }
+
+    return nil
+}
+
+
+func (conn *RadosConnection) StatObject(key string, id uint64) error {
+    _, err := conn.ioctx.Stat(key)
+    return err
+}
+
+
+func (conn *RadosConnection) DeleteObject(ctx context.Context, key string, id uint64) error {
     err := conn.ioctx.Delete(key)
     return err
 }
 
 
+/* Ask the monitors for our pool's pg_num, so that we can report how our keys spread across it. */
+func (conn *RadosConnection) PlacementGroupCount() (uint64, error) {
+    pool := conn.protocol["pool"]
+
+    cmd, err := json.Marshal(map[string]string{ "prefix": "osd pool get", "pool": pool, "var": "pg_num", "format": "json" })
+    if err != nil {
+        return 0, err
+    }
+
+    buf, info, err := conn.client.MonCommand(cmd)
+    if err != nil {
+        return 0, fmt.Errorf("Failure getting pg_num of pool %v: %v (%v)", pool, err, info)
+    }
+
+    var resp struct {
+        PgNum uint64 `json:"pg_num"`
+    }
+
+    err = json.Unmarshal(buf, &resp)
+    if err != nil {
+        return 0, fmt.Errorf("Bad response getting pg_num of pool %v: %v", pool, err)
+    }
+
+    return resp.PgNum, nil
+}
+
+
 func (conn *RadosConnection) InvalidateCache() error {
     return nil
 }
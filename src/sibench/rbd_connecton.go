@@ -5,6 +5,7 @@
 
 package main
 
+import "context"
 import "fmt"
 import "logger"
 import "github.com/ceph/go-ceph/rados"
@@ -18,9 +19,11 @@ type RbdConnection struct {
     monitor string
     protocol ProtocolConfig
     worker WorkerConnectionConfig
+    layout blockLayout
     client *rados.Conn
     ioctx *rados.IOContext
     image *rbd.Image
+    imageCreated bool       // Whether we have created our image, so that a reconnect only has to open it.
 }
 
 
@@ -29,6 +32,9 @@ func NewRbdConnection(target string, protocol ProtocolConfig, worker WorkerConne
     conn.monitor = target
     conn.protocol = protocol
     conn.worker = worker
+
+    // Each worker creates its own image, so we lay it out over just the worker's range.
+    conn.layout = worker.workerBlockLayout()
     return &conn, nil
 }
 
@@ -60,24 +66,51 @@ func (conn *RbdConnection) ManagerClose(cleanup bool) error {
 }
 
 
+/*
+ * Connect, and open our image, creating it the first time.  Without connection reuse, the worker
+ * closes and re-opens its connection around every op, and the image must survive that.
+ */
 func (conn *RbdConnection) WorkerConnect() error {
     err := conn.ManagerConnect()
     if err != nil {
         return err
     }
 
+    imageName := fmt.Sprintf("%v-%v-%v", conn.protocol["image_prefix"], conn.worker.Hostname, conn.worker.WorkerId)
+
+    if !conn.imageCreated {
+        err = conn.createImage(imageName)
+        if err != nil {
+            return err
+        }
+
+        conn.imageCreated = true
+    }
+
+    openImage, err := rbd.OpenImage(conn.ioctx, imageName, "")
+    if err != nil {
+        conn.image.Remove()
+        conn.image = nil
+        return fmt.Errorf("Failure opening RBD image %v: %v", imageName, err)
+    }
+
+    conn.image = openImage
+    return nil
+}
+
+
+func (conn *RbdConnection) createImage(imageName string) error {
     // The Manager just tested to make sure it could connect, and that the pool exists (so 
     // we can fail fast if there's a problem).  The workers have to create an RBD image to
     // use.  The connection protocol map know how much data we will be managing.
 
-    imageSize := uint64((conn.worker.WorkerRangeEnd - conn.worker.WorkerRangeStart) * conn.worker.ObjectSize)
-    imageName := fmt.Sprintf("%v-%v-%v", conn.protocol["image_prefix"], conn.worker.Hostname, conn.worker.WorkerId)
+    imageSize := conn.layout.Size()
     imageOrder := uint64(22) // 1 << 22 gives a 4MB object size
 
     options := rbd.NewRbdImageOptions()
     defer options.Destroy()
 
-    err = options.SetUint64(rbd.ImageOptionOrder, imageOrder)
+    err := options.SetUint64(rbd.ImageOptionOrder, imageOrder)
     if err != nil {
         return fmt.Errorf("Failure setting ImageOrder option for RBD Image: %v", err)
     }
@@ -96,14 +129,6 @@ func (conn *RbdConnection) WorkerConnect() error {
         return fmt.Errorf("Failure creating RBD image %v: %v", imageName, err)
     }
 
-    openImage, err := rbd.OpenImage(conn.ioctx, imageName, "")
-    if err != nil {
-        conn.image.Remove()
-        conn.image = nil
-        return fmt.Errorf("Failure opening RBD image %v: %v", imageName, err)
-    }
-
-    conn.image = openImage
     return nil
 }
 
@@ -114,21 +139,16 @@ func (conn *RbdConnection) WorkerClose(cleanup bool) error {
 
         if cleanup {
             conn.image.Remove()
+            conn.imageCreated = false
         }
+
+        conn.image = nil
     }
 
     return conn.ManagerClose(cleanup)
 }
 
 
-/* 
- * Helper function to determine an object's offset into the image from an object key 
- */
-func (conn *RbdConnection) objectOffset(id uint64) int64 {
-    return int64((id - conn.worker.WorkerRangeStart) * conn.worker.ObjectSize)
-}
-
-
 func (conn *RbdConnection) RequiresKey() bool {
     return false
 }
@@ -139,11 +159,15 @@ func (conn *RbdConnection) CanDelete() bool {
 }
 
 
-func (conn *RbdConnection) PutObject(key string, id uint64, buffer []byte) error {
+func (conn *RbdConnection) PutObject(ctx context.Context, key string, id uint64, buffer []byte) error {
     logger.Tracef("Put rados object %v on %v: start\n", key, conn.monitor)
 
-    offset := conn.objectOffset(id)
-    _, err := conn.image.Seek(offset, rbd.SeekSet)
+    offset, err := conn.layout.Offset(id, 0, uint64(len(buffer)))
+    if err != nil {
+        return err
+    }
+
+    _, err = conn.image.Seek(offset, rbd.SeekSet)
     if err != nil {
         return fmt.Errorf("Failure in PutObject for RBD: %v", err)
     }
@@ -156,8 +180,14 @@ func (conn *RbdConnection) PutObject(key string, id uint64, buffer []byte) error
         return fmt.Errorf("Failure in RBD image write: %v", err)
     }
 
-    if uint64(nwrite) != conn.worker.ObjectSize {
-        return fmt.Errorf("Short write in RBD PutObject: expected %v bytes, but got %v", conn.worker.ObjectSize, nwrite)
+    // With target sizes or a size mix, the object may be smaller than its slot in the image.
+    if nwrite != len(buffer) {
+        return fmt.Errorf("Short write in RBD PutObject: expected %v bytes, but got %v", len(buffer), nwrite)
+    }
+
+    // With explicit flushes, the worker times the flush separately.
+    if conn.worker.ExplicitFlush {
+        return nil
     }
 
     err = conn.image.Flush()
@@ -165,9 +195,18 @@ func (conn *RbdConnection) PutObject(key string, id uint64, buffer []byte) error
 }
 
 
-func (conn *RbdConnection) GetObject(key string, id uint64, buffer []byte) error {
-    offset := conn.objectOffset(id)
-    _, err := conn.image.Seek(offset, rbd.SeekSet)
+func (conn *RbdConnection) FlushObject(key string, id uint64) error {
+    return conn.image.Flush()
+}
+
+
+func (conn *RbdConnection) GetObject(ctx context.Context, key string, id uint64, buffer []byte) error {
+    offset, err := conn.layout.Offset(id, 0, uint64(len(buffer)))
+    if err != nil {
+        return err
+    }
+
+    _, err = conn.image.Seek(offset, rbd.SeekSet)
     if err != nil {
         return fmt.Errorf("Failure in RBD image seek: %v", err)
     }
@@ -178,15 +217,47 @@ func (conn *RbdConnection) GetObject(key string, id uint64, buffer []byte) error
         return fmt.Errorf("Failure in RBD image read: %v", err)
     }
 
-    if uint64(nread) != conn.worker.ObjectSize {
-        return fmt.Errorf("Short read: wanted %v bytes, but got %v", conn.worker.ObjectSize, nread)
+    if nread != len(buffer) {
+        return fmt.Errorf("Short read: wanted %v bytes, but got %v", len(buffer), nread)
     }
 
     return nil
 }
 
 
-func (conn *RbdConnection) DeleteObject(key string, id uint64) error {
+func (conn *RbdConnection) GetObjectRange(ctx context.Context, key string, id uint64, offset uint64, buffer []byte) error {
+    start, err := conn.layout.Offset(id, offset, uint64(len(buffer)))
+    if err != nil {
+        return err
+    }
+
+    _, err = conn.image.Seek(start, rbd.SeekSet)
+    if err != nil {
+        return fmt.Errorf("Failure in RBD image seek: %v", err)
+    }
+
+    nread, err := conn.image.Read2(buffer, rbd.LIBRADOS_OP_FLAG_FADVISE_NOCACHE)
+
+    if err != nil {
+        return fmt.Errorf("Failure in RBD image read: %v", err)
+    }
+
+    if nread != len(buffer) {
+        return fmt.Errorf("Short read: wanted %v bytes, but got %v", len(buffer), nread)
+    }
+
+    return nil
+}
+
+
+/* Our objects are just regions of the image, so all we can check is that the object fits in it. */
+func (conn *RbdConnection) StatObject(key string, id uint64) error {
+    _, err := conn.layout.Offset(id, 0, conn.worker.ObjectSize)
+    return err
+}
+
+
+func (conn *RbdConnection) DeleteObject(ctx context.Context, key string, id uint64) error {
     return nil
 }
 
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "math/rand"
+import "strconv"
+import "strings"
+
+
+/*
+ * Ranged reads are aligned to this, since the file and block connections use O_DIRECT, which
+ * will not read from unaligned offsets.
+ */
+const readRangeAlignment = 4096
+
+
+/*
+ * A ReadRange makes each read fetch only part of an object, rather than the whole thing.
+ *
+ * It is given either as OFFSET:LENGTH, such as "8K:4K", which reads the same part of every object,
+ * or as a fraction, such as "0.25", which reads that much of each object from a random offset.
+ *
+ * A fixed range is trimmed to fit any object that is too small for it.  A fraction is rounded up to
+ * a whole number of aligned blocks, and its offset is also aligned.  Either way, a range is trimmed
+ * to a whole number of blocks, so that reads stay aligned for O_DIRECT.
+ */
+type ReadRange struct {
+    Offset uint64
+    Length uint64
+    Fraction float64     // Zero if we are using a fixed offset and length.
+}
+
+
+func ParseReadRange(spec string) (*ReadRange, error) {
+    var rr ReadRange
+
+    offsetStr, lengthStr, found := strings.Cut(spec, ":")
+    if !found {
+        f, err := strconv.ParseFloat(spec, 64)
+        if (err != nil) || !(f > 0) || (f > 1) {
+            return nil, fmt.Errorf("Bad read range: %v.  Should be OFFSET:LENGTH or a fraction greater than 0 and no more than 1", spec)
+        }
+
+        rr.Fraction = f
+        return &rr, nil
+    }
+
+    var err error
+    rr.Offset, err = expandUnits(offsetStr)
+    if err != nil {
+        return nil, fmt.Errorf("Bad offset in read range: %v", offsetStr)
+    }
+
+    rr.Length, err = expandUnits(lengthStr)
+    if (err != nil) || (rr.Length == 0) {
+        return nil, fmt.Errorf("Bad length in read range: %v", lengthStr)
+    }
+
+    if (rr.Offset % readRangeAlignment != 0) || (rr.Length % readRangeAlignment != 0) {
+        return nil, fmt.Errorf("Bad read range: %v.  The offset and length must be multiples of %v", spec, readRangeAlignment)
+    }
+
+    return &rr, nil
+}
+
+
+/*
+ * Pick the offset and length to read from an object of the given size.  Anything after the last
+ * aligned block of the object is never read, so that the length stays aligned when we trim a range
+ * to fit.  Objects smaller than a block can only be read whole.
+ */
+func (rr *ReadRange) Choose(size uint64) (uint64, uint64) {
+    aligned := size / readRangeAlignment * readRangeAlignment
+    if aligned == 0 {
+        return 0, size
+    }
+
+    if rr.Fraction == 0 {
+        offset := rr.Offset
+        if offset >= aligned {
+            offset = 0
+        }
+
+        length := rr.Length
+        if offset + length > aligned {
+            length = aligned - offset
+        }
+
+        return offset, length
+    }
+
+    length := uint64(rr.Fraction * float64(size))
+    length = (length + readRangeAlignment - 1) / readRangeAlignment * readRangeAlignment
+    if length >= aligned {
+        return 0, aligned
+    }
+
+    blocks := (aligned - length) / readRangeAlignment
+    offset := uint64(rand.Int63n(int64(blocks) + 1)) * readRangeAlignment
+    return offset, length
+}
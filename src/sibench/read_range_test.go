@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for ranged reads.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Test functions.
+
+func TestParseReadRange(t *testing.T) {
+    rr, err := ParseReadRange("8K:4K")
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, 8192, int(rr.Offset))
+    testutil.CheckInt(t, 4096, int(rr.Length))
+
+    rr, err = ParseReadRange("0.25")
+    testutil.CheckNoError(t, err)
+    testutil.CheckBool(t, true, rr.Fraction == 0.25)
+
+    for _, spec := range []string{ "1:4K", "4K:1", "4K:0", "0", "1.5", "x" } {
+        _, err = ParseReadRange(spec)
+        testutil.CheckError(t, err)
+    }
+}
+
+
+// Fixed ranges are trimmed to fit, but always to a whole number of aligned blocks.
+func TestReadRangeChooseFixed(t *testing.T) {
+    tests := []struct {
+        size uint64
+        offset uint64
+        length uint64
+    }{
+        { 64 * 1024, 8192, 8192 },          // Fits.
+        { 12 * 1024 + 100, 8192, 4096 },    // Trimmed to the last whole block.
+        { 10 * 1024, 0, 8192 },             // Starts after the last whole block, so read from the start.
+        { 6 * 1024, 0, 4096 },
+        { 100, 0, 100 },                    // Smaller than a block, so read whole.
+    }
+
+    rr := &ReadRange{ Offset: 8192, Length: 8192 }
+
+    for _, test := range tests {
+        offset, length := rr.Choose(test.size)
+        testutil.CheckInt(t, int(test.offset), int(offset))
+        testutil.CheckInt(t, int(test.length), int(length))
+    }
+}
+
+
+// Fractions give aligned ranges which fit in the object's whole blocks.
+func TestReadRangeChooseFraction(t *testing.T) {
+    rr := &ReadRange{ Fraction: 0.25 }
+
+    for _, size := range []uint64{ 64 * 1024, 64 * 1024 + 100, 4096 + 1, 5 * 4096 - 1 } {
+        for i := 0; i < 100; i++ {
+            offset, length := rr.Choose(size)
+            testutil.CheckInt(t, 0, int(offset % readRangeAlignment))
+            testutil.CheckInt(t, 0, int(length % readRangeAlignment))
+            testutil.CheckBool(t, true, length > 0)
+            testutil.CheckBool(t, true, offset + length <= size)
+        }
+    }
+
+    offset, length := (&ReadRange{ Fraction: 1 }).Choose(4096 + 1)
+    testutil.CheckInt(t, 0, int(offset))
+    testutil.CheckInt(t, 4096, int(length))
+
+    _, length = rr.Choose(100)
+    testutil.CheckInt(t, 100, int(length))
+}
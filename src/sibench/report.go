@@ -6,46 +6,199 @@ package main
 import "bufio"
 import "encoding/json"
 import "fmt"
+import "io"
 import "logger"
 import "os"
+import "path/filepath"
 import "strings"
+import "time"
 
 
 
+/*
+ * A summary of how hard one of our sibench drivers was working during a run.  If these numbers
+ * are high, then sibench itself may be the bottleneck rather than the storage under test.
+ *
+ * We also record the versions of sibench, Go and the Ceph client libraries that the driver was
+ * built with, since a driver which behaves differently to the rest may have a mismatched library.
+ */
+type DriverUsage struct {
+    Name string
+    Cores uint64
+    Ram uint64
+    Version string
+    GoVersion string
+    LibradosVersion string
+    LibrbdVersion string
+    AverageCpuPercent float64
+    PeakCpuPercent float64
+    AverageRss uint64
+    PeakRss uint64
+    RangeStart uint64               // The objects that the driver was given, from RangeStart up to RangeEnd.
+    RangeEnd uint64
+    Workers int                     // The number of workers the driver used, and the objects each was given.
+    WorkerRanges []ObjectRange
+}
+
+
+/*
+ * When a phase of the benchmark ran, by the manager's clock.  All times are in UTC, and are
+ * written in RFC 3339 format.
+ *
+ * RampUpEnd and RampDownStart bound the part of the phase whose stats we analyse.  They are
+ * only set for phases which run for a fixed time, and only once we reach them.  End is when the
+ * workers stopped: it does not include the time taken to retrieve their stats afterwards.
+ */
+type PhaseTiming struct {
+    Phase string
+    Start time.Time
+    RampUpEnd *time.Time
+    RampDownStart *time.Time
+    End *time.Time
+}
+
+
+/* Return the current time, ready to fill in one of the optional fields of a PhaseTiming. */
+func phaseTimestamp() *time.Time {
+    t := time.Now().UTC()
+    return &t
+}
+
+
+/* Record the end of a phase, unless we already have. */
+func (p *PhaseTiming) Finish() {
+    if p.End == nil {
+        p.End = phaseTimestamp()
+    }
+}
+
+
+/*
+ * One second of a benchmark phase, for a single type of op, taken from the summaries that the
+ * manager prints once a second whilst the phase runs.  Time is when the second ended, in UTC.
+ *
+ * Ops and Bytes only count successful ops.  Bytes uses the mean object (or read) size, just as
+ * the printed summaries do.
+ */
+type TimeSample struct {
+    Time time.Time
+    Phase string
+    Ops uint64
+    Bytes uint64
+    Failures uint64
+    VerifyFailures uint64
+}
+
+
+/*
+ * A server which we lost part way through a run, when we were allowed to wait for it to restart.
+ * Phase is the phase we were running when we lost it.
+ */
+type ServerLoss struct {
+    Server string
+    Phase string
+    Error string
+    Rejoined bool       // Whether the server restarted in time to rejoin the run.
+}
+
+
+/* The most objects which failed verification that we list in a report. */
+const maxReportedVerifyFailures = 10000
+
+
+/*
+ * Where we print our tables of results for humans to read.  This is stdout, unless our report is
+ * being written there, in which case we move out of its way to stderr.
+ */
+var display io.Writer = os.Stdout
+
+
+/* How often we checkpoint a report as we go, so that a run which is killed still leaves valid JSON. */
+const reportCheckpointInterval = 10 * time.Second
+
+/*
+ * How much of the report we buffer before writing it out.  Writing out our buffer overwrites the end
+ * of our last checkpoint, so we write a new checkpoint first, and this keeps us from doing so too often.
+ */
+const reportBufferSize = 1024 * 1024
+
+
+/* The fewest successful ops that a timed phase needs for its 95th percentile to be more than a handful of ops. */
+const minAnalysedOps = 100
+
+
 /* 
  * A Report contains all the information about a run.  This includes:
  *
  *    The job object we were executing
  *    The errors encountered
+ *    When each phase ran
+ *    A time series of the ops completed in each second of each phase
  *    The details from every single operation performed by the system.
  *    An analysis of the results, both as summaries, and broken down by sibench node and
  *    by target node/
+ *    The CPU and memory usage of each sibench node.
+ *    Any sibench nodes which we lost part way through the run.
+ *    For backends with placement groups, how evenly our objects spread across them.
+ *    If we were given a baseline, how our analyses compare with it.
+ *    The objects which failed verification, up to a limit.
  *
- * The report is written as a JSON file.  It is continually added to as we progress 
- * through the phases of a benchmark.
+ * The report is written as a JSON file, or to stdout if the output is "-".  It is continually
+ * added to as we progress through the phases of a benchmark.
  *
  * We do our best to hold as little data in memory as possible, but it can still end up
  * being pretty large.
+ *
+ * If we are asked for fio's output format, then we write a fio-style report instead, with just
+ * our analyses in it.  See fio_report.go for what that contains.
+ *
+ * Every so often, we write a checkpoint: the end of a valid report, holding everything but the
+ * stats that we have so far, and marked as Incomplete.  We then seek back to the start of it, so
+ * that the next checkpoint, or the real end of the report, overwrites it.  Individual stats stay in
+ * our buffer until the next checkpoint, so that they never overwrite one on their own.  A run which
+ * is killed part way through then leaves a report that can still be read, just with less in it.  We
+ * can only do this with a file that we created ourselves, since we need to be able to seek.
+ *
+ * If we are asked for an atomic report, we stream to a temporary file alongside the output
+ * file instead, and only rename it into place once the report is complete.  That way, anyone
+ * reading the output never sees a half-written report if we die part way through a run.
  */
 type Report struct {
     job *Job
     analyses []*Analysis
     errors []error
+    phases []*PhaseTiming
+    timeSeries []*TimeSample
+    driverUsages []*DriverUsage
+    serverLosses []*ServerLoss
+    verifyFailures []VerifyFailure
+    placement *PlacementSpread
+    baseline *BaselineSummary
 
     /* The stats that we are still waiting to analyse. */
     stats []*ServerStat
 
-    /* The file handle we use to write out a JSON version of the report. */
+    /* The file we created to write out a JSON version of the report, or nil if we were given a writer. */
     jsonFile *os.File
 
     /* Buffered Writer for the JSON file */
     jsonWriter *bufio.Writer
 
+    /* The name of the file we are writing to, which is a temporary file for atomic reports. */
+    jsonPath string
+
     /* Error we maintain to avoid huge amounts of error checking everywhere */
     jsonErr error
 
     /* Whether or not our next stat object needs a comma. */
     jsonStatSeparator string
+
+    /* When we last wrote a checkpoint, and whether we ever have. */
+    lastCheckpoint time.Time
+    checkpointed bool
+
+    /* Set if our file turned out not to be seekable, so that we can't write checkpoints. */
+    noCheckpoints bool
 }
 
 
@@ -53,23 +206,65 @@ type Report struct {
  * Create a new Report object.
  *
  * This will also create a new output file for the JSON results, with the filename
- * being set with the --output argumenmt.
+ * being set with the --output argument.  An output of "-" means stdout.
  */
 func MakeReport(job *Job) (*Report, error) {
-    var r Report
-    r.job = job
+    output := job.arguments.Output
 
-    logger.Infof("Creating report: %s\n", job.arguments.Output)
+    logger.Infof("Creating report: %s\n", output)
 
-    r.jsonFile, r.jsonErr = os.Create(job.arguments.Output)
-    if r.jsonErr != nil {
-        logger.Errorf("Failure creating file: %s, %v\n", job.arguments.Output, r.jsonErr)
+    if output == "-" {
+        return NewReport(job, os.Stdout)
     }
 
-    r.jsonWriter = bufio.NewWriter(r.jsonFile)
+    var file *os.File
+    var path string
+    var err error
+
+    if job.arguments.AtomicReport {
+        file, err = os.CreateTemp(filepath.Dir(output), "." + filepath.Base(output) + ".*.tmp")
+        if err == nil {
+            // Temporary files are private by default, but our report shouldn't be.
+            path = file.Name()
+            err = file.Chmod(0644)
+        }
+    } else {
+        file, err = os.Create(output)
+        path = output
+    }
+
+    if err != nil {
+        logger.Errorf("Failure creating file: %s, %v\n", output, err)
+        return &Report{ job: job, jsonFile: file, jsonPath: path, jsonErr: err }, err
+    }
+
+    r, err := NewReport(job, file)
+    r.jsonFile = file
+    r.jsonPath = path
+    r.Checkpoint()
+    return r, err
+}
+
+
+/*
+ * Create a new Report object which writes its JSON to an already open writer.  Closing the
+ * report flushes what we have written, but leaves the writer open.
+ */
+func NewReport(job *Job, w io.Writer) (*Report, error) {
+    var r Report
+    r.job = job
+    r.jsonWriter = bufio.NewWriterSize(w, reportBufferSize)
+
+    if job.arguments.OutputFormat == "fio" {
+        return &r, r.jsonErr
+    }
 
     r.writeString("{\n  \"Arguments\": ")
     r.writeJson(job.arguments)
+    r.writeString(",\n  \"Lineage\": ")
+    r.writeJson(job.lineage)
+    r.writeString(",\n  \"Manifest\": ")
+    r.writeJson(NewManifest(job))
     r.writeString(",\n  \"Stats\": [\n")
 
     return &r, r.jsonErr
@@ -78,21 +273,72 @@ func MakeReport(job *Job) (*Report, error) {
 
 /*
  * Closes the File object which we are using to write the JSON, having first added 
- * any last sections to it.
+ * any last sections to it.  For atomic reports, this is when we move the report into place.
+ * A report made with NewReport just flushes its writer.
  */
 func (r *Report) Close() {
-    if r.jsonErr != nil {
+    if r.job.arguments.OutputFormat == "fio" {
+        r.writeJson(newFioReport(r.job, r.analyses))
+        r.writeString("\n")
+    } else if r.jsonErr == nil {
+        r.writeProgress()
+        r.writeString(",\n  \"DriverUsages\": ")
+        r.writeJson(r.driverUsages)
+
+        if len(r.serverLosses) > 0 {
+            r.writeString(",\n  \"ServerLosses\": ")
+            r.writeJson(r.serverLosses)
+        }
+
+        if r.placement != nil {
+            r.writeString(",\n  \"Placement\": ")
+            r.writeJson(r.placement)
+        }
+
+        if r.baseline != nil {
+            r.writeString(",\n  \"Baseline\": ")
+            r.writeJson(r.baseline)
+        }
+
+        r.writeString(",\n  \"VerifyFailures\": ")
+        r.writeJson(r.verifyFailures)
+
+        r.writeString("\n}")
+    }
+
+    if r.jsonErr == nil {
+        r.jsonErr = r.jsonWriter.Flush()
+    }
+
+    if r.jsonFile == nil {
         return
     }
 
-    r.writeString("\n  ],\n  \"Errors\": ")
-    r.writeJson(r.errors)
-    r.writeString(",\n  \"Analyses\": ")
-    r.writeJson(r.analyses)
-    r.writeString("\n}")
+    // The end of our last checkpoint may stick out past the real end of the report.
+    if (r.jsonErr == nil) && r.checkpointed {
+        var end int64
+        end, r.jsonErr = r.jsonFile.Seek(0, io.SeekCurrent)
+        if r.jsonErr == nil {
+            r.jsonErr = r.jsonFile.Truncate(end)
+        }
+    }
+
+    if r.jsonErr == nil {
+        r.jsonErr = r.jsonFile.Close()
+    }
+
+    if !r.job.arguments.AtomicReport || (r.jsonPath == "") {
+        return
+    }
+
+    if r.jsonErr == nil {
+        r.jsonErr = os.Rename(r.jsonPath, r.job.arguments.Output)
+    }
 
-    r.jsonWriter.Flush()
-    r.jsonFile.Close()
+    if r.jsonErr != nil {
+        logger.Errorf("Failure writing report: %s, %v\n", r.job.arguments.Output, r.jsonErr)
+        os.Remove(r.jsonPath)
+    }
 }
 
 
@@ -110,7 +356,7 @@ func (r *Report) writeJson(val interface{}) {
     jsonVal, err := json.MarshalIndent(val, "  ", "  ")
     if err != nil {
         logger.Errorf("Failure marshalling arguments to json: %v\n", err)
-        r.jsonFile.Close()
+        r.closeFile()
         r.jsonErr = err
         return
     }
@@ -119,7 +365,7 @@ func (r *Report) writeJson(val interface{}) {
 
     if r.jsonErr != nil {
         logger.Errorf("Failure writing to file: %s, %v\n", r.job.arguments.Output, r.jsonErr)
-        r.jsonFile.Close()
+        r.closeFile()
     }
 }
 
@@ -139,11 +385,96 @@ func (r *Report) writeString(val string) {
 
     if r.jsonErr != nil {
         logger.Errorf("Failure writing to file: %s, %v\n", r.job.arguments.Output, r.jsonErr)
+        r.closeFile()
+    }
+}
+
+
+/* Writes the sections of the report which we have built up in memory so far, after closing the stats. */
+func (r *Report) writeProgress() {
+    r.writeString("\n  ],\n  \"Phases\": ")
+    r.writeJson(r.phases)
+    r.writeString(",\n  \"TimeSeries\": ")
+    r.writeJson(r.timeSeries)
+    r.writeString(",\n  \"Errors\": ")
+    r.writeJson(r.errors)
+    r.writeString(",\n  \"Analyses\": ")
+    r.writeJson(r.analyses)
+}
+
+
+/*
+ * Writes a checkpoint, so that the report is valid JSON if we die before we close it, and then
+ * seeks back so that whatever we write next replaces it.  See the Report comment above.
+ */
+func (r *Report) Checkpoint() {
+    if (r.jsonFile == nil) || r.noCheckpoints || (r.jsonErr != nil) || (r.job.arguments.OutputFormat == "fio") {
+        return
+    }
+
+    r.jsonErr = r.jsonWriter.Flush()
+    if r.jsonErr != nil {
+        logger.Errorf("Failure writing to file: %s, %v\n", r.job.arguments.Output, r.jsonErr)
+        r.closeFile()
+        return
+    }
+
+    start, err := r.jsonFile.Seek(0, io.SeekCurrent)
+    if err != nil {
+        logger.Debugf("Not checkpointing report %s, which we can't seek: %v\n", r.job.arguments.Output, err)
+        r.noCheckpoints = true
+        return
+    }
+
+    r.writeProgress()
+    r.writeString(",\n  \"Incomplete\": true\n}\n")
+
+    if r.jsonErr == nil {
+        r.jsonErr = r.jsonWriter.Flush()
+    }
+
+    if r.jsonErr == nil {
+        _, r.jsonErr = r.jsonFile.Seek(start, io.SeekStart)
+    }
+
+    if r.jsonErr != nil {
+        logger.Errorf("Failure checkpointing report: %s, %v\n", r.job.arguments.Output, r.jsonErr)
+        r.closeFile()
+        return
+    }
+
+    r.lastCheckpoint = time.Now()
+    r.checkpointed = true
+}
+
+
+/* Writes a checkpoint if it has been long enough since the last one. */
+func (r *Report) checkpointIfDue() {
+    if time.Since(r.lastCheckpoint) >= reportCheckpointInterval {
+        r.Checkpoint()
+    }
+}
+
+
+/* Close our file after a failure, if we have one. */
+func (r *Report) closeFile() {
+    if r.jsonFile != nil {
         r.jsonFile.Close()
     }
 }
 
 
+/* Write a whole report that we built in memory to the output file, or to stdout if the output is "-". */
+func writeOutput(output string, data []byte) error {
+    if output == "-" {
+        _, err := os.Stdout.Write(append(data, '\n'))
+        return err
+    }
+
+    return os.WriteFile(output, data, 0644)
+}
+
+
 /**
  * Adds a Stat to the report.  It will be written into the JSON immediately.
  * The Stat will be held on to in memory until AnalyseStats is next called.
@@ -155,7 +486,7 @@ func (r *Report) AddStat(s *ServerStat) {
         return
     }
 
-    template := `%s    {"StartMillis": %v, "DurationMicros": %v, "Phase": "%s", "Error": "%s", "Target": "%s", "Server": "%s"}`
+    template := `%s    {"StartMillis": %v, "DurationMicros": %v, "Phase": "%s", "Error": "%s", "Size": %v, "Target": "%s", "Server": "%s", "Worker": %v}`
     target := r.job.order.Targets[s.TargetIndex]
     server := r.job.servers[s.ServerIndex]
 
@@ -166,11 +497,59 @@ func (r *Report) AddStat(s *ServerStat) {
             s.DurationMicros,
             s.Phase.ToString(),
             s.Error.ToString(),
+            s.Bytes(r.job.order.ObjectSize),
             target,
-            server)
+            server,
+            s.WorkerIndex)
+
+    // Don't let the stats spill out of our buffer over the end of our last checkpoint.
+    if r.jsonWriter.Available() < len(val) {
+        r.Checkpoint()
+    }
 
     r.writeString(val)
     r.jsonStatSeparator = ",\n"
+    r.checkpointIfDue()
+}
+
+
+/* Record the start of a new phase, returning its timing so that the caller can fill in the rest. */
+func (r *Report) StartPhase(phase string) *PhaseTiming {
+    p := &PhaseTiming{ Phase: phase, Start: time.Now().UTC() }
+    r.phases = append(r.phases, p)
+    r.Checkpoint()
+    return p
+}
+
+
+/* Add a sample to our time series for each type of op in a second's summary. */
+func (r *Report) AddTimeSamples(s *StatSummary) {
+    now := time.Now().UTC()
+    objectSize := r.job.order.MeanObjectSize()
+    readSize := r.job.order.MeanReadSize()
+
+    for phase := StatPhase(0); phase < SP_Len; phase++ {
+        total := uint64(0)
+        for err := StatError(0); err < SE_Len; err++ {
+            total += s[phase][err]
+        }
+
+        if total == 0 {
+            continue
+        }
+
+        ts := &TimeSample {
+            Time: now,
+            Phase: phase.ToString(),
+            Ops: s[phase][SE_None],
+            Bytes: s[phase][SE_None] * phase.OpSize(objectSize, readSize),
+            Failures: s.OperationFailures(phase),
+            VerifyFailures: s[phase][SE_VerifyFailure] }
+
+        r.timeSeries = append(r.timeSeries, ts)
+    }
+
+    r.checkpointIfDue()
 }
 
 
@@ -182,6 +561,123 @@ func (r *Report) AddError(e error) {
 }
 
 
+/*
+ * Adds the resource usage of one of our drivers to the Report.
+ */
+func (r *Report) AddDriverUsage(d *ServerDetails) {
+    du := &DriverUsage {
+        Name: d.Name,
+        Cores: d.Cores,
+        Ram: d.Ram,
+        Version: d.Version,
+        GoVersion: d.GoVersion,
+        LibradosVersion: d.LibradosVersion,
+        LibrbdVersion: d.LibrbdVersion,
+        AverageCpuPercent: d.Usage.AverageCpuPercent(),
+        PeakCpuPercent: d.Usage.PeakCpuPercent,
+        AverageRss: d.Usage.AverageRss(),
+        PeakRss: d.Usage.PeakRss,
+        RangeStart: d.order.RangeStart,
+        RangeEnd: d.order.RangeEnd,
+        Workers: len(d.workerRanges),
+        WorkerRanges: d.workerRanges }
+
+    r.driverUsages = append(r.driverUsages, du)
+}
+
+
+/*
+ * Records that we have lost one of our servers.  We return the record, so that it can be updated if
+ * the server rejoins.
+ */
+func (r *Report) AddServerLoss(server string, phase string, err error) *ServerLoss {
+    sl := &ServerLoss{ Server: server, Phase: phase, Error: err.Error() }
+    r.serverLosses = append(r.serverLosses, sl)
+    return sl
+}
+
+
+/*
+ * Sets how evenly the run's objects spread across the backend's placement groups.
+ */
+func (r *Report) SetPlacement(ps *PlacementSpread) {
+    r.placement = ps
+}
+
+
+/*
+ * Adds the objects which one of our drivers found to fail verification to the Report.  We only
+ * list so many of them, so that a run which fails every read doesn't use up all our memory.
+ */
+func (r *Report) AddVerifyFailures(failures []VerifyFailure) {
+    room := maxReportedVerifyFailures - len(r.verifyFailures)
+    if len(failures) > room {
+        failures = failures[:room]
+    }
+
+    r.verifyFailures = append(r.verifyFailures, failures...)
+}
+
+
+/*
+ * Returns how many objects failed verification.  This can be more than we have listed, since we
+ * only list so many.  In a verification pass, an object which we can't read at all has failed too.
+ */
+func (r *Report) VerifyFailureCount() uint64 {
+    var count uint64
+
+    for _, a := range r.analyses {
+        if !a.IsTotal {
+            continue
+        }
+
+        if a.Phase == SP_Verify.ToString() {
+            count += a.Failures()
+        } else {
+            count += a.VerifyFailures
+        }
+    }
+
+    if count < uint64(len(r.verifyFailures)) {
+        count = uint64(len(r.verifyFailures))
+    }
+
+    return count
+}
+
+
+/*
+ * Returns a description of each way in which our write and read totals breach the thresholds
+ * given with --slo-res-95 and --slo-bandwidth, or nil if they don't breach any.
+ */
+func (r *Report) SloBreaches() []string {
+    return sloBreaches(r.analyses, r.job.arguments.SloRes95Value, r.job.arguments.SloBandwidthInBits)
+}
+
+
+/* The guts of SloBreaches, taking the thresholds directly.  A threshold of zero is not checked. */
+func sloBreaches(analyses []*Analysis, maxRes95 time.Duration, minBandwidth uint64) []string {
+    var breaches []string
+
+    for _, a := range analyses {
+        if !a.IsTotal || ((a.Phase != SP_Write.ToString()) && (a.Phase != SP_Read.ToString())) {
+            continue
+        }
+
+        res95 := time.Duration(a.ResTime95) * time.Millisecond
+        if (maxRes95 > 0) && (res95 > maxRes95) {
+            breaches = append(breaches, fmt.Sprintf("%v 95th percentile response time %v is over %v", a.Name, res95, maxRes95))
+        }
+
+        if (minBandwidth > 0) && (a.Bandwidth < minBandwidth) {
+            breaches = append(breaches, fmt.Sprintf("%v bandwidth %v bits/s is under %v bits/s", a.Name, a.Bandwidth, minBandwidth))
+        }
+    }
+
+    return breaches
+}
+
+
 /*
  * Do the maths on all the stats we are currently holding, in order to generate
  * some number of Analysis objects for the report.
@@ -194,7 +690,7 @@ func (r *Report) AnalyseStats() {
     // Start off by throwing out anything in a ramp period.
     stats := filter(r.stats, rampFilter(r.job))
 
-    phases := []StatPhase{ SP_Write, SP_Read }
+    phases := []StatPhase{ SP_Write, SP_Flush, SP_Append, SP_Stat, SP_Read, SP_Verify, SP_BucketCreate, SP_BucketDelete, SP_ChurnDelete, SP_HoldConnect, SP_Keepalive }
 
     // Produce per-target and per-server analyses for each phase
     for _, phase := range phases {
@@ -211,6 +707,10 @@ func (r *Report) AnalyseStats() {
                 sstats := filter(pstats, serverFilter(uint16(sIndex)))
                 a := NewAnalysis(sstats, "Server[" + limit(s, 12) + "] " + phase.ToString(), phase, false, r.job)
                 r.analyses = append(r.analyses, a)
+
+                if r.job.arguments.WorkerStats {
+                    r.analyseWorkers(sstats, uint16(sIndex), phase)
+                }
             }
         }
     }
@@ -221,18 +721,73 @@ func (r *Report) AnalyseStats() {
         if len(pstats) > 0 {
             a := NewAnalysis(pstats, "Total " + phase.ToString(), phase, true, r.job)
             r.analyses = append(r.analyses, a)
+            checkOpCount(a, phase)
+            noteRetries(a, phase)
         }
     }
 
     r.stats = nil
+    r.Checkpoint()
+}
+
+
+/*
+ * Warn about, and flag, a total for a timed phase with too few successful ops for its response
+ * times to mean much.  This is usually a run time that is too short for the object size and
+ * bandwidth, and is easy to miss, since everything else about the run looks fine.
+ */
+func checkOpCount(a *Analysis, phase StatPhase) {
+    if !phase.IsTimed() || (a.Successes >= minAnalysedOps) {
+        return
+    }
+
+    a.TooFewOps = true
+    logger.Warnf("Only %v successful %v ops were analysed, which is too few for reliable response times.  " +
+        "Try a longer run time, or smaller objects\n", a.Successes, phase.ToString())
+}
+
+
+/*
+ * Tell the user how many ops in a phase needed retrying.  The response times only include each
+ * op's last attempt, so a phase with a lot of retries is slower than it looks.
+ */
+func noteRetries(a *Analysis, phase StatPhase) {
+    if a.RetriedOperations == 0 {
+        return
+    }
+
+    logger.Warnf("%v %v ops needed retries, taking %v retries in all\n", a.RetriedOperations, phase.ToString(), a.Retries)
+}
+
+
+/*
+ * Adds an analysis for each worker on a server, so that one slow worker can't hide amongst the
+ * rest.  We don't know how many workers each server had, so we look for the highest worker
+ * index in its stats.
+ */
+func (r *Report) analyseWorkers(sstats []*ServerStat, serverIndex uint16, phase StatPhase) {
+    workers := 0
+    for _, s := range sstats {
+        if int(s.WorkerIndex) >= workers {
+            workers = int(s.WorkerIndex) + 1
+        }
+    }
+
+    server := r.job.servers[serverIndex]
+
+    for wIndex := 0; wIndex < workers; wIndex++ {
+        wstats := filter(sstats, workerFilter(serverIndex, uint16(wIndex)))
+        name := fmt.Sprintf("Worker[%v:%v] %v", limit(server, 8), wIndex, phase.ToString())
+        r.analyses = append(r.analyses, NewAnalysis(wstats, name, phase, false, r.job))
+    }
 }
 
 
 /*
- * Prints the analyses to stdout with some nice formatting.
+ * Prints the analyses with some nice formatting.
  */
 func (r *Report) DisplayAnalyses(useBytes bool) {
-    lineWidth := 160
+    lineWidth := 176
     lastPhase := "" // Choosing a value that will not be a real phase.
 
     // First print out the target and server analyses
@@ -241,22 +796,111 @@ func (r *Report) DisplayAnalyses(useBytes bool) {
         if !a.IsTotal {
             if a.Phase != lastPhase {
                 lastPhase = a.Phase
-                fmt.Printf("%v\n", strings.Repeat("-", lineWidth))
+                fmt.Fprintf(display, "%v\n", strings.Repeat("-", lineWidth))
             }
 
-            fmt.Printf("%v\n", a.String(useBytes))
+            fmt.Fprintf(display, "%v\n", a.String(useBytes))
         }
     }
 
     // Now print the grand totals
 
-    fmt.Printf("%v\n", strings.Repeat("=", lineWidth))
+    fmt.Fprintf(display, "%v\n", strings.Repeat("=", lineWidth))
 
     for _, a := range r.analyses {
         if a.IsTotal {
-            fmt.Printf("%v\n", a.String(useBytes))
+            fmt.Fprintf(display, "%v\n", a.String(useBytes))
         }
     }
 
-    fmt.Printf("%v\n", strings.Repeat("=", lineWidth))
+    fmt.Fprintf(display, "%v\n", strings.Repeat("=", lineWidth))
+}
+
+
+/*
+ * Compares each of our analyses with the analysis of the same name in a baseline report.  Anything
+ * which we can't compare gets a note saying why, rather than failing the run.
+ */
+func (r *Report) CompareWithBaseline(b *Baseline) {
+    r.baseline = &BaselineSummary{ File: b.File, Notes: b.Notes(r.job.arguments) }
+
+    for _, a := range r.analyses {
+        a.Baseline = b.Compare(a)
+    }
+}
+
+
+/*
+ * Prints how our totals compare with the baseline, along with anything that makes the
+ * comparison less meaningful.
+ */
+func (r *Report) DisplayBaselineComparisons() {
+    if r.baseline == nil {
+        return
+    }
+
+    fmt.Fprintf(display, "Compared with %v:\n", r.baseline.File)
+
+    for _, note := range r.baseline.Notes {
+        fmt.Fprintf(display, "    Note: %v\n", note)
+    }
+
+    for _, a := range r.analyses {
+        if a.IsTotal && (a.Baseline != nil) {
+            fmt.Fprintf(display, "%-28v   %v\n", a.Name, a.Baseline)
+        }
+    }
+
+    fmt.Fprintf(display, "%v\n", strings.Repeat("=", 176))
+}
+
+
+/*
+ * Prints the resource usage of each of our drivers.
+ */
+func (r *Report) DisplayDriverUsages() {
+    for _, du := range r.driverUsages {
+        fmt.Fprintf(display, "%-28v   cpu-avg: %5.1f%%,  cpu-peak: %5.1f%%,  rss-avg: %7vB,  rss-peak: %7vB\n",
+            "Driver[" + limit(du.Name, 12) + "]",
+            du.AverageCpuPercent,
+            du.PeakCpuPercent,
+            ToUnits(du.AverageRss),
+            ToUnits(du.PeakRss))
+    }
+
+    if len(r.driverUsages) > 0 {
+        fmt.Fprintf(display, "%v\n", strings.Repeat("=", 176))
+    }
+}
+
+
+/*
+ * Prints the objects which failed verification.  There could be a great many of them,
+ * so we only print the first few: the rest are in the JSON report.
+ */
+func (r *Report) DisplayVerifyFailures() {
+    const maxDisplayed = 20
+
+    if len(r.verifyFailures) == 0 {
+        fmt.Fprintf(display, "All objects passed verification\n")
+        return
+    }
+
+    fmt.Fprintf(display, "%v objects failed verification:\n", r.VerifyFailureCount())
+
+    for i, f := range r.verifyFailures {
+        if i == maxDisplayed {
+            where := r.job.arguments.Output
+            if where == "-" {
+                where = "the report"
+            }
+
+            fmt.Fprintf(display, "    ... and more: see the VerifyFailures in %v\n", where)
+            break
+        }
+
+        fmt.Fprintf(display, "    object<%v> on %v from %v: %v\n", f.Id, f.Target, f.Server, f.Error)
+    }
+
+    fmt.Fprintf(display, "%v\n", strings.Repeat("=", 176))
 }
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the analyses in our reports, and for writing them out.
+
+package main
+
+import "bytes"
+import "encoding/json"
+import "errors"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+import "silib/testutil"
+
+
+// Test functions.
+
+// Each worker on a server must get an analysis of its own stats, even one that did nothing.
+func TestAnalyseWorkers(t *testing.T) {
+    job := &Job{ servers: []string{ "alpha", "beta" }, runTime: 1 }
+    r := &Report{ job: job }
+
+    var sstats []*ServerStat
+    for _, w := range []uint16{ 0, 0, 2, 2, 2 } {
+        s := &ServerStat{ ServerIndex: 1 }
+        s.WorkerIndex = w
+        s.DurationMicros = 1000
+        sstats = append(sstats, s)
+    }
+
+    r.analyseWorkers(sstats, 1, SP_Read)
+
+    testutil.CheckInt(t, 3, len(r.analyses))
+    testutil.CheckString(t, "Worker[beta:0] Read", r.analyses[0].Name)
+    testutil.CheckInt(t, 2, int(r.analyses[0].Successes))
+    testutil.CheckInt(t, 0, int(r.analyses[1].Successes))
+    testutil.CheckInt(t, 3, int(r.analyses[2].Successes))
+    testutil.CheckBool(t, false, r.analyses[2].IsTotal)
+}
+
+
+// Totals for timed phases with too few successes are flagged, but untimed phases can have as few as they like.
+func TestCheckOpCount(t *testing.T) {
+    a := &Analysis{ Successes: minAnalysedOps - 1 }
+    checkOpCount(a, SP_Read)
+    testutil.CheckBool(t, true, a.TooFewOps)
+
+    a = &Analysis{ Successes: minAnalysedOps }
+    checkOpCount(a, SP_Write)
+    testutil.CheckBool(t, false, a.TooFewOps)
+
+    a = &Analysis{ Successes: 1 }
+    checkOpCount(a, SP_Verify)
+    testutil.CheckBool(t, false, a.TooFewOps)
+}
+
+
+// Only the write and read totals are checked, and only against the thresholds which were given.
+func TestSloBreaches(t *testing.T) {
+    analyses := []*Analysis {
+        { Name: "Total Write", Phase: SP_Write.ToString(), IsTotal: true, ResTime95: 40, Bandwidth: 2000 },
+        { Name: "Total Read", Phase: SP_Read.ToString(), IsTotal: true, ResTime95: 60, Bandwidth: 1000 },
+        { Name: "Server[a] Read", Phase: SP_Read.ToString(), ResTime95: 90, Bandwidth: 10 },
+        { Name: "Total Stat", Phase: SP_Stat.ToString(), IsTotal: true, ResTime95: 90, Bandwidth: 0 } }
+
+    tests := []struct {
+        maxRes95 time.Duration
+        minBandwidth uint64
+        expected int
+    } {
+        { 0, 0, 0 },
+        { 60 * time.Millisecond, 1000, 0 },
+        { 50 * time.Millisecond, 0, 1 },
+        { 30 * time.Millisecond, 0, 2 },
+        { 0, 1500, 1 },
+        { 30 * time.Millisecond, 3000, 4 },
+    }
+
+    for _, test := range tests {
+        testutil.CheckInt(t, test.expected, len(sloBreaches(analyses, test.maxRes95, test.minBandwidth)))
+    }
+
+    breaches := sloBreaches(analyses, 50 * time.Millisecond, 0)
+    testutil.CheckString(t, "Total Read 95th percentile response time 60ms is over 50ms", breaches[0])
+    testutil.CheckInt(t, ExitSloFailure, exitCode(&SloError{ breaches }))
+}
+
+
+// A report can go to any writer, such as stdout, which it leaves to its owner to close.
+func TestNewReportWriter(t *testing.T) {
+    var buf bytes.Buffer
+    job := &Job{ arguments: &Arguments{ Output: "-", OutputFormat: "sibench" } }
+
+    r, err := NewReport(job, &buf)
+    testutil.CheckNoError(t, err)
+    r.Close()
+
+    var report struct {
+        Arguments Arguments
+        Stats []interface{}
+    }
+
+    testutil.CheckNoError(t, json.Unmarshal(buf.Bytes(), &report))
+    testutil.CheckString(t, "-", report.Arguments.Output)
+    testutil.CheckInt(t, 0, len(report.Stats))
+}
+
+
+// A report is valid JSON at every checkpoint, marked as incomplete until it is closed.
+func TestReportCheckpoint(t *testing.T) {
+    output := filepath.Join(t.TempDir(), "report.json")
+    job := &Job{ arguments: &Arguments{ Output: output, OutputFormat: "sibench" } }
+
+    r, err := MakeReport(job)
+    testutil.CheckNoError(t, err)
+    r.StartPhase("Write")
+    r.AddError(errors.New("lost a server"))
+    r.Checkpoint()
+
+    var report struct {
+        Phases []PhaseTiming
+        Errors []interface{}
+        Incomplete bool
+    }
+
+    data, err := os.ReadFile(output)
+    testutil.CheckNoError(t, err)
+    testutil.CheckNoError(t, json.Unmarshal(data, &report))
+    testutil.CheckBool(t, true, report.Incomplete)
+    testutil.CheckInt(t, 1, len(report.Phases))
+    testutil.CheckInt(t, 1, len(report.Errors))
+
+    r.StartPhase("Read")
+    r.Close()
+
+    report.Incomplete = false
+    data, err = os.ReadFile(output)
+    testutil.CheckNoError(t, err)
+    testutil.CheckNoError(t, json.Unmarshal(data, &report))
+    testutil.CheckBool(t, false, report.Incomplete)
+    testutil.CheckInt(t, 2, len(report.Phases))
+}
+
+
+// Stats written after a checkpoint must not break it, even once there are more than we can buffer.
+func TestReportCheckpointAddStat(t *testing.T) {
+    output := filepath.Join(t.TempDir(), "report.json")
+    job := &Job{
+        arguments: &Arguments{ Output: output, OutputFormat: "sibench", IndividualStats: true },
+        order: WorkOrder{ Targets: []string{ "target" }, ObjectSize: 1024 },
+        servers: []string{ "server" } }
+
+    r, err := MakeReport(job)
+    testutil.CheckNoError(t, err)
+    r.StartPhase("Write")
+
+    var report struct {
+        Stats []interface{}
+        Incomplete bool
+    }
+
+    for i := 0; i < 20000; i++ {
+        s := &ServerStat{}
+        s.Phase = SP_Write
+        r.AddStat(s)
+
+        if (i == 0) || (i == 19999) {
+            data, err := os.ReadFile(output)
+            testutil.CheckNoError(t, err)
+            testutil.CheckNoError(t, json.Unmarshal(data, &report))
+            testutil.CheckBool(t, true, report.Incomplete)
+        }
+    }
+
+    // The stats we had to write out to make room must have made it into a checkpoint.
+    testutil.CheckBool(t, true, len(report.Stats) > 0)
+
+    r.Close()
+
+    report.Incomplete = false
+    data, err := os.ReadFile(output)
+    testutil.CheckNoError(t, err)
+    testutil.CheckNoError(t, json.Unmarshal(data, &report))
+    testutil.CheckBool(t, false, report.Incomplete)
+    testutil.CheckInt(t, 20000, len(report.Stats))
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "github.com/aws/aws-sdk-go/aws/credentials"
+import "github.com/aws/aws-sdk-go/aws/signer/v4"
+import "io"
+import "logger"
+import "net/http"
+import "net/url"
+import "strings"
+import "time"
+
+
+/*
+ * RgwAdmin talks to the admin ops API of a Ceph RadosGateway, so that we can create a throwaway
+ * S3 user for a run, rather than making the user set one up by hand.
+ *
+ * Admin ops requests are signed in the same way as S3 requests, with the keys of a user which has
+ * the "users=*" admin capability.  We only need the user calls: see
+ * https://docs.ceph.com/en/latest/radosgw/adminops/
+ */
+type RgwAdmin struct {
+    url string
+    creds *credentials.Credentials
+    client *http.Client
+}
+
+
+/* The credentials of an S3 user that we created for a run. */
+type RgwUser struct {
+    Uid string
+    AccessKey string
+    SecretKey string
+}
+
+
+/* The parts of the admin ops API's description of a user that we need. */
+type rgwUserInfo struct {
+    UserId string                 `json:"user_id"`
+    Keys []struct {
+        User string                 `json:"user"`
+        AccessKey string            `json:"access_key"`
+        SecretKey string            `json:"secret_key"`
+    }                               `json:"keys"`
+}
+
+
+func NewRgwAdmin(adminUrl string, accessKey string, secretKey string) *RgwAdmin {
+    var a RgwAdmin
+    a.url = strings.TrimSuffix(adminUrl, "/")
+    a.creds = credentials.NewStaticCredentials(accessKey, secretKey, "")
+    a.client = &http.Client{ Timeout: 30 * time.Second }
+    return &a
+}
+
+
+/* Create a user with a generated S3 key pair.  The uid must not already exist. */
+func (a *RgwAdmin) CreateUser(uid string) (*RgwUser, error) {
+    query := url.Values{}
+    query.Set("uid", uid)
+    query.Set("display-name", "sibench run " + uid)
+    query.Set("key-type", "s3")
+    query.Set("generate-key", "true")
+    query.Set("format", "json")
+
+    body, err := a.request("PUT", "/admin/user", query)
+    if err != nil {
+        return nil, fmt.Errorf("Failure creating RGW user %v: %v", uid, err)
+    }
+
+    return parseRgwUser(uid, body)
+}
+
+
+/* Delete a user, along with any buckets and objects that it owns. */
+func (a *RgwAdmin) DeleteUser(uid string) error {
+    query := url.Values{}
+    query.Set("uid", uid)
+    query.Set("purge-data", "true")
+
+    _, err := a.request("DELETE", "/admin/user", query)
+    if err != nil {
+        return fmt.Errorf("Failure deleting RGW user %v: %v", uid, err)
+    }
+
+    return nil
+}
+
+
+/* Make a signed admin ops request, returning the body of the response. */
+func (a *RgwAdmin) request(method string, path string, query url.Values) ([]byte, error) {
+    req, err := http.NewRequest(method, a.url + path + "?" + query.Encode(), nil)
+    if err != nil {
+        return nil, err
+    }
+
+    // RGW doesn't care about the region, but it has to match when it checks the signature.
+    _, err = v4.NewSigner(a.creds).Sign(req, nil, "s3", "us-east-1", time.Now())
+    if err != nil {
+        return nil, err
+    }
+
+    logger.Debugf("RGW admin request: %v %v\n", method, req.URL)
+
+    resp, err := a.client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+
+    defer resp.Body.Close()
+
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, err
+    }
+
+    if (resp.StatusCode < 200) || (resp.StatusCode >= 300) {
+        return nil, fmt.Errorf("%v: %v", resp.Status, string(bytes.TrimSpace(body)))
+    }
+
+    return body, nil
+}
+
+
+/* Pull the S3 keys for a user out of the admin ops API's description of it. */
+func parseRgwUser(uid string, body []byte) (*RgwUser, error) {
+    var info rgwUserInfo
+
+    err := json.Unmarshal(body, &info)
+    if err != nil {
+        return nil, fmt.Errorf("Bad response creating RGW user %v: %v", uid, err)
+    }
+
+    for _, k := range info.Keys {
+        if (k.User == uid) && (k.AccessKey != "") && (k.SecretKey != "") {
+            return &RgwUser{ Uid: uid, AccessKey: k.AccessKey, SecretKey: k.SecretKey }, nil
+        }
+    }
+
+    return nil, fmt.Errorf("No S3 keys in response creating RGW user %v", uid)
+}
+
+
+/*
+ * Create a throwaway S3 user for a single benchmark, and point the job's protocol config at its
+ * keys.  The user should be deleted with deleteRunUser once the benchmark is over.
+ */
+func createRgwRunUser(j *Job) (*RgwAdmin, *RgwUser, error) {
+    args := j.arguments
+    admin := NewRgwAdmin(args.RgwAdminUrl, args.RgwAdminAccessKey, args.RgwAdminSecretKey)
+
+    uid := createUniquePrefix()
+    logger.Infof("Creating RGW user %v for the run\n", uid)
+
+    user, err := admin.CreateUser(uid)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    j.order.ProtocolConfig["access_key"] = user.AccessKey
+    j.order.ProtocolConfig["secret_key"] = user.SecretKey
+    return admin, user, nil
+}
+
+
+/* Delete a user made by createRgwRunUser.  Failing to is not fatal, since the run itself is done. */
+func (a *RgwAdmin) deleteRunUser(user *RgwUser) {
+    logger.Infof("Deleting RGW user %v\n", user.Uid)
+
+    err := a.DeleteUser(user.Uid)
+    if err != nil {
+        logger.Warnf("%v.  It will need deleting by hand\n", err)
+    }
+}
@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for creating and deleting S3 users with the RGW admin ops API.
+
+package main
+
+import "net/http"
+import "net/http/httptest"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+/* Start a fake admin ops API, which passes on the requests it gets, and answers them with a status and body. */
+func startFakeRgwAdmin(t *testing.T, status int, body string, requests chan<- *http.Request) *RgwAdmin {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests <- r
+        w.WriteHeader(status)
+        w.Write([]byte(body))
+    }))
+
+    t.Cleanup(server.Close)
+    return NewRgwAdmin(server.URL + "/", "admin", "secret")
+}
+
+
+// Test functions.
+
+// Creating a user asks for a generated S3 key, and hands back the keys for that user.
+func TestRgwAdminCreateUser(t *testing.T) {
+    requests := make(chan *http.Request, 10)
+    body := `{ "user_id": "run", "keys": [ { "user": "run", "access_key": "AK", "secret_key": "SK" } ] }`
+    admin := startFakeRgwAdmin(t, http.StatusOK, body, requests)
+
+    user, err := admin.CreateUser("run")
+    testutil.CheckNoError(t, err)
+    testutil.CheckString(t, "AK", user.AccessKey)
+    testutil.CheckString(t, "SK", user.SecretKey)
+
+    r := <-requests
+    testutil.CheckString(t, "PUT", r.Method)
+    testutil.CheckString(t, "/admin/user", r.URL.Path)
+    testutil.CheckString(t, "run", r.URL.Query().Get("uid"))
+    testutil.CheckString(t, "true", r.URL.Query().Get("generate-key"))
+}
+
+
+// Deleting a user purges its buckets too, since nobody else can use them.
+func TestRgwAdminDeleteUser(t *testing.T) {
+    requests := make(chan *http.Request, 10)
+    admin := startFakeRgwAdmin(t, http.StatusOK, "", requests)
+
+    testutil.CheckNoError(t, admin.DeleteUser("run"))
+    r := <-requests
+    testutil.CheckString(t, "DELETE", r.Method)
+    testutil.CheckString(t, "true", r.URL.Query().Get("purge-data"))
+}
+
+
+// Refusals from the gateway, and responses without keys for our user, are errors.
+func TestRgwAdminFailures(t *testing.T) {
+    requests := make(chan *http.Request, 10)
+
+    admin := startFakeRgwAdmin(t, http.StatusForbidden, `{ "Code": "AccessDenied" }`, requests)
+    _, err := admin.CreateUser("run")
+    testutil.CheckError(t, err)
+    testutil.CheckError(t, admin.DeleteUser("run"))
+
+    admin = startFakeRgwAdmin(t, http.StatusOK, `{ "user_id": "run", "keys": [ { "user": "other", "access_key": "AK", "secret_key": "SK" } ] }`, requests)
+    _, err = admin.CreateUser("run")
+    testutil.CheckError(t, err)
+
+    admin = startFakeRgwAdmin(t, http.StatusOK, `not json`, requests)
+    _, err = admin.CreateUser("run")
+    testutil.CheckError(t, err)
+}
@@ -4,6 +4,7 @@
 package main
 
 import "bytes"
+import "context"
 import "fmt"
 import "github.com/aws/aws-sdk-go/aws"
 import "github.com/aws/aws-sdk-go/aws/awserr"
@@ -12,6 +13,11 @@ import "github.com/aws/aws-sdk-go/aws/session"
 import "github.com/aws/aws-sdk-go/service/s3"
 import "io"
 import "logger"
+import "net"
+import "net/http"
+import "strconv"
+import "sync/atomic"
+import "time"
 
 
 /*
@@ -23,6 +29,21 @@ type S3Connection struct {
     bucket string
     bucketCreatedBySibench bool
     client *s3.S3
+
+    /* Objects larger than this are uploaded in parts of multipartPartSize bytes */
+    multipartThreshold uint64
+    multipartPartSize uint64
+
+    /* If false, every client we create gets its own HTTP transport without keep-alives */
+    connectionReuse bool
+
+    /* If true, we are held idle, so we have our own HTTP transport, and count the network connections it makes */
+    holdConnection bool
+    transport *http.Transport
+    dials uint64
+
+    /* If non-zero, we cancel any op which takes longer than this */
+    opTimeout time.Duration
 }
 
 
@@ -31,6 +52,14 @@ func NewS3Connection(target string, protocol ProtocolConfig, worker WorkerConnec
     conn.gateway = target
     conn.protocol = protocol
     conn.bucket = protocol["bucket"]
+    conn.connectionReuse = worker.ConnectionReuse
+    conn.holdConnection = worker.HoldConnection
+    conn.opTimeout = worker.OpTimeout
+
+    // No need to check for conversion errors here: these are the result of FormatUint calls anyway.
+    conn.multipartThreshold, _ = strconv.ParseUint(protocol["multipart_threshold"], 10, 64)
+    conn.multipartPartSize, _ = strconv.ParseUint(protocol["multipart_part_size"], 10, 64)
+
     return &conn, nil
 }
 
@@ -46,7 +75,9 @@ func (conn *S3Connection) ManagerConnect() error {
         return err
     }
 
-    return conn.createBucket(conn.bucket)
+    return retryProvisioning(conn.protocol, "creating bucket " + conn.bucket, func() error {
+        return conn.createBucket(conn.bucket)
+    })
 }
 
 
@@ -83,13 +114,36 @@ func (conn *S3Connection) WorkerConnect() error {
 	awsConfig = awsConfig.WithS3ForcePathStyle(true)
 	awsConfig = awsConfig.WithCredentials(creds)
 
+    // The default HTTP transport pools its connections, which would let a new client pick up an
+    // already established connection.  When we're not reusing connections, we don't want that.
+    if !conn.connectionReuse {
+        transport := &http.Transport{ Proxy: http.ProxyFromEnvironment, DisableKeepAlives: true }
+        awsConfig = awsConfig.WithHTTPClient(&http.Client{ Transport: transport })
+    }
+
+    // A held connection mustn't share the pool either, or we wouldn't really hold as many as we
+    // were asked to.  Counting the dials lets us tell when the gateway has dropped it.
+    if conn.holdConnection {
+        dialer := &net.Dialer{ Timeout: 30 * time.Second, KeepAlive: 30 * time.Second }
+        conn.transport = &http.Transport{
+            Proxy: http.ProxyFromEnvironment,
+            MaxIdleConnsPerHost: 1,
+            DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+                atomic.AddUint64(&conn.dials, 1)
+                return dialer.DialContext(ctx, network, address)
+            },
+        }
+
+        awsConfig = awsConfig.WithHTTPClient(&http.Client{ Transport: conn.transport })
+    }
+
     // Create an AWS session
     session, err := session.NewSession()
     if err != nil {
         return err
     }
 
-    logger.Infof("Creating S3 Connection to %v\n", endpoint)
+    logger.Debugf("Creating S3 Connection to %v\n", endpoint)
     conn.client = s3.New(session, awsConfig)
 
     return nil
@@ -97,7 +151,12 @@ func (conn *S3Connection) WorkerConnect() error {
 
 
 func (conn *S3Connection) WorkerClose(cleanup bool) error {
-    // Since S3 is a stateless protocol, there is no Close necessary.
+    // Since S3 is a stateless protocol, there is no Close necessary, unless we own a transport
+    // that is holding a network connection open.
+    if conn.transport != nil {
+        conn.transport.CloseIdleConnections()
+    }
+
     return nil
 }
 
@@ -126,6 +185,22 @@ func (conn *S3Connection) deleteBucket(bucket string) error {
 }
 
 
+/*
+ * Creates a bucket for a bucket operations benchmark.  Unlike createBucket, we don't check whether
+ * the bucket already exists, since that would add a request to the timed operation.
+ */
+func (conn *S3Connection) CreateBucket(bucket string) error {
+    _, err := conn.client.CreateBucket(&s3.CreateBucketInput{ Bucket: aws.String(bucket) })
+    return err
+}
+
+
+/* Deletes a bucket for a bucket operations benchmark. */
+func (conn *S3Connection) DeleteBucket(bucket string) error {
+    return conn.deleteBucket(bucket)
+}
+
+
 /**
  * The S3 API says that creating a bucket if it already exists should fail with a
  * 'BucketAlreadyExists' error.  Unfortunately RGW does not implement the S3 protocol
@@ -152,6 +227,26 @@ func (conn *S3Connection) bucketExists(bucket string) (bool, error) {
 }
 
 
+/*
+ * A keepalive on a held connection, which checks that our bucket exists.  The first one makes our
+ * network connection, so after that, any dial means that the gateway dropped the one we had.
+ */
+func (conn *S3Connection) Keepalive() error {
+    dials := atomic.LoadUint64(&conn.dials)
+
+    _, err := conn.client.HeadBucket(&s3.HeadBucketInput{ Bucket: aws.String(conn.bucket) })
+    if err != nil {
+        return err
+    }
+
+    if (dials > 0) && (atomic.LoadUint64(&conn.dials) > dials) {
+        return ErrConnectionDropped
+    }
+
+    return nil
+}
+
+
 func (conn *S3Connection) RequiresKey() bool {
     return true
 }
@@ -161,10 +256,31 @@ func (conn *S3Connection) CanDelete() bool {
 }
 
 
-func (conn *S3Connection) PutObject(key string, id uint64, buffer []byte) error {
+/*
+ * Returns a context for a single op, which cancels the op's requests if it takes longer than our op
+ * timeout, or if the worker's context is cancelled first.  The caller must call the cancel function
+ * once it has finished with the response.
+ */
+func (conn *S3Connection) opContext(parent context.Context) (context.Context, context.CancelFunc) {
+    if conn.opTimeout == 0 {
+        return context.WithCancel(parent)
+    }
+
+    return context.WithTimeout(parent, conn.opTimeout)
+}
+
+
+func (conn *S3Connection) PutObject(ctx context.Context, key string, id uint64, buffer []byte) error {
+    ctx, cancel := conn.opContext(ctx)
+    defer cancel()
+
+    if (conn.multipartThreshold > 0) && (uint64(len(buffer)) > conn.multipartThreshold) {
+        return conn.putMultipartObject(ctx, key, buffer)
+    }
+
     reader := bytes.NewReader(buffer)
 
-	_, err := conn.client.PutObject(&s3.PutObjectInput{
+	_, err := conn.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
 		Body:   reader,
 		Bucket: &conn.bucket,
 		Key:    &key,
@@ -174,13 +290,92 @@ func (conn *S3Connection) PutObject(key string, id uint64, buffer []byte) error
 }
 
 
-func (conn *S3Connection) GetObject(key string, id uint64, buffer []byte) error {
+/*
+ * Uploads a large object using the S3 multipart upload API, so that no single request has to carry
+ * the whole object.  Each part is sent straight from the caller's buffer, without copying.
+ *
+ * If any part fails, we abort the upload so that the gateway can discard the parts it already has.
+ * The abort doesn't use the op's context, so that it still happens if the op timed out.
+ */
+func (conn *S3Connection) putMultipartObject(ctx context.Context, key string, buffer []byte) error {
+    upload, err := conn.client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+        Bucket: &conn.bucket,
+        Key:    &key,
+    })
 
-    resp, err := conn.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(conn.bucket), Key: aws.String(key)})
     if err != nil {
         return err
     }
 
+    parts := make([]*s3.CompletedPart, 0, (uint64(len(buffer)) / conn.multipartPartSize) + 1)
+
+    for start := uint64(0); start < uint64(len(buffer)); start += conn.multipartPartSize {
+        end := start + conn.multipartPartSize
+        if end > uint64(len(buffer)) {
+            end = uint64(len(buffer))
+        }
+
+        partNumber := int64(len(parts) + 1)
+
+        resp, err := conn.client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+            Body:       bytes.NewReader(buffer[start:end]),
+            Bucket:     &conn.bucket,
+            Key:        &key,
+            PartNumber: aws.Int64(partNumber),
+            UploadId:   upload.UploadId,
+        })
+
+        if err != nil {
+            conn.abortMultipartUpload(key, upload.UploadId)
+            return fmt.Errorf("Failure uploading part %v: %w", partNumber, err)
+        }
+
+        parts = append(parts, &s3.CompletedPart{ ETag: resp.ETag, PartNumber: aws.Int64(partNumber) })
+    }
+
+    _, err = conn.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+        Bucket:          &conn.bucket,
+        Key:             &key,
+        UploadId:        upload.UploadId,
+        MultipartUpload: &s3.CompletedMultipartUpload{ Parts: parts },
+    })
+
+    if err != nil {
+        conn.abortMultipartUpload(key, upload.UploadId)
+    }
+
+    return err
+}
+
+
+func (conn *S3Connection) abortMultipartUpload(key string, uploadId *string) {
+    _, err := conn.client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+        Bucket:   &conn.bucket,
+        Key:      &key,
+        UploadId: uploadId,
+    })
+
+    if err != nil {
+        logger.Warnf("Failure aborting multipart upload of %v on %v: %v\n", key, conn.gateway, err)
+    }
+}
+
+
+/*
+ * Reads an object by streaming the response body directly into the caller's buffer, so that
+ * we never hold more than one copy of the object in memory, however large it is.
+ */
+func (conn *S3Connection) GetObject(ctx context.Context, key string, id uint64, buffer []byte) error {
+    ctx, cancel := conn.opContext(ctx)
+    defer cancel()
+
+    resp, err := conn.client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: aws.String(conn.bucket), Key: aws.String(key)})
+    if err != nil {
+        return err
+    }
+
+    defer resp.Body.Close()
+
     if *resp.ContentLength != int64(cap(buffer)) {
         return fmt.Errorf("Object has wrong size: expected %v, but got %v", cap(buffer), *resp.ContentLength)
     }
@@ -200,9 +395,43 @@ func (conn *S3Connection) GetObject(key string, id uint64, buffer []byte) error
 }
 
 
-func (conn *S3Connection) DeleteObject(key string, id uint64) error {
+/* Reads part of an object using an HTTP Range header. */
+func (conn *S3Connection) GetObjectRange(ctx context.Context, key string, id uint64, offset uint64, buffer []byte) error {
+    byteRange := fmt.Sprintf("bytes=%v-%v", offset, offset + uint64(len(buffer)) - 1)
+
+    ctx, cancel := conn.opContext(ctx)
+    defer cancel()
+
+    resp, err := conn.client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: aws.String(conn.bucket), Key: aws.String(key), Range: aws.String(byteRange)})
+    if err != nil {
+        return err
+    }
+
+    defer resp.Body.Close()
+
+    if *resp.ContentLength != int64(len(buffer)) {
+        return fmt.Errorf("Range has wrong size: expected %v, but got %v", len(buffer), *resp.ContentLength)
+    }
+
+    _, err = io.ReadFull(resp.Body, buffer)
+    return err
+}
+
+
+func (conn *S3Connection) StatObject(key string, id uint64) error {
+    ctx, cancel := conn.opContext(context.Background())
+    defer cancel()
+
+    _, err := conn.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{Bucket: aws.String(conn.bucket), Key: aws.String(key)})
+    return err
+}
+
+
+func (conn *S3Connection) DeleteObject(ctx context.Context, key string, id uint64) error {
+    ctx, cancel := conn.opContext(ctx)
+    defer cancel()
 
-	_, err := conn.client.DeleteObject(&s3.DeleteObjectInput{
+	_, err := conn.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
         Bucket: &conn.bucket,
         Key:    &key,
     })
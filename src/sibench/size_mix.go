@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "strconv"
+import "strings"
+
+
+/*
+ * A SizeMix lets a benchmark use objects of several different sizes, rather than one fixed size.
+ *
+ * It is given as a list of SIZE:WEIGHT pairs, such as "4K:50,1M:50".  The weights are relative, and
+ * are interpreted according to the basis:
+ *
+ *   count   The weights are shares of the number of objects: half the objects are 4K and half are 1M.
+ *   bytes   The weights are shares of the total bytes: as much data is stored in 4K objects as in 1M
+ *           objects, so there are 256 4K objects for every 1M object.
+ *
+ * Either way, we convert the weights into cumulative probabilities by object count, so that we
+ * can pick the size of each object.  The choice depends only on the seed and the object's id, so
+ * every worker that touches an object agrees on how big it should be.
+ */
+type SizeMix struct {
+    Sizes []uint64
+    Cumulative []float64
+}
+
+
+/* The valid values for --size-mix-basis. */
+const sizeMixByCount = "count"
+const sizeMixByBytes = "bytes"
+
+
+func ParseSizeMix(mix string, basis string) (*SizeMix, error) {
+    if (basis != sizeMixByCount) && (basis != sizeMixByBytes) {
+        return nil, fmt.Errorf("Bad size mix basis: %v.  Should be %v or %v", basis, sizeMixByCount, sizeMixByBytes)
+    }
+
+    var sm SizeMix
+    var weights []float64
+    total := 0.0
+
+    for _, entry := range strings.Split(mix, ",") {
+        sizeStr, weightStr, found := strings.Cut(entry, ":")
+        if !found {
+            return nil, fmt.Errorf("Bad size mix entry: %v.  Should be of the form SIZE:WEIGHT", entry)
+        }
+
+        size, err := expandUnits(sizeStr)
+        if (err != nil) || (size == 0) {
+            return nil, fmt.Errorf("Bad size in size mix: %v", sizeStr)
+        }
+
+        weight, err := strconv.ParseFloat(weightStr, 64)
+        if (err != nil) || !(weight > 0) {
+            return nil, fmt.Errorf("Bad weight in size mix: %v.  Should be a positive number", weightStr)
+        }
+
+        // Byte shares become object counts once we divide by the size of each object.
+        if basis == sizeMixByBytes {
+            weight /= float64(size)
+        }
+
+        sm.Sizes = append(sm.Sizes, size)
+        weights = append(weights, weight)
+        total += weight
+    }
+
+    sum := 0.0
+    for _, w := range weights {
+        sum += w
+        sm.Cumulative = append(sm.Cumulative, sum / total)
+    }
+
+    return &sm, nil
+}
+
+
+/* Return the size of a given object. */
+func (sm *SizeMix) SizeFor(seed uint64, id uint64) uint64 {
+    // A single round of our prng doesn't mix the id into the high bits, so we do several.
+    next := prng(seed)
+    next = prng(next ^ id)
+    next = prng(next)
+    next = prng(next)
+
+    // Use the top 53 bits as a float in [0, 1).
+    r := float64(next >> 11) / (1 << 53)
+
+    for i, c := range sm.Cumulative {
+        if r < c {
+            return sm.Sizes[i]
+        }
+    }
+
+    return sm.Sizes[len(sm.Sizes) - 1]
+}
+
+
+/* Return the largest size in the mix, which is how big our buffers need to be. */
+func (sm *SizeMix) MaxSize() uint64 {
+    var max uint64
+    for _, s := range sm.Sizes {
+        if s > max {
+            max = s
+        }
+    }
+
+    return max
+}
+
+
+/* Return the expected size of an object, averaged over the mix. */
+func (sm *SizeMix) MeanSize() uint64 {
+    mean := 0.0
+    last := 0.0
+
+    for i, c := range sm.Cumulative {
+        mean += (c - last) * float64(sm.Sizes[i])
+        last = c
+    }
+
+    return uint64(mean)
+}
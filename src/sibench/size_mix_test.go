@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for parsing size mixes and choosing the size of each object.
+
+package main
+
+import "math"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+func checkFloatsClose(t *testing.T, expected []float64, actual []float64) {
+    t.Helper()
+
+    if len(expected) != len(actual) {
+        t.Errorf("Expected %v but got %v", expected, actual)
+        return
+    }
+
+    for i := range expected {
+        if math.Abs(expected[i] - actual[i]) > 1e-9 {
+            t.Errorf("Expected %v but got %v", expected, actual)
+            return
+        }
+    }
+}
+
+
+// Pick the size of count objects, and return the fraction of objects, and of bytes, that went to each size.
+func measureSizeMix(sm *SizeMix, seed uint64, count uint64) ([]float64, []float64) {
+    objects := make([]float64, len(sm.Sizes))
+    bytes := make([]float64, len(sm.Sizes))
+    totalBytes := 0.0
+
+    for id := uint64(0); id < count; id++ {
+        size := sm.SizeFor(seed, id)
+        for i, s := range sm.Sizes {
+            if s == size {
+                objects[i]++
+                bytes[i] += float64(size)
+                totalBytes += float64(size)
+                break
+            }
+        }
+    }
+
+    for i := range objects {
+        objects[i] /= float64(count)
+        bytes[i] /= totalBytes
+    }
+
+    return objects, bytes
+}
+
+
+// Test functions.
+
+func TestParseSizeMix(t *testing.T) {
+    tests := []struct {
+        mix string
+        basis string
+        sizes []uint64
+        cumulative []float64
+    } {
+        { "4K:1", "count", []uint64{ 4096 }, []float64{ 1 } },
+        { "4K:50,1M:50", "count", []uint64{ 4096, 1024 * 1024 }, []float64{ 0.5, 1 } },
+        { "4K:1,8K:2,16K:1", "count", []uint64{ 4096, 8192, 16384 }, []float64{ 0.25, 0.75, 1 } },
+        { "4K:0.5,4K:1.5", "count", []uint64{ 4096, 4096 }, []float64{ 0.25, 1 } },
+        { "4K:50,1M:50", "bytes", []uint64{ 4096, 1024 * 1024 }, []float64{ 256.0 / 257.0, 1 } },
+        { "4K:1,8K:1", "bytes", []uint64{ 4096, 8192 }, []float64{ 2.0 / 3.0, 1 } },
+        { "1M:3,1M:1", "bytes", []uint64{ 1024 * 1024, 1024 * 1024 }, []float64{ 0.75, 1 } },
+    }
+
+    for _, test := range tests {
+        sm, err := ParseSizeMix(test.mix, test.basis)
+        testutil.CheckNoError(t, err)
+        if sm == nil {
+            continue
+        }
+
+        testutil.CheckInt(t, len(test.sizes), len(sm.Sizes))
+        for i := range test.sizes {
+            if i < len(sm.Sizes) {
+                testutil.CheckInt(t, int(test.sizes[i]), int(sm.Sizes[i]))
+            }
+        }
+
+        checkFloatsClose(t, test.cumulative, sm.Cumulative)
+    }
+}
+
+
+func TestParseSizeMixBad(t *testing.T) {
+    tests := []struct {
+        mix string
+        basis string
+    } {
+        { "4K:1", "objects" },      // Unknown basis.
+        { "4K:1", "" },
+        { "", "count" },            // No entries.
+        { "4K", "count" },          // No weight.
+        { "4K:1,", "count" },       // An empty entry.
+        { "x:1", "count" },         // Bad sizes.
+        { "0:1", "count" },
+        { "4K:lots", "count" },     // Bad weights.
+        { "4K:0", "count" },
+        { "4K:-1", "bytes" },
+        { "4K:NaN", "count" },
+    }
+
+    for _, test := range tests {
+        _, err := ParseSizeMix(test.mix, test.basis)
+        testutil.CheckError(t, err)
+    }
+}
+
+
+// Sizes are chosen in the proportions asked for, by object count or by bytes depending on the basis.
+func TestSizeMixSizeFor(t *testing.T) {
+    tests := []struct {
+        mix string
+        basis string
+        objects []float64
+        bytes []float64
+    } {
+        { "64K:1", "count", []float64{ 1 }, []float64{ 1 } },
+        { "4K:50,64K:50", "count", []float64{ 0.5, 0.5 }, []float64{ 1.0 / 17.0, 16.0 / 17.0 } },
+        { "4K:1,8K:2,16K:1", "count", []float64{ 0.25, 0.5, 0.25 }, []float64{ 1.0 / 9.0, 4.0 / 9.0, 4.0 / 9.0 } },
+        { "4K:50,64K:50", "bytes", []float64{ 16.0 / 17.0, 1.0 / 17.0 }, []float64{ 0.5, 0.5 } },
+        { "4K:1,8K:2,16K:1", "bytes", []float64{ 4.0 / 9.0, 4.0 / 9.0, 1.0 / 9.0 }, []float64{ 0.25, 0.5, 0.25 } },
+    }
+
+    for _, test := range tests {
+        sm, err := ParseSizeMix(test.mix, test.basis)
+        testutil.CheckNoError(t, err)
+
+        objects, bytes := measureSizeMix(sm, 42, 200000)
+        for i := range test.objects {
+            if math.Abs(objects[i] - test.objects[i]) > 0.01 {
+                t.Errorf("%v by %v: expected object shares of %v but got %v", test.mix, test.basis, test.objects, objects)
+            }
+
+            if math.Abs(bytes[i] - test.bytes[i]) > 0.02 {
+                t.Errorf("%v by %v: expected byte shares of %v but got %v", test.mix, test.basis, test.bytes, bytes)
+            }
+        }
+    }
+}
+
+
+// Every worker must agree on the size of an object, and a different seed must give a different mix.
+func TestSizeMixSizeForDeterministic(t *testing.T) {
+    sm, err := ParseSizeMix("4K:1,8K:1,16K:1,32K:1", "count")
+    testutil.CheckNoError(t, err)
+
+    differs := false
+    for id := uint64(0); id < 1000; id++ {
+        testutil.CheckInt(t, int(sm.SizeFor(42, id)), int(sm.SizeFor(42, id)))
+        if sm.SizeFor(42, id) != sm.SizeFor(43, id) {
+            differs = true
+        }
+    }
+
+    testutil.CheckBool(t, true, differs)
+}
+
+
+func TestSizeMixMaxAndMeanSize(t *testing.T) {
+    tests := []struct {
+        mix string
+        basis string
+        max uint64
+        mean uint64
+    } {
+        { "4K:1", "count", 4096, 4096 },
+        { "4K:1,8K:2,16K:1", "count", 16384, 9216 },
+        { "16K:1,4K:3", "count", 16384, 7168 },
+        { "4K:50,64K:50", "bytes", 65536, 7710 },
+    }
+
+    for _, test := range tests {
+        sm, err := ParseSizeMix(test.mix, test.basis)
+        testutil.CheckNoError(t, err)
+        testutil.CheckInt(t, int(test.max), int(sm.MaxSize()))
+        testutil.CheckInt(t, int(test.mean), int(sm.MeanSize()))
+    }
+}
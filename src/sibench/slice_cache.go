@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "container/list"
+import "sync"
+
+
+/*
+ * A sliceCache holds a bounded number of a slice generator's slices in memory, for when the
+ * generator is reading its slices from disk on demand.  When it is full, the least recently used
+ * slice is dropped.
+ *
+ * A generator can be used by several of a worker's ops at once, so the cache has its own lock.
+ */
+type sliceCache struct {
+    mutex sync.Mutex
+    capacity int
+    entries map[int64]*list.Element
+    order *list.List                // The most recently used slice is at the front.
+}
+
+
+type sliceCacheEntry struct {
+    index int64
+    data []byte
+}
+
+
+func newSliceCache(capacity int) *sliceCache {
+    return &sliceCache{ capacity: capacity, entries: make(map[int64]*list.Element), order: list.New() }
+}
+
+
+/* Return a slice, or nil if we don't hold it. */
+func (c *sliceCache) Get(index int64) []byte {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    e, ok := c.entries[index]
+    if !ok {
+        return nil
+    }
+
+    c.order.MoveToFront(e)
+    return e.Value.(*sliceCacheEntry).data
+}
+
+
+/* Add a slice, dropping the least recently used one if we are full. */
+func (c *sliceCache) Add(index int64, data []byte) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    // Another op may have loaded the same slice whilst we were loading it.
+    if e, ok := c.entries[index]; ok {
+        c.order.MoveToFront(e)
+        return
+    }
+
+    if c.order.Len() >= c.capacity {
+        oldest := c.order.Back()
+        c.order.Remove(oldest)
+        delete(c.entries, oldest.Value.(*sliceCacheEntry).index)
+    }
+
+    c.entries[index] = c.order.PushFront(&sliceCacheEntry{ index, data })
+}
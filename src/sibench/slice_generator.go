@@ -8,9 +8,10 @@ import "encoding/binary"
 import "fmt"
 import "io"
 import "io/fs"
+import "logger"
 import "math/rand"
 import "os"
-import "path"
+import "path/filepath"
 import "strconv"
 
 
@@ -25,7 +26,8 @@ import "strconv"
  * slices, each containing (say) 4Kb of data.  (Both of those values are run-time arguments).
  *
  * When asked to generate a new workload object we do the following:
- *   1,  Create a seed (chosen from our master PRNG).
+ *   1,  Create a seed, derived from our master seed and the object's id and cycle.  This means
+ *       that two runs with the same master seed produce identical objects.
  *   2.  Write the seed into the start of the workload object.
  *   3.  Use the seed to create a PRNG just for this workload object.
  *   4.  Use that prng to select slices from our library, which are concatenated onto the object
@@ -34,12 +36,28 @@ import "strconv"
  * This approach means that we do not need to ever store the objects themselves: we can verify a 
  * read operation by reading the seed from the first few bytes, and then recreating the object we
  * would expect.
+ *
+ * Normally we hold the whole library in memory.  If that would take too much memory, then we can
+ * instead remember only where each slice starts, and read the slices from disk when we need them,
+ * keeping a bounded number of them in a cache.  Both modes pick the same slices, so they generate
+ * identical objects.
+ *
+ * Either way, the first objects we generate can be slow, since the pages holding our slices (or the
+ * slice files, if we read on demand) may not be in memory yet.  If asked to, we generate a few
+ * objects up front to warm things up, so that the cost doesn't land in the timed phases.
  */
 type SliceGenerator struct {
+    seed uint64
     prng *rand.Rand
     sliceCount int
     sliceSize int
-    slices [][]byte
+    slices [][]byte             // Our library of slices, or nil if we read them on demand.
+
+    /* These fields are used when we read slices on demand. */
+
+    files []sliceFile           // The files that we take slices from.
+    sliceStarts []int64         // Where each slice starts, if we concatenate all of our files.
+    cache *sliceCache
 }
 
 
@@ -50,21 +68,114 @@ func CreateSliceGenerator(seed uint64, config GeneratorConfig) (*SliceGenerator,
     // No need to check for conversion errors here: these are the result of Itoa calls anyway.
     sg.sliceSize, _ = strconv.Atoi(config["size"])
     sg.sliceCount, _ = strconv.Atoi(config["count"])
+    sg.seed = seed
     sg.prng = rand.New(rand.NewSource(int64(seed)))
-    sg.slices = make([][]byte, sg.sliceCount)
+    cacheSize, _ := strconv.Atoi(config["cache"])
+    warmup, _ := strconv.Atoi(config["warmup"])
+    warmupSize, _ := strconv.ParseUint(config["warmup-size"], 10, 64)
 
     dirname := config["dir"]
+    var files []sliceFile
+    var err error
+
+    if config["recursive"] == "true" {
+        files, err = listSliceFilesRecursively(dirname)
+    } else {
+        files, err = listSliceFiles(dirname)
+    }
+
+    if err != nil {
+        return nil, err
+    }
+
+    /* Compute the total number of bytes in our files. */
+    var totalBytes uint64 = 0
+    for _, f := range files {
+        totalBytes += uint64(f.size)
+    }
+
+    /* If our total number of bytes is less than our requested slice sliceSize, then we can't make slices! */
+    if uint64(sg.sliceSize) > totalBytes {
+        return nil, fmt.Errorf("Not enough bytes in the files in %v - need at least %v", dirname, sg.sliceSize)
+    }
+
+    /* A cache which can hold every slice is no different to keeping them all in memory. */
+    if (cacheSize == 0) || (cacheSize >= sg.sliceCount) {
+        sg.slices = make([][]byte, sg.sliceCount)
+
+        for i :=0; i < sg.sliceCount; i++ {
+            sg.slices[i], err = sg.loadSlice(totalBytes, files)
+            if err != nil {
+                return nil, err
+            }
+        }
+
+        sg.warmUp(warmup, warmupSize)
+        return &sg, nil
+    }
+
+    /* Otherwise, load each slice once to check that we can, and fill our cache as we go. */
+    sg.files = files
+    sg.sliceStarts = make([]int64, sg.sliceCount)
+    sg.cache = newSliceCache(cacheSize)
+
+    for i :=0; i < sg.sliceCount; i++ {
+        sg.sliceStarts[i] = sg.pickSliceStart(totalBytes)
+
+        slice, err := readSlice(files, sg.sliceStarts[i], sg.sliceSize)
+        if err != nil {
+            return nil, err
+        }
+
+        if i < cacheSize {
+            sg.cache.Add(int64(i), slice)
+        }
+    }
+
+    sg.warmUp(warmup, warmupSize)
+    return &sg, nil
+}
+
+
+/*
+ * Generate and throw away the given number of objects, to pull our slices into memory before we
+ * are timed.  Objects are generated from their seeds alone, so this changes nothing that we
+ * generate later.  If we don't know the object size, then we use one which holds as many slices as
+ * our library.
+ */
+func (sg *SliceGenerator) warmUp(count int, size uint64) {
+    if count <= 0 {
+        return
+    }
+
+    if size == 0 {
+        size = 4 + uint64(sg.sliceSize * sg.sliceCount)
+    }
+
+    buffer := make([]byte, size)
+    for i := 0; i < count; i++ {
+        sg.Generate(size, uint64(i), 0, &buffer)
+    }
+}
+
+
+
+/* A file that we can load slices from. */
+type sliceFile struct {
+    path string
+    size int64
+}
+
+
+/* Build a list of all the regular files directly in our slice directory. */
+func listSliceFiles(dirname string) ([]sliceFile, error) {
 	entries, err := os.ReadDir(dirname)
 
 	if err != nil {
         return nil, fmt.Errorf("Unable to read slice directory %v: %v", dirname, err)
     }
 
-    /* Build a array of file info objects for all the regular files in our directory.
-     * Also, compute the total number of bytes in those files. */
-
-    var totalBytes uint64 = 0
-    infos := make([]fs.FileInfo, 0)
+    files := make([]sliceFile, 0)
 
 	for _, e := range entries {
 		info, err := e.Info()
@@ -73,24 +184,45 @@ func CreateSliceGenerator(seed uint64, config GeneratorConfig) (*SliceGenerator,
         }
 
         if info.Mode().IsRegular() {
-            infos = append(infos, info)
-            totalBytes += uint64(info.Size())
+            files = append(files, sliceFile{ filepath.Join(dirname, e.Name()), info.Size() })
         }
 	}
 
-    /* If our total number of bytes is less than our requested slice sliceSize, then we can't make slices! */
-    if uint64(sg.sliceSize) > totalBytes {
-        return nil, fmt.Errorf("Not enough bytes in the files in %v - need at least %v", dirname, sg.sliceSize)
-    }
+    return files, nil
+}
 
-    for i :=0; i < sg.sliceCount; i++ {
-        sg.slices[i], err = sg.loadSlice(totalBytes, dirname, infos)
+
+/*
+ * Build a list of all the regular files in our slice directory and its subdirectories.
+ * WalkDir visits them in lexical order, so every driver with the same tree builds the same list.
+ * As with the flat listing, we don't follow symbolic links.
+ */
+func listSliceFilesRecursively(dirname string) ([]sliceFile, error) {
+    files := make([]sliceFile, 0)
+
+    err := filepath.WalkDir(dirname, func(filename string, d fs.DirEntry, err error) error {
         if err != nil {
-            return nil, err
+            return err
+        }
+
+        if !d.Type().IsRegular() {
+            return nil
+        }
+
+        info, err := d.Info()
+        if err != nil {
+            return fmt.Errorf("Unable to stat %v: %v", filename, err)
         }
+
+        files = append(files, sliceFile{ filename, info.Size() })
+        return nil
+    })
+
+    if err != nil {
+        return nil, fmt.Errorf("Unable to read slice directory %v: %v", dirname, err)
     }
 
-    return &sg, nil
+    return files, nil
 }
 
 
@@ -102,17 +234,28 @@ func CreateSliceGenerator(seed uint64, config GeneratorConfig) (*SliceGenerator,
  * in that buffer to read as many bytes as we need for our slice.
  * (We don't actually do it like, obviously, but the effect is the same).
  */
-func (sg *SliceGenerator) loadSlice(totalBytes uint64, dirname string, infos []fs.FileInfo) ([]byte, error) {
+func (sg *SliceGenerator) loadSlice(totalBytes uint64, files []sliceFile) ([]byte, error) {
+    return readSlice(files, sg.pickSliceStart(totalBytes), sg.sliceSize)
+}
+
+
+/* Pick a random position for a slice, as if we had concatenated all of our files. */
+func (sg *SliceGenerator) pickSliceStart(totalBytes uint64) int64 {
     lastStart := totalBytes - uint64(sg.sliceSize)
-    start := sg.prng.Int63n(int64(lastStart))
+    return sg.prng.Int63n(int64(lastStart))
+}
+
+
+/* Read a slice of the given size from a position in our concatenated files. */
+func readSlice(files []sliceFile, start int64, sliceSize int) ([]byte, error) {
     var pos int64 = 0
     read := 0
 
-    result := make([]byte, sg.sliceSize)
+    result := make([]byte, sliceSize)
 
-    for _, info := range infos {
-        if pos + info.Size() >= start {
-            filename := path.Join(dirname, info.Name())
+    for _, sf := range files {
+        if pos + sf.size >= start {
+            filename := sf.path
             f, err := os.Open(filename)
             if err != nil {
                 return nil, fmt.Errorf("Unable to open %v: %v", filename, err)
@@ -128,16 +271,16 @@ func (sg *SliceGenerator) loadSlice(totalBytes uint64, dirname string, infos []f
             n, err := f.ReadAt(result[read:], offset)
             read += n
 
-            if read == sg.sliceSize {
+            if read == sliceSize {
                 return result, nil
             }
 
             if err != io.EOF {
-                return nil, fmt.Errorf("Unable read %v bytes from offset %v in %v: %v", sg.sliceSize - read, offset, filename, err)
+                return nil, fmt.Errorf("Unable read %v bytes from offset %v in %v: %v", sliceSize - read, offset, filename, err)
             }
         }
 
-        pos += info.Size()
+        pos += sf.size
     }
 
     return nil, fmt.Errorf("Should never happen!")
@@ -146,8 +289,17 @@ func (sg *SliceGenerator) loadSlice(totalBytes uint64, dirname string, infos []f
 
 
 func (sg *SliceGenerator) Generate(size uint64, id uint64, cycle uint64, buffer *[]byte) {
-    seed := uint32(sg.prng.Int())
-    sg.generateFromSeed(size, seed, buffer)
+    sg.generateFromSeed(size, sg.objectSeed(size, id, cycle), buffer)
+}
+
+
+/* Derive the seed for a particular object from our master seed. */
+func (sg *SliceGenerator) objectSeed(size uint64, id uint64, cycle uint64) uint32 {
+    next := sg.seed
+    next = prng(next ^ size)
+    next = prng(next ^ cycle)
+    next = prng(next ^ id)
+    return uint32(next)
 }
 
 
@@ -158,13 +310,70 @@ func (sg *SliceGenerator) generateFromSeed(size uint64, seed uint32, buffer *[]b
 
     for start := uint64(4); start < size; start += uint64(sg.sliceSize) {
         /* Copy does the computation of min( len(src), len(dst) ) for us, so we don't need to worry */
-        copy((*buffer)[start:], sg.slices[tmp_prng.Int63n(int64(sg.sliceCount))])
+        copy((*buffer)[start:], sg.slice(tmp_prng.Int63n(int64(sg.sliceCount))))
     }
 }
 
 
+/*
+ * As generateFromSeed, but only for the part of the object in the buffer.  We still have to pick
+ * every slice up to the end of the range, to keep our prng in step, but only copy those we need.
+ */
+func (sg *SliceGenerator) GenerateRange(size uint64, id uint64, cycle uint64, offset uint64, buffer []byte) {
+    var header [4]byte
+    seed := sg.objectSeed(size, id, cycle)
+    end := offset + uint64(len(buffer))
+
+    binary.LittleEndian.PutUint32(header[:], seed)
+    copyOverlap(buffer, offset, header[:], 0)
+
+    tmp_prng := rand.New(rand.NewSource(int64(seed)))
+
+    for start := uint64(4); start < end; start += uint64(sg.sliceSize) {
+        index := tmp_prng.Int63n(int64(sg.sliceCount))
+        if start + uint64(sg.sliceSize) <= offset {
+            continue
+        }
+
+        slice := sg.slice(index)
+        if start + uint64(len(slice)) > size {
+            slice = slice[:size - start]
+        }
+
+        copyOverlap(buffer, offset, slice, start)
+    }
+}
 
-func (sg *SliceGenerator) Verify(size uint64, id uint64, buffer *[]byte, scratch *[]byte) error {
+
+/*
+ * Return one of our slices, reading it from disk if we need to.
+ *
+ * We have no way to return an error from here, since Generate can't fail.  If we can no longer read
+ * a slice (perhaps because someone has changed our files during the run) then we log it and return
+ * nil.  Copying nil leaves whatever was in the buffer, so the object will fail verification.
+ */
+func (sg *SliceGenerator) slice(index int64) []byte {
+    if sg.slices != nil {
+        return sg.slices[index]
+    }
+
+    if slice := sg.cache.Get(index); slice != nil {
+        return slice
+    }
+
+    slice, err := readSlice(sg.files, sg.sliceStarts[index], sg.sliceSize)
+    if err != nil {
+        logger.Errorf("Unable to reload slice %v: %v\n", index, err)
+        return nil
+    }
+
+    sg.cache.Add(index, slice)
+    return slice
+}
+
+
+
+func (sg *SliceGenerator) Verify(size uint64, id uint64, cycle uint64, buffer *[]byte, scratch *[]byte) error {
     if uint64(len(*buffer)) != size {
         return fmt.Errorf("Incorrect size: expected %v but got %v\n", size, len(*buffer))
     }
@@ -172,6 +381,12 @@ func (sg *SliceGenerator) Verify(size uint64, id uint64, buffer *[]byte, scratch
     // Read the seed from the header of the payload
     seed := binary.LittleEndian.Uint32(*buffer)
 
+    // We can't recover the cycle from the seed, but if we know which cycle to expect then we can
+    // check that the seed is the one for that cycle.
+    if (cycle != AnyCycle) && (seed != sg.objectSeed(size, id, cycle)) {
+        return fmt.Errorf("Stale object: not written in expected cycle %v\n", cycle)
+    }
+
     // Now we can generate the expected buffer to compare against.
     sg.generateFromSeed(size, seed, scratch)
 
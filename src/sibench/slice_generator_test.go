@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the slice generator.
+
+package main
+
+import "os"
+import "path/filepath"
+import "strconv"
+import "testing"
+import "silib/testutil"
+
+
+// Helper functions.
+
+// Make a slice generator from a directory holding a single file of data.
+func makeTestSliceGenerator(t *testing.T) Generator {
+    dir := t.TempDir()
+    data := make([]byte, 64 * 1024)
+    for i := range data {
+        data[i] = byte(i * 7)
+    }
+
+    err := os.WriteFile(filepath.Join(dir, "data"), data, 0644)
+    testutil.CheckNoError(t, err)
+
+    config := GeneratorConfig{ "dir": dir, "size": strconv.Itoa(1024), "count": strconv.Itoa(16) }
+    sg, err := CreateSliceGenerator(42, config)
+    testutil.CheckNoError(t, err)
+
+    return sg
+}
+
+
+// Test functions.
+
+// Generate an object, then verify it, using buffers of exactly the object's size.
+func TestSliceGeneratorRoundTrip(t *testing.T) {
+    g := makeTestSliceGenerator(t)
+
+    // Deliberately not a multiple of the slice size.
+    size := uint64(10000)
+    buffer := make([]byte, size)
+    scratch := make([]byte, size)
+
+    g.Generate(size, 7, 3, &buffer)
+
+    testutil.CheckNoError(t, g.Verify(size, 7, 3, &buffer, &scratch))
+    testutil.CheckNoError(t, g.Verify(size, 7, AnyCycle, &buffer, &scratch))
+}
+
+
+// Objects from a different cycle, or with bad contents, must fail verification.
+func TestSliceGeneratorVerifyFailures(t *testing.T) {
+    g := makeTestSliceGenerator(t)
+
+    size := uint64(4096)
+    buffer := make([]byte, size)
+    scratch := make([]byte, size)
+
+    g.Generate(size, 7, 3, &buffer)
+
+    testutil.CheckBool(t, true, g.Verify(size, 7, 4, &buffer, &scratch) != nil)
+
+    short := buffer[:size - 1]
+    testutil.CheckBool(t, true, g.Verify(size, 7, 3, &short, &scratch) != nil)
+
+    buffer[size - 1]++
+    testutil.CheckBool(t, true, g.Verify(size, 7, 3, &buffer, &scratch) != nil)
+}
+
+
+// Warming up must not change the objects that we generate afterwards.
+func TestSliceGeneratorWarmup(t *testing.T) {
+    dir := t.TempDir()
+    data := make([]byte, 64 * 1024)
+    for i := range data {
+        data[i] = byte(i * 7)
+    }
+
+    err := os.WriteFile(filepath.Join(dir, "data"), data, 0644)
+    testutil.CheckNoError(t, err)
+
+    size := uint64(10000)
+    expected := make([]byte, size)
+    makeTestSliceGenerator(t).Generate(size, 7, 3, &expected)
+
+    for _, cache := range []string{ "0", "4" } {
+        config := GeneratorConfig{ "dir": dir, "size": "1024", "count": "16", "cache": cache, "warmup": "5", "warmup-size": "8192" }
+        sg, err := CreateSliceGenerator(42, config)
+        testutil.CheckNoError(t, err)
+
+        buffer := make([]byte, size)
+        sg.Generate(size, 7, 3, &buffer)
+        testutil.CheckBytes(t, expected, buffer)
+    }
+}
+
+
+// Each worker has a generator of its own.  Whatever else a generator has made, an object depends only on its
+// id and cycle, so any worker can verify it, and objects with different ids are different.
+func TestSliceGeneratorIndependentOfWorker(t *testing.T) {
+    writer := makeTestSliceGenerator(t)
+    reader := makeTestSliceGenerator(t)
+
+    size := uint64(10000)
+    buffer := make([]byte, size)
+    scratch := make([]byte, size)
+
+    for id := uint64(0); id < 5; id++ {
+        writer.Generate(size, id, 1, &buffer)
+    }
+
+    writer.Generate(size, 7, 3, &buffer)
+    testutil.CheckNoError(t, reader.Verify(size, 7, 3, &buffer, &scratch))
+
+    reader.Generate(size, 7, 3, &scratch)
+    testutil.CheckBytes(t, buffer, scratch)
+
+    // The 4 byte header holds the object's seed, so compare the slices which follow it too.
+    reader.Generate(size, 8, 3, &scratch)
+    testutil.CheckBool(t, false, string(buffer[:4]) == string(scratch[:4]))
+    testutil.CheckBool(t, false, string(buffer[4:]) == string(scratch[4:]))
+}
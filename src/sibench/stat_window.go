@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "comms"
+import "sync"
+
+
+/* The maximum number of stats we send in a single StatDetails message. */
+const statUploadChunkSize = 64 * 1024
+
+
+/*
+ * StatWindow bounds the number of StatDetails messages that a Foreman has in flight to the Manager.
+ *
+ * Without it, we would queue up every worker's stats on the TCP connection at once, which can use
+ * a great deal of memory if the link to the Manager is slow.  Instead, the Manager acks each
+ * StatDetails message as it processes it, and we block once the window is full until an ack
+ * arrives.
+ *
+ * Acks arrive on the Foreman's main loop, which passes them on to us with Ack().  Abort() wakes up
+ * any sender that is blocked, so that we can't hang if the Manager goes away mid-upload.
+ */
+type StatWindow struct {
+    size int
+    inFlight int
+    acks chan bool
+    abort chan bool
+    abortOnce sync.Once
+}
+
+
+func NewStatWindow(size int) *StatWindow {
+    var sw StatWindow
+    sw.size = size
+    sw.acks = make(chan bool, size)
+    sw.abort = make(chan bool)
+    return &sw
+}
+
+
+/*
+ * Send a set of stats, split into chunks, waiting for space in the window before each one.
+ * Returns false if we were aborted.
+ */
+func (sw *StatWindow) Send(tcpConnection *comms.MessageConnection, stats []Stat) bool {
+    for len(stats) > 0 {
+        n := len(stats)
+        if n > statUploadChunkSize {
+            n = statUploadChunkSize
+        }
+
+        if (sw.inFlight >= sw.size) && !sw.wait() {
+            return false
+        }
+
+        tcpConnection.Send(OP_StatDetails, stats[:n])
+        sw.inFlight++
+        stats = stats[n:]
+    }
+
+    return true
+}
+
+
+/* Wait until all our messages have been acked.  Returns false if we were aborted. */
+func (sw *StatWindow) Drain() bool {
+    for sw.inFlight > 0 {
+        if !sw.wait() {
+            return false
+        }
+    }
+
+    return true
+}
+
+
+/* Record an ack from the Manager.  Called from the Foreman's main loop. */
+func (sw *StatWindow) Ack() {
+    select {
+        case sw.acks <- true:
+        default:
+            // More acks than messages: the Manager is confused, but there's nobody waiting to care.
+    }
+}
+
+
+/* Wake up any sender, and make all future sends fail. */
+func (sw *StatWindow) Abort() {
+    sw.abortOnce.Do(func() { close(sw.abort) })
+}
+
+
+/* Wait for a single ack, returning false if we were aborted. */
+func (sw *StatWindow) wait() bool {
+    select {
+        case <-sw.acks:
+            sw.inFlight--
+            return true
+
+        case <-sw.abort:
+            return false
+    }
+}
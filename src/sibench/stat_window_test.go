@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the window which bounds the stats that a foreman has in flight to its manager.
+
+package main
+
+import "comms"
+import "net"
+import "reflect"
+import "testing"
+import "time"
+import "silib/testutil"
+
+
+// Helper functions.
+
+/* Make a pair of message connections over loopback TCP, each of which talks to the other. */
+func makeTestMessageConnPair(t *testing.T) (*comms.MessageConnection, *comms.MessageConnection) {
+    // Find a free port for our listener.
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    testutil.CheckNoError(t, err)
+    address := l.Addr().String()
+    l.Close()
+
+    accepted := make(chan *comms.MessageConnection, 1)
+    listener, err := comms.ListenTCP(address, comms.MakeGobEncoderFactory(), accepted)
+    testutil.CheckNoError(t, err)
+    defer listener.StopListening()
+
+    client, err := comms.ConnectTCP(address, comms.MakeGobEncoderFactory(), time.Second)
+    testutil.CheckNoError(t, err)
+
+    server := <-accepted
+    t.Cleanup(client.Close)
+    t.Cleanup(server.Close)
+
+    return client, server
+}
+
+
+/*
+ * Send count stats through a window of the given size, acting as the manager at the other end.
+ * We only ack once nothing more arrives, which is when the sender is blocked on a full window.
+ * Returns the number of stats in each message, and the most messages that were ever unacked.
+ */
+func sendThroughStatWindow(t *testing.T, size int, count int) ([]int, int) {
+    client, server := makeTestMessageConnPair(t)
+    received := make(chan *comms.ReceivedMessageInfo, 16)
+    server.ReceiveToChannel(received)
+
+    sw := NewStatWindow(size)
+    done := make(chan bool, 1)
+    go func() { done <- sw.Send(client, make([]Stat, count)) && sw.Drain() }()
+
+    var lengths []int
+    unacked := 0
+    maxUnacked := 0
+
+    for {
+        select {
+            case info := <-received:
+                testutil.CheckNoError(t, info.Error)
+                var stats []Stat
+                info.Message.Data(&stats)
+                lengths = append(lengths, len(stats))
+
+                unacked++
+                if unacked > maxUnacked {
+                    maxUnacked = unacked
+                }
+
+            case ok := <-done:
+                testutil.CheckBool(t, true, ok)
+                return lengths, maxUnacked
+
+            case <-time.After(50 * time.Millisecond):
+                for ; unacked > 0; unacked-- {
+                    sw.Ack()
+                }
+        }
+    }
+}
+
+
+// Test functions.
+
+// Stats go in chunks, and no more chunks than the window holds are ever waiting for an ack.
+func TestStatWindowSend(t *testing.T) {
+    const chunk = statUploadChunkSize
+
+    tests := []struct {
+        size int
+        count int
+        expected []int
+    } {
+        { 1, 0, nil },
+        { 2, 1, []int{ 1 } },
+        { 2, chunk, []int{ chunk } },
+        { 2, chunk + 1, []int{ chunk, 1 } },
+        { 1, (2 * chunk) + 5, []int{ chunk, chunk, 5 } },
+        { 2, (4 * chunk) + 5, []int{ chunk, chunk, chunk, chunk, 5 } },
+        { 8, (2 * chunk) + 5, []int{ chunk, chunk, 5 } },
+    }
+
+    for _, test := range tests {
+        lengths, maxUnacked := sendThroughStatWindow(t, test.size, test.count)
+        testutil.CheckBool(t, true, reflect.DeepEqual(test.expected, lengths))
+
+        if maxUnacked > test.size {
+            t.Errorf("Window of %v had %v messages waiting for acks", test.size, maxUnacked)
+        }
+    }
+}
+
+
+// A sender blocked on a full window gives up when we abort, and so does a later drain.
+func TestStatWindowAbort(t *testing.T) {
+    client, server := makeTestMessageConnPair(t)
+    received := make(chan *comms.ReceivedMessageInfo, 16)
+    server.ReceiveToChannel(received)
+
+    sw := NewStatWindow(1)
+    done := make(chan bool, 1)
+    go func() { done <- sw.Send(client, make([]Stat, statUploadChunkSize + 1)) }()
+
+    <-received
+    sw.Abort()
+    testutil.CheckBool(t, false, <-done)
+    testutil.CheckBool(t, false, sw.Drain())
+}
+
+
+// Acks that we aren't waiting for must not block the foreman's main loop.
+func TestStatWindowExtraAcks(t *testing.T) {
+    sw := NewStatWindow(1)
+    sw.Ack()
+    sw.Ack()
+
+    testutil.CheckBool(t, true, sw.Drain())
+}
@@ -4,6 +4,8 @@
 package main
 
 import "fmt"
+import "math"
+import "math/bits"
 import "sort"
 
 
@@ -18,6 +20,26 @@ type ServerStat struct {
 
 
 
+/* Convert an object size into the form we hold in a Stat. */
+func statSize(size uint64) uint32 {
+    if size > math.MaxUint32 {
+        return 0
+    }
+
+    return uint32(size)
+}
+
+
+/* Return the number of bytes transferred by an op, falling back to a default if the size wasn't recorded. */
+func (s *Stat) Bytes(defaultSize uint64) uint64 {
+    if s.Size == 0 {
+        return defaultSize
+    }
+
+    return uint64(s.Size)
+}
+
+
 /* Reset all off the summary's counters to 0. */
 func (s *StatSummary) Zero() {
     for phase := 0; phase < int(SP_Len); phase++ {
@@ -38,6 +60,32 @@ func (s *StatSummary) Add(other *StatSummary) {
 }
 
 
+/* Return the number of failed operations in a phase, not counting verification failures. */
+func (s *StatSummary) OperationFailures(phase StatPhase) uint64 {
+    total := uint64(0)
+
+    for err := StatError(0); err < SE_Len; err++ {
+        if (err != SE_None) && (err != SE_VerifyFailure) {
+            total += s[phase][err]
+        }
+    }
+
+    return total
+}
+
+
+/* Return the number of successful ops in the summary, across all phases. */
+func (s *StatSummary) Successes() uint64 {
+    total := uint64(0)
+
+    for phase := 0; phase < int(SP_Len); phase++ {
+        total += s[phase][SE_None]
+    }
+
+    return total
+}
+
+
 func (s *StatSummary) Total() uint64 {
     total := uint64(0)
 
@@ -51,8 +99,75 @@ func (s *StatSummary) Total() uint64 {
 }
 
 
+/* Add a single sample to our resource usage. */
+func (u *ResourceUsage) AddSample(cpuPercent float64, rss uint64) {
+    u.Samples++
+    u.TotalCpuPercent += cpuPercent
+    u.TotalRss += rss
+
+    if cpuPercent > u.PeakCpuPercent {
+        u.PeakCpuPercent = cpuPercent
+    }
+
+    if rss > u.PeakRss {
+        u.PeakRss = rss
+    }
+}
+
+
+/* Merge another set of resource usage samples into this one. */
+func (u *ResourceUsage) Add(other *ResourceUsage) {
+    u.Samples += other.Samples
+    u.TotalCpuPercent += other.TotalCpuPercent
+    u.TotalRss += other.TotalRss
+
+    if other.PeakCpuPercent > u.PeakCpuPercent {
+        u.PeakCpuPercent = other.PeakCpuPercent
+    }
+
+    if other.PeakRss > u.PeakRss {
+        u.PeakRss = other.PeakRss
+    }
+}
+
+
+func (u *ResourceUsage) AverageCpuPercent() float64 {
+    if u.Samples == 0 {
+        return 0
+    }
+
+    return u.TotalCpuPercent / float64(u.Samples)
+}
+
+
+func (u *ResourceUsage) AverageRss() uint64 {
+    if u.Samples == 0 {
+        return 0
+    }
+
+    return u.TotalRss / u.Samples
+}
+
+
+/* Convert a StatSummary into nested maps of phase to error type to count, for use in JSON. */
+func (s *StatSummary) ToMap() map[string]map[string]uint64 {
+    result := make(map[string]map[string]uint64)
+
+    for phase := StatPhase(0); phase < SP_Len; phase++ {
+        errs := make(map[string]uint64)
+        for err := StatError(0); err < SE_Len; err++ {
+            errs[err.ToString()] = s[phase][err]
+        }
+
+        result[phase.ToString()] = errs
+    }
+
+    return result
+}
+
+
 /* Produce a human readable string from a StatSummary object */
-func (s *StatSummary) String(objectSize uint64, useBytes bool) string {
+func (s *StatSummary) String(objectSize uint64, readSize uint64, useBytes bool) string {
     result := ""
 
     for i := StatPhase(0); i < SP_Len; i++ {
@@ -66,10 +181,12 @@ func (s *StatSummary) String(objectSize uint64, useBytes bool) string {
         if data {
             phase := i.ToString()
             ops := s[i][SE_None]
-            ofail := s[i][SE_OperationFailure]
+            ofail := s.OperationFailures(i)
             vfail := s[i][SE_VerifyFailure]
-            bwb := ToUnits(ops * objectSize)
-            bw := ToUnits(ops * objectSize * 8)
+            size := i.OpSize(objectSize, readSize)
+
+            bwb := ToUnits(ops * size)
+            bw := ToUnits(ops * size * 8)
             bwstr := ""
             if useBytes {
                 bwstr = fmt.Sprintf("%vB/s", bwb)
@@ -112,14 +229,23 @@ func errorFilter(err StatError) filterFunc {
 }
 
 
-/* Filter out stats that are not in the relevant time period */
+/*
+ * Filter out stats that are not in the relevant time period.  A verification pass runs until it
+ * has read every object, rather than for a time, so all of its stats are relevant.
+ */
 func rampFilter(job *Job) filterFunc {
 
     // Convert seonds to milliseconds
-    up := uint32(job.rampUp * 1000)
+    up := uint32(job.phaseRampUp * 1000)
     time := uint32(job.runTime * 1000)
 
     return func(s *ServerStat) bool {
+        // Verification isn't timed, and held connections are mostly opened during the ramp-up, but
+        // we want to count all of them.
+        if (s.Phase == SP_Verify) || (s.Phase == SP_HoldConnect) {
+            return true
+        }
+
         start := uint32(s.TimeSincePhaseStartMillis)
         return (start > up) && (start <= up + time)
     }
@@ -142,6 +268,14 @@ func serverFilter(serverIndex uint16) filterFunc {
 }
 
 
+/* Filter on a single worker, which needs its server as well as its index on that server. */
+func workerFilter(serverIndex uint16, workerIndex uint16) filterFunc {
+    return func(s *ServerStat) bool {
+        return (s.ServerIndex == serverIndex) && (s.WorkerIndex == workerIndex)
+    }
+}
+
+
 /* Inverts the sense of a filter function */
 func invertFilter(fn filterFunc) filterFunc {
     return func(s *ServerStat) bool {
@@ -197,13 +331,113 @@ type Analysis struct {
     ResTime95  uint64   // The response time by which 95% of our successful operations completed
     ResTimeAvg uint64   // The average response time for a successful operation
 
+    /* The response times at each of analysisPercentiles, for tools which want more than the 95th. */
+    ResTimePercentiles []PercentileResTime
+
+    /* How the response times of our successful operations are spread, from the fastest to the slowest. */
+    ResTimeHistogram []HistogramBucket  `json:",omitempty"`
+
     /* Bandwidth is in bits per seconds */
     Bandwidth uint64
     BandwidthBytes uint64
 
     /* Counts */
     Successes uint64
-    Failures uint64
+    OperationFailures uint64    // Operations which failed outright.
+    VerifyFailures uint64       // Reads which succeeded, but whose content failed verification.
+
+    /* The number of failures of each type */
+    FailuresByType map[string]uint64
+
+    /* How many operations needed retrying after transient failures, and how many retries they took in all. */
+    RetriedOperations uint64        `json:",omitempty"`
+    Retries uint64                  `json:",omitempty"`
+
+    /* How we compare with the same analysis in a baseline report, if we were given one. */
+    Baseline *BaselineComparison    `json:",omitempty"`
+
+    /* Set on the total for a timed phase with fewer than minAnalysedOps successes, whose response times are noise. */
+    TooFewOps bool                  `json:",omitempty"`
+}
+
+
+/* Return the total number of failures, of either kind. */
+func (a *Analysis) Failures() uint64 {
+    return a.OperationFailures + a.VerifyFailures
+}
+
+
+/* The response time by which a given percentage of our successful operations completed. */
+type PercentileResTime struct {
+    Percentile float64
+    ResTime uint64
+}
+
+
+/* The percentiles we report in each Analysis.  These are the ones that fio reports by default. */
+var analysisPercentiles = []float64{ 1, 5, 10, 20, 30, 40, 50, 60, 70, 80, 90, 95, 99, 99.5, 99.9, 99.95, 99.99 }
+
+
+/*
+ * One bar of a response time histogram: the number of successful operations which took at least
+ * ResTimeLow, but less than ResTimeHigh, microseconds.
+ */
+type HistogramBucket struct {
+    ResTimeLow uint64
+    ResTimeHigh uint64
+    Count uint64
+}
+
+
+/*
+ * Return the index of the histogram bucket for a response time in microseconds.
+ *
+ * The buckets are log-linear: each doubling of the response time is split into four equal buckets,
+ * so that they are narrow enough to show the shape of the distribution at any scale, but there are
+ * never more than a few hundred of them.  The first few are a microsecond wide.
+ */
+func histogramBucket(micros uint64) int {
+    if micros < 4 {
+        return int(micros)
+    }
+
+    // The bits after the top one pick which quarter of the doubling we are in.
+    n := bits.Len64(micros)
+    return (4 * (n - 2)) + int((micros >> (n - 3)) & 3)
+}
+
+
+/* Return the range of response times, in microseconds, covered by a histogram bucket. */
+func histogramBucketBounds(index int) (uint64, uint64) {
+    if index < 4 {
+        return uint64(index), uint64(index + 1)
+    }
+
+    shift := (index / 4) - 1
+    quarter := uint64(index % 4)
+    return (4 + quarter) << shift, (5 + quarter) << shift
+}
+
+
+/*
+ * Build the histogram of a slice of stats, which must already be sorted by duration.  We include
+ * every bucket between the fastest and slowest ops, even if they are empty, so that the bars can be
+ * drawn side by side.
+ */
+func newHistogram(sorted []*ServerStat) []HistogramBucket {
+    first := histogramBucket(uint64(sorted[0].DurationMicros))
+    last := histogramBucket(uint64(sorted[len(sorted) - 1].DurationMicros))
+
+    histogram := make([]HistogramBucket, last - first + 1)
+    for i := range histogram {
+        histogram[i].ResTimeLow, histogram[i].ResTimeHigh = histogramBucketBounds(first + i)
+    }
+
+    for _, s := range sorted {
+        histogram[histogramBucket(uint64(s.DurationMicros)) - first].Count++
+    }
+
+    return histogram
 }
 
 
@@ -219,11 +453,12 @@ func (a *Analysis) String(useBytes bool) string {
         bwstr = fmt.Sprintf("%vb/s", ToUnits(a.Bandwidth))
     }
 
-    return fmt.Sprintf("%-28v   bandwidth: %7v,  ok: %6v,  fail: %6v,  res-min: %5v ms,  res-max: %5v ms,  res-95: %6v ms, res-avg: %6v ms",
+    return fmt.Sprintf("%-28v   bandwidth: %7v,  ok: %6v,  ofail: %6v,  vfail: %6v,  res-min: %5v ms,  res-max: %5v ms,  res-95: %6v ms, res-avg: %6v ms",
         a.Name,
         bwstr,
         a.Successes,
-        a.Failures,
+        a.OperationFailures,
+        a.VerifyFailures,
         a.ResTimeMin / 1000,
         a.ResTimeMax / 1000,
         a.ResTime95  / 1000,
@@ -243,8 +478,22 @@ func NewAnalysis(stats []*ServerStat, name string, phase StatPhase, isTotal bool
     result.IsTotal = isTotal
 
     good := filter(stats, errorFilter(SE_None))
+    verifyFailed := filter(stats, errorFilter(SE_VerifyFailure))
     result.Successes = uint64(len(good))
-    result.Failures = uint64(len(stats) - len(good))
+    result.VerifyFailures = uint64(len(verifyFailed))
+    result.OperationFailures = uint64(len(stats) - len(good) - len(verifyFailed))
+
+    result.FailuresByType = make(map[string]uint64)
+    for _, s := range stats {
+        if s.Error != SE_None {
+            result.FailuresByType[s.Error.ToString()]++
+        }
+
+        if s.Retries > 0 {
+            result.RetriedOperations++
+            result.Retries += uint64(s.Retries)
+        }
+    }
 
     if len(good) > 0 {
         sortByDuration(good)
@@ -253,8 +502,33 @@ func NewAnalysis(stats []*ServerStat, name string, phase StatPhase, isTotal bool
         result.ResTimeMin = uint64(good[0].DurationMicros)
         result.ResTimeMax = uint64(good[len(good) - 1].DurationMicros)
         result.ResTime95  = uint64(good[int(float64(len(good)) * 0.95)].DurationMicros)
-        result.Bandwidth  = uint64(8 * len(good)) * job.order.ObjectSize / job.runTime
-        result.BandwidthBytes  = uint64(len(good)) * job.order.ObjectSize / job.runTime
+
+        for _, p := range analysisPercentiles {
+            i := int(float64(len(good)) * p / 100)
+            if i >= len(good) {
+                i = len(good) - 1
+            }
+
+            result.ResTimePercentiles = append(result.ResTimePercentiles, PercentileResTime{ p, uint64(good[i].DurationMicros) })
+        }
+
+        result.ResTimeHistogram = newHistogram(good)
+
+        // Use the size of each op, in case they weren't all the same.
+        bytes := uint64(0)
+        if phase.MovesData() {
+            for _, s := range good {
+                bytes += s.Bytes(job.order.ObjectSize)
+            }
+        }
+
+        secs := job.runTime
+        if phase == SP_Verify {
+            secs = phaseSeconds(stats)
+        }
+
+        result.Bandwidth  = 8 * bytes / secs
+        result.BandwidthBytes  = bytes / secs
 
 
         total := uint64(0)
@@ -269,6 +543,23 @@ func NewAnalysis(stats []*ServerStat, name string, phase StatPhase, isTotal bool
 }
 
 
+/*
+ * Return how long a phase took to complete the given stats, in whole seconds (and at least one).
+ * This is for phases which run to completion, rather than for a fixed time.
+ */
+func phaseSeconds(stats []*ServerStat) uint64 {
+    endMillis := uint64(0)
+    for _, s := range stats {
+        end := uint64(s.TimeSincePhaseStartMillis) + uint64(s.DurationMicros / 1000)
+        if end > endMillis {
+            endMillis = end
+        }
+    }
+
+    return (endMillis / 1000) + 1
+}
+
+
 /*
  * Limit a string to a particular length.  Longer strings will be truncated and '...' appended to them
  * to indiate that the truncation has taken place.
@@ -282,3 +573,30 @@ func limit(s string, length int) string {
 }
 
 
+/*
+ * Returns the coefficient of variation (the standard deviation divided by the mean) of a set of
+ * values.  If the mean is zero, then we return +Inf, since there's nothing steady about no data.
+ */
+func coefficientOfVariation(vals []uint64) float64 {
+    if len(vals) == 0 {
+        return math.Inf(1)
+    }
+
+    sum := 0.0
+    for _, v := range vals {
+        sum += float64(v)
+    }
+
+    mean := sum / float64(len(vals))
+    if mean == 0 {
+        return math.Inf(1)
+    }
+
+    variance := 0.0
+    for _, v := range vals {
+        variance += (float64(v) - mean) * (float64(v) - mean)
+    }
+
+    variance /= float64(len(vals))
+    return math.Sqrt(variance) / mean
+}
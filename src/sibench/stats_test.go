@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the response time histograms in our analyses.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Test functions.
+
+// Every response time must land in a bucket whose bounds contain it, and the buckets must tile the range without gaps.
+func TestHistogramBuckets(t *testing.T) {
+    prevHigh := uint64(0)
+    for i := 0; i < 100; i++ {
+        low, high := histogramBucketBounds(i)
+        testutil.CheckBool(t, true, low == prevHigh)
+        testutil.CheckBool(t, true, high > low)
+        prevHigh = high
+    }
+
+    for _, micros := range []uint64{ 0, 1, 3, 4, 7, 8, 9, 1000, 1023, 1024, 123456, 4294967295 } {
+        low, high := histogramBucketBounds(histogramBucket(micros))
+        testutil.CheckBool(t, true, (low <= micros) && (micros < high))
+    }
+}
+
+
+func TestNewHistogram(t *testing.T) {
+    var stats []*ServerStat
+    for _, d := range []uint32{ 5, 5, 6, 40, 41 } {
+        s := &ServerStat{}
+        s.DurationMicros = d
+        stats = append(stats, s)
+    }
+
+    h := newHistogram(stats)
+    testutil.CheckInt(t, 5, int(h[0].ResTimeLow))
+    testutil.CheckInt(t, 2, int(h[0].Count))
+    testutil.CheckInt(t, 1, int(h[1].Count))
+    testutil.CheckInt(t, 40, int(h[len(h) - 1].ResTimeLow))
+    testutil.CheckInt(t, 2, int(h[len(h) - 1].Count))
+
+    total := 0
+    for _, b := range h {
+        total += int(b.Count)
+    }
+
+    testutil.CheckInt(t, 5, total)
+}
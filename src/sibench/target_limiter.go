@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+
+/*
+ * A TargetLimiter caps the number of ops that a Foreman's workers may have in flight to each
+ * target at once, to model a front end which only accepts so many connections.  Without one, a
+ * small list of targets shared by many workers can have every worker hammering the same gateway.
+ *
+ * The Foreman creates a single TargetLimiter and shares it between all of its workers, so the
+ * limit is per sibench server: with several servers, each of them may have that many ops in
+ * flight to each target.
+ *
+ * Workers acquire a slot just before the timed part of an op, and release it straight after, so
+ * time spent waiting for a slot is not counted in the op's response time.  Like our bandwidth
+ * limit, the limit shows up as lower throughput instead.
+ *
+ * A nil TargetLimiter places no limit on anything, so that workers don't have to check for one.
+ */
+type TargetLimiter struct {
+    slots []chan struct{}
+}
+
+
+/* Create a TargetLimiter for the given number of targets, or return nil if the limit is zero. */
+func NewTargetLimiter(targets int, limit uint64) *TargetLimiter {
+    if limit == 0 {
+        return nil
+    }
+
+    var tl TargetLimiter
+    tl.slots = make([]chan struct{}, targets)
+    for i := range tl.slots {
+        tl.slots[i] = make(chan struct{}, limit)
+    }
+
+    return &tl
+}
+
+
+/* Block until there is a free slot for the given target, and take it. */
+func (tl *TargetLimiter) Acquire(target uint64) {
+    if tl != nil {
+        tl.slots[target] <- struct{}{}
+    }
+}
+
+
+/* Give back a slot taken with Acquire. */
+func (tl *TargetLimiter) Release(target uint64) {
+    if tl != nil {
+        <-tl.slots[target]
+    }
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the per-target concurrency limiter.
+
+package main
+
+import "sync"
+import "sync/atomic"
+import "testing"
+import "time"
+import "silib/testutil"
+
+
+// Test functions.
+
+// Many goroutines sharing a limiter never have more than the limit in flight to one target.
+func TestTargetLimiterLimit(t *testing.T) {
+    const limit = 3
+    tl := NewTargetLimiter(2, limit)
+
+    var inFlight [2]int32
+    var peak [2]int32
+    var wg sync.WaitGroup
+
+    for i := 0; i < 20; i++ {
+        wg.Add(1)
+        go func(target uint64) {
+            defer wg.Done()
+
+            for j := 0; j < 10; j++ {
+                tl.Acquire(target)
+
+                n := atomic.AddInt32(&inFlight[target], 1)
+                for {
+                    p := atomic.LoadInt32(&peak[target])
+                    if (n <= p) || atomic.CompareAndSwapInt32(&peak[target], p, n) {
+                        break
+                    }
+                }
+
+                time.Sleep(time.Millisecond)
+                atomic.AddInt32(&inFlight[target], -1)
+                tl.Release(target)
+            }
+        }(uint64(i % 2))
+    }
+
+    wg.Wait()
+
+    for target := range peak {
+        testutil.CheckBool(t, true, peak[target] <= limit)
+        testutil.CheckBool(t, true, peak[target] > 1)
+    }
+}
+
+
+// Each target has its own slots, so a busy target doesn't hold up the others.
+func TestTargetLimiterIndependentTargets(t *testing.T) {
+    tl := NewTargetLimiter(2, 1)
+    tl.Acquire(0)
+
+    done := make(chan bool)
+    go func() {
+        tl.Acquire(1)
+        tl.Release(1)
+        done <- true
+    }()
+
+    select {
+        case <-done:
+        case <-time.After(time.Second):
+            t.Fatalf("Acquire blocked on a target with free slots")
+    }
+
+    tl.Release(0)
+}
+
+
+// A zero limit gives a nil limiter, which never blocks.
+func TestTargetLimiterUnlimited(t *testing.T) {
+    tl := NewTargetLimiter(1, 0)
+    testutil.CheckBool(t, true, tl == nil)
+
+    for i := 0; i < 100; i++ {
+        tl.Acquire(0)
+    }
+
+    tl.Release(0)
+}
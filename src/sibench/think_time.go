@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "math/rand"
+import "strings"
+import "time"
+
+
+/*
+ * A ThinkTime is a pause that each worker takes between its operations, to model clients which
+ * don't issue a new request as soon as the last one completes.
+ *
+ * It can be given as:
+ *
+ *   DURATION      A fixed pause, such as "10ms".
+ *   MIN-MAX       A pause picked uniformly from a range, such as "5ms-20ms".
+ *   exp:MEAN      A pause picked from an exponential distribution with the given mean, such as
+ *                 "exp:10ms".  This gives Poisson arrivals from each worker.
+ *
+ * Durations use Go's syntax, so they need units.
+ */
+type ThinkTime struct {
+    Min time.Duration
+    Max time.Duration
+    Mean time.Duration      // Non-zero only for exponential think times.
+}
+
+
+/*
+ * Workers sleep for at most this long at a time whilst thinking, so that they can still respond
+ * to opcodes from their foreman.
+ */
+const thinkTimeSlice = 50 * time.Millisecond
+
+
+func ParseThinkTime(spec string) (*ThinkTime, error) {
+    var tt ThinkTime
+    var err error
+
+    if strings.HasPrefix(spec, "exp:") {
+        mean := strings.TrimPrefix(spec, "exp:")
+        tt.Mean, err = time.ParseDuration(mean)
+        if (err != nil) || (tt.Mean <= 0) {
+            return nil, fmt.Errorf("Bad mean in think time: %v.  Should be a positive duration, such as 10ms", mean)
+        }
+
+        return &tt, nil
+    }
+
+    minStr, maxStr, found := strings.Cut(spec, "-")
+    if !found {
+        maxStr = minStr
+    }
+
+    tt.Min, err = time.ParseDuration(minStr)
+    if (err != nil) || (tt.Min < 0) {
+        return nil, fmt.Errorf("Bad think time: %v.  Should be a duration such as 10ms", minStr)
+    }
+
+    tt.Max, err = time.ParseDuration(maxStr)
+    if (err != nil) || (tt.Max < tt.Min) {
+        return nil, fmt.Errorf("Bad think time: %v.  Should be a duration such as 20ms, and no less than %v", maxStr, minStr)
+    }
+
+    return &tt, nil
+}
+
+
+/* Pick how long to pause before our next operation. */
+func (tt *ThinkTime) Next() time.Duration {
+    if tt.Mean > 0 {
+        return time.Duration(rand.ExpFloat64() * float64(tt.Mean))
+    }
+
+    if tt.Max == tt.Min {
+        return tt.Min
+    }
+
+    return tt.Min + time.Duration(rand.Int63n(int64(tt.Max - tt.Min) + 1))
+}
+
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "math"
+import "time"
+
+
+/*
+ * A TokenBucket limits the rate at which a worker moves data.
+ *
+ * The bucket holds tokens, each of which allows one byte to be transferred, and is refilled at
+ * our target rate as time passes.  Before each op, the worker takes as many tokens as the op will
+ * transfer.  If there are not enough, then the bucket goes into debt, and the worker sleeps until
+ * the debt has been repaid.  This lets us limit ops which are larger than the bucket itself, and
+ * means that the rate we achieve does not depend on how long each op takes, or on how their sizes
+ * are mixed.
+ *
+ * The bucket can only hold so many tokens, so that a worker which has been idle (or slow) for a
+ * while can't then burst far above the target rate to catch up.
+ *
+ * The bucket can also ramp up: for a given time after each reset, the rate at which it refills
+ * rises linearly from zero to the target rate, so that the load we offer rises gently rather than
+ * starting at full speed.
+ *
+ * Time is read through the now and sleep functions, so that tests can simulate the passing of time.
+ */
+type TokenBucket struct {
+    rate uint64                     // Bytes per second.
+    capacity float64                // The most tokens we can hold.
+    tokens float64                  // May be negative, if we are in debt.
+    lastRefill time.Time
+    start time.Time                 // When we were last reset.
+    ramp time.Duration              // How long we take to ramp up to our full rate after a reset.
+
+    now func() time.Time
+    sleep func(time.Duration)
+}
+
+
+func NewTokenBucket(rate uint64, capacity uint64) *TokenBucket {
+    return newTokenBucketWithClock(rate, capacity, time.Now, time.Sleep)
+}
+
+
+func newTokenBucketWithClock(rate uint64, capacity uint64, now func() time.Time, sleep func(time.Duration)) *TokenBucket {
+    tb := TokenBucket{ rate: rate, capacity: float64(capacity), now: now, sleep: sleep }
+    tb.Reset(0)
+    return &tb
+}
+
+
+/*
+ * Empty the bucket, so that we start again from a standing start.  If ramp is non-zero, then our
+ * rate rises linearly from zero over that time.
+ */
+func (tb *TokenBucket) Reset(ramp time.Duration) {
+    tb.tokens = 0
+    tb.start = tb.now()
+    tb.lastRefill = tb.start
+    tb.ramp = ramp
+}
+
+
+/* Return the total number of tokens earned in the given time since our last reset, ignoring our capacity. */
+func (tb *TokenBucket) earned(elapsed time.Duration) float64 {
+    rate := float64(tb.rate)
+    t := elapsed.Seconds()
+    r := tb.ramp.Seconds()
+
+    if t >= r {
+        return rate * (t - r / 2)
+    }
+
+    return rate * t * t / (2 * r)
+}
+
+
+/* The inverse of earned: return how long after our last reset we will have earned the given tokens. */
+func (tb *TokenBucket) timeToEarn(tokens float64) time.Duration {
+    rate := float64(tb.rate)
+    r := tb.ramp.Seconds()
+
+    var t float64
+    if tokens >= rate * r / 2 {
+        t = tokens / rate + r / 2
+    } else {
+        t = math.Sqrt(2 * r * tokens / rate)
+    }
+
+    return time.Duration(t * float64(time.Second))
+}
+
+
+/* Add the tokens we have earned since we last refilled. */
+func (tb *TokenBucket) refill() {
+    now := tb.now()
+    tb.tokens += tb.earned(now.Sub(tb.start)) - tb.earned(tb.lastRefill.Sub(tb.start))
+    tb.lastRefill = now
+
+    if tb.tokens > tb.capacity {
+        tb.tokens = tb.capacity
+    }
+}
+
+
+/* Take the tokens for an op which transfers the given number of bytes, sleeping if we need to. */
+func (tb *TokenBucket) Take(bytes uint64) {
+    tb.refill()
+    tb.tokens -= float64(bytes)
+
+    if tb.tokens < 0 {
+        elapsed := tb.lastRefill.Sub(tb.start)
+        tb.sleep(tb.timeToEarn(tb.earned(elapsed) - tb.tokens) - elapsed)
+        tb.refill()
+    }
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the token bucket bandwidth limiter.
+
+package main
+
+import "math/rand"
+import "testing"
+import "time"
+import "silib/testutil"
+
+
+// Helper functions.
+
+// A clock which only moves when we sleep, or when our simulated backend does some work.
+type fakeClock struct {
+    t time.Time
+}
+
+
+func (c *fakeClock) now() time.Time {
+    return c.t
+}
+
+
+func (c *fakeClock) sleep(d time.Duration) {
+    c.t = c.t.Add(d)
+}
+
+
+/*
+ * Run ops through a token bucket against a simulated backend, which takes opTime for every op,
+ * until the given window has passed.  Return the bandwidth we achieved, in bytes per second.
+ */
+func runSimulatedBackend(rate uint64, window time.Duration, opTime time.Duration, sizes []uint64) float64 {
+    clock := &fakeClock{ time.Unix(1000, 0) }
+    tb := newTokenBucketWithClock(rate, 1024 * 1024, clock.now, clock.sleep)
+
+    start := clock.now()
+    var total uint64
+
+    for i := 0; clock.now().Sub(start) < window; i++ {
+        size := sizes[i % len(sizes)]
+        tb.Take(size)
+        clock.sleep(opTime)
+        total += size
+    }
+
+    return float64(total) / clock.now().Sub(start).Seconds()
+}
+
+
+func checkWithinPercent(t *testing.T, target float64, achieved float64, percent float64) {
+    t.Helper()
+    if (achieved < target * (1 - percent / 100)) || (achieved > target * (1 + percent / 100)) {
+        t.Errorf("achieved %.0f bytes/s, more than %v%% from target %.0f", achieved, percent, target)
+    }
+}
+
+
+// Test functions.
+
+// A backend which is much faster than our limit must be held to within 5% of it.
+func TestTokenBucketFastBackend(t *testing.T) {
+    rate := uint64(10 * 1024 * 1024)
+    achieved := runSimulatedBackend(rate, 10 * time.Second, 100 * time.Microsecond, []uint64{ 64 * 1024 })
+    checkWithinPercent(t, float64(rate), achieved, 5)
+}
+
+
+// Mixed object sizes must not make us drift from our limit.
+func TestTokenBucketMixedSizes(t *testing.T) {
+    prng := rand.New(rand.NewSource(7))
+    sizes := make([]uint64, 1000)
+    for i := range sizes {
+        sizes[i] = uint64(4096 + prng.Intn(1024 * 1024))
+    }
+
+    rate := uint64(50 * 1024 * 1024)
+    achieved := runSimulatedBackend(rate, 10 * time.Second, 200 * time.Microsecond, sizes)
+    checkWithinPercent(t, float64(rate), achieved, 5)
+}
+
+
+// Ops larger than the bucket are still limited correctly, by going into debt.
+func TestTokenBucketLargeObjects(t *testing.T) {
+    rate := uint64(1024 * 1024)
+    achieved := runSimulatedBackend(rate, 10 * time.Second, time.Millisecond, []uint64{ 4 * 1024 * 1024 })
+    checkWithinPercent(t, float64(rate), achieved, 5)
+}
+
+
+// A bucket which has been idle can't burst beyond its capacity.
+func TestTokenBucketIdleCapacity(t *testing.T) {
+    clock := &fakeClock{ time.Unix(1000, 0) }
+    tb := newTokenBucketWithClock(1000, 5000, clock.now, clock.sleep)
+
+    clock.sleep(time.Hour)
+    start := clock.now()
+
+    tb.Take(5000)
+    testutil.CheckBool(t, true, clock.now() == start)
+
+    tb.Take(1000)
+    testutil.CheckBool(t, true, clock.now().Sub(start) == time.Second)
+}
+
+
+// A linear ramp must offer half the target's bytes over the ramp, and then the full rate after it.
+func TestTokenBucketLinearRamp(t *testing.T) {
+    clock := &fakeClock{ time.Unix(1000, 0) }
+    rate := uint64(10 * 1024 * 1024)
+    ramp := 10 * time.Second
+    tb := newTokenBucketWithClock(rate, 1024 * 1024, clock.now, clock.sleep)
+    tb.Reset(ramp)
+
+    start := clock.now()
+    var rampBytes, fullBytes uint64
+
+    for clock.now().Sub(start) < 2 * ramp {
+        inRamp := clock.now().Sub(start) < ramp
+        tb.Take(64 * 1024)
+        clock.sleep(100 * time.Microsecond)
+
+        if inRamp {
+            rampBytes += 64 * 1024
+        } else {
+            fullBytes += 64 * 1024
+        }
+    }
+
+    checkWithinPercent(t, float64(rate) / 2, float64(rampBytes) / ramp.Seconds(), 5)
+    checkWithinPercent(t, float64(rate), float64(fullBytes) / ramp.Seconds(), 5)
+}
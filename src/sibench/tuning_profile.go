@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+package main
+
+import "fmt"
+import "github.com/docopt/docopt-go"
+import "regexp"
+import "strconv"
+import "strings"
+
+
+/*
+ * A TuningProfile holds the defaults which suit a backend better than our plain ones.  With
+ * "--profile auto", we use the profile for the run's backend in place of our own defaults, but
+ * only for the options which weren't given: anything on the command line still wins.
+ *
+ * The values are starting points, taken from what we have seen on our own clusters:
+ *
+ *    S3 spends most of each op waiting on HTTP, so fewer workers, each with several ops in
+ *    flight, get more out of a gateway without using up the server's CPU.
+ *
+ *    RBD and the block backends make each worker wait in the kernel for every op, so they want
+ *    more workers.  RBD's caches also take longer to settle, so it gets a longer ramp-up.
+ *
+ *    RADOS does a little better with a couple of ops in flight per worker.
+ *
+ * A zero in a profile means that it leaves our own default alone.
+ */
+type TuningProfile struct {
+    Workers float64
+    QueueDepth int
+    RampUp int
+}
+
+
+/* The profile for each connection type.  Backends without one keep our own defaults. */
+var tuningProfiles = map[string]TuningProfile {
+    "s3":    { Workers: 0.5, QueueDepth: 4 },
+    "rados": { QueueDepth: 2 },
+    "rbd":   { Workers: 2.0, RampUp: 10 },
+    "block": { Workers: 2.0 },
+    "iscsi": { Workers: 2.0 },
+}
+
+
+/* Matches the defaults in our usage, so that we can take them out. */
+var usageDefaults = regexp.MustCompile(`\[default: [^\]]*\]`)
+
+
+/* The connection type of the backend that a command line is for, or "" if it isn't for one. */
+func connectionType(args *Arguments) string {
+    switch {
+        case args.S3:     return "s3"
+        case args.Rados:  return "rados"
+        case args.Nfs:    return "nfs"
+        case args.Cephfs: return "cephfs"
+        case args.Rbd:    return "rbd"
+        case args.Block:  return "block"
+        case args.Iscsi:  return "iscsi"
+        case args.File:   return "file"
+    }
+
+    return ""
+}
+
+
+/*
+ * Find which options were given on a command line, rather than left to their defaults, by
+ * parsing it again against our usage without any defaults.  Docopt leaves any option which
+ * wasn't given as nil (or false, for flags), so we don't have to worry about the short forms
+ * or abbreviations of the long ones.
+ */
+func givenOptions(argv []string) (map[string]bool, error) {
+    parser := &docopt.Parser{ HelpHandler: docopt.NoHelpHandler }
+    opts, err := parser.ParseArgs(usageDefaults.ReplaceAllString(usage(), ""), argv, "")
+    if err != nil {
+        return nil, err
+    }
+
+    given := make(map[string]bool)
+    for name, val := range opts {
+        if strings.HasPrefix(name, "--") && (val != nil) && (val != false) {
+            given[name] = true
+        }
+    }
+
+    return given, nil
+}
+
+
+/*
+ * Apply the tuning profile chosen by our arguments, setting any of its options which weren't
+ * given on the command line, argv.  This must happen before we validate the arguments, so that
+ * the profile's values are checked just like the user's.  The values we end up with are in the
+ * Arguments of the report, just as though they had been given.
+ */
+func applyTuningProfile(args *Arguments, argv []string) error {
+    switch args.Profile {
+        case "none": return nil
+        case "auto":
+        default:     return fmt.Errorf("Bad profile: %v.  Should be \"none\" or \"auto\"", args.Profile)
+    }
+
+    backend := connectionType(args)
+    profile, ok := tuningProfiles[backend]
+    if !ok {
+        return nil
+    }
+
+    given, err := givenOptions(argv)
+    if err != nil {
+        return err
+    }
+
+    if (profile.Workers != 0) && !given["--workers"] {
+        args.Workers = profile.Workers
+    }
+
+    // A queue depth can't be combined with some options, so we don't add one to them.
+    reuse, _ := strconv.ParseBool(args.ConnectionReuse)
+    if (profile.QueueDepth != 0) && !given["--queue-depth"] && !given["--read-ahead"] && reuse {
+        args.QueueDepth = profile.QueueDepth
+    }
+
+    // With automatic steady state detection, there's no fixed ramp-up to set, and we mustn't
+    // leave a short run with nothing to analyse.
+    rampUp := profile.RampUp
+    if (rampUp != 0) && !given["--ramp-up"] && !args.AutoSteadyState && (args.RunTime > rampUp + args.RampDown) {
+        args.RampUp = rampUp
+    }
+
+    return nil
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the tuning profiles of each backend.
+
+package main
+
+import "testing"
+import "github.com/docopt/docopt-go"
+import "silib/testutil"
+
+
+// Helper functions.
+
+/* Parse a command line and apply its tuning profile, as main would. */
+func profiledArgs(t *testing.T, argv ...string) *Arguments {
+    parser := &docopt.Parser{ HelpHandler: docopt.NoHelpHandler }
+    opts, err := parser.ParseArgs(usage(), argv, "")
+    testutil.CheckNoError(t, err)
+
+    var args Arguments
+    testutil.CheckNoError(t, opts.Bind(&args))
+    testutil.CheckNoError(t, applyTuningProfile(&args, argv))
+    return &args
+}
+
+
+// Test functions.
+
+// Without a profile, we keep our own defaults.
+func TestTuningProfileNone(t *testing.T) {
+    args := profiledArgs(t, "rbd", "run", "--ceph-key", "key", "mon")
+    testutil.CheckBool(t, true, args.Workers == 1.0)
+    testutil.CheckInt(t, 5, args.RampUp)
+}
+
+
+// A profile sets the options that weren't given, in any of their forms, and leaves the rest alone.
+func TestTuningProfileAuto(t *testing.T) {
+    args := profiledArgs(t, "rbd", "run", "--ceph-key", "key", "--profile", "auto", "mon")
+    testutil.CheckBool(t, true, args.Workers == 2.0)
+    testutil.CheckInt(t, 10, args.RampUp)
+    testutil.CheckInt(t, 1, args.QueueDepth)
+
+    args = profiledArgs(t, "rbd", "run", "--ceph-key", "key", "--profile", "auto", "-w", "1.0", "--ramp-up=3", "mon")
+    testutil.CheckBool(t, true, args.Workers == 1.0)
+    testutil.CheckInt(t, 3, args.RampUp)
+
+    args = profiledArgs(t, "s3", "run", "--profile", "auto", "--s3-access-key", "key", "--s3-secret-key", "secret", "--workers", "3", "gw")
+    testutil.CheckBool(t, true, args.Workers == 3.0)
+    testutil.CheckInt(t, 4, args.QueueDepth)
+}
+
+
+// A profile doesn't add anything which would turn a good command line into a bad one.
+func TestTuningProfileConflicts(t *testing.T) {
+    s3 := []string{ "s3", "run", "--profile", "auto", "--s3-access-key", "key", "--s3-secret-key", "secret" }
+    testutil.CheckNoError(t, validateCommandLine(t, append(s3, "--read-ahead", "4", "gw")...))
+    testutil.CheckNoError(t, validateCommandLine(t, append(s3, "--connection-reuse", "false", "gw")...))
+    testutil.CheckNoError(t, validateCommandLine(t, "rbd", "run", "--ceph-key", "key", "--profile", "auto", "-r", "8", "mon"))
+    testutil.CheckInt(t, 5, profiledArgs(t, "rbd", "run", "--ceph-key", "key", "--profile", "auto", "-r", "8", "mon").RampUp)
+
+    testutil.CheckError(t, validateS3Run(t, "--profile", "fast"))
+}
@@ -6,6 +6,7 @@
 package main
 
 import "syscall"
+import "time"
 
 
 type FileDescriptor int
@@ -47,6 +48,11 @@ func (fd FileDescriptor) Pwrite(p []byte, offset int64) (int, error) {
 }
 
 
+func (fd FileDescriptor) Sync() error {
+	return syscall.Fsync(int(fd))
+}
+
+
 func (fd FileDescriptor) Close() error {
 	if fd != 0 {
 		return syscall.Close(int(fd))
@@ -59,3 +65,18 @@ func (fd FileDescriptor) Close() error {
 func Unmount(path string, flags int) error {
 	return syscall.Unmount(path, flags)
 }
+
+
+/*
+ * Returns the total CPU time (user and system) consumed by this process so far, or 0 if we are
+ * unable to determine it.
+ */
+func GetProcessCpuTime() time.Duration {
+	var usage syscall.Rusage
+
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
@@ -9,6 +9,7 @@ import "logger"
 import "os/exec"
 import "runtime"
 import "syscall"
+import "golang.org/x/sys/unix"
 
 
 func Open(path string, mode int, perm uint32) (FileDescriptor, error) {
@@ -28,6 +29,17 @@ func Open(path string, mode int, perm uint32) (FileDescriptor, error) {
 }
 
 
+/* As Open, but writes are not durable until the file descriptor is synced. */
+func OpenUnsynced(path string, mode int, perm uint32) (FileDescriptor, error) {
+	fd, err := syscall.Open(path, mode, perm)
+	if err != nil {
+		return -1, err
+	}
+
+	return FileDescriptor(fd), nil
+}
+
+
 func Mount(source string, target string, fstype string, flags uintptr, data string) error {
 	var out bytes.Buffer
 
@@ -53,10 +65,54 @@ func NewRbdConnection(target string, protocol ProtocolConfig, worker WorkerConne
 }
 
 
+/* We don't link against the Ceph libraries here, so we have no versions to report. */
+func CephLibraryVersions() (string, string) {
+	return "", ""
+}
+
+
 /*
  * Returns the number of bytes of physical memory in the system, or 0 if we are unable to determine it.
+ * Our callers fall back to their own defaults when we return 0.
  */
 func GetPhysicalMemorySize() uint64 {
-    // XXX Need to work this out on a Mac!
-    return 0
+    size, err := unix.SysctlUint64("hw.memsize")
+    if err != nil {
+        logger.Warnf("Unable to determine physical memory size: %v\n", err)
+        return 0
+    }
+
+    return size
+}
+
+
+/*
+ * Returns the resident set size of this process in bytes, or 0 if we are unable to determine it.
+ */
+func GetProcessRss() uint64 {
+    // XXX This is the peak RSS rather than the current value, as that's all getrusage gives us.
+    var usage syscall.Rusage
+
+    if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+        return 0
+    }
+
+    // Unlike Linux, MacOS reports maxrss in bytes.
+    return uint64(usage.Maxrss)
+}
+
+
+/*
+ * Locks the calling goroutine to its OS thread, and pins that thread to a single CPU.
+ */
+func PinThreadToCpu(cpu int) error {
+    return fmt.Errorf("pinning workers to CPUs is not supported on %q", runtime.GOOS)
+}
+
+
+/*
+ * Returns the CPUs that we may run on.
+ */
+func GetAllowedCpus() ([]int, error) {
+    return nil, fmt.Errorf("pinning workers to CPUs is not supported on %q", runtime.GOOS)
 }
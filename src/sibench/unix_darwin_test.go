@@ -0,0 +1,16 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the MacOS-specific system queries.
+
+package main
+
+import "testing"
+import "silib/testutil"
+
+
+// Test functions.
+
+func TestGetPhysicalMemorySize(t *testing.T) {
+    testutil.CheckBool(t, true, GetPhysicalMemorySize() > 0)
+}
@@ -3,7 +3,13 @@
 
 package main
 
+import "os"
+import "path/filepath"
+import "runtime"
+import "strconv"
+import "strings"
 import "syscall"
+import "golang.org/x/sys/unix"
 
 
 func Open(path string, mode int, perm uint32) (FileDescriptor, error) {
@@ -13,13 +19,68 @@ func Open(path string, mode int, perm uint32) (FileDescriptor, error) {
 }
 
 
+/* As Open, but writes are not durable until the file descriptor is synced. */
+func OpenUnsynced(path string, mode int, perm uint32) (FileDescriptor, error) {
+	fd, err := syscall.Open(path, mode|syscall.O_DIRECT, perm)
+
+	return FileDescriptor(fd), err
+}
+
+
+/*
+ * The generic mount options, which mount(8) turns into flags rather than passing them on to the
+ * filesystem.  Those which are the default have no flag, and are just dropped.
+ */
+var genericMountOptions = map[string]uintptr {
+	"ro": syscall.MS_RDONLY,
+	"rw": 0,
+	"noatime": syscall.MS_NOATIME,
+	"atime": 0,
+	"nodiratime": syscall.MS_NODIRATIME,
+	"diratime": 0,
+	"relatime": syscall.MS_RELATIME,
+	"strictatime": syscall.MS_STRICTATIME,
+	"nosuid": syscall.MS_NOSUID,
+	"suid": 0,
+	"nodev": syscall.MS_NODEV,
+	"dev": 0,
+	"noexec": syscall.MS_NOEXEC,
+	"exec": 0,
+	"sync": syscall.MS_SYNCHRONOUS,
+	"async": 0,
+	"dirsync": syscall.MS_DIRSYNC,
+}
+
+
+/* Mount a filesystem, with any generic options in the data turned into flags, since the filesystem won't understand them. */
 func Mount(source string, target string, fstype string, flags uintptr, data string) error {
-	return syscall.Mount(source, target, fstype, flags, data)
+	extraFlags, data := splitMountOptions(data)
+	return syscall.Mount(source, target, fstype, flags|extraFlags, data)
+}
+
+
+/* Split the generic options out of a comma-separated list of mount options, returning their flags and the rest of the list. */
+func splitMountOptions(options string) (uintptr, string) {
+	var flags uintptr
+	var rest []string
+
+	for _, option := range strings.Split(options, ",") {
+		flag, ok := genericMountOptions[option]
+		if ok {
+			flags |= flag
+		} else if option != "" {
+			rest = append(rest, option)
+		}
+	}
+
+	return flags, strings.Join(rest, ",")
 }
 
 
 /*
  * Returns the number of bytes of physical memory in the system, or 0 if we are unable to determine it.
+ * If we are in a cgroup with a smaller memory limit, as we will be in a container, then we return
+ * that instead, since it is all that we can use before we are killed.
  */
 func GetPhysicalMemorySize() uint64 {
 	info := &syscall.Sysinfo_t{}
@@ -28,6 +89,149 @@ func GetPhysicalMemorySize() uint64 {
 		return 0
 	}
 
+	limit := getCgroupMemoryLimit()
+	if (limit > 0) && (limit < info.Totalram) {
+		return limit
+	}
+
 	return info.Totalram
 }
 
+
+/*
+ * Returns the memory limit of the cgroup we are in, or 0 if we are unable to find one.
+ */
+func getCgroupMemoryLimit() uint64 {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return 0
+	}
+
+	for _, path := range cgroupMemoryLimitPaths(string(data)) {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		limit, ok := parseCgroupMemoryLimit(string(contents))
+		if ok {
+			return limit
+		}
+	}
+
+	return 0
+}
+
+
+/*
+ * Returns the files which may hold our cgroup's memory limit, in the order we should try them,
+ * given the contents of /proc/self/cgroup.  Cgroup v2 keeps the limit in memory.max, and v1 in
+ * memory.limit_in_bytes under the memory controller.
+ *
+ * Without a cgroup namespace, a container sees the path of its cgroup on the host, but has its own
+ * cgroup mounted at the root of /sys/fs/cgroup, so we try the root as well.
+ */
+func cgroupMemoryLimitPaths(procCgroup string) []string {
+	var v2, v1 []string
+
+	for _, line := range strings.Split(procCgroup, "\n") {
+		// Each line is "hierarchy-id:controllers:path", with no controllers for v2.
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		if fields[1] == "" {
+			v2 = append(v2, filepath.Join("/sys/fs/cgroup", fields[2], "memory.max"), "/sys/fs/cgroup/memory.max")
+			continue
+		}
+
+		for _, controller := range strings.Split(fields[1], ",") {
+			if controller == "memory" {
+				v1 = append(v1, filepath.Join("/sys/fs/cgroup/memory", fields[2], "memory.limit_in_bytes"), "/sys/fs/cgroup/memory/memory.limit_in_bytes")
+			}
+		}
+	}
+
+	return append(v2, v1...)
+}
+
+
+/*
+ * Parses the contents of a cgroup memory limit file, returning false if there is no limit.  V2 says
+ * "max" for no limit, and v1 gives a huge number, which is no smaller than the system's RAM.
+ */
+func parseCgroupMemoryLimit(contents string) (uint64, bool) {
+	limit, err := strconv.ParseUint(strings.TrimSpace(contents), 10, 64)
+	if (err != nil) || (limit == 0) {
+		return 0, false
+	}
+
+	return limit, true
+}
+
+
+/*
+ * Returns the resident set size of this process in bytes, or 0 if we are unable to determine it.
+ */
+func GetProcessRss() uint64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+
+	// The second field is the number of resident pages.
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return pages * uint64(os.Getpagesize())
+}
+
+
+/*
+ * Locks the calling goroutine to its OS thread, and pins that thread to a single CPU.
+ */
+func PinThreadToCpu(cpu int) error {
+	runtime.LockOSThread()
+
+	var set unix.CPUSet
+	set.Set(cpu)
+
+	err := unix.SchedSetaffinity(0, &set)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+
+	return nil
+}
+
+
+/*
+ * Returns the CPUs that we may run on, which may be fewer than the machine has, and not numbered
+ * from zero, if we are in a cpuset, as we will be in a container.
+ */
+func GetAllowedCpus() ([]int, error) {
+	var set unix.CPUSet
+
+	err := unix.SchedGetaffinity(0, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	var cpus []int
+	for cpu := 0; len(cpus) < set.Count(); cpu++ {
+		if set.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	return cpus, nil
+}
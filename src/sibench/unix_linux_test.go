@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for the Linux-specific system queries.
+
+package main
+
+import "runtime"
+import "syscall"
+import "testing"
+import "silib/testutil"
+
+
+// Test functions.
+
+func TestGetPhysicalMemorySize(t *testing.T) {
+    testutil.CheckBool(t, true, GetPhysicalMemorySize() > 0)
+}
+
+
+// We can always run on at least one CPU, and must be able to pin ourselves to any that we can run on.
+func TestGetAllowedCpus(t *testing.T) {
+    cpus, err := GetAllowedCpus()
+    testutil.CheckNoError(t, err)
+    testutil.CheckBool(t, true, len(cpus) > 0)
+    testutil.CheckBool(t, true, len(cpus) <= runtime.NumCPU())
+
+    // Pinning locks the goroutine to its thread, so we do it on one of its own.
+    pinned := make(chan error)
+    go func() {
+        defer runtime.UnlockOSThread()
+        pinned <- PinThreadToCpu(cpus[len(cpus) - 1])
+    }()
+
+    testutil.CheckNoError(t, <-pinned)
+}
+
+
+// We look for a v2 limit before a v1 limit, both in our own cgroup and at the root.
+func TestCgroupMemoryLimitPaths(t *testing.T) {
+    paths := cgroupMemoryLimitPaths("4:cpu,memory:/docker/abc\n3:cpuset:/\n0::/kubepods/pod1\n")
+    testutil.CheckInt(t, 4, len(paths))
+    testutil.CheckString(t, "/sys/fs/cgroup/kubepods/pod1/memory.max", paths[0])
+    testutil.CheckString(t, "/sys/fs/cgroup/memory.max", paths[1])
+    testutil.CheckString(t, "/sys/fs/cgroup/memory/docker/abc/memory.limit_in_bytes", paths[2])
+    testutil.CheckString(t, "/sys/fs/cgroup/memory/memory.limit_in_bytes", paths[3])
+
+    testutil.CheckInt(t, 0, len(cgroupMemoryLimitPaths("3:cpuset:/\n")))
+}
+
+
+func TestParseCgroupMemoryLimit(t *testing.T) {
+    limit, ok := parseCgroupMemoryLimit("1073741824\n")
+    testutil.CheckBool(t, true, ok)
+    testutil.CheckInt(t, 1073741824, int(limit))
+
+    _, ok = parseCgroupMemoryLimit("max\n")
+    testutil.CheckBool(t, false, ok)
+
+    _, ok = parseCgroupMemoryLimit("")
+    testutil.CheckBool(t, false, ok)
+}
+
+
+// Generic options become flags, and everything else is left for the filesystem, in order.
+func TestSplitMountOptions(t *testing.T) {
+    tests := []struct {
+        options string
+        flags uintptr
+        rest string
+    }{
+        { "", 0, "" },
+        { "name=admin,secret=x", 0, "name=admin,secret=x" },
+        { "name=admin,secret=x,mds_namespace=fs2,noatime", syscall.MS_NOATIME, "name=admin,secret=x,mds_namespace=fs2" },
+        { "ro,vers=4.1,nodev,nosuid,rw", syscall.MS_RDONLY | syscall.MS_NODEV | syscall.MS_NOSUID, "vers=4.1" },
+        { "addr=10.0.0.1,,sync", syscall.MS_SYNCHRONOUS, "addr=10.0.0.1" },
+    }
+
+    for _, test := range tests {
+        flags, rest := splitMountOptions(test.options)
+        testutil.CheckInt(t, int(test.flags), int(flags))
+        testutil.CheckString(t, test.rest, rest)
+    }
+}
@@ -7,6 +7,7 @@ package main
 
 import "fmt"
 import"runtime"
+import "time"
 import "unsafe"
 import "golang.org/x/sys/windows"
 
@@ -15,7 +16,18 @@ type FileDescriptor windows.Handle
 
 
 func Open(path string, mode int, perm uint32) (FileDescriptor, error) {
-	// Copy stdlib windows implementation of this just to add the
+	return openFile(path, mode, perm, windows.FILE_FLAG_WRITE_THROUGH)
+}
+
+
+/* As Open, but writes are not durable until the file descriptor is synced. */
+func OpenUnsynced(path string, mode int, perm uint32) (FileDescriptor, error) {
+	return openFile(path, mode, perm, 0)
+}
+
+
+func openFile(path string, mode int, perm uint32, flags uint32) (FileDescriptor, error) {
+	// Copy stdlib windows implementation of this so that we can add the
 	// FILE_FLAG_WRITE_THROUGH flag. See windows.Open (syscall_windows.go) for original code.
 	//
 	// https://docs.microsoft.com/en-US/windows/win32/api/fileapi/nf-fileapi-createfilea
@@ -80,8 +92,8 @@ func Open(path string, mode int, perm uint32) (FileDescriptor, error) {
 		attrs = windows.FILE_ATTRIBUTE_READONLY
 	}
 
-	// mix in the O_SYNC like 0x80000000
-	fd, err := windows.CreateFile(pathp, access, sharemode, &sa, createmode, attrs|windows.FILE_FLAG_WRITE_THROUGH, 0)
+	// mix in the O_SYNC like 0x80000000, unless we have been asked not to
+	fd, err := windows.CreateFile(pathp, access, sharemode, &sa, createmode, attrs|flags, 0)
 
 	return FileDescriptor(fd), err
 }
@@ -140,6 +152,11 @@ func (fd FileDescriptor) Pwrite(p []byte, offset int64) (int, error) {
 }
 
 
+func (fd FileDescriptor) Sync() error {
+	return windows.FlushFileBuffers(windows.Handle(fd))
+}
+
+
 func (fd FileDescriptor) Close() error {
 	return windows.Close(windows.Handle(fd))
 }
@@ -165,11 +182,57 @@ func NewRbdConnection(target string, protocol ProtocolConfig, worker WorkerConne
 }
 
 
+/* We don't link against the Ceph libraries here, so we have no versions to report. */
+func CephLibraryVersions() (string, string) {
+	return "", ""
+}
+
+
 /*
  * Returns the number of bytes of physical memory in the system, or 0 if we are unable to determine it.
  */
 func GetPhysicalMemorySize() uint64 {
+    var status windows.MemoryStatusEx
+    status.Length = uint32(unsafe.Sizeof(status))
+
+    err := windows.GlobalMemoryStatusEx(&status)
+    if err != nil {
+        return 0
+    }
+
+    return status.TotalPhys
+}
+
+
+/*
+ * Returns the total CPU time consumed by this process so far, or 0 if we are unable to determine it.
+ */
+func GetProcessCpuTime() time.Duration {
     // XXX Need to work this out on Windows!
     return 0
 }
 
+
+/*
+ * Returns the resident set size of this process in bytes, or 0 if we are unable to determine it.
+ */
+func GetProcessRss() uint64 {
+    // XXX Need to work this out on Windows!
+    return 0
+}
+
+
+/*
+ * Locks the calling goroutine to its OS thread, and pins that thread to a single CPU.
+ */
+func PinThreadToCpu(cpu int) error {
+    return fmt.Errorf("pinning workers to CPUs is not supported on %q", runtime.GOOS)
+}
+
+
+/*
+ * Returns the CPUs that we may run on.
+ */
+func GetAllowedCpus() ([]int, error) {
+    return nil, fmt.Errorf("pinning workers to CPUs is not supported on %q", runtime.GOOS)
+}
@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests that our messages survive both of the wire formats, and benchmarks of what they cost.
+
+package main
+
+import "bytes"
+import "comms"
+import "reflect"
+import "testing"
+import "time"
+import "silib/testutil"
+
+
+// Helper functions.
+
+var wireFormats = []string{ comms.WireFormatGob, comms.WireFormatJSON }
+
+
+// Make an encoder for the given format, which sends into a buffer that it then receives from.
+func makeLoopbackEncoder(format string) (comms.Encoder, *bytes.Buffer) {
+    comms.SetWireFormat(format)
+    defer comms.SetWireFormat(comms.WireFormatGob)
+
+    var buf bytes.Buffer
+    return comms.MakeEncoderFactory().Make(&buf), &buf
+}
+
+
+// Send a message through an encoder and back out again.
+func roundTrip(t *testing.T, encoder comms.Encoder, op Opcode, in interface{}, out interface{}) {
+    t.Helper()
+
+    err := encoder.Send(uint8(op), in)
+    testutil.CheckNoError(t, err)
+
+    msg, err := encoder.Receive()
+    testutil.CheckNoError(t, err)
+    testutil.CheckInt(t, int(op), int(msg.ID()))
+
+    msg.Data(out)
+}
+
+
+func makeTestStats(count int) []Stat {
+    stats := make([]Stat, count)
+    for i := range stats {
+        stats[i] = Stat{
+            Phase: StatPhase(i % int(SP_Len)),
+            Error: StatError(i % 3),
+            TargetIndex: uint16(i % 7),
+            WorkerIndex: uint16(i % 11),
+            TimeSincePhaseStartMillis: uint32(i / 10),
+            DurationMicros: uint32(1000 + i % 5000),
+            Size: uint32(4096 * (1 + i % 256)) }
+    }
+
+    return stats
+}
+
+
+// Test functions.
+
+// A batch of stats must come back exactly as it was sent.
+func TestWireFormatStats(t *testing.T) {
+    for _, format := range wireFormats {
+        encoder, _ := makeLoopbackEncoder(format)
+
+        in := makeTestStats(10000)
+        var out []Stat
+        roundTrip(t, encoder, OP_StatDetails, in, &out)
+
+        testutil.CheckInt(t, len(in), len(out))
+        testutil.CheckBool(t, true, reflect.DeepEqual(in, out))
+    }
+}
+
+
+// A work order, with all of its optional parts filled in, must come back exactly as it was sent.
+func TestWireFormatWorkOrder(t *testing.T) {
+    in := WorkOrder{
+        JobId: 7,
+        Bandwidth: 1000000,
+        WorkerFactor: 1.5,
+        ReadWriteMix: 30,
+        ConnectionReuse: true,
+        HotSetSize: 100,
+        StatUploadWindow: 10,
+        MaxConcurrencyPerTarget: 2,
+        AppendObjects: 2,
+        VerifyOnly: true,
+        ThinkTime: &ThinkTime{ Min: time.Millisecond, Max: 2 * time.Millisecond },
+        QueueDepth: 4,
+        Churn: 0.1,
+        RampShape: "linear",
+        RampUp: 5,
+        ObjectKeyPrefix: "prefix",
+        ObjectKeyScheme: "hashed",
+        ObjectSize: 1024 * 1024,
+        SizeMix: &SizeMix{ Sizes: []uint64{ 4096, 1024 * 1024 }, Cumulative: []float64{ 0.5, 1 } },
+        PadSize: 64 * 1024,
+        ReadRange: &ReadRange{ Offset: 4096, Length: 8192 },
+        Seed: 12345,
+        GeneratorType: "prng",
+        RangeStart: 100,
+        RangeEnd: 200,
+        ConnectionType: "s3",
+        Targets: []string{ "a", "b" },
+        TargetWeights: []uint64{ 1, 3 },
+        TargetSizes: []uint64{ 4096, 64 * 1024 },
+        ProtocolConfig: ProtocolConfig{ "access_key": "key" },
+        GeneratorConfig: GeneratorConfig{ "dir": "/tmp" },
+        CleanUpOnClose: true }
+
+    for _, format := range wireFormats {
+        encoder, _ := makeLoopbackEncoder(format)
+
+        var out WorkOrder
+        roundTrip(t, encoder, OP_Connect, &in, &out)
+
+        testutil.CheckBool(t, true, reflect.DeepEqual(in, out))
+    }
+}
+
+
+// A response must come back with its verify failures, and an error response with none.
+func TestWireFormatVerifyFailures(t *testing.T) {
+    in := ForemanGenericResponse{ VerifyFailures: []VerifyFailure{ { Id: 7, Target: "a", Error: "Buffers do not match" } } }
+
+    for _, format := range wireFormats {
+        encoder, _ := makeLoopbackEncoder(format)
+
+        var out ForemanGenericResponse
+        roundTrip(t, encoder, OP_ReadStop, &in, &out)
+        testutil.CheckBool(t, true, reflect.DeepEqual(in, out))
+
+        var empty ForemanGenericResponse
+        roundTrip(t, encoder, OP_ReadStop, &ForemanGenericResponse{ Error: "bad" }, &empty)
+        testutil.CheckString(t, "bad", empty.Error)
+        testutil.CheckInt(t, 0, len(empty.VerifyFailures))
+    }
+}
+
+
+// A connect response must come back with the ranges that the server gave its workers.
+func TestWireFormatWorkerRanges(t *testing.T) {
+    in := ForemanGenericResponse{ WorkerRanges: []ObjectRange{ { 0, 50 }, { 50, 100 } } }
+
+    for _, format := range wireFormats {
+        encoder, _ := makeLoopbackEncoder(format)
+
+        var out ForemanGenericResponse
+        roundTrip(t, encoder, OP_Connect, &in, &out)
+        testutil.CheckBool(t, true, reflect.DeepEqual(in, out))
+    }
+}
+
+
+// Unknown formats must be rejected.
+func TestWireFormatBad(t *testing.T) {
+    err := comms.SetWireFormat("xml")
+    testutil.CheckError(t, err)
+}
+
+
+// Benchmark functions.
+
+// Encode and decode a million stats, as one of our larger uploads would, and report the bytes sent.
+func benchmarkWireFormatStats(b *testing.B, format string) {
+    encoder, buf := makeLoopbackEncoder(format)
+    in := makeTestStats(1000 * 1000)
+
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        err := encoder.Send(uint8(OP_StatDetails), in)
+        if err != nil {
+            b.Fatal(err)
+        }
+
+        b.ReportMetric(float64(buf.Len()), "wire-bytes")
+
+        msg, err := encoder.Receive()
+        if err != nil {
+            b.Fatal(err)
+        }
+
+        var out []Stat
+        msg.Data(&out)
+    }
+}
+
+
+func BenchmarkWireFormatStatsGob(b *testing.B) {
+    benchmarkWireFormatStats(b, comms.WireFormatGob)
+}
+
+
+func BenchmarkWireFormatStatsJSON(b *testing.B) {
+    benchmarkWireFormatStats(b, comms.WireFormatJSON)
+}
@@ -4,9 +4,12 @@
 package main
 
 import "comms"
+import "context"
+import "errors"
 import "fmt"
 import "logger"
 import "math/rand"
+import "strings"
 import "time"
 
 
@@ -28,6 +31,14 @@ const (
     WS_ReadWriteDone
     WS_Delete
     WS_DeleteDone
+    WS_BucketOps
+    WS_BucketOpsDone
+    WS_Verify
+    WS_VerifyDone
+    WS_StatOps
+    WS_StatOpsDone
+    WS_HoldConnections
+    WS_HoldConnectionsDone
     WS_Terminated
 )
 
@@ -48,6 +59,14 @@ func workerStateToStr(state workerState) string {
         case WS_ReadWriteDone:  return "ReadWriteDone"
         case WS_Delete:         return "Delete"
         case WS_DeleteDone:     return "DeleteDone"
+        case WS_BucketOps:      return "BucketOps"
+        case WS_BucketOpsDone:  return "BucketOpsDone"
+        case WS_Verify:         return "Verify"
+        case WS_VerifyDone:     return "VerifyDone"
+        case WS_StatOps:        return "StatOps"
+        case WS_StatOpsDone:    return "StatOpsDone"
+        case WS_HoldConnections:        return "HoldConnections"
+        case WS_HoldConnectionsDone:    return "HoldConnectionsDone"
         case WS_Terminated:     return "Terminated"
         default:                return "Unknown WorkerState"
     }
@@ -102,6 +121,14 @@ func init() {
         WS_ReadWriteDone:  { false,        false,      OP_ReadWriteStop,   nil,        nil              },
         WS_Delete:         { true,         true,       OP_None,            onDelete,   onDeleteEvent    },
         WS_DeleteDone:     { false,        false,      OP_Delete,          nil,        nil              },
+        WS_BucketOps:      { true,         true,       OP_BucketOpsStart,  nil,        onBucketOpsEvent },
+        WS_BucketOpsDone:  { false,        false,      OP_BucketOpsStop,   nil,        nil              },
+        WS_Verify:         { true,         true,       OP_None,            onVerify,   onVerifyEvent    },
+        WS_VerifyDone:     { false,        false,      OP_Verify,          nil,        nil              },
+        WS_StatOps:        { true,         true,       OP_StatOpsStart,    nil,        onStatOpsEvent   },
+        WS_StatOpsDone:    { false,        false,      OP_StatOpsStop,     nil,        nil              },
+        WS_HoldConnections:     { true,    true,       OP_HoldConnectionsStart,  nil,                    onHoldConnectionsEvent },
+        WS_HoldConnectionsDone: { false,   false,      OP_HoldConnectionsStop,   onHoldConnectionsDone,  nil                    },
         WS_Terminated:     { false,        false,      OP_Terminate,       nil,        nil              },
     }
 }
@@ -120,12 +147,24 @@ var validWSTransitions = map[Opcode]map[workerState]workerState {
     OP_WriteStop:       { WS_Write:          WS_WriteDone },
     OP_Prepare:         { WS_ConnectDone:    WS_Prepare,
                           WS_WriteDone:      WS_Prepare },
-    OP_ReadStart:       { WS_PrepareDone:    WS_Read },
+    OP_ReadStart:       { WS_PrepareDone:    WS_Read,
+                          WS_StatOpsDone:    WS_Read,
+                          WS_VerifyDone:     WS_Read },
     OP_ReadStop:        { WS_Read:           WS_ReadDone },
     OP_ReadWriteStart:  { WS_PrepareDone:    WS_ReadWrite },
     OP_ReadWriteStop:   { WS_ReadWrite:      WS_ReadWriteDone },
-    OP_Delete:          { WS_ReadDone:       WS_Delete,
-                          WS_ReadWriteDone:  WS_Delete },
+    OP_Delete:          { WS_WriteDone:      WS_Delete,
+                          WS_ReadDone:       WS_Delete,
+                          WS_ReadWriteDone:  WS_Delete,
+                          WS_VerifyDone:     WS_Delete },
+    OP_BucketOpsStart:  { WS_ConnectDone:    WS_BucketOps },
+    OP_BucketOpsStop:   { WS_BucketOps:      WS_BucketOpsDone },
+    OP_Verify:          { WS_PrepareDone:    WS_Verify },
+    OP_StatOpsStart:    { WS_PrepareDone:    WS_StatOps,
+                          WS_VerifyDone:     WS_StatOps },
+    OP_StatOpsStop:     { WS_StatOps:        WS_StatOpsDone },
+    OP_HoldConnectionsStart:  { WS_ConnectDone:       WS_HoldConnections },
+    OP_HoldConnectionsStop:   { WS_HoldConnections:   WS_HoldConnectionsDone },
     OP_Terminate:       { WS_Init:           WS_Terminated,
                           WS_Connect:        WS_Terminated,
                           WS_ConnectDone:    WS_Terminated,
@@ -139,6 +178,14 @@ var validWSTransitions = map[Opcode]map[workerState]workerState {
                           WS_ReadWriteDone:  WS_Terminated,
                           WS_Delete:         WS_Terminated,
                           WS_DeleteDone:     WS_Terminated,
+                          WS_BucketOps:      WS_Terminated,
+                          WS_BucketOpsDone:  WS_Terminated,
+                          WS_Verify:         WS_Terminated,
+                          WS_VerifyDone:     WS_Terminated,
+                          WS_StatOps:        WS_Terminated,
+                          WS_StatOpsDone:    WS_Terminated,
+                          WS_HoldConnections:     WS_Terminated,
+                          WS_HoldConnectionsDone: WS_Terminated,
                           WS_Terminated:     WS_Terminated },
 }
 
@@ -153,6 +200,14 @@ const (
 )
 
 
+/*
+ * The most verification failures that each worker will list in each response.  They are all
+ * counted in our stats, but if something is badly wrong (such as the wrong seed), then listing
+ * every object would make for a huge response.
+ */
+const maxVerifyFailuresPerWorker = 1000
+
+
 
 /*
  * WorkerResponse reports the error from an opcode, which is nil if the opcode succeeded. 
@@ -161,6 +216,7 @@ type WorkerResponse struct {
     WorkerId uint64
     Op Opcode
     Error error
+    VerifyFailures []VerifyFailure  // The objects which failed verification since our last response.
 }
 
 
@@ -182,6 +238,7 @@ type WorkerSpec struct {
     ResponseChannel chan<- *WorkerResponse
     SummaryChannel chan<- WorkerSummary
     StatPreallocationCount uint64
+    TargetLimiter *TargetLimiter    // Shared between all of a foreman's workers.  Nil for no limit.
 }
 
 
@@ -195,7 +252,13 @@ type Worker struct {
     generator Generator
     connections []Connection
     connIndex uint64
+    reference Connection        // If we verify against a reference target, our connection to it.  Otherwise nil.
+    connSchedule []uint64       // The order in which we use our connections, weighted by target.
+    connScheduleIndex int
+    hotIndex uint64             // The next object to read from our hot set (if we have one).
+    readOrder []int             // If we read in random order, the offsets from RangeStart in the order we read them.
     phaseStart time.Time
+    phaseOpsLeft uint64         // How many more ops we may start in this phase, or Uncapped.
     objectBuffer []byte
     verifyBuffer []byte
     lastSummary time.Time
@@ -205,12 +268,55 @@ type Worker struct {
     statSliceIndex int
     statLastSliceIndex int
 
+    /* If we are verifying overwrites or ranged reads, the cycle in which we last wrote each object
+       in our range (indexed from RangeStart), or AnyCycle if we don't know.  Otherwise nil. */
+    objectCycles []uint64
+
+    /* If we are modelling churn, the objects which we have deleted during the read phase, and which
+       reads must therefore avoid.  Otherwise nil. */
+    churned map[uint64]bool
+
     /* These fields are used for the bandwidth-limiting delays code */
 
     phaseFirstOp bool           // Whether this is the first op since we started a phase.
-    lastOpStart time.Time       // The start time of our last read or write
-    avgElapsed time.Duration    // Our running average operation time.
-    postDelay time.Duration     // A delay we need to insert after the next op completes.
+    bandwidthLimiter *TokenBucket // Nil if we have no bandwidth limit.
+    thinkUntil time.Time        // If we have a think time, when we may start our next op.
+    burstOpsLeft uint64         // If we run in bursts, how many more ops we start before we next idle.
+
+    /* This field is used when we pin our goroutines to a CPU */
+
+    cpu int                     // The CPU our goroutines run on, if our order asks us to pin them.
+
+    /* These fields are used when we have more than one op in flight: see op_queue.go */
+
+    laneOps chan *workerOp      // Ops for our lanes to perform.
+    completions chan *workerOp  // Ops that our lanes have completed.
+    inFlight uint64             // How many ops we have handed to our lanes, and not yet completed.
+    inFlightIds map[uint64]bool // The objects of the ops that are in flight.
+    readBuffers [][]byte        // With read ahead, the buffers which no read in flight is using.
+
+    /* These fields are used for benchmarking bucket operations */
+
+    bucketPrefix string         // The prefix for the names of the buckets we create.
+    bucketIndex uint64          // Used to give each bucket we create a new name.
+    buckets []string            // The buckets we have created and not yet deleted, oldest first.
+
+    /* This field is used for benchmarking appends to shared objects */
+
+    appendIndex uint64          // The next shared object we will append to.
+
+    /* This field is used for verification passes */
+
+    verifyFailures []VerifyFailure  // The objects which have failed verification.
+
+    /* This field is used when holding idle connections: see hold_connections.go */
+
+    held []*heldConnection      // The connections we have opened and are holding, in the order we opened them.
+
+    /* These fields are used to abandon any ops still in flight when we terminate */
+
+    ctx context.Context         // The context we give our connections for each op.
+    cancel context.CancelFunc   // Cancels ctx.  This is safe to call from any goroutine.
 }
 
 
@@ -221,12 +327,42 @@ func NewWorker(spec *WorkerSpec, order *WorkOrder) (*Worker, error) {
     w.spec = *spec
     w.order = *order
     w.objectIndex = order.RangeStart
+    w.phaseOpsLeft = Uncapped
+    w.ctx, w.cancel = context.WithCancel(context.Background())
+    w.connSchedule = buildConnSchedule(len(order.Targets), order.TargetWeights)
+    w.connIndex = w.connSchedule[0]
     w.setState(WS_Init)
 
-    w.objectBuffer = make([]byte, w.order.ObjectSize)
-    w.verifyBuffer = make([]byte, w.order.ObjectSize)
+    w.objectBuffer = make([]byte, w.order.PaddedSize(w.order.ObjectSize))
+    w.verifyBuffer = make([]byte, w.order.PaddedSize(w.order.ObjectSize))
     w.summary.workerId = spec.Id
 
+    // A ranged read may not include the object's header, so we can only verify it if we know
+    // which cycle the object was written in.
+    if order.VerifyOverwrites || ((order.ReadRange != nil) && !order.SkipReadValidation) {
+        w.objectCycles = make([]uint64, order.RangeEnd - order.RangeStart)
+        for i, _ := range w.objectCycles {
+            w.objectCycles[i] = AnyCycle
+        }
+    }
+
+    if order.Churn > 0 {
+        w.churned = make(map[uint64]bool)
+    }
+
+    if order.ReadRandom && (order.RangeEnd > order.RangeStart) {
+        w.readOrder = buildReadOrder(order.Seed, order.RangeStart, order.RangeEnd)
+    }
+
+    // Start each worker on a different shared object, so that appends are spread across them.
+    if order.AppendObjects > 0 {
+        w.appendIndex = spec.Id % order.AppendObjects
+    }
+
+    // Bucket names must be lower case.  Our range isn't enough to make them unique, since workers
+    // can have empty ranges, so we add a random tag.
+    w.bucketPrefix = fmt.Sprintf("%v-%08x", strings.ToLower(order.ObjectKeyPrefix), rand.Uint32())
+
     w.stats = make([][]Stat, 0, 100)
     w.stats = append(w.stats, make([]Stat, w.spec.StatPreallocationCount))
     w.clearStats()
@@ -238,18 +374,66 @@ func NewWorker(spec *WorkerSpec, order *WorkOrder) (*Worker, error) {
         return nil, err
     }
 
-    // Start the worker's event loop
-    go w.eventLoop()
+    if order.Bandwidth > 0 {
+        w.bandwidthLimiter = NewTokenBucket(order.Bandwidth, order.ObjectSize)
+    }
+
+    // Spread our workers across the CPUs we may use, so that each one has a CPU to itself when we can.
+    if order.PinWorkers {
+        cpus, err := GetAllowedCpus()
+        if err != nil {
+            logger.Errorf("[worker %v] failure finding the cpus we may use: %v\n", spec.Id, err)
+            return nil, err
+        }
+
+        w.cpu = cpus[spec.Id % uint64(len(cpus))]
+    }
+
+    w.startLanes()
+
+    // Start the worker's event loop.  Pinning has to be done by the goroutine that is to be pinned,
+    // so we wait for the event loop to tell us how that went.
+    started := make(chan error)
+    go w.eventLoop(started)
+
+    err = <-started
+    if err != nil {
+        w.stopLanes()
+        logger.Errorf("[worker %v] failure pinning to cpu %v: %v\n", spec.Id, w.cpu, err)
+        return nil, err
+    }
 
     return &w, nil
 }
 
 
-func (w *Worker) eventLoop() {
+/*
+ * Cancel any ops we have in flight, and any we start from now on.  Our foreman calls this from its
+ * own goroutine when it terminates us, since an op that is stuck waiting on its backend would
+ * otherwise keep us from ever seeing the terminate opcode.
+ */
+func (w *Worker) Cancel() {
+    w.cancel()
+}
+
+
+func (w *Worker) eventLoop(started chan<- error) {
+    if w.order.PinWorkers {
+        err := PinThreadToCpu(w.cpu)
+        if err != nil {
+            started <- err
+            return
+        }
+    }
+
+    started <- nil
+
     for w.state != WS_Terminated {
         select {
             case op := <-w.spec.OpChannel: w.handleOpcode(op)
 
+            case op := <-w.completions: w.completeLaneOp(op)
+
             default:
                 fn := wsDetails[w.state].onEventLoop
                 if fn != nil {
@@ -260,9 +444,18 @@ func (w *Worker) eventLoop() {
 
     logger.Debugf("[worker %v] shutting down\n", w.spec.Id)
 
+    w.stopLanes()
+    w.deleteBuckets()
+    w.closeHeldConnections()
+
     for _, conn := range w.connections {
         conn.WorkerClose(w.order.CleanUpOnClose)
     }
+
+    // The reference target's objects are never ours to clean up.
+    if w.reference != nil {
+        w.reference.WorkerClose(false)
+    }
 }
 
 
@@ -281,6 +474,9 @@ func (w *Worker) handleOpcode(op Opcode) {
 
 
 func (w *Worker) setState(state workerState) {
+    // Any ops still in flight belong to the state we are leaving.
+    w.drainOps()
+
     logger.Debugf("[worker %v] changing state: %v -> %v\n", w.spec.Id, workerStateToStr(w.state), workerStateToStr(state))
     w.state = state
 
@@ -299,7 +495,10 @@ func (w *Worker) setState(state workerState) {
     // If we're starting a new phase, then clear our stats and set suitable flags.
     if wsDetails[state].isStartOfPhase {
         w.phaseFirstOp = true
+        w.thinkUntil = time.Time{}
+        w.burstOpsLeft = w.order.BurstOps
         w.phaseStart = time.Now()
+        w.phaseOpsLeft = w.order.PhaseCaps.For(wsDetails[state].opcodeOnEntry)
         w.lastSummary = w.phaseStart
         w.summary.data.Zero()
     }
@@ -333,24 +532,61 @@ func onConnect(w *Worker) {
             return
         }
 
+        if _, ok := conn.(FlushConnection); w.order.ExplicitFlush && !ok {
+            w.fail(fmt.Errorf("[worker %v] connection to %v does not support explicit flushes", w.spec.Id, t))
+            return
+        }
+
         logger.Tracef("[worker %v] completed connect to %v\n", w.spec.Id, t)
         w.connections = append(w.connections, conn)
     }
 
+    if w.order.ReferenceTarget != "" {
+        // The reference target may be on another cluster, with its own bucket, pool or credentials.
+        protocol := w.order.ReferenceProtocolConfig
+        if protocol == nil {
+            protocol = w.order.ProtocolConfig
+        }
+
+        conn, err := NewConnection(w.order.ConnectionType, w.order.ReferenceTarget, protocol, w.spec.ConnConfig)
+        if err == nil {
+            err = conn.WorkerConnect()
+        }
+
+        if err != nil {
+            w.fail(fmt.Errorf("[worker %v] failure during connect to reference target %v: %v", w.spec.Id, w.order.ReferenceTarget, err))
+            return
+        }
+
+        w.reference = conn
+    }
+
     logger.Debugf("[worker %v] successfully connected\n", w.spec.Id)
     w.setState(WS_ConnectDone)
 }
 
 
 func onWriteEvent(w *Worker) {
-    w.limitBandwidth()
-    w.writeOrPrepare(SP_Write)
+    if w.thinking() || w.capReached() {
+        return
+    }
+
+    if w.order.AppendObjects > 0 {
+        w.appendToSharedObject()
+    } else {
+        w.writeOrPrepare(SP_Write)
+    }
+
+    w.startThinking()
 }
 
 
 func onPrepareEvent(w *Worker) {
-    // See if we've prepared a whole cycle of objects.
-    if w.cycle > 0 {
+    // See if we've prepared a whole cycle of objects - or, if we have a hot set, all of those.
+    // With no-write, an earlier run has already prepared them for us.
+    hotSetReady := (w.order.HotSetSize > 0) && (w.objectIndex >= w.order.RangeStart + w.order.HotSetSize)
+
+    if (w.cycle > 0) || hotSetReady || w.order.NoWrite {
         logger.Debugf("[worker %v] finished preparing\n", w.spec.Id)
         w.invalidateConnectionCaches()
         w.setState(WS_PrepareDone)
@@ -361,56 +597,178 @@ func onPrepareEvent(w *Worker) {
 }
 
 
+/* Return the size of an object, which depends on its id if we are using a size mix or per-target sizes. */
+func (w *Worker) objectSize(id uint64) uint64 {
+    switch {
+        case w.order.SizeMix != nil:        return w.order.SizeMix.SizeFor(w.order.Seed, id)
+        case w.order.TargetSizes != nil:    return w.order.TargetSizes[w.connFor(id)]
+        default:                            return w.order.ObjectSize
+    }
+}
+
+
 func onReadEvent(w *Worker) {
-    w.limitBandwidth()
+    if w.thinking() || w.capReached() {
+        return
+    }
 
-    conn := w.connections[w.connIndex]
+    // Now and then, delete an object instead, if we are modelling churn.
+    if (w.state == WS_Read) && (w.order.Churn > 0) && (rand.Float64() < w.order.Churn) && w.churnObject() {
+        w.nextConnection()
+        w.startThinking()
+        return
+    }
 
-    var key string
-    if conn.RequiresKey() {
-        key = fmt.Sprintf("%v-%v", w.order.ObjectKeyPrefix, w.objectIndex)
+    id := w.nextReadObject()
+
+    op := w.newOp(SP_Read, id)
+    op.length = op.size
+    if w.order.ReadRange != nil {
+        op.offset, op.length = w.order.ReadRange.Choose(op.size)
     }
 
-    logger.Tracef("[worker %v] starting get for object<%v> on %v\n", w.spec.Id, w.objectIndex, conn.Target())
+    op.cycle = AnyCycle
+    w.limitBandwidth(op.length)
+    w.runOp(op)
 
-    start := time.Now()
-    err := conn.GetObject(key, w.objectIndex, w.objectBuffer)
-    end := time.Now()
+    // Advance our object ID ready for next time.  We don't do this with a hot set, since it has its
+    // own index (and we don't want to invalidate caches that it is trying to keep warm).
+    if w.order.HotSetSize == 0 {
+        w.advanceReadIndex()
+    }
 
-    logger.Tracef("[worker %v] completed get for object<%v> on %v\n", w.spec.Id, w.objectIndex, conn.Target())
+    // Advance our connection index ready for next time
+    w.nextConnection()
+    w.startThinking()
+}
 
-    s := w.nextStat()
-    s.Error = SE_None
-    s.Phase = SP_Read
-    s.TimeSincePhaseStartMillis = uint32(start.Sub(w.phaseStart) / (1000 * 1000))
-    s.DurationMicros = uint32(end.Sub(start) / 1000)
-    s.TargetIndex = uint16(w.connIndex)
 
-    if err != nil {
-        logger.Warnf("[worker %v] failure getting object<%v> to %v: %v\n", w.spec.Id, w.objectIndex, conn.Target(), err)
-        s.Error = SE_OperationFailure
-    } else {
-        if !w.order.SkipReadValidation {
-            err = w.generator.Verify(w.order.ObjectSize, w.objectIndex, &w.objectBuffer, &w.verifyBuffer)
-            if err != nil {
-                logger.Warnf("[worker %v] failure verfiying object<%v> to %v: %v\n", w.spec.Id, w.objectIndex, conn.Target(), err)
-                s.Error = SE_VerifyFailure
-            }
-        }
+/*
+ * Check that an object exists, without reading it, so that we can benchmark metadata ops on their
+ * own.  We work through the same objects as the read phase would.
+ */
+func onStatOpsEvent(w *Worker) {
+    if w.thinking() || w.capReached() {
+        return
     }
 
-    w.summary.data[SP_Read][s.Error]++
-    w.sendSummary(&end, true)
+    id := w.nextReadObject()
+    w.runOp(w.newOp(SP_Stat, id))
 
-    // Advance our object ID ready for next time.
+    if w.order.HotSetSize == 0 {
+        w.advanceReadIndex()
+    }
+
+    w.nextConnection()
+    w.startThinking()
+}
+
+
+func (w *Worker) advanceReadIndex() {
     w.objectIndex++
     if w.objectIndex >= w.order.RangeEnd {
         w.objectIndex = w.order.RangeStart
         w.invalidateConnectionCaches()
     }
+}
 
-    // Advance our connection index ready for next time
-    w.connIndex = (w.connIndex + 1) % uint64(len(w.connections))
+
+/* Return the id of the next object to read in our read order, skipping any that we have deleted to model churn. */
+func (w *Worker) nextReadObject() uint64 {
+    for {
+        id := w.objectIndex
+        if w.readOrder != nil {
+            id = w.order.RangeStart + uint64(w.readOrder[w.objectIndex - w.order.RangeStart])
+        }
+
+        if w.order.HotSetSize > 0 {
+            id = w.nextHotObject()
+        } else if w.churned[id] {
+            w.advanceReadIndex()
+        }
+
+        if !w.churned[id] {
+            return id
+        }
+    }
+}
+
+
+/* Return how many objects our reads can reach: either our hot set, or our whole range. */
+func (w *Worker) readableObjects() uint64 {
+    count := w.order.RangeEnd - w.order.RangeStart
+    if (w.order.HotSetSize > 0) && (w.order.HotSetSize < count) {
+        count = w.order.HotSetSize
+    }
+
+    return count
+}
+
+
+/*
+ * Delete a random object from those that our reads can reach, to model the background deletes
+ * (from garbage collection, lifecycle rules and so on) that real read workloads see.
+ *
+ * We never delete more than half of the objects, so that reads always have plenty left to
+ * choose from.  Returns false if we didn't delete anything.
+ */
+func (w *Worker) churnObject() bool {
+    count := w.readableObjects()
+    if (uint64(len(w.churned)) + 1) * 2 > count {
+        return false
+    }
+
+    id := w.order.RangeStart + uint64(rand.Int63n(int64(count)))
+    for w.churned[id] {
+        id = w.order.RangeStart + uint64(rand.Int63n(int64(count)))
+    }
+
+    // Mark the object before the delete starts, so that we don't pick it for a read in the meantime.
+    w.churned[id] = true
+    w.runOp(w.newOp(SP_ChurnDelete, id))
+    return true
+}
+
+
+func onVerify(w *Worker) {
+    w.objectIndex = w.order.RangeStart
+    w.verifyFailures = nil
+}
+
+
+/*
+ * Read each object in our range once, in order, and check its content.  Unlike the read phase, we
+ * always verify, even if read verification has been turned off, and we stop at the end of the range.
+ *
+ * When prewarming, we make the same pass to fill the caches before the read phase, but as reads
+ * rather than as a verification pass: we don't analyse them, and they only verify if reads do.
+ */
+func onVerifyEvent(w *Worker) {
+    // A consistency check's verification pass only reads its sample, so skip on to the next one.
+    if w.order.VerifyOnly && (w.order.ConsistencyStride != 0) {
+        stride := w.order.ConsistencyStride
+        w.objectIndex = (w.objectIndex + stride - 1) / stride * stride
+    }
+
+    if w.objectIndex >= w.order.RangeEnd {
+        logger.Debugf("[worker %v] finished verifying with %v failures\n", w.spec.Id, len(w.verifyFailures))
+        w.setState(WS_VerifyDone)
+        return
+    }
+
+    phase := SP_Verify
+    if w.order.PrewarmReads {
+        phase = SP_Prewarm
+    }
+
+    op := w.newOp(phase, w.objectIndex)
+    op.length = op.size
+    op.cycle = AnyCycle
+    w.limitBandwidth(op.length)
+    w.runOp(op)
+
+    w.objectIndex++
+    w.nextConnection()
 }
 
 
@@ -423,24 +781,150 @@ func onReadWriteEvent(w *Worker) {
 }
 
 
+/*
+ * Create or delete a bucket.  We create buckets until we have as many as we were asked to keep,
+ * and then alternate between deleting the oldest one and creating a new one.
+ */
+func onBucketOpsEvent(w *Worker) {
+    if w.thinking() {
+        return
+    }
+
+    conn := w.connections[w.connIndex]
+    bc, ok := conn.(BucketConnection)
+    if !ok {
+        w.fail(fmt.Errorf("[worker %v] connection to %v does not support bucket operations", w.spec.Id, conn.Target()))
+        return
+    }
+
+    phase := SP_BucketCreate
+    bucket := ""
+
+    if uint64(len(w.buckets)) < w.order.BucketOps {
+        bucket = fmt.Sprintf("%v-%v", w.bucketPrefix, w.bucketIndex)
+        w.bucketIndex++
+    } else {
+        phase = SP_BucketDelete
+        bucket = w.buckets[0]
+        w.buckets = w.buckets[1:]
+    }
+
+    logger.Tracef("[worker %v] starting %v for bucket %v on %v\n", w.spec.Id, phase.ToString(), bucket, conn.Target())
+
+    w.spec.TargetLimiter.Acquire(w.connIndex)
+    start := time.Now()
+    err := w.reconnect(conn)
+    if err == nil {
+        if phase == SP_BucketCreate {
+            err = bc.CreateBucket(bucket)
+        } else {
+            err = bc.DeleteBucket(bucket)
+        }
+    }
+    end := time.Now()
+    w.spec.TargetLimiter.Release(w.connIndex)
+
+    logger.Tracef("[worker %v] completed %v for bucket %v on %v\n", w.spec.Id, phase.ToString(), bucket, conn.Target())
+
+    s := w.nextStat()
+    s.Error = SE_None
+    s.Phase = phase
+    s.TimeSincePhaseStartMillis = uint32(start.Sub(w.phaseStart) / (1000 * 1000))
+    s.DurationMicros = uint32(end.Sub(start) / 1000)
+    s.TargetIndex = uint16(w.connIndex)
+
+    if err != nil {
+        // If a delete fails, we forget about the bucket rather than retrying it forever.
+        logger.Warnf("[worker %v] failure in %v for bucket %v on %v: %v\n", w.spec.Id, phase.ToString(), bucket, conn.Target(), err)
+        s.Error = w.errorType(err)
+    } else if phase == SP_BucketCreate {
+        w.buckets = append(w.buckets, bucket)
+    }
+
+    w.summary.data[phase][s.Error]++
+    w.sendSummary(&end, true)
+
+    w.nextConnection()
+    w.startThinking()
+}
+
+
+/* Delete any buckets left over from benchmarking bucket operations. */
+func (w *Worker) deleteBuckets() {
+    if (len(w.buckets) == 0) || (len(w.connections) == 0) {
+        return
+    }
+
+    bc, ok := w.connections[0].(BucketConnection)
+    if !ok {
+        return
+    }
+
+    for _, bucket := range w.buckets {
+        err := bc.DeleteBucket(bucket)
+        if err != nil {
+            logger.Warnf("[worker %v] failure deleting bucket %v: %v\n", w.spec.Id, bucket, err)
+        }
+    }
+
+    w.buckets = nil
+}
+
+
 func onDelete(w *Worker) {
     w.objectIndex = w.order.RangeStart
 }
 
 
+/*
+ * Return the end of the range of objects that we delete.  When benchmarking appends, the only
+ * objects are the shared ones, which the workers whose ranges they fall in delete.
+ */
+func (w *Worker) deleteEnd() uint64 {
+    if (w.order.AppendObjects > 0) && (w.order.AppendObjects < w.order.RangeEnd) {
+        return w.order.AppendObjects
+    }
+
+    return w.order.RangeEnd
+}
+
+
 func onDeleteEvent(w *Worker) {
-    conn := w.connections[w.connIndex]
+    // When benchmarking appends, most workers have no shared objects in their range.
+    if w.objectIndex >= w.deleteEnd() {
+        logger.Tracef("[worker %v] all objects deleted\n", w.spec.Id)
+        w.setState(WS_DeleteDone)
+        return
+    }
+
+    // Skip any objects that we have already deleted to model churn.
+    for w.churned[w.objectIndex] {
+        w.objectIndex++
+        if w.objectIndex >= w.deleteEnd() {
+            logger.Tracef("[worker %v] all objects deleted\n", w.spec.Id)
+            w.setState(WS_DeleteDone)
+            return
+        }
+    }
+
+    connIndex := w.connFor(w.objectIndex)
+    conn := w.connections[connIndex]
 
     var key string
     if conn.RequiresKey() {
-        key = fmt.Sprintf("%v-%v", w.order.ObjectKeyPrefix, w.objectIndex)
+        key = ObjectKey(w.order.ObjectKeyScheme, w.order.ObjectKeyPrefix, w.objectIndex)
     }
 
     logger.Tracef("[worker %v] starting delete for object<%v> on %v at %v\n", w.spec.Id, w.objectIndex, conn.Target(), time.Now())
 
+    w.spec.TargetLimiter.Acquire(connIndex)
     start := time.Now()
-    err := conn.DeleteObject(key, w.objectIndex)
+    err := w.reconnect(conn)
+    if err == nil {
+        err = conn.DeleteObject(w.ctx, key, w.objectIndex)
+    }
     end := time.Now()
+    w.spec.TargetLimiter.Release(connIndex)
 
     logger.Tracef("[worker %v] completed delete for object<%v> on %v\n", w.spec.Id, w.objectIndex, conn.Target())
 
@@ -449,11 +933,12 @@ func onDeleteEvent(w *Worker) {
     s.Phase = SP_Delete
     s.TimeSincePhaseStartMillis = uint32(start.Sub(w.phaseStart) / (1000 * 1000))
     s.DurationMicros = uint32(end.Sub(start) / 1000)
-    s.TargetIndex = uint16(w.connIndex)
+    s.TargetIndex = uint16(connIndex)
+    s.Size = statSize(w.objectSize(w.objectIndex))
 
     if err != nil {
         logger.Warnf("[worker %v] failure deleting object<%v> from %v: %v\n", w.spec.Id, w.objectIndex, conn.Target(), err)
-        s.Error = SE_OperationFailure
+        s.Error = w.errorType(err)
     }
 
     w.summary.data[SP_Delete][s.Error]++
@@ -461,69 +946,349 @@ func onDeleteEvent(w *Worker) {
 
     // Advance our object ID ready for next time.
     w.objectIndex++
-    if w.objectIndex >= w.order.RangeEnd {
+    if w.objectIndex >= w.deleteEnd() {
         logger.Tracef("[worker %v] all objects deleted\n", w.spec.Id)
         w.setState(WS_DeleteDone)
         return
     }
 
     // Advance our connection index ready for next time
-    w.connIndex = (w.connIndex + 1) % uint64(len(w.connections))
+    w.nextConnection()
 }
 
 
 
 func (w *Worker) writeOrPrepare(phase StatPhase) {
-    w.generator.Generate(w.order.ObjectSize, w.objectIndex, w.cycle, &w.objectBuffer)
+    op := w.newOp(phase, w.objectIndex)
+    op.length = op.size
+    op.cycle = w.cycle
+    if phase == SP_Write {
+        w.limitBandwidth(op.length)
+    }
+
+    w.runOp(op)
+
+    // Advance our object ID ready for next time.
+    w.objectIndex++
+    if w.objectIndex >= w.order.RangeEnd {
+        w.objectIndex = w.order.RangeStart
+        w.cycle++
+        logger.Tracef("[worker %v] advancing cycle to %v\n", w.spec.Id, w.cycle)
+    }
+
+    // Advance our connection index ready for next time
+    w.nextConnection()
+}
+
+
+/*
+ * Append to one of the objects that every worker shares, when we are benchmarking appends.  These
+ * are the objects with the lowest ids, so that the delete phase can clean them up.
+ */
+func (w *Worker) appendToSharedObject() {
     conn := w.connections[w.connIndex]
+    if _, ok := conn.(AppendConnection); !ok {
+        w.fail(fmt.Errorf("[worker %v] connection to %v does not support appends", w.spec.Id, conn.Target()))
+        return
+    }
 
-    var key string
-    if conn.RequiresKey() {
-        key = fmt.Sprintf("%v-%v", w.order.ObjectKeyPrefix, w.objectIndex)
+    op := w.newOp(SP_Append, w.appendIndex)
+    op.length = op.size
+    w.limitBandwidth(op.length)
+    w.runOp(op)
+
+    w.appendIndex = (w.appendIndex + 1) % w.order.AppendObjects
+    w.nextConnection()
+}
+
+
+/* Create an op for an object, using our current connection unless the object has its own. */
+func (w *Worker) newOp(phase StatPhase, id uint64) *workerOp {
+    connIndex := w.connFor(id)
+    op := &workerOp{ phase: phase, id: id, connIndex: connIndex, conn: w.connections[connIndex] }
+    op.size = w.objectSize(id)
+    op.padded = w.order.PaddedSize(op.size)
+
+    if op.conn.RequiresKey() {
+        op.key = ObjectKey(w.order.ObjectKeyScheme, w.order.ObjectKeyPrefix, id)
     }
 
-    logger.Tracef("[worker %v] starting put for object<%v> on %v at %v\n", w.spec.Id, w.objectIndex, conn.Target(), time.Now())
+    return op
+}
 
-    start := time.Now()
-    err := conn.PutObject(key, w.objectIndex, w.objectBuffer)
-    end := time.Now()
 
-    logger.Tracef("[worker %v] completed put for object<%v> on %v\n", w.spec.Id, w.objectIndex, conn.Target())
+/*
+ * If we are tracking cycles, then work out which cycle a read should expect, or note that we
+ * can't be sure which cycle an object holds whilst a put is in flight.  This is done just
+ * before an op starts, since an earlier op on the same object may complete whilst we wait.
+ */
+func (w *Worker) startOpCycle(op *workerOp) {
+    if w.objectCycles == nil {
+        return
+    }
 
+    if (op.phase == SP_Read) || (op.phase == SP_Verify) || (op.phase == SP_Prewarm) {
+        op.cycle = w.objectCycles[op.id - w.order.RangeStart]
+    } else {
+        w.objectCycles[op.id - w.order.RangeStart] = AnyCycle
+    }
+}
+
+
+/* Record the results of a read or write, once it has completed. */
+func (w *Worker) completeOp(op *workerOp) {
     s := w.nextStat()
     s.Error = SE_None
-    s.Phase = phase
-    s.TimeSincePhaseStartMillis = uint32(start.Sub(w.phaseStart) / (1000 * 1000))
-    s.DurationMicros = uint32(end.Sub(start) / 1000)
-    s.TargetIndex = uint16(w.connIndex)
+    s.Phase = op.phase
+    s.TimeSincePhaseStartMillis = uint32(op.start.Sub(w.phaseStart) / (1000 * 1000))
+    s.DurationMicros = uint32(op.end.Sub(op.start) / 1000)
+    s.TargetIndex = uint16(op.connIndex)
+    s.Size = statSize(op.length)
+    s.Retries = uint8(op.retries)
+
+    // Connections which can't cancel a slow op still fail it once it completes, if it took too long.
+    if op.err == nil {
+        op.err = w.opTimeoutError(op.start, op.end)
+    }
+
+    // Only the write phase times its flushes as ops of their own.  Anywhere else, a failed flush fails the write.
+    if (op.err == nil) && (op.phase != SP_Write) {
+        op.err = op.flushErr
+    }
+
+    switch {
+        case (op.err != nil) && ((op.phase == SP_Read) || (op.phase == SP_Verify) || (op.phase == SP_Prewarm)):
+            logger.Warnf("[worker %v] failure getting object<%v> to %v: %v\n", w.spec.Id, op.id, op.conn.Target(), op.err)
+            s.Error = w.errorType(op.err)
+
+        case (op.err != nil) && (op.phase == SP_Append):
+            logger.Warnf("[worker %v] failure appending to object<%v> on %v: %v\n", w.spec.Id, op.id, op.conn.Target(), op.err)
+            s.Error = w.errorType(op.err)
+
+        case (op.err != nil) && (op.phase == SP_Stat):
+            logger.Warnf("[worker %v] failure statting object<%v> on %v: %v\n", w.spec.Id, op.id, op.conn.Target(), op.err)
+            s.Error = w.errorType(op.err)
+
+        case (op.err != nil) && (op.phase == SP_ChurnDelete):
+            logger.Warnf("[worker %v] failure deleting object<%v> from %v: %v\n", w.spec.Id, op.id, op.conn.Target(), op.err)
+            s.Error = w.errorType(op.err)
+
+        case op.err != nil:
+            logger.Warnf("[worker %v] failure putting object<%v> to %v: %v\n", w.spec.Id, op.id, op.conn.Target(), op.err)
+            s.Error = w.errorType(op.err)
+
+        case op.verifyErr != nil:
+            logger.Warnf("[worker %v] failure verfiying object<%v> to %v: %v\n", w.spec.Id, op.id, op.conn.Target(), op.verifyErr)
+            s.Error = SE_VerifyFailure
+    }
+
+    // We report which objects failed verification, and not just how many.  In a verification pass,
+    // an object which we can't read at all has failed too.
+    isVerifyFailure := (s.Error == SE_VerifyFailure) || ((op.phase == SP_Verify) && (s.Error != SE_None))
+    if isVerifyFailure && (len(w.verifyFailures) < maxVerifyFailuresPerWorker) {
+        err := op.err
+        if err == nil {
+            err = op.verifyErr
+        }
+
+        f := VerifyFailure{ Id: op.id, Target: w.order.Targets[op.connIndex], Error: strings.TrimSpace(err.Error()) }
+        w.verifyFailures = append(w.verifyFailures, f)
+    }
+
+    // Remember which cycle the object now holds, and that it exists again if we had churned it.
+    if ((op.phase == SP_Write) || (op.phase == SP_Prepare)) && (op.err == nil) {
+        if w.objectCycles != nil {
+            w.objectCycles[op.id - w.order.RangeStart] = op.cycle
+        }
+
+        delete(w.churned, op.id)
+    }
+
+    w.summary.data[op.phase][s.Error]++
+
+    if (op.phase == SP_Write) && !op.flushStart.IsZero() {
+        w.completeFlush(op)
+    }
+
+    w.sendSummary(&op.end, true)
+}
+
+
+/* Record the flush which followed a write, as an op of its own. */
+func (w *Worker) completeFlush(op *workerOp) {
+    s := w.nextStat()
+    s.Error = SE_None
+    s.Phase = SP_Flush
+    s.TimeSincePhaseStartMillis = uint32(op.flushStart.Sub(w.phaseStart) / (1000 * 1000))
+    s.DurationMicros = uint32(op.flushEnd.Sub(op.flushStart) / 1000)
+    s.TargetIndex = uint16(op.connIndex)
+    s.Size = statSize(op.length)
+
+    err := op.flushErr
+    if err == nil {
+        err = w.opTimeoutError(op.flushStart, op.flushEnd)
+    }
 
     if err != nil {
-        logger.Warnf("[worker %v] failure putting object<%v> to %v: %v\n", w.spec.Id, w.objectIndex, conn.Target(), err)
-        s.Error = SE_OperationFailure
+        logger.Warnf("[worker %v] failure flushing object<%v> to %v: %v\n", w.spec.Id, op.id, op.conn.Target(), err)
+        s.Error = w.errorType(err)
     }
 
-    w.summary.data[phase][s.Error]++
-    w.sendSummary(&end, true)
+    w.summary.data[SP_Flush][s.Error]++
+}
 
-    // Advance our object ID ready for next time.
-    w.objectIndex++
-    if w.objectIndex >= w.order.RangeEnd {
-        w.objectIndex = w.order.RangeStart
-        w.cycle++
-        logger.Tracef("[worker %v] advancing cycle to %v\n", w.spec.Id, w.cycle)
+
+/* Returns an error if an op took longer than our op timeout, or nil if it didn't (or we have none). */
+func (w *Worker) opTimeoutError(start time.Time, end time.Time) error {
+    timeout := time.Duration(w.order.OpTimeoutMillis) * time.Millisecond
+    if (timeout == 0) || (end.Sub(start) <= timeout) {
+        return nil
     }
 
-    // Advance our connection index ready for next time
-    w.connIndex = (w.connIndex + 1) % uint64(len(w.connections))
+    return fmt.Errorf("Op took %v, which is longer than the op timeout of %v", end.Sub(start), timeout)
 }
 
 
-/* 
- * Sleep in order to limit bandwidth 
+/* If we have a think time, then pick how long to pause before our next op. */
+func (w *Worker) startThinking() {
+    if w.order.ThinkTime != nil {
+        w.thinkUntil = time.Now().Add(w.order.ThinkTime.Next())
+    }
+
+    w.countBurstOp()
+}
+
+
+/*
+ * If we run in bursts, then count the op we just started towards our current burst.  Once the
+ * burst is over, we wait for its ops to complete, so that nothing is in flight whilst we idle, and
+ * then idle for the burst idle time in place of any think time.  This sits above the ops themselves,
+ * so every timed phase runs in bursts, whatever its ops are.
+ */
+func (w *Worker) countBurstOp() {
+    if w.order.BurstOps == 0 {
+        return
+    }
+
+    w.burstOpsLeft--
+    if w.burstOpsLeft > 0 {
+        return
+    }
+
+    w.drainOps()
+    logger.Tracef("[worker %v] finished burst, idling\n", w.spec.Id)
+
+    w.burstOpsLeft = w.order.BurstOps
+    w.thinkUntil = time.Now().Add(time.Duration(w.order.BurstIdleMillis) * time.Millisecond)
+}
+
+
+/*
+ * Returns true if we are still pausing before our next op.
+ *
+ * We sleep in short slices, rather than for the whole pause, so that the event loop can still
+ * handle opcodes.  Our bandwidth limiter keeps refilling whilst we pause, so it only adds
+ * whatever extra delay is still needed.
+ *
+ * We keep sending summaries whilst we pause, even though they are empty, since our foreman takes
+ * a worker which goes quiet for long enough (such as when idling between bursts) to have hung.
+ */
+func (w *Worker) thinking() bool {
+    remaining := time.Until(w.thinkUntil)
+    if remaining <= 0 {
+        return false
+    }
+
+    if remaining > thinkTimeSlice {
+        remaining = thinkTimeSlice
+    }
+
+    time.Sleep(remaining)
+
+    now := time.Now()
+    w.sendSummary(&now, false)
+    return true
+}
+
+
+/*
+ * Return true if we have started as many ops as our share of the phase's cap allows, in which case
+ * we have nothing to do until our foreman ends the phase.  The first time, we wait for our ops to
+ * finish, and then tell our foreman, which also stops it expecting summaries from us (so that it
+ * doesn't think that we have hung).
+ */
+func (w *Worker) capReached() bool {
+    if w.phaseOpsLeft > 0 {
+        return false
+    }
+
+    if w.summary.canTimeout {
+        w.drainOps()
+        logger.Debugf("[worker %v] reached our share of the cap for this phase\n", w.spec.Id)
+
+        w.summary.canTimeout = false
+        now := time.Now()
+        w.sendSummary(&now, true)
+
+        // We don't use sendResponse, since any verify failures belong with the response to the phase's stop.
+        w.spec.ResponseChannel <- &WorkerResponse{ WorkerId: w.spec.Id, Op: OP_PhaseCapped }
+    }
+
+    time.Sleep(thinkTimeSlice)
+    return true
+}
+
+
+/*
+ * Determine which type of failure an error from a connection should be counted as.
+ *
+ * Unless finer-grained HTTP stats have been requested, everything is just an operation failure.
  */
-func (w *Worker) limitBandwidth() {
+func (w *Worker) errorType(err error) StatError {
+    var httpErr HttpStatusError
+
+    if !w.order.HttpErrorStats || !errors.As(err, &httpErr) {
+        return SE_OperationFailure
+    }
+
+    code := httpErr.StatusCode()
+
+    switch {
+        case (code == 429) || (code == 503):  return SE_HttpThrottled
+        case (code >= 400) && (code < 500):   return SE_HttpClientError
+        case (code >= 500) && (code < 600):   return SE_HttpServerError
+        default:                              return SE_OperationFailure
+    }
+}
+
+
+/*
+ * If connection reuse is disabled, then close and re-open a connection before we use it, so that
+ * we measure the cost of establishing connections as well as that of the operations themselves.
+ * This must be called from inside the timed part of an operation.
+ */
+func (w *Worker) reconnect(conn Connection) error {
+    if w.order.ConnectionReuse {
+        return nil
+    }
+
+    err := conn.WorkerClose(false)
+    if err != nil {
+        return err
+    }
+
+    return conn.WorkerConnect()
+}
+
+
+/*
+ * Sleep in order to limit bandwidth, before an op which transfers the given number of bytes.
+ * With a queue depth of more than one, this is called before each op is handed to a lane, so
+ * all of a worker's ops share the same limiter.
+ */
+func (w *Worker) limitBandwidth(bytes uint64) {
     // See if we need to do anything in the first place.
-    if w.order.Bandwidth == 0 {
+    if w.bandwidthLimiter == nil {
         return
     }
 
@@ -533,40 +1298,28 @@ func (w *Worker) limitBandwidth() {
         time.Sleep(time.Duration(rand.Intn(1000 * 1000 * 10)))
 
         w.phaseFirstOp = false
-        w.lastOpStart = time.Now()
-        w.avgElapsed = 0
-        w.postDelay = 0
-        return
+        w.bandwidthLimiter.Reset(w.bandwidthRamp())
     }
 
-    elapsed := time.Now().Sub(w.lastOpStart)
-    time.Sleep(w.postDelay)
-
-    // Compute our rolling average.
-    if w.avgElapsed == 0 {
-        // This is our first completed op, so start the rolling average with this value.
-        w.avgElapsed = elapsed
-    } else {
-        // This is not our first complete op: merge in the new value to our rolling average
-        w.avgElapsed = ((w.avgElapsed * 7) + elapsed) / 8
-    }
+    w.bandwidthLimiter.Take(bytes)
+}
 
-    // Compute how log we would like an op to take to maintain our limited bandwidth.
-    desired := time.Duration(1000 * 1000 * 1000 * w.order.ObjectSize / w.order.Bandwidth)
 
-    // If the desired value is slower than the average value, sleep for a bit.
-    if desired > w.avgElapsed {
-        // Compute the total delay we want
-        totalDelay := desired - w.avgElapsed
+/*
+ * Return how long our bandwidth limit should take to ramp up at the start of the current phase.
+ * We only ramp the timed phases, since those are the only ones with a ramp-up period.
+ */
+func (w *Worker) bandwidthRamp() time.Duration {
+    if w.order.RampShape != "linear" {
+        return 0
+    }
 
-        // Then split it into two parts: a pre-delay that we'll do before the operation, 
-        // and a post-delay that we'll do afterwards (in the interests of traffic smoothing).
-        preDelay := time.Duration(rand.Intn(int(totalDelay)))
-        w.postDelay = totalDelay - preDelay
-        time.Sleep(preDelay)
+    switch w.state {
+        case WS_Write, WS_Read, WS_ReadWrite:
+            return time.Duration(w.order.RampUp) * time.Second
     }
 
-    w.lastOpStart = time.Now()
+    return 0
 }
 
 
@@ -577,7 +1330,10 @@ func (w *Worker) Id() uint64 {
 
 func (w *Worker) sendResponse(op Opcode, err error) {
     logger.Debugf("[worker %v] sending Response: %v, %v\n", w.spec.Id, op.ToString(), err)
-    w.spec.ResponseChannel <- &WorkerResponse{ WorkerId: w.spec.Id, Op: op, Error: err }
+    resp := &WorkerResponse{ WorkerId: w.spec.Id, Op: op, Error: err, VerifyFailures: w.verifyFailures }
+    w.verifyFailures = nil
+
+    w.spec.ResponseChannel <- resp
 }
 
 
@@ -601,6 +1357,99 @@ func (w *Worker) clearStats() {
 }
 
 
+/* Return the id of the next object to read from our hot set, which is the start of our range. */
+func (w *Worker) nextHotObject() uint64 {
+    if w.order.HotSetRandom {
+        return w.order.RangeStart + uint64(rand.Int63n(int64(w.order.HotSetSize)))
+    }
+
+    id := w.order.RangeStart + w.hotIndex
+    w.hotIndex = (w.hotIndex + 1) % w.order.HotSetSize
+    return id
+}
+
+
+/*
+ * Return the connection to use for an object.  Normally that is just our current connection, but
+ * when each target has its own object size, every object belongs to one target, so that it is
+ * always written and read at that target's size.  Objects are shared out using the same weighted
+ * schedule as our connections, so each target still gets its share of the ops.
+ */
+func (w *Worker) connFor(id uint64) uint64 {
+    if w.order.TargetSizes == nil {
+        return w.connIndex
+    }
+
+    return w.connSchedule[id % uint64(len(w.connSchedule))]
+}
+
+
+/* Move on to the next connection in our schedule. */
+func (w *Worker) nextConnection() {
+    w.connScheduleIndex = (w.connScheduleIndex + 1) % len(w.connSchedule)
+    w.connIndex = w.connSchedule[w.connScheduleIndex]
+}
+
+
+/**
+ * Builds the order in which a worker reads its range with --read-order random, as offsets from
+ * rangeStart.  We shuffle the same way every time for a given seed and range, so that runs can be
+ * repeated.
+ */
+func buildReadOrder(seed uint64, rangeStart uint64, rangeEnd uint64) []int {
+    source := rand.NewSource(int64(prng(seed ^ rangeStart)))
+    return rand.New(source).Perm(int(rangeEnd - rangeStart))
+}
+
+
+/**
+ * Builds the order in which a worker should use its connections, so that each target gets a share
+ * of the ops in proportion to its weight.  With no weights, this is just plain round-robin.
+ *
+ * We use a smooth weighted round-robin, which spreads each target's ops out evenly across the
+ * schedule rather than bunching them together: weights of 2 and 1 give [0, 1, 0], not [0, 0, 1].
+ */
+func buildConnSchedule(count int, weights []uint64) []uint64 {
+    if weights == nil {
+        weights = make([]uint64, count)
+        for i := range weights {
+            weights[i] = 1
+        }
+    }
+
+    // Reduce the weights to their smallest equivalent, to keep the schedule short.
+    divisor := weights[0]
+    for _, w := range weights {
+        for b := w; b != 0; {
+            divisor, b = b, divisor % b
+        }
+    }
+
+    total := int64(0)
+    for _, w := range weights {
+        total += int64(w / divisor)
+    }
+
+    result := make([]uint64, 0, total)
+    current := make([]int64, count)
+
+    for len(result) < int(total) {
+        best := 0
+        for i, w := range weights {
+            current[i] += int64(w / divisor)
+            if current[i] > current[best] {
+                best = i
+            }
+        }
+
+        current[best] -= total
+        result = append(result, uint64(best))
+    }
+
+    return result
+}
+
+
 /**
  * Returns a pointer to the next Stat object to fill in when we complete an op.
  *
@@ -609,6 +1458,8 @@ func (w *Worker) clearStats() {
  */
 func (w *Worker) nextStat() *Stat {
     result := &(w.stats[w.statSliceIndex][w.nextStatIndex])
+    result.WorkerIndex = uint16(w.spec.Id)
+    result.Retries = 0
 
     w.nextStatIndex++
     if w.nextStatIndex == len(w.stats[w.statSliceIndex]) {
@@ -626,18 +1477,24 @@ func (w *Worker) nextStat() *Stat {
 
 /**
  * At the end of a phase, the Foreman asks each worker in turn to send their Stats back to the 
- * manager, using a TCP connection that the Foreman provides.
+ * manager, using a TCP connection that the Foreman provides.  The window limits how many
+ * messages we have in flight at any one time.
  *
  * When we're done, we clear our stats so we can reuse them.
  */
-func (w *Worker) UploadStats(tcpConnection *comms.MessageConnection) {
+func (w *Worker) UploadStats(tcpConnection *comms.MessageConnection, window *StatWindow) {
     for i := 0; i <= w.statSliceIndex; i++ {
+        stats := w.stats[i]
         if i != w.statSliceIndex {
             logger.Debugf("[worker %v] sending complete stats buffer: %v entries\n", w.spec.Id, len(w.stats[i]))
-            tcpConnection.Send(OP_StatDetails, w.stats[i])
         } else {
             logger.Debugf("[worker %v] sending partial stats buffer: %v entries\n", w.spec.Id, w.nextStatIndex)
-            tcpConnection.Send(OP_StatDetails, w.stats[i][:w.nextStatIndex])
+            stats = stats[:w.nextStatIndex]
+        }
+
+        if !window.Send(tcpConnection, stats) {
+            logger.Warnf("[worker %v] stats upload aborted\n", w.spec.Id)
+            break
         }
     }
 
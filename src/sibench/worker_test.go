@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2022 SoftIron Limited <info@softiron.com>
+// SPDX-License-Identifier: GNU General Public License v2.0 only WITH Classpath exception 2.0
+
+// Tests for how workers share their ops between targets, the order they read in, and how they pace them.
+
+package main
+
+import "reflect"
+import "testing"
+import "time"
+import "silib/testutil"
+
+
+// Test functions.
+
+// Without weights, each target (such as each of several S3 gateways) gets every nth op in turn.
+func TestConnScheduleRoundRobin(t *testing.T) {
+    testutil.CheckBool(t, true, reflect.DeepEqual([]uint64{ 0 }, buildConnSchedule(1, nil)))
+    testutil.CheckBool(t, true, reflect.DeepEqual([]uint64{ 0, 1, 2 }, buildConnSchedule(3, nil)))
+}
+
+
+// Weights give each target its share of the ops, spread out evenly rather than bunched together.
+func TestConnScheduleWeighted(t *testing.T) {
+    testutil.CheckBool(t, true, reflect.DeepEqual([]uint64{ 0, 1, 0 }, buildConnSchedule(2, []uint64{ 2, 1 })))
+    testutil.CheckBool(t, true, reflect.DeepEqual([]uint64{ 0, 1, 0 }, buildConnSchedule(2, []uint64{ 200, 100 })))
+}
+
+
+// A random read order visits every object in the range exactly once.
+func TestReadOrderIsPermutation(t *testing.T) {
+    order := buildReadOrder(42, 1000, 2000)
+    testutil.CheckInt(t, 1000, len(order))
+
+    seen := make([]bool, len(order))
+    for _, offset := range order {
+        testutil.CheckBool(t, false, seen[offset])
+        seen[offset] = true
+    }
+}
+
+
+// Workers with the same seed and range read in the same order, so that runs can be repeated.
+func TestReadOrderSeeded(t *testing.T) {
+    tests := []struct {
+        seedA uint64
+        seedB uint64
+        start uint64
+        same bool
+    } {
+        { 42, 42, 0, true },
+        { 42, 42, 5000, true },
+        { 42, 43, 0, false },
+        { 42, 43, 5000, false },
+        { 1, 12345, 100, false },
+    }
+
+    for _, test := range tests {
+        a := buildReadOrder(test.seedA, test.start, test.start + 1000)
+        b := buildReadOrder(test.seedB, test.start, test.start + 1000)
+        testutil.CheckBool(t, test.same, reflect.DeepEqual(a, b))
+    }
+
+    // Nor is it just the id order.
+    identity := make([]int, 1000)
+    for i := range identity {
+        identity[i] = i
+    }
+
+    testutil.CheckBool(t, false, reflect.DeepEqual(identity, buildReadOrder(42, 0, 1000)))
+}
+
+
+// A worker idles once it has started a whole burst of ops, and then starts the next burst.
+func TestBurstOps(t *testing.T) {
+    w := &Worker{}
+    w.order.BurstOps = 3
+    w.order.BurstIdleMillis = 5000
+    w.burstOpsLeft = 3
+
+    w.countBurstOp()
+    w.countBurstOp()
+    testutil.CheckBool(t, true, w.thinkUntil.IsZero())
+
+    w.countBurstOp()
+    testutil.CheckBool(t, true, time.Until(w.thinkUntil) > 4 * time.Second)
+    testutil.CheckInt(t, 3, int(w.burstOpsLeft))
+
+    // Without bursts, we never idle.
+    w = &Worker{}
+    w.countBurstOp()
+    testutil.CheckBool(t, true, w.thinkUntil.IsZero())
+}